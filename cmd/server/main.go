@@ -1,19 +1,32 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	"hospital-project/internal/config"
 	"hospital-project/internal/controllers"
+	"hospital-project/internal/events"
+	"hospital-project/internal/hl7"
+	"hospital-project/internal/metrics"
 	"hospital-project/internal/middleware"
+	"hospital-project/internal/ratelimit"
 	"hospital-project/internal/repositories"
 	"hospital-project/internal/services"
+	"hospital-project/internal/tracing"
 )
 
 // @title Hospital Management System API
@@ -37,6 +50,13 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 func main() {
+	// Initialize tracing
+	tracingConfig := config.NewTracingConfig()
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
 	// Initialize database
 	dbConfig := config.NewDatabaseConfig()
 	db, err := dbConfig.Connect()
@@ -44,36 +64,171 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	if err := db.Use(tracing.GORMPlugin{}); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+
 	// Migrate database
 	err = config.MigrateDB(db)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	// Initialize metrics
+	appMetrics := metrics.New()
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database connection pool: %v", err)
+	}
+	appMetrics.RegisterDB(sqlDB, dbConfig.Name)
+
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
 	patientRepo := repositories.NewPatientRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	duplicateRepo := repositories.NewDuplicateRepository(db)
+	mergeRepo := repositories.NewMergeRepository(db)
+	savedSearchRepo := repositories.NewSavedSearchRepository(db)
+	webhookRepo := repositories.NewWebhookRepository(db)
+	idempotencyRepo := repositories.NewIdempotencyRepository(db)
+	healthRepo := repositories.NewHealthRepository(db)
+	transactions := repositories.NewTransactionManager(db)
+	appMetrics.Register(metrics.NewBusinessCollector(patientRepo))
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo)
 	userService := services.NewUserService(userRepo, authService)
-	patientService := services.NewPatientService(patientRepo)
+	webhookService := services.NewWebhookService(webhookRepo, config.NewWebhookConfig())
+	patientService := services.NewPatientService(patientRepo, transactions, config.NewDuplicatesConfig())
+	duplicateService := services.NewDuplicateService(duplicateRepo)
+	mergeService := services.NewMergeService(mergeRepo, transactions, config.NewMergeConfig())
+	savedSearchService := services.NewSavedSearchService(savedSearchRepo, patientService)
+	auditService := services.NewAuditService(auditRepo)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, config.NewIdempotencyConfig())
+	healthService := services.NewHealthService(healthRepo, config.Models())
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(authService)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyService)
+
+	rateLimitConfig := config.NewRateLimitConfig()
+	var rateLimitStore ratelimit.Store
+	var rateLimitSweeper ratelimit.Sweeper
+	if rateLimitConfig.Enabled {
+		switch rateLimitConfig.Store {
+		case config.RateLimitStorePostgres:
+			rateLimitRepo := repositories.NewRateLimitRepository(db)
+			rateLimitStore, rateLimitSweeper = rateLimitRepo, rateLimitRepo
+		case config.RateLimitStoreMemory:
+			rateLimitStore = ratelimit.NewMemoryStore()
+		default:
+			log.Fatalf("Unknown rate limit store %q", rateLimitConfig.Store)
+		}
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore, map[string]ratelimit.Limit{
+		middleware.RateLimitDefault: rateLimitConfig.Default,
+		middleware.RateLimitAuth:    rateLimitConfig.Auth,
+		middleware.RateLimitSearch:  rateLimitConfig.Search,
+	})
 
 	// Initialize controllers
-	authController := controllers.NewAuthController(authService, userService)
-	userController := controllers.NewUserController(userService, authMiddleware)
-	patientController := controllers.NewPatientController(patientService, authMiddleware)
+	healthController := controllers.NewHealthController(healthService)
+	authController := controllers.NewAuthController(authService, userService, appMetrics, rateLimiter)
+	userController := controllers.NewUserController(userService, authMiddleware, rateLimiter, idempotencyMiddleware)
+	patientController := controllers.NewPatientController(patientService, mergeService, auditService, authMiddleware, rateLimiter, idempotencyMiddleware)
+	duplicateController := controllers.NewDuplicateController(duplicateService, authMiddleware, rateLimiter)
+	mergeController := controllers.NewMergeController(mergeService, authMiddleware, rateLimiter, idempotencyMiddleware)
+	savedSearchController := controllers.NewSavedSearchController(savedSearchService, authMiddleware, rateLimiter, idempotencyMiddleware)
+	fhirController := controllers.NewFHIRController(patientService, authMiddleware, rateLimiter, idempotencyMiddleware)
+	webhookController := controllers.NewWebhookController(webhookService, authMiddleware, rateLimiter, idempotencyMiddleware)
 
 	// Initialize router
-	router := gin.Default()
+	serverConfig := config.NewServerConfig()
+	if serverConfig.DrainDelay >= serverConfig.ShutdownTimeout {
+		log.Fatalf("SHUTDOWN_DRAIN_DELAY (%s) must be shorter than SHUTDOWN_TIMEOUT (%s)", serverConfig.DrainDelay, serverConfig.ShutdownTimeout)
+	}
+	router := gin.New()
+	router.Use(gin.Logger(), middleware.Recovery())
+	if err := router.SetTrustedProxies(serverConfig.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	router.Use(otelgin.Middleware(tracingConfig.ServiceName))
+	router.Use(middleware.Metrics(appMetrics))
+	router.Use(middleware.Timeout(serverConfig.RequestTimeout, map[string]time.Duration{
+		"/api/patients/export": serverConfig.ExportTimeout,
+	}))
 
 	// Register routes
+	healthController.RegisterRoutes(router)
 	authController.RegisterRoutes(router)
 	userController.RegisterRoutes(router)
 	patientController.RegisterRoutes(router)
+	duplicateController.RegisterRoutes(router)
+	mergeController.RegisterRoutes(router)
+	savedSearchController.RegisterRoutes(router)
+	fhirController.RegisterRoutes(router)
+	webhookController.RegisterRoutes(router)
+
+	// Background workers run until shutdown cancels their context
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	// Publish domain events from the outbox and deliver queued webhooks
+	bus := events.NewBus()
+	bus.Subscribe("webhooks", webhookService)
+	runWorker(events.NewDispatcher(transactions, bus, config.NewOutboxConfig()).Run)
+	runWorker(webhookService.Run)
+
+	// Purge expired idempotency keys
+	runWorker(idempotencyService.Run)
+
+	// Delete rate limit buckets that have refilled
+	if rateLimitSweeper != nil {
+		runWorker(ratelimit.Sweep(rateLimitSweeper, rateLimitConfig.SweepInterval, rateLimitConfig.LongestWindow()))
+	}
+
+	// Start the HL7 ADT listener
+	var hl7Server *hl7.Server
+	hl7Config := config.NewHL7Config()
+	if hl7Config.Enabled {
+		systemUser, err := userService.GetByUsername(context.Background(), hl7Config.SystemUser)
+		if err != nil {
+			log.Fatalf("Failed to find HL7 system user %q: %v", hl7Config.SystemUser, err)
+		}
+
+		hl7Service := services.NewHL7Service(
+			patientService,
+			mergeService,
+			repositories.NewHL7MessageRepository(db),
+			repositories.NewPatientIdentifierRepository(db),
+			transactions,
+			systemUser.ID,
+		)
+		controllers.NewHL7Controller(hl7Service, authMiddleware, rateLimiter, idempotencyMiddleware).RegisterRoutes(router)
+
+		hl7Server = &hl7.Server{
+			Addr:           hl7Config.Addr,
+			Handler:        hl7Service,
+			IdleTimeout:    hl7Config.IdleTimeout,
+			MessageTimeout: hl7Config.MessageTimeout,
+		}
+		go func() {
+			fmt.Printf("HL7 MLLP listener running on %s\n", hl7Config.Addr)
+			if err := hl7Server.ListenAndServe(); err != nil && !errors.Is(err, hl7.ErrServerClosed) {
+				log.Fatalf("Failed to start HL7 listener: %v", err)
+			}
+		}()
+	}
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(appMetrics.Handler()))
 
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -85,9 +240,74 @@ func main() {
 	}
 
 	// Start server
-	fmt.Printf("Server running on port %s\n", port)
-	err = router.Run(":" + port)
-	if err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           router,
+		ReadTimeout:       serverConfig.ReadTimeout,
+		ReadHeaderTimeout: serverConfig.ReadHeaderTimeout,
+		WriteTimeout:      serverConfig.WriteTimeout,
+		IdleTimeout:       serverConfig.IdleTimeout,
+	}
+	go func() {
+		fmt.Printf("Server running on port %s\n", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Wait for a shutdown signal
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signals.Done()
+	stop()
+
+	log.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+	defer cancel()
+
+	// Keep serving while /readyz reports not ready, until load balancers
+	// have noticed and stopped sending new requests
+	healthService.Drain()
+	time.Sleep(serverConfig.DrainDelay)
+
+	// Stop accepting requests and let in-flight ones finish
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server did not shut down cleanly: %v", err)
+	}
+
+	// Let the HL7 listener finish and acknowledge the messages it is handling
+	if hl7Server != nil {
+		if err := hl7Server.Shutdown(ctx); err != nil {
+			log.Printf("HL7 listener did not shut down cleanly: %v", err)
+		}
+	}
+
+	// Stop the background workers after the last request that could feed them
+	stopWorkers()
+	if err := waitFor(ctx, workers.Wait); err != nil {
+		log.Printf("Background workers did not stop in time: %v", err)
+	}
+
+	sqlDB.Close()
+
+	// Flush the spans of the last requests
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+}
+
+// waitFor runs fn and waits for it to return or ctx to be done
+func waitFor(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
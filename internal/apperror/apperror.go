@@ -0,0 +1,116 @@
+// Package apperror defines the typed errors returned by services and
+// repositories. Each error carries a kind, which decides how it is reported
+// to clients, and a stable machine-readable code.
+package apperror
+
+import "errors"
+
+// Kind classifies a domain error
+type Kind string
+
+const (
+	KindValidation   Kind = "validation"
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindForbidden    Kind = "forbidden"
+	KindUnauthorized Kind = "unauthorized"
+	// KindPreconditionFailed means the resource changed since the client read it
+	KindPreconditionFailed Kind = "precondition_failed"
+	// KindPreconditionRequired means a conditional request header is missing
+	KindPreconditionRequired Kind = "precondition_required"
+	// KindUnsupportedMediaType means the request body has a media type the endpoint does not accept
+	KindUnsupportedMediaType Kind = "unsupported_media_type"
+	// KindUnprocessable means the request is well-formed but cannot be applied
+	KindUnprocessable Kind = "unprocessable"
+	// KindTimeout means the request ran past its deadline
+	KindTimeout Kind = "timeout"
+	// KindClientClosed means the client disconnected before the response was ready
+	KindClientClosed Kind = "client_closed"
+	// KindUnavailable means the service cannot handle requests right now
+	KindUnavailable Kind = "unavailable"
+	// KindTooManyRequests means the client exceeded its rate limit
+	KindTooManyRequests Kind = "too_many_requests"
+	KindInternal        Kind = "internal"
+)
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error with a stable code
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// Error returns the human-readable message
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying cause, if any
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithCause records the error that caused e and returns e
+func (e *Error) WithCause(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// New creates an error of the given kind
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Validation creates an error for invalid input
+func Validation(code, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+// NotFound creates an error for a missing resource
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+// Conflict creates an error for a change that clashes with existing state
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// Forbidden creates an error for an action the caller may not perform
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+// Unauthorized creates an error for a missing or invalid identity
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+// As returns the first *Error in err's chain
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
+
+// KindOf returns the kind of err, or KindInternal for untyped errors
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+// IsNotFound reports whether err is a not found error
+func IsNotFound(err error) bool {
+	return KindOf(err) == KindNotFound
+}
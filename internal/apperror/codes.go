@@ -0,0 +1,54 @@
+package apperror
+
+// Error codes returned to clients. They are part of the API and must not
+// change once released.
+const (
+	CodeInternal           = "internal_error"
+	CodeValidationFailed   = "validation_failed"
+	CodeMalformedBody      = "malformed_body"
+	CodeInvalidID          = "invalid_id"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidToken       = "invalid_token"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeForbidden          = "forbidden"
+	CodeRequestTimeout     = "request_timeout"
+	CodeClientClosed       = "client_closed_request"
+	CodeVersionMismatch    = "version_mismatch"
+	CodeIfMatchRequired    = "if_match_required"
+	CodeNotReady           = "not_ready"
+	CodeInvalidCursor      = "invalid_cursor"
+	CodeInvalidFilter      = "invalid_filter"
+	CodeRateLimited        = "rate_limited"
+
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInvalidPatch         = "invalid_patch"
+	CodePatchTestFailed      = "patch_test_failed"
+
+	CodeInvalidIdempotencyKey    = "invalid_idempotency_key"
+	CodeIdempotencyKeyReused     = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+
+	CodeUserNotFound  = "user_not_found"
+	CodeUsernameTaken = "username_taken"
+
+	CodePatientNotFound  = "patient_not_found"
+	CodePatientDuplicate = "patient_duplicate"
+
+	CodeDuplicateNotFound = "duplicate_not_found"
+	CodeDuplicateReviewed = "duplicate_reviewed"
+
+	CodeMergeNotFound        = "merge_not_found"
+	CodeMergeExpired         = "merge_expired"
+	CodeMergeUndone          = "merge_undone"
+	CodeMergeSurvivorChanged = "merge_survivor_changed"
+
+	CodeSavedSearchNotFound  = "saved_search_not_found"
+	CodeSavedSearchNameTaken = "saved_search_name_taken"
+
+	CodeHL7MessageNotFound        = "hl7_message_not_found"
+	CodeHL7MessageInvalid         = "hl7_message_invalid"
+	CodePatientIdentifierNotFound = "patient_identifier_not_found"
+
+	CodeWebhookNotFound         = "webhook_not_found"
+	CodeWebhookDeliveryNotFound = "webhook_delivery_not_found"
+)
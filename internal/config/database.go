@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -22,6 +25,8 @@ type Database struct {
 	Password string
 	Name     string
 	URL      string
+	// StatementTimeout is the Postgres statement_timeout of every connection
+	StatementTimeout time.Duration
 }
 
 // NewDatabaseConfig creates a new database configuration from environment variables
@@ -39,6 +44,8 @@ func NewDatabaseConfig() *Database {
 		Password: getEnv("DB_PASSWORD", "postgres"),
 		Name:     getEnv("DB_NAME", "hospital"),
 		URL:      getEnv("DB_URL", ""),
+
+		StatementTimeout: getEnvDuration("DB_STATEMENT_TIMEOUT", 10*time.Second),
 	}
 }
 
@@ -67,8 +74,17 @@ func (db *Database) Connect() (*gorm.DB, error) {
 		},
 	)
 
+	// Abort queries that run longer than the statement timeout
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database connection string: %w", err)
+	}
+	if db.StatementTimeout > 0 {
+		connConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(db.StatementTimeout.Milliseconds(), 10)
+	}
+
 	// Connect to database
-	gormDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: stdlib.OpenDB(*connConfig)}), &gorm.Config{
 		Logger: newLogger,
 	})
 	if err != nil {
@@ -88,12 +104,35 @@ func (db *Database) Connect() (*gorm.DB, error) {
 	return gormDB, nil
 }
 
-// MigrateDB performs database migrations
-func MigrateDB(db *gorm.DB) error {
-	return db.AutoMigrate(
+// Models returns every model whose table is managed by MigrateDB
+func Models() []interface{} {
+	return []interface{}{
 		&models.User{},
 		&models.Patient{},
-	)
+		&models.AuditLog{},
+		&models.HL7Message{},
+		&models.PatientIdentifier{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.OutboxEvent{},
+		&models.IdempotencyKey{},
+		&models.RateLimitBucket{},
+		&models.PatientDuplicate{},
+		&models.PatientMerge{},
+		&models.SavedSearch{},
+		&models.SavedSearchPin{},
+	}
+}
+
+// MigrateDB performs database migrations
+func MigrateDB(db *gorm.DB) error {
+	if err := db.AutoMigrate(Models()...); err != nil {
+		return err
+	}
+	if err := migrateSchema(db); err != nil {
+		return err
+	}
+	return backfillMatchKeys(db)
 }
 
 // Helper function to get environment variable with fallback
@@ -0,0 +1,22 @@
+package config
+
+// Duplicates configuration for detecting duplicate patient registrations
+type Duplicates struct {
+	// BlockThreshold is the match score from which a new patient is refused
+	// as a probable duplicate, unless the check is overridden
+	BlockThreshold float64
+	// ReviewThreshold is the match score from which a new patient and the
+	// patient it matches are queued for review
+	ReviewThreshold float64
+	// Candidates is the most existing patients a new one is scored against
+	Candidates int
+}
+
+// NewDuplicatesConfig creates a new duplicate detection configuration from environment variables
+func NewDuplicatesConfig() *Duplicates {
+	return &Duplicates{
+		BlockThreshold:  getEnvFloat("DUPLICATE_BLOCK_THRESHOLD", 0.9),
+		ReviewThreshold: getEnvFloat("DUPLICATE_REVIEW_THRESHOLD", 0.7),
+		Candidates:      getEnvInt("DUPLICATE_CANDIDATES", 50),
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// HL7 configuration for the MLLP listener
+type HL7 struct {
+	Enabled        bool
+	Addr           string
+	SystemUser     string
+	IdleTimeout    time.Duration
+	MessageTimeout time.Duration
+}
+
+// NewHL7Config creates a new HL7 configuration from environment variables
+func NewHL7Config() *HL7 {
+	enabled, _ := strconv.ParseBool(getEnv("HL7_ENABLED", "false"))
+
+	return &HL7{
+		Enabled:        enabled,
+		Addr:           getEnv("HL7_ADDR", ":2575"),
+		SystemUser:     getEnv("HL7_SYSTEM_USER", "admin"),
+		IdleTimeout:    getEnvDuration("HL7_IDLE_TIMEOUT", 5*time.Minute),
+		MessageTimeout: getEnvDuration("HL7_MESSAGE_TIMEOUT", 30*time.Second),
+	}
+}
@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+// Idempotency configuration for Idempotency-Key handling
+type Idempotency struct {
+	// TTL is how long a key and its stored response are kept
+	TTL             time.Duration
+	CleanupInterval time.Duration
+}
+
+// NewIdempotencyConfig creates a new idempotency configuration from environment variables
+func NewIdempotencyConfig() *Idempotency {
+	return &Idempotency{
+		TTL:             getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		CleanupInterval: getEnvDuration("IDEMPOTENCY_CLEANUP_INTERVAL", time.Hour),
+	}
+}
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// Merge configuration for merging duplicate patients
+type Merge struct {
+	// UnmergeWindow is how long after a merge it can be undone
+	UnmergeWindow time.Duration
+}
+
+// NewMergeConfig creates a new merge configuration from environment variables
+func NewMergeConfig() *Merge {
+	return &Merge{
+		UnmergeWindow: getEnvDuration("PATIENT_UNMERGE_WINDOW", 72*time.Hour),
+	}
+}
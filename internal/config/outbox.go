@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// Outbox configuration for the domain event dispatcher
+type Outbox struct {
+	PollInterval time.Duration
+	BatchSize    int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// Retention is how long published events are kept
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+// NewOutboxConfig creates a new outbox configuration from environment variables
+func NewOutboxConfig() *Outbox {
+	return &Outbox{
+		PollInterval:    getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		BatchSize:       getEnvInt("OUTBOX_BATCH_SIZE", 100),
+		BaseBackoff:     getEnvDuration("OUTBOX_BASE_BACKOFF", time.Second),
+		MaxBackoff:      getEnvDuration("OUTBOX_MAX_BACKOFF", 5*time.Minute),
+		Retention:       getEnvDuration("OUTBOX_RETENTION", 7*24*time.Hour),
+		CleanupInterval: getEnvDuration("OUTBOX_CLEANUP_INTERVAL", time.Hour),
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"hospital-project/internal/ratelimit"
+)
+
+// Rate limit stores
+const (
+	// RateLimitStoreMemory keeps buckets in each instance's memory
+	RateLimitStoreMemory = "memory"
+	// RateLimitStorePostgres shares buckets between instances through the database
+	RateLimitStorePostgres = "postgres"
+)
+
+// RateLimit configuration for request rate limiting
+type RateLimit struct {
+	Enabled bool
+	Store   string
+	// Default applies to every authenticated route group
+	Default ratelimit.Limit
+	// Auth applies to login and registration, per client IP
+	Auth ratelimit.Limit
+	// Search applies to the patient search endpoints on top of Default
+	Search ratelimit.Limit
+	// SweepInterval is how often idle buckets are deleted from the store
+	SweepInterval time.Duration
+}
+
+// NewRateLimitConfig creates a new rate limit configuration from environment variables
+func NewRateLimitConfig() *RateLimit {
+	enabled, _ := strconv.ParseBool(getEnv("RATE_LIMIT_ENABLED", "true"))
+
+	return &RateLimit{
+		Enabled:       enabled,
+		Store:         getEnv("RATE_LIMIT_STORE", RateLimitStoreMemory),
+		Default:       getEnvLimit("RATE_LIMIT_DEFAULT", ratelimit.PerPeriod(300, time.Minute)),
+		Auth:          getEnvLimit("RATE_LIMIT_AUTH", ratelimit.PerPeriod(10, time.Minute)),
+		Search:        getEnvLimit("RATE_LIMIT_SEARCH", ratelimit.PerPeriod(60, time.Minute)),
+		SweepInterval: getEnvDuration("RATE_LIMIT_SWEEP_INTERVAL", 10*time.Minute),
+	}
+}
+
+// LongestWindow is the longest time any bucket takes to refill, after which
+// an idle bucket may be deleted
+func (c *RateLimit) LongestWindow() time.Duration {
+	longest := c.Default.Window()
+	for _, limit := range []ratelimit.Limit{c.Auth, c.Search} {
+		if window := limit.Window(); window > longest {
+			longest = window
+		}
+	}
+	return longest
+}
+
+// Helper function to get a rate limit environment variable, such as
+// "100/1m", with fallback
+func getEnvLimit(key string, fallback ratelimit.Limit) ratelimit.Limit {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+	limit, err := ratelimit.Parse(value)
+	if err != nil {
+		log.Printf("config: ignoring %s: %v", key, err)
+		return fallback
+	}
+	return limit
+}
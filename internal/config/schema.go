@@ -0,0 +1,135 @@
+package config
+
+import (
+	"slices"
+
+	"gorm.io/gorm"
+
+	"hospital-project/internal/matching"
+	"hospital-project/internal/models"
+	"hospital-project/internal/phonetic"
+)
+
+// extensions are the PostgreSQL extensions the schema needs (000011).
+// Creating one takes privileges the application does not otherwise need, so
+// they are only created when missing.
+var extensions = []string{"pg_trgm", "unaccent"}
+
+// schema holds the parts of the database schema AutoMigrate cannot express,
+// such as triggers and expression indexes. Each statement mirrors a SQL
+// migration and is cheap to run again on every start: none of them rewrites
+// rows or drops what it replaces.
+var schema = []string{
+	// 000010: full-text search over medical notes. The column is added, and
+	// existing notes indexed, by addNotesSearch.
+	`CREATE OR REPLACE FUNCTION patients_notes_tsv_update() RETURNS trigger AS $$
+BEGIN
+    NEW.notes_tsv := to_tsvector('english', coalesce(NEW.medical_notes, ''));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql`,
+	`CREATE OR REPLACE TRIGGER patients_notes_tsv_trigger BEFORE INSERT OR UPDATE OF medical_notes ON patients
+    FOR EACH ROW EXECUTE FUNCTION patients_notes_tsv_update()`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_notes_tsv ON patients USING GIN (notes_tsv)`,
+
+	// 000011: trigram indexes for fuzzy name and contact info search
+	`CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text AS $$
+    SELECT public.unaccent('public.unaccent'::regdictionary, $1)
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_name_trgm ON patients USING GIN (f_unaccent(name) gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_patients_contact_info_trgm ON patients USING GIN (f_unaccent(contact_info) gin_trgm_ops)`,
+
+	// 000012: phonetic keys of patient names, added by AutoMigrate and
+	// filled in by backfillMatchKeys
+	`CREATE INDEX IF NOT EXISTS idx_patients_name_phonetic ON patients USING GIN (string_to_array(name_phonetic, ' '))`,
+
+	// 000013: duplicate detection. The birth date, address and contact keys
+	// of patients and the review queue are added by AutoMigrate, and the
+	// keys filled in by backfillMatchKeys.
+	`CREATE INDEX IF NOT EXISTS idx_patients_contact_keys ON patients USING GIN (string_to_array(contact_keys, ' '))`,
+}
+
+// migrateSchema creates the missing extensions and the notes search column,
+// then applies the statements of schema in order
+func migrateSchema(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := createExtensions(tx); err != nil {
+			return err
+		}
+		if err := addNotesSearch(tx); err != nil {
+			return err
+		}
+		for _, statement := range schema {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// createExtensions creates the extensions that are not installed yet
+func createExtensions(db *gorm.DB) error {
+	var installed []string
+	if err := db.Raw("SELECT extname FROM pg_extension WHERE extname IN ?", extensions).Scan(&installed).Error; err != nil {
+		return err
+	}
+	for _, extension := range extensions {
+		if slices.Contains(installed, extension) {
+			continue
+		}
+		if err := db.Exec("CREATE EXTENSION IF NOT EXISTS " + extension).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addNotesSearch adds the full-text search vector of medical notes and
+// indexes the notes of existing patients. It does nothing once the column
+// exists, as the trigger keeps the vector up to date from then on.
+func addNotesSearch(db *gorm.DB) error {
+	if db.Migrator().HasColumn(&models.Patient{}, "notes_tsv") {
+		return nil
+	}
+	if err := db.Exec(`ALTER TABLE patients ADD COLUMN notes_tsv tsvector`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`UPDATE patients SET notes_tsv = to_tsvector('english', coalesce(medical_notes, ''))`).Error
+}
+
+// noMatchKeys marks a key column that backfillMatchKeys computed without
+// finding any key, such as the contact keys of "n/a". No phonetic or
+// contact key is "-", so it never matches a search.
+const noMatchKeys = "-"
+
+// backfillMatchKeys computes the phonetic keys of the names and the keys of
+// the contact info of patients saved before they had them. The keys are
+// computed in Go, so no migration can add them. A column left without keys
+// is set to noMatchKeys so the row is not read again on the next start.
+func backfillMatchKeys(db *gorm.DB) error {
+	var patients []models.Patient
+	return db.Unscoped().Model(&models.Patient{}).Select("id", "name", "contact_info").
+		Where("(name_phonetic = '' AND name <> '') OR (contact_keys = '' AND contact_info <> '')").
+		FindInBatches(&patients, 500, func(tx *gorm.DB, batch int) error {
+			for _, patient := range patients {
+				keys := map[string]interface{}{
+					"name_phonetic": orNoMatchKeys(phonetic.Encode(patient.Name)),
+					"contact_keys":  orNoMatchKeys(matching.EncodeContactKeys(patient.ContactInfo)),
+				}
+				err := db.Unscoped().Model(&models.Patient{}).Where("id = ?", patient.ID).UpdateColumns(keys).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// orNoMatchKeys returns keys, or noMatchKeys when there are none
+func orNoMatchKeys(keys string) string {
+	if keys == "" {
+		return noMatchKeys
+	}
+	return keys
+}
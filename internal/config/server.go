@@ -0,0 +1,64 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// Server configuration for the HTTP server
+type Server struct {
+	// RequestTimeout is the deadline of a request's context, which cancels
+	// its database queries when it passes
+	RequestTimeout time.Duration
+	// ExportTimeout replaces RequestTimeout for streaming exports
+	ExportTimeout time.Duration
+
+	// ReadTimeout bounds reading a whole request, including its body
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout bounds reading the request headers
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout bounds writing the response. It should be longer than
+	// RequestTimeout; routes with their own timeout extend it themselves.
+	WriteTimeout time.Duration
+	// IdleTimeout closes keep-alive connections left idle this long
+	IdleTimeout time.Duration
+	// ShutdownTimeout is how long in-flight requests and background workers
+	// get to finish once a shutdown signal is received
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long the server keeps accepting requests while
+	// reporting not ready, so that load balancers stop routing to it before
+	// it closes its listener. It is part of ShutdownTimeout and must be
+	// shorter than it.
+	DrainDelay time.Duration
+
+	// TrustedProxies are the proxies whose X-Forwarded-For header is believed
+	// when working out the client IP. None are trusted by default, so clients
+	// cannot dodge per-IP rate limits by sending the header themselves.
+	TrustedProxies []string
+}
+
+// NewServerConfig creates a new server configuration from environment variables
+func NewServerConfig() *Server {
+	return &Server{
+		RequestTimeout:    getEnvDuration("REQUEST_TIMEOUT", 15*time.Second),
+		ExportTimeout:     getEnvDuration("EXPORT_REQUEST_TIMEOUT", 10*time.Minute),
+		ReadTimeout:       getEnvDuration("HTTP_READ_TIMEOUT", 30*time.Second),
+		ReadHeaderTimeout: getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:      getEnvDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       getEnvDuration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+		ShutdownTimeout:   getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		DrainDelay:        getEnvDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+		TrustedProxies:    getEnvList("TRUSTED_PROXIES"),
+	}
+}
+
+// Helper function to get a comma-separated environment variable
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,42 @@
+package config
+
+import "strconv"
+
+// Trace exporters supported by the tracing setup
+const (
+	TracesExporterNone   = "none"
+	TracesExporterOTLP   = "otlp"
+	TracesExporterStdout = "stdout"
+)
+
+// Tracing configuration for OpenTelemetry. The variable names follow the
+// OpenTelemetry SDK conventions.
+type Tracing struct {
+	// Exporter is one of TracesExporterNone, TracesExporterOTLP or TracesExporterStdout
+	Exporter    string
+	ServiceName string
+	// Endpoint is the OTLP/HTTP collector URL, such as http://localhost:4318
+	Endpoint string
+	// SampleRatio is the fraction of new traces that are recorded. Traces
+	// started upstream follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// NewTracingConfig creates a new tracing configuration from environment variables
+func NewTracingConfig() *Tracing {
+	return &Tracing{
+		Exporter:    getEnv("OTEL_TRACES_EXPORTER", TracesExporterNone),
+		ServiceName: getEnv("OTEL_SERVICE_NAME", "hospital-project"),
+		Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+		SampleRatio: getEnvFloat("OTEL_TRACES_SAMPLER_ARG", 1),
+	}
+}
+
+// Helper function to get a float environment variable with fallback
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// Webhook configuration for outgoing webhook deliveries
+type Webhook struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	DisableAfter int
+	// AllowedHosts are endpoint hosts that may resolve to loopback, private
+	// or link-local addresses, such as receivers inside the hospital network
+	AllowedHosts []string
+}
+
+// NewWebhookConfig creates a new webhook configuration from environment variables
+func NewWebhookConfig() *Webhook {
+	return &Webhook{
+		PollInterval: getEnvDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		Timeout:      getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		MaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		BaseBackoff:  getEnvDuration("WEBHOOK_BASE_BACKOFF", 30*time.Second),
+		MaxBackoff:   getEnvDuration("WEBHOOK_MAX_BACKOFF", time.Hour),
+		DisableAfter: getEnvInt("WEBHOOK_DISABLE_AFTER", 20),
+		AllowedHosts: getEnvList("WEBHOOK_ALLOWED_HOSTS"),
+	}
+}
+
+// Helper function to get a duration environment variable with fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Helper function to get an integer environment variable with fallback
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
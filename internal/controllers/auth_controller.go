@@ -6,7 +6,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/metrics"
+	"hospital-project/internal/middleware"
 	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
 	"hospital-project/internal/services"
 )
 
@@ -14,13 +18,17 @@ import (
 type AuthController struct {
 	authService services.AuthService
 	userService services.UserService
+	metrics     *metrics.Metrics
+	rateLimiter *middleware.RateLimiter
 }
 
 // NewAuthController creates a new auth controller
-func NewAuthController(authService services.AuthService, userService services.UserService) *AuthController {
+func NewAuthController(authService services.AuthService, userService services.UserService, metrics *metrics.Metrics, rateLimiter *middleware.RateLimiter) *AuthController {
 	return &AuthController{
 		authService: authService,
 		userService: userService,
+		metrics:     metrics,
+		rateLimiter: rateLimiter,
 	}
 }
 
@@ -31,29 +39,33 @@ func NewAuthController(authService services.AuthService, userService services.Us
 // @Produce json
 // @Param request body models.LoginRequest true "Login Request"
 // @Success 200 {object} models.LoginResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/auth/login [post]
 func (c *AuthController) Login(ctx *gin.Context) {
 	var request models.LoginRequest
 
 	// Bind request body
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		problem.Write(ctx, problem.Binding(err))
 		return
 	}
 
 	// Validate request
 	if request.Username == "" || request.Password == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Username and password are required"})
+		problem.Write(ctx, apperror.Validation(apperror.CodeValidationFailed, "Username and password are required",
+			apperror.FieldError{Field: "username", Code: "required", Message: "is required"},
+			apperror.FieldError{Field: "password", Code: "required", Message: "is required"},
+		))
 		return
 	}
 
 	// Login
-	response, err := c.authService.Login(request)
+	response, err := c.authService.Login(ctx.Request.Context(), request)
+	c.recordLogin(err)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		problem.Write(ctx, err)
 		return
 	}
 
@@ -80,6 +92,18 @@ func (c *AuthController) Login(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+// recordLogin counts a login attempt by its outcome
+func (c *AuthController) recordLogin(err error) {
+	switch {
+	case err == nil:
+		c.metrics.RecordLogin(metrics.LoginSuccess)
+	case apperror.KindOf(err) == apperror.KindUnauthorized:
+		c.metrics.RecordLogin(metrics.LoginFailure)
+	default:
+		c.metrics.RecordLogin(metrics.LoginError)
+	}
+}
+
 // @Summary Register
 // @Description Register a new user (doctor or receptionist)
 // @Tags auth
@@ -87,41 +111,46 @@ func (c *AuthController) Login(ctx *gin.Context) {
 // @Produce json
 // @Param request body models.RegisterRequest true "Register Request"
 // @Success 201 {object} models.LoginResponse
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/auth/register [post]
 func (c *AuthController) Register(ctx *gin.Context) {
 	var request models.RegisterRequest
 
 	// Bind request body
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		problem.Write(ctx, problem.Binding(err))
 		return
 	}
 
 	// Validate request
 	if request.Username == "" || request.Password == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Username and password are required"})
+		problem.Write(ctx, apperror.Validation(apperror.CodeValidationFailed, "Username and password are required",
+			apperror.FieldError{Field: "username", Code: "required", Message: "is required"},
+			apperror.FieldError{Field: "password", Code: "required", Message: "is required"},
+		))
 		return
 	}
 
 	// Validate role
 	if request.Role != models.RoleDoctor && request.Role != models.RoleReceptionist {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Role must be either doctor or receptionist"})
+		problem.Write(ctx, apperror.Validation(apperror.CodeValidationFailed, "Role must be either doctor or receptionist",
+			apperror.FieldError{Field: "role", Code: "oneof", Message: "must be one of: doctor receptionist"},
+		))
 		return
 	}
 
 	// Create user
-	user, err := c.userService.Create(request.Username, request.Password, request.Role)
+	user, err := c.userService.Create(ctx.Request.Context(), request.Username, request.Password, request.Role)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Write(ctx, err)
 		return
 	}
 
 	// Generate JWT token
 	token, err := c.authService.GenerateToken(user)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		problem.Write(ctx, err)
 		return
 	}
 
@@ -157,6 +186,7 @@ func (c *AuthController) Register(ctx *gin.Context) {
 // RegisterRoutes registers the auth routes
 func (c *AuthController) RegisterRoutes(router *gin.Engine) {
 	auth := router.Group("/api/auth")
+	auth.Use(c.rateLimiter.Limit(middleware.RateLimitAuth))
 	{
 		auth.POST("/login", c.Login)
 		auth.POST("/register", c.Register)
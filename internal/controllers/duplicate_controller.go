@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/problem"
+	"hospital-project/internal/services"
+)
+
+// DuplicateController handles requests to review suspected duplicate patients
+type DuplicateController struct {
+	duplicateService services.DuplicateService
+	authMiddleware   *middleware.AuthMiddleware
+	rateLimiter      *middleware.RateLimiter
+}
+
+// NewDuplicateController creates a new duplicate controller
+func NewDuplicateController(duplicateService services.DuplicateService, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter) *DuplicateController {
+	return &DuplicateController{
+		duplicateService: duplicateService,
+		authMiddleware:   authMiddleware,
+		rateLimiter:      rateLimiter,
+	}
+}
+
+// @Summary List suspected duplicates
+// @Description List the pairs of patients queued as suspected duplicates, most probable first (Receptionist only). Pages are selected like the patient list, by page number or cursor.
+// @Tags duplicates
+// @Produce json
+// @Param status query string false "Review status: pending (default), confirmed, dismissed or merged (closed because a patient was merged)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Number of items per page (default: 10, max: 100)"
+// @Param cursor query string false "Cursor from a previous page, used instead of page"
+// @Param include_total query bool false "Count the total when paging by cursor"
+// @Success 200 {object} models.PaginatedResponse[models.PatientDuplicateResponse]
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/patients/duplicates [get]
+// @Security Bearer
+func (c *DuplicateController) ListDuplicates(ctx *gin.Context) {
+	var query models.DuplicateListQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+	request, err := pagination.NewRequest(query.Page, query.Limit, query.Cursor, query.IncludeTotal)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	page, err := c.duplicateService.List(ctx.Request.Context(), query.Status, request)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pageResponse(ctx, page, (*models.PatientDuplicate).ToResponse))
+}
+
+// @Summary Review suspected duplicate
+// @Description Confirm that a pair of patients are the same person, or dismiss the pair as different people (Receptionist only). A dismissed pair is not queued again.
+// @Tags duplicates
+// @Accept json
+// @Produce json
+// @Param id path int true "Suspected duplicate ID"
+// @Param request body models.ReviewDuplicateRequest true "Review decision"
+// @Success 200 {object} models.PatientDuplicateResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/patients/duplicates/{id}/review [post]
+// @Security Bearer
+func (c *DuplicateController) ReviewDuplicate(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "duplicate ID")
+	if !ok {
+		return
+	}
+
+	var request models.ReviewDuplicateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	duplicate, err := c.duplicateService.Review(ctx.Request.Context(), id, request.Status, currentUser.ID)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, duplicate.ToResponse())
+}
+
+// RegisterRoutes registers the duplicate review routes
+func (c *DuplicateController) RegisterRoutes(router *gin.Engine) {
+	duplicates := router.Group("/api/patients/duplicates")
+	duplicates.Use(c.authMiddleware.Authenticate(), c.rateLimiter.Limit(middleware.RateLimitDefault), c.authMiddleware.RequireRole(models.RoleReceptionist))
+	{
+		duplicates.GET("", c.ListDuplicates)
+		duplicates.POST("/:id/review", c.ReviewDuplicate)
+	}
+}
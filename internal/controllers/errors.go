@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/problem"
+)
+
+// pathID parses a numeric ID path parameter, writing a problem response if it is invalid
+func pathID(ctx *gin.Context, param, label string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(param), 10, 32)
+	if err != nil || id == 0 {
+		problem.Write(ctx, apperror.Validation(apperror.CodeInvalidID, "invalid "+label, apperror.FieldError{
+			Field:   param,
+			Code:    "numeric",
+			Message: "must be a positive integer",
+		}))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// errUnauthenticated is reported when a protected handler runs without a user
+func errUnauthenticated() *apperror.Error {
+	return apperror.Unauthorized(apperror.CodeUnauthorized, "unauthorized")
+}
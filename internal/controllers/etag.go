@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
+)
+
+// patientETag returns the strong entity tag of a patient version
+func patientETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// requireIfMatch writes a 428 response if the request has no If-Match header
+func requireIfMatch(ctx *gin.Context) bool {
+	if ctx.GetHeader("If-Match") == "" {
+		problem.Write(ctx, apperror.New(apperror.KindPreconditionRequired, apperror.CodeIfMatchRequired,
+			"If-Match header is required; send the ETag returned when the patient was read"))
+		return false
+	}
+	return true
+}
+
+// matchesETag reports whether a comma-separated If-Match or If-None-Match
+// header lists the entity tag, or is "*"
+func matchesETag(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch writes a 412 response carrying the current patient if the
+// If-Match header does not match its version
+func checkIfMatch(ctx *gin.Context, patient *models.Patient) bool {
+	if matchesETag(ctx.GetHeader("If-Match"), patientETag(patient.Version)) {
+		return true
+	}
+	writePreconditionFailed(ctx, patient, apperror.New(apperror.KindPreconditionFailed, apperror.CodeVersionMismatch,
+		"patient has been modified since it was read"))
+	return false
+}
+
+// writePreconditionFailed writes a 412 response with the current patient
+// and its ETag so the client can reapply its change and retry
+func writePreconditionFailed(ctx *gin.Context, patient *models.Patient, err error) {
+	ctx.Header("ETag", patientETag(patient.Version))
+	problem.WriteCurrent(ctx, err, patient.ToResponse())
+}
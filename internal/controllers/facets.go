@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"slices"
+	"strings"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+)
+
+// patientFacets reads a facets parameter listing the PatientFacets a client
+// wants counted. It returns nil, meaning no counts, if the parameter is
+// empty.
+func patientFacets(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var facets []string
+	for _, facet := range strings.Split(value, ",") {
+		facet = strings.TrimSpace(facet)
+		if !slices.Contains(models.PatientFacets, facet) {
+			return nil, apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+				Field:   "facets",
+				Code:    "oneof",
+				Message: "must list facets of " + strings.Join(models.PatientFacets, ", "),
+			})
+		}
+		if !slices.Contains(facets, facet) {
+			facets = append(facets, facet)
+		}
+	}
+	return facets, nil
+}
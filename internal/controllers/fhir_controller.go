@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/fhir"
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/problem"
+	"hospital-project/internal/services"
+)
+
+// FHIRController exposes patients as FHIR R4 Patient resources
+type FHIRController struct {
+	patientService services.PatientService
+	authMiddleware *middleware.AuthMiddleware
+	rateLimiter    *middleware.RateLimiter
+	idempotency    *middleware.IdempotencyMiddleware
+}
+
+// NewFHIRController creates a new FHIR controller
+func NewFHIRController(patientService services.PatientService, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter, idempotency *middleware.IdempotencyMiddleware) *FHIRController {
+	return &FHIRController{
+		patientService: patientService,
+		authMiddleware: authMiddleware,
+		rateLimiter:    rateLimiter,
+		idempotency:    idempotency,
+	}
+}
+
+// @Summary FHIR capability statement
+// @Description Describe the FHIR interactions supported by this server
+// @Tags fhir
+// @Produce json
+// @Success 200 {object} fhir.CapabilityStatement
+// @Router /fhir/metadata [get]
+func (c *FHIRController) Metadata(ctx *gin.Context) {
+	writeResource(ctx, http.StatusOK, fhir.Capabilities(time.Now()))
+}
+
+// @Summary Read FHIR patient
+// @Description Read a Patient resource by ID (Both Receptionist and Doctor)
+// @Tags fhir
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Success 200 {object} fhir.Patient
+// @Failure 400 {object} fhir.OperationOutcome
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} fhir.OperationOutcome
+// @Failure 500 {object} fhir.OperationOutcome
+// @Router /fhir/Patient/{id} [get]
+// @Security Bearer
+func (c *FHIRController) ReadPatient(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		writeOutcome(ctx, http.StatusBadRequest, "invalid", "Invalid patient ID")
+		return
+	}
+
+	patient, ok := c.findPatient(ctx, uint(id))
+	if !ok {
+		return
+	}
+
+	ctx.Header("ETag", versionETag(patient.Version))
+	writeResource(ctx, http.StatusOK, fhir.FromPatient(patient, time.Now()))
+}
+
+// @Summary Search FHIR patients
+// @Description Search Patient resources by name, gender and birthdate (Both Receptionist and Doctor)
+// @Tags fhir
+// @Produce json
+// @Param name query string false "Patient name"
+// @Param gender query string false "Patient gender"
+// @Param birthdate query string false "Birth date with optional eq, ge, gt, le or lt prefix"
+// @Param _count query int false "Number of entries per page (default: 20, max: 100)"
+// @Param _sort query string false "Comma-separated sort fields, prefix with - for descending"
+// @Success 200 {object} fhir.Bundle
+// @Failure 400 {object} fhir.OperationOutcome
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} fhir.OperationOutcome
+// @Router /fhir/Patient [get]
+// @Security Bearer
+func (c *FHIRController) SearchPatients(ctx *gin.Context) {
+	now := time.Now()
+
+	query, err := fhir.ParseSearch(ctx.Request.URL.Query(), now)
+	if err != nil {
+		writeOutcome(ctx, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	// The search is sorted, paged and counted in SQL. _count=0 asks for
+	// the total alone, so it reads a single row and returns no entries.
+	request := pagination.Request{Number: 1, Limit: max(query.Count, 1), IncludeTotal: true}
+	results, err := c.patientService.Search(ctx.Request.Context(), query.Params, query.Sort, request)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+
+	total := int(*results.Total)
+	matches := results.Items
+	if len(matches) > query.Count {
+		matches = matches[:query.Count]
+	}
+
+	bundle := fhir.Bundle{
+		ResourceType: "Bundle",
+		Type:         "searchset",
+		Total:        &total,
+		Link:         []fhir.BundleLink{{Relation: "self", URL: requestURL(ctx)}},
+	}
+	for i := range matches {
+		resource := fhir.FromPatient(&matches[i].Patient, now)
+		bundle.Entry = append(bundle.Entry, fhir.BundleEntry{
+			FullURL:  baseURL(ctx) + "/fhir/Patient/" + resource.ID,
+			Resource: resource,
+			Search:   &fhir.BundleSearch{Mode: "match"},
+		})
+	}
+
+	writeResource(ctx, http.StatusOK, bundle)
+}
+
+// @Summary Create FHIR patient
+// @Description Create a patient from a Patient resource (Receptionist only)
+// @Tags fhir
+// @Accept json
+// @Produce json
+// @Param request body fhir.Patient true "Patient resource"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original response"
+// @Success 201 {object} fhir.Patient
+// @Failure 400 {object} fhir.OperationOutcome
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 422 {object} fhir.OperationOutcome
+// @Router /fhir/Patient [post]
+// @Security Bearer
+func (c *FHIRController) CreatePatient(ctx *gin.Context) {
+	var resource fhir.Patient
+	if err := ctx.ShouldBindJSON(&resource); err != nil {
+		writeOutcome(ctx, http.StatusBadRequest, "structure", "Invalid Patient resource")
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		writeOutcome(ctx, http.StatusUnauthorized, "login", "Unauthorized")
+		return
+	}
+
+	now := time.Now()
+	patient := &models.Patient{CreatedBy: currentUser.ID}
+	if err := fhir.ApplyToPatient(resource, patient, now); err != nil {
+		writeOutcome(ctx, http.StatusUnprocessableEntity, "invalid", err.Error())
+		return
+	}
+
+	if _, err := c.patientService.Create(ctx.Request.Context(), patient, false); err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", baseURL(ctx)+"/fhir/Patient/"+strconv.FormatUint(uint64(patient.ID), 10))
+	writeResource(ctx, http.StatusCreated, fhir.FromPatient(patient, now))
+}
+
+// @Summary Update FHIR patient
+// @Description Replace a patient's demographics from a Patient resource (Receptionist only)
+// @Tags fhir
+// @Accept json
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param If-Match header string false "W/\"versionId\" of the version being replaced"
+// @Param request body fhir.Patient true "Patient resource"
+// @Success 200 {object} fhir.Patient
+// @Failure 400 {object} fhir.OperationOutcome
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} fhir.OperationOutcome
+// @Failure 412 {object} fhir.OperationOutcome
+// @Failure 422 {object} fhir.OperationOutcome
+// @Router /fhir/Patient/{id} [put]
+// @Security Bearer
+func (c *FHIRController) UpdatePatient(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		writeOutcome(ctx, http.StatusBadRequest, "invalid", "Invalid patient ID")
+		return
+	}
+
+	var resource fhir.Patient
+	if err := ctx.ShouldBindJSON(&resource); err != nil {
+		writeOutcome(ctx, http.StatusBadRequest, "structure", "Invalid Patient resource")
+		return
+	}
+	if resource.ID != ctx.Param("id") {
+		writeOutcome(ctx, http.StatusBadRequest, "invalid", "Patient.id must match the ID in the URL")
+		return
+	}
+
+	patient, ok := c.findPatient(ctx, uint(id))
+	if !ok {
+		return
+	}
+
+	// Versioned update: If-Match is optional, but must match when sent
+	if ifMatch := ctx.GetHeader("If-Match"); ifMatch != "" && !matchesETag(ifMatch, versionETag(patient.Version)) {
+		writeOutcome(ctx, http.StatusPreconditionFailed, "conflict",
+			"Patient/"+ctx.Param("id")+" has been modified; the current version is "+versionETag(patient.Version))
+		return
+	}
+
+	// Only demographics are carried by the resource; medical notes are kept
+	now := time.Now()
+	if err := fhir.ApplyToPatient(resource, patient, now); err != nil {
+		writeOutcome(ctx, http.StatusUnprocessableEntity, "invalid", err.Error())
+		return
+	}
+
+	if err := c.patientService.Update(ctx.Request.Context(), patient); err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+
+	ctx.Header("ETag", versionETag(patient.Version))
+	writeResource(ctx, http.StatusOK, fhir.FromPatient(patient, now))
+}
+
+// versionETag returns the weak entity tag FHIR uses for a resource version
+func versionETag(version uint) string {
+	return "W/" + patientETag(version)
+}
+
+// findPatient loads a patient, writing an OperationOutcome if it cannot
+func (c *FHIRController) findPatient(ctx *gin.Context, id uint) (*models.Patient, bool) {
+	patient, err := c.patientService.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		if apperror.IsNotFound(err) {
+			writeOutcome(ctx, http.StatusNotFound, "not-found", "Patient/"+ctx.Param("id")+" is not known")
+		} else {
+			writeServiceError(ctx, err)
+		}
+		return nil, false
+	}
+	return patient, true
+}
+
+// writeResource writes a FHIR resource with the FHIR JSON media type
+func writeResource(ctx *gin.Context, status int, resource interface{}) {
+	ctx.Header("Content-Type", fhir.ContentType)
+	ctx.JSON(status, resource)
+}
+
+// writeOutcome writes an OperationOutcome describing an error
+func writeOutcome(ctx *gin.Context, status int, code, diagnostics string) {
+	writeResource(ctx, status, fhir.NewOperationOutcome(code, diagnostics))
+}
+
+// writeServiceError writes an OperationOutcome for an error returned by a
+// service, with the same status a problem details response would have
+func writeServiceError(ctx *gin.Context, err error) {
+	p := problem.New(err, ctx.Request.URL.Path)
+	problem.Log(ctx, p, err)
+
+	code := "exception"
+	switch apperror.KindOf(err) {
+	case apperror.KindValidation:
+		code = "invalid"
+	case apperror.KindNotFound:
+		code = "not-found"
+	case apperror.KindConflict:
+		code = "duplicate"
+	case apperror.KindForbidden:
+		code = "forbidden"
+	case apperror.KindUnauthorized:
+		code = "login"
+	case apperror.KindPreconditionFailed:
+		code = "conflict"
+	}
+	writeOutcome(ctx, p.Status, code, p.Detail)
+}
+
+// baseURL returns the scheme and host the request was addressed to
+func baseURL(ctx *gin.Context) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := ctx.GetHeader("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + ctx.Request.Host
+}
+
+// requestURL returns the absolute URL of the request
+func requestURL(ctx *gin.Context) string {
+	return baseURL(ctx) + ctx.Request.URL.RequestURI()
+}
+
+// RegisterRoutes registers the FHIR routes
+func (c *FHIRController) RegisterRoutes(router *gin.Engine) {
+	fhirRoutes := router.Group("/fhir")
+	fhirRoutes.GET("/metadata", c.Metadata)
+
+	patients := fhirRoutes.Group("/Patient")
+	patients.Use(c.authMiddleware.Authenticate(), c.rateLimiter.Limit(middleware.RateLimitDefault))
+	{
+		// Routes for both receptionist and doctor
+		patients.GET("", c.rateLimiter.Limit(middleware.RateLimitSearch), c.SearchPatients)
+		patients.GET("/:id", c.ReadPatient)
+
+		// Routes for receptionist only
+		receptionistRoutes := patients.Group("")
+		receptionistRoutes.Use(c.authMiddleware.RequireRole(models.RoleReceptionist))
+		{
+			receptionistRoutes.POST("", c.idempotency.Handle(), c.CreatePatient)
+			receptionistRoutes.PUT("/:id", c.UpdatePatient)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+
+	"hospital-project/internal/apperror"
+)
+
+// sparseFields reads a fields parameter listing the JSON members of R a
+// client wants. It returns nil, meaning every member, if the parameter is
+// empty. The id member is always included.
+func sparseFields[R any](value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	members := jsonMembers(reflect.TypeOf((*R)(nil)).Elem())
+	fields := []string{"id"}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(members, field) {
+			return nil, apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+				Field:   "fields",
+				Code:    "oneof",
+				Message: "must list fields of " + strings.Join(members, ", "),
+			})
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// selectFields returns only the given members of a response, or the whole
+// response if fields is nil
+func selectFields(response interface{}, fields []string) interface{} {
+	if fields == nil {
+		return response
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return response
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return response
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := members[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected
+}
+
+// jsonMembers lists the JSON member names of a struct type, including those
+// promoted from embedded structs
+func jsonMembers(t reflect.Type) []string {
+	var members []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			members = append(members, jsonMembers(field.Type)...)
+			continue
+		}
+		if name != "" && name != "-" {
+			members = append(members, name)
+		}
+	}
+	return members
+}
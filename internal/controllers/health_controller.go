@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/problem"
+	"hospital-project/internal/services"
+)
+
+// HealthController handles liveness and readiness probes
+type HealthController struct {
+	healthService services.HealthService
+}
+
+// NewHealthController creates a new health controller
+func NewHealthController(healthService services.HealthService) *HealthController {
+	return &HealthController{
+		healthService: healthService,
+	}
+}
+
+// @Summary Liveness probe
+// @Description Reports that the process is running. It does not check dependencies.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (c *HealthController) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// @Summary Readiness probe
+// @Description Reports whether the server can handle requests: the database is reachable, migrations are applied and the server is not shutting down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 503 {object} problem.Problem
+// @Router /readyz [get]
+func (c *HealthController) Readyz(ctx *gin.Context) {
+	if err := c.healthService.Ready(ctx.Request.Context()); err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// RegisterRoutes registers the health routes
+func (c *HealthController) RegisterRoutes(router *gin.Engine) {
+	router.GET("/healthz", c.Healthz)
+	router.GET("/readyz", c.Readyz)
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
+	"hospital-project/internal/services"
+)
+
+// HL7Controller handles requests for stored HL7 messages
+type HL7Controller struct {
+	hl7Service     services.HL7Service
+	authMiddleware *middleware.AuthMiddleware
+	rateLimiter    *middleware.RateLimiter
+	idempotency    *middleware.IdempotencyMiddleware
+}
+
+// NewHL7Controller creates a new HL7 controller
+func NewHL7Controller(hl7Service services.HL7Service, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter, idempotency *middleware.IdempotencyMiddleware) *HL7Controller {
+	return &HL7Controller{
+		hl7Service:     hl7Service,
+		authMiddleware: authMiddleware,
+		rateLimiter:    rateLimiter,
+		idempotency:    idempotency,
+	}
+}
+
+// @Summary List HL7 messages
+// @Description List inbound HL7 ADT messages, newest first (Receptionist only)
+// @Tags hl7
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Number of items per page (default: 10, max: 100)"
+// @Success 200 {object} models.PaginatedResponse[models.HL7MessageResponse]
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/hl7/messages [get]
+// @Security Bearer
+func (c *HL7Controller) ListMessages(ctx *gin.Context) {
+	// Get pagination parameters
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	messages, total, err := c.hl7Service.ListMessages(ctx.Request.Context(), page, limit)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	// Convert to response
+	responseData := make([]models.HL7MessageResponse, 0, len(messages))
+	for _, message := range messages {
+		responseData = append(responseData, message.ToResponse())
+	}
+
+	ctx.JSON(http.StatusOK, models.PaginatedResponse[models.HL7MessageResponse]{
+		Data:       responseData,
+		Page:       page,
+		Limit:      limit,
+		Total:      &total,
+		TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+	})
+}
+
+// @Summary Replay HL7 message
+// @Description Process a stored HL7 message again (Receptionist only)
+// @Tags hl7
+// @Produce json
+// @Param id path int true "Message ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original response"
+// @Success 200 {object} models.HL7MessageResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/hl7/messages/{id}/replay [post]
+// @Security Bearer
+func (c *HL7Controller) ReplayMessage(ctx *gin.Context) {
+	// Get ID from path
+	id, ok := pathID(ctx, "id", "message ID")
+	if !ok {
+		return
+	}
+
+	message, err := c.hl7Service.Replay(ctx.Request.Context(), id)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, message.ToResponse())
+}
+
+// RegisterRoutes registers the HL7 routes
+func (c *HL7Controller) RegisterRoutes(router *gin.Engine) {
+	messages := router.Group("/api/hl7/messages")
+	messages.Use(c.authMiddleware.Authenticate(), c.rateLimiter.Limit(middleware.RateLimitDefault), c.authMiddleware.RequireRole(models.RoleReceptionist))
+	{
+		messages.GET("", c.ListMessages)
+		messages.POST("/:id/replay", c.idempotency.Handle(), c.ReplayMessage)
+	}
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
+	"hospital-project/internal/services"
+)
+
+// MergeController handles requests to merge duplicate patients
+type MergeController struct {
+	mergeService   services.MergeService
+	authMiddleware *middleware.AuthMiddleware
+	rateLimiter    *middleware.RateLimiter
+	idempotency    *middleware.IdempotencyMiddleware
+}
+
+// NewMergeController creates a new merge controller
+func NewMergeController(mergeService services.MergeService, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter, idempotency *middleware.IdempotencyMiddleware) *MergeController {
+	return &MergeController{
+		mergeService:   mergeService,
+		authMiddleware: authMiddleware,
+		rateLimiter:    rateLimiter,
+		idempotency:    idempotency,
+	}
+}
+
+// @Summary Merge duplicate patient
+// @Description Fold a duplicate patient into the patient in the path (Receptionist only). The duplicate's notes are appended to the survivor's, the fields the survivor lacks are taken from it, its identifiers and HL7 messages are moved to the survivor and it is deleted. Reading the duplicate afterwards redirects to the survivor. The merge can be undone within a configured window.
+// @Tags merges
+// @Accept json
+// @Produce json
+// @Param id path int true "Surviving patient ID"
+// @Param request body models.MergePatientRequest true "Duplicate to merge"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original response"
+// @Success 201 {object} models.PatientMergeResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/patients/{id}/merge [post]
+// @Security Bearer
+func (c *MergeController) MergePatient(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "patient ID")
+	if !ok {
+		return
+	}
+
+	var request models.MergePatientRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	merge, err := c.mergeService.Merge(ctx.Request.Context(), id, request.DuplicateID, currentUser.ID)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, merge.ToResponse())
+}
+
+// @Summary Get patient merge
+// @Description Get a patient merge by ID (Receptionist only)
+// @Tags merges
+// @Produce json
+// @Param id path int true "Merge ID"
+// @Success 200 {object} models.PatientMergeResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/patients/merges/{id} [get]
+// @Security Bearer
+func (c *MergeController) GetMerge(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "merge ID")
+	if !ok {
+		return
+	}
+
+	merge, err := c.mergeService.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, merge.ToResponse())
+}
+
+// @Summary Undo patient merge
+// @Description Undo a merge within its window (Receptionist only). The survivor is restored as it was before the merge, the merged patient comes back and the records moved from it are moved back. A merge whose survivor has been changed since cannot be undone.
+// @Tags merges
+// @Produce json
+// @Param id path int true "Merge ID"
+// @Success 200 {object} models.PatientMergeResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/patients/merges/{id}/unmerge [post]
+// @Security Bearer
+func (c *MergeController) UnmergePatient(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "merge ID")
+	if !ok {
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	merge, err := c.mergeService.Unmerge(ctx.Request.Context(), id, currentUser.ID)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, merge.ToResponse())
+}
+
+// RegisterRoutes registers the patient merge routes
+func (c *MergeController) RegisterRoutes(router *gin.Engine) {
+	patients := router.Group("/api/patients")
+	patients.Use(c.authMiddleware.Authenticate(), c.rateLimiter.Limit(middleware.RateLimitDefault), c.authMiddleware.RequireRole(models.RoleReceptionist))
+	{
+		patients.POST("/:id/merge", c.idempotency.Handle(), c.MergePatient)
+		patients.GET("/merges/:id", c.GetMerge)
+		patients.POST("/merges/:id/unmerge", c.UnmergePatient)
+	}
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/problem"
+)
+
+// pageRequest reads the page selected by the page, limit, cursor and
+// include_total query parameters
+func pageRequest(ctx *gin.Context) (pagination.Request, error) {
+	var query models.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		return pagination.Request{}, problem.Binding(err)
+	}
+	return pagination.NewRequest(query.Page, query.Limit, query.Cursor, query.IncludeTotal)
+}
+
+// pageResponse converts a page to the paginated response envelope and links
+// to its neighbours in the Link header
+func pageResponse[T any, R any](ctx *gin.Context, page *pagination.Page[T], convert func(*T) R) models.PaginatedResponse[R] {
+	response := models.PaginatedResponse[R]{
+		Data:       make([]R, 0, len(page.Items)),
+		Page:       page.Number,
+		Limit:      page.Limit,
+		Total:      page.Total,
+		TotalPages: page.TotalPages(),
+	}
+	for i := range page.Items {
+		response.Data = append(response.Data, convert(&page.Items[i]))
+	}
+
+	var links []string
+	if page.Next != nil {
+		response.NextCursor = page.Next.Encode()
+		links = append(links, pageLink(ctx, response.NextCursor, page.Limit, "next"))
+	}
+	if page.Prev != nil {
+		response.PrevCursor = page.Prev.Encode()
+		links = append(links, pageLink(ctx, response.PrevCursor, page.Limit, "prev"))
+	}
+	if len(links) > 0 {
+		ctx.Header("Link", strings.Join(links, ", "))
+	}
+	return response
+}
+
+// pageLink links to the page at a cursor, keeping the request's other query
+// parameters such as search filters
+func pageLink(ctx *gin.Context, cursor string, limit int, rel string) string {
+	query := ctx.Request.URL.Query()
+	query.Del("page")
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(limit))
+	return fmt.Sprintf("<%s?%s>; rel=\"%s\"", ctx.Request.URL.Path, query.Encode(), rel)
+}
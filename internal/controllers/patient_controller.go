@@ -1,28 +1,47 @@
 package controllers
 
 import (
-	"math"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/export"
 	"hospital-project/internal/middleware"
 	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/patch"
+	"hospital-project/internal/problem"
 	"hospital-project/internal/services"
 )
 
 // PatientController handles patient requests
 type PatientController struct {
 	patientService services.PatientService
+	mergeService   services.MergeService
+	auditService   services.AuditService
 	authMiddleware *middleware.AuthMiddleware
+	rateLimiter    *middleware.RateLimiter
+	idempotency    *middleware.IdempotencyMiddleware
 }
 
 // NewPatientController creates a new patient controller
-func NewPatientController(patientService services.PatientService, authMiddleware *middleware.AuthMiddleware) *PatientController {
+func NewPatientController(patientService services.PatientService, mergeService services.MergeService, auditService services.AuditService, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter, idempotency *middleware.IdempotencyMiddleware) *PatientController {
 	return &PatientController{
 		patientService: patientService,
+		mergeService:   mergeService,
+		auditService:   auditService,
 		authMiddleware: authMiddleware,
+		rateLimiter:    rateLimiter,
+		idempotency:    idempotency,
 	}
 }
 
@@ -32,11 +51,13 @@ func NewPatientController(patientService services.PatientService, authMiddleware
 // @Accept json
 // @Produce json
 // @Param request body models.CreatePatientRequest true "Create Patient Request"
-// @Success 201 {object} models.PatientResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original response"
+// @Success 201 {object} models.CreatePatientResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "Probable duplicate, with the matching patients as candidates"
+// @Failure 500 {object} problem.Problem
 // @Router /api/patients [post]
 // @Security Bearer
 func (c *PatientController) CreatePatient(ctx *gin.Context) {
@@ -44,61 +65,91 @@ func (c *PatientController) CreatePatient(ctx *gin.Context) {
 
 	// Bind and validate request body
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Write(ctx, problem.Binding(err))
 		return
 	}
 
 	// Get current user
 	currentUser, ok := middleware.GetCurrentUser(ctx)
 	if !ok {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		problem.Write(ctx, errUnauthenticated())
 		return
 	}
 
-	// Map request to patient model
+	// Map request to patient model. The birth date has been validated.
+	birthDate, _ := models.ParseDate(request.BirthDate)
 	patient := &models.Patient{
 		Name:         request.Name,
 		Age:          request.Age,
 		Gender:       request.Gender,
 		ContactInfo:  request.ContactInfo,
+		BirthDate:    birthDate,
+		Address:      request.Address,
 		MedicalNotes: request.MedicalNotes,
 		CreatedBy:    currentUser.ID,
 	}
 
-	err := c.patientService.Create(patient)
+	matches, err := c.patientService.Create(ctx.Request.Context(), patient, request.OverrideDuplicates)
+	candidates := make([]models.DuplicateMatchResponse, len(matches))
+	for i := range matches {
+		candidates[i] = matches[i].ToResponse(currentUser.Role)
+	}
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if appErr, ok := apperror.As(err); ok && appErr.Code == apperror.CodePatientDuplicate {
+			problem.WriteCandidates(ctx, err, candidates)
+			return
+		}
+		problem.Write(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, patient.ToResponse())
+	ctx.JSON(http.StatusCreated, models.CreatePatientResponse{
+		PatientResponse:     patient.ToResponse(),
+		DuplicateCandidates: candidates,
+	})
 }
 
 // @Summary Get patient by ID
-// @Description Get a patient by ID (Both Receptionist and Doctor)
+// @Description Get a patient by ID (Both Receptionist and Doctor). A patient merged into another redirects to the survivor.
 // @Tags patients
 // @Produce json
 // @Param id path int true "Patient ID"
+// @Param If-None-Match header string false "ETag from a previous read"
 // @Success 200 {object} models.PatientResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 200 {string} ETag "Patient version"
+// @Success 304 "Not Modified"
+// @Success 307 "Patient has been merged into the patient in the Location header"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/patients/{id} [get]
 // @Security Bearer
 func (c *PatientController) GetPatient(ctx *gin.Context) {
 	// Get ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+	id, ok := pathID(ctx, "id", "patient ID")
+	if !ok {
 		return
 	}
 
 	// Get patient
-	patient, err := c.patientService.GetByID(uint(id))
+	patient, err := c.patientService.GetByID(ctx.Request.Context(), id)
+	if apperror.IsNotFound(err) {
+		// A merged patient lives on as the survivor, until the merge is undone
+		if survivorID, mergeErr := c.mergeService.Survivor(ctx.Request.Context(), id); mergeErr == nil {
+			ctx.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("/api/patients/%d", survivorID))
+			return
+		}
+	}
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		problem.Write(ctx, err)
+		return
+	}
+
+	etag := patientETag(patient.Version)
+	ctx.Header("ETag", etag)
+	if matchesETag(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
 		return
 	}
 
@@ -106,26 +157,32 @@ func (c *PatientController) GetPatient(ctx *gin.Context) {
 }
 
 // @Summary Update patient
-// @Description Update a patient (Receptionist only)
+// @Description Update a patient (Receptionist only). If-Match must carry the ETag from the last read.
 // @Tags patients
 // @Accept json
 // @Produce json
 // @Param id path int true "Patient ID"
+// @Param If-Match header string true "ETag of the version being updated"
 // @Param request body models.UpdatePatientRequest true "Update Patient Request"
 // @Success 200 {object} models.PatientResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 200 {string} ETag "New patient version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/patients/{id} [put]
 // @Security Bearer
 func (c *PatientController) UpdatePatient(ctx *gin.Context) {
 	// Get ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+	id, ok := pathID(ctx, "id", "patient ID")
+	if !ok {
+		return
+	}
+
+	if !requireIfMatch(ctx) {
 		return
 	}
 
@@ -133,14 +190,17 @@ func (c *PatientController) UpdatePatient(ctx *gin.Context) {
 
 	// Bind and validate request body
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Write(ctx, problem.Binding(err))
 		return
 	}
 
 	// Get existing patient
-	existingPatient, err := c.patientService.GetByID(uint(id))
+	existingPatient, err := c.patientService.GetByID(ctx.Request.Context(), id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		problem.Write(ctx, err)
+		return
+	}
+	if !checkIfMatch(ctx, existingPatient) {
 		return
 	}
 
@@ -157,41 +217,138 @@ func (c *PatientController) UpdatePatient(ctx *gin.Context) {
 	if request.ContactInfo != "" {
 		existingPatient.ContactInfo = request.ContactInfo
 	}
+	if request.BirthDate != "" {
+		existingPatient.BirthDate, _ = models.ParseDate(request.BirthDate)
+	}
+	if request.Address != "" {
+		existingPatient.Address = request.Address
+	}
 	if request.MedicalNotes != "" {
 		existingPatient.MedicalNotes = request.MedicalNotes
 	}
 
 	// Update patient
-	err = c.patientService.Update(existingPatient)
+	err = c.patientService.Update(ctx.Request.Context(), existingPatient)
+	if err != nil {
+		c.writeUpdateError(ctx, id, err)
+		return
+	}
+
+	ctx.Header("ETag", patientETag(existingPatient.Version))
+	ctx.JSON(http.StatusOK, existingPatient.ToResponse())
+}
+
+// @Summary Patch patient
+// @Description Partially update a patient with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) (Receptionist only). Null or removed members clear contact_info and medical_notes; the patched patient must still be valid. If-Match must carry the ETag from the last read.
+// @Tags patients
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param If-Match header string true "ETag of the version being updated"
+// @Param request body object true "Merge patch object or array of JSON Patch operations"
+// @Success 200 {object} models.PatientResponse
+// @Header 200 {string} ETag "New patient version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/patients/{id} [patch]
+// @Security Bearer
+func (c *PatientController) PatchPatient(ctx *gin.Context) {
+	// Get ID from path
+	id, ok := pathID(ctx, "id", "patient ID")
+	if !ok {
+		return
+	}
+
+	if !requireIfMatch(ctx) {
+		return
+	}
+
+	contentType := ctx.ContentType()
+	if contentType != patch.MergePatchContentType && contentType != patch.JSONPatchContentType {
+		problem.Write(ctx, apperror.New(apperror.KindUnsupportedMediaType, apperror.CodeUnsupportedMediaType,
+			"Content-Type must be "+patch.MergePatchContentType+" or "+patch.JSONPatchContentType))
+		return
+	}
+
+	body, err := ctx.GetRawData()
+	if err != nil {
+		problem.Write(ctx, apperror.Validation(apperror.CodeMalformedBody, "Request body could not be read"))
+		return
+	}
+
+	// Get existing patient
+	existingPatient, err := c.patientService.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	if !checkIfMatch(ctx, existingPatient) {
+		return
+	}
+
+	// Apply the patch to the patient's document and validate the result
+	original, err := json.Marshal(existingPatient.ToDocument())
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	patched, err := patch.Apply(contentType, original, body)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	document, err := decodeDocument(patched)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	document.ApplyTo(existingPatient)
+
+	// Update patient
+	err = c.patientService.Update(ctx.Request.Context(), existingPatient)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.writeUpdateError(ctx, id, err)
 		return
 	}
 
+	ctx.Header("ETag", patientETag(existingPatient.Version))
 	ctx.JSON(http.StatusOK, existingPatient.ToResponse())
 }
 
 // @Summary Update medical notes
-// @Description Update a patient's medical notes (Doctor only)
+// @Description Update a patient's medical notes (Doctor only). If-Match must carry the ETag from the last read.
 // @Tags patients
 // @Accept json
 // @Produce json
 // @Param id path int true "Patient ID"
+// @Param If-Match header string true "ETag of the version being updated"
 // @Param request body models.UpdateMedicalNotesRequest true "Update Medical Notes Request"
 // @Success 200 {object} models.PatientResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 200 {string} ETag "New patient version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/patients/{id}/medical-notes [put]
 // @Security Bearer
 func (c *PatientController) UpdateMedicalNotes(ctx *gin.Context) {
 	// Get ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+	id, ok := pathID(ctx, "id", "patient ID")
+	if !ok {
+		return
+	}
+
+	if !requireIfMatch(ctx) {
 		return
 	}
 
@@ -199,24 +356,35 @@ func (c *PatientController) UpdateMedicalNotes(ctx *gin.Context) {
 
 	// Bind request body
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	// Check the version the doctor edited
+	existingPatient, err := c.patientService.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	if !checkIfMatch(ctx, existingPatient) {
 		return
 	}
 
 	// Update medical notes
-	err = c.patientService.UpdateMedicalNotes(uint(id), request.MedicalNotes)
+	err = c.patientService.UpdateMedicalNotes(ctx.Request.Context(), id, request.MedicalNotes, existingPatient.Version)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.writeUpdateError(ctx, id, err)
 		return
 	}
 
 	// Get updated patient
-	patient, err := c.patientService.GetByID(uint(id))
+	patient, err := c.patientService.GetByID(ctx.Request.Context(), id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve updated patient"})
+		problem.Write(ctx, err)
 		return
 	}
 
+	ctx.Header("ETag", patientETag(patient.Version))
 	ctx.JSON(http.StatusOK, patient.ToResponse())
 }
 
@@ -225,26 +393,24 @@ func (c *PatientController) UpdateMedicalNotes(ctx *gin.Context) {
 // @Tags patients
 // @Param id path int true "Patient ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/patients/{id} [delete]
 // @Security Bearer
 func (c *PatientController) DeletePatient(ctx *gin.Context) {
 	// Get ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+	id, ok := pathID(ctx, "id", "patient ID")
+	if !ok {
 		return
 	}
 
 	// Delete patient
-	err = c.patientService.Delete(uint(id))
+	err := c.patientService.Delete(ctx.Request.Context(), id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		problem.Write(ctx, err)
 		return
 	}
 
@@ -252,51 +418,50 @@ func (c *PatientController) DeletePatient(ctx *gin.Context) {
 }
 
 // @Summary List patients
-// @Description List all patients (Both Receptionist and Doctor)
+// @Description List all patients in the order they were registered (Both Receptionist and Doctor). Pages are selected by number or, to page consistently while patients are added, by the next_cursor or prev_cursor of a previous page, which are also sent as Link headers.
 // @Tags patients
 // @Produce json
 // @Param page query int false "Page number (default: 1)"
 // @Param limit query int false "Number of items per page (default: 10, max: 100)"
+// @Param cursor query string false "Cursor from a previous page, used instead of page"
+// @Param include_total query bool false "Count the total when paging by cursor"
+// @Param facets query string false "Comma-separated facets (gender, age_band, created_month) to count all patients by"
 // @Success 200 {object} models.PaginatedResponse[models.PatientResponse]
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/patients [get]
 // @Security Bearer
 func (c *PatientController) ListPatients(ctx *gin.Context) {
-	// Get pagination parameters
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-
-	// Get patients with pagination
-	patients, total, err := c.patientService.List(page, limit)
+	request, err := pageRequest(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve patients"})
+		problem.Write(ctx, err)
 		return
 	}
-
-	// Convert to response
-	var responseData []models.PatientResponse
-	for _, patient := range patients {
-		responseData = append(responseData, patient.ToResponse())
+	facets, err := patientFacets(ctx.Query("facets"))
+	if err != nil {
+		problem.Write(ctx, err)
+		return
 	}
 
-	// Calculate total pages
-	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	page, err := c.patientService.List(ctx.Request.Context(), request)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
 
-	// Create paginated response
-	response := models.PaginatedResponse[models.PatientResponse]{
-		Data:       responseData,
-		Page:       page,
-		Limit:      limit,
-		Total:      total,
-		TotalPages: totalPages,
+	response := pageResponse(ctx, page, (*models.Patient).ToResponse)
+	response.Facets, err = c.patientService.Facets(ctx.Request.Context(), "", models.PatientSearchRequest{}, facets)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
 // @Summary Search patients
-// @Description Search for patients (Receptionist only)
+// @Description Search for patients (Both Receptionist and Doctor). Results are paged like the patient list, by page number or cursor, and are sorted by registration unless sort is given. Name and contact info match substrings or similar text, ignoring case and accents, with the most similar first; match=phonetic also matches names that sound alike. Doctors may add a full-text query q over medical notes, which ranks results by relevance and adds a highlighted snippet of the matching notes.
 // @Tags patients
 // @Produce json
 // @Param name query string false "Patient name"
@@ -304,55 +469,278 @@ func (c *PatientController) ListPatients(ctx *gin.Context) {
 // @Param age_max query int false "Maximum age"
 // @Param gender query string false "Patient gender"
 // @Param contact_info query string false "Contact information"
-// @Success 200 {array} models.PatientResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param threshold query number false "Least similarity (0-1] of a name or contact info that is not a substring match (default: 0.3)"
+// @Param match query string false "How names are matched: fuzzy (default) or phonetic, which also matches names that sound alike"
+// @Param filter query string false "Filter expression, e.g. age>=65 and gender=female and created_at>2026-01-01"
+// @Param q query string false "Full-text query over medical notes, in web search syntax (Doctor only, cannot be combined with sort)"
+// @Param sort query string false "Comma-separated sort fields (id, name, age, gender, created_at, updated_at), prefix with - for descending"
+// @Param fields query string false "Comma-separated response fields to return; id is always included"
+// @Param facets query string false "Comma-separated facets (gender, age_band, created_month) to count the matching patients by"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Number of items per page (default: 10, max: 100)"
+// @Param cursor query string false "Cursor from a previous page, used instead of page"
+// @Param include_total query bool false "Count the total when paging by cursor"
+// @Success 200 {object} models.PaginatedResponse[models.PatientSearchResult]
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/patients/search [get]
 // @Security Bearer
 func (c *PatientController) SearchPatients(ctx *gin.Context) {
-	var request models.PatientSearchRequest
+	var query models.PatientSearchQuery
 
 	// Bind query parameters
-	if err := ctx.ShouldBindQuery(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters"})
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	fields, err := sparseFields[models.PatientSearchResult](query.Fields)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	facets, err := patientFacets(query.Facets)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	request, err := pagination.NewRequest(query.Page, query.Limit, query.Cursor, query.IncludeTotal)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	if query.Q != "" {
+		c.searchNotes(ctx, &query, fields, facets, request)
+		return
+	}
+
+	sort, err := pagination.ParseSort(query.Sort, models.PatientSortFields)
+	if err != nil {
+		problem.Write(ctx, err)
 		return
 	}
 
 	// Search patients
-	patients, err := c.patientService.Search(request)
+	page, err := c.patientService.Search(ctx.Request.Context(), query.PatientSearchRequest, sort, request)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	response := pageResponse(ctx, page, func(match *models.PatientMatch) interface{} {
+		return selectFields(match.ToResult(), fields)
+	})
+	response.Facets, err = c.patientService.Facets(ctx.Request.Context(), "", query.PatientSearchRequest, facets)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// searchNotes answers a search with a full-text query over medical notes,
+// which only users allowed to read the notes may run
+func (c *PatientController) searchNotes(ctx *gin.Context, query *models.PatientSearchQuery, fields, facets []string, request pagination.Request) {
+	// Get current user
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+	if !models.CanReadMedicalNotes(currentUser.Role) {
+		problem.Write(ctx, apperror.Forbidden(apperror.CodeForbidden, "Only doctors may search medical notes"))
+		return
+	}
+
+	// Matches are always ordered by relevance
+	if query.Sort != "" {
+		problem.Write(ctx, apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+			Field:   "sort",
+			Code:    "excluded_with",
+			Message: "cannot be combined with q",
+		}))
+		return
+	}
+
+	page, err := c.patientService.SearchNotes(ctx.Request.Context(), query.Q, query.PatientSearchRequest, request)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Write(ctx, err)
 		return
 	}
 
-	// Convert to response
-	var response []models.PatientResponse
-	for _, patient := range patients {
-		response = append(response, patient.ToResponse())
+	response := pageResponse(ctx, page, func(match *models.PatientMatch) interface{} {
+		return selectFields(match.ToResult(), fields)
+	})
+	response.Facets, err = c.patientService.Facets(ctx.Request.Context(), query.Q, query.PatientSearchRequest, facets)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
+// @Summary Export patients
+// @Description Stream patients matching the search filters as CSV, NDJSON or XLSX (Both Receptionist and Doctor). Medical notes are only included for doctors.
+// @Tags patients
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "Export format: csv, ndjson or xlsx (default: csv)"
+// @Param name query string false "Patient name"
+// @Param age_min query int false "Minimum age"
+// @Param age_max query int false "Maximum age"
+// @Param gender query string false "Patient gender"
+// @Param contact_info query string false "Contact information"
+// @Param threshold query number false "Least similarity (0-1] of a name or contact info that is not a substring match (default: 0.3)"
+// @Param match query string false "How names are matched: fuzzy (default) or phonetic, which also matches names that sound alike"
+// @Param filter query string false "Filter expression, e.g. age>=65 and gender=female and created_at>2026-01-01"
+// @Success 200 {file} file
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/patients/export [get]
+// @Security Bearer
+func (c *PatientController) ExportPatients(ctx *gin.Context) {
+	var request models.PatientExportRequest
+
+	// Bind query parameters
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	// Get current user
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	format := export.Format(request.Format)
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	// A filter that does not parse is rejected before the export is recorded
+	if _, err := request.ParseFilter(); err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	// Record the export before any data leaves the system
+	err := c.auditService.Record(ctx.Request.Context(), currentUser.ID, models.AuditActionPatientExport, "patients", 0, ctx.Request.URL.RawQuery)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	// Headers are set before the writer, which may write its first bytes as
+	// soon as it is created
+	ctx.Header("Content-Type", format.ContentType())
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="patients-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format))
+
+	writer, err := export.NewWriter(format, ctx.Writer)
+	if err != nil {
+		writeExportError(ctx, apperror.Validation(apperror.CodeValidationFailed, err.Error()))
+		return
+	}
+
+	// Stream rows straight from the database cursor to the client
+	rows := 0
+	err = c.patientService.Export(ctx.Request.Context(), request.PatientSearchRequest, func(patient *models.Patient) error {
+		if err := writer.Write(patient.ToResponse().RedactFor(currentUser.Role)); err != nil {
+			return err
+		}
+		rows++
+		if rows%500 == 0 {
+			ctx.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		// Once the body has started the status can no longer be changed, so
+		// the connection is reset instead of ending the response, which
+		// would make the truncated file look complete
+		if !ctx.Writer.Written() {
+			writeExportError(ctx, err)
+			return
+		}
+		log.Printf("patient export aborted after %d rows: %v", rows, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// writeExportError writes the error of an export that has not started, in
+// place of the file headers
+func writeExportError(ctx *gin.Context, err error) {
+	ctx.Header("Content-Disposition", "")
+	problem.Write(ctx, err)
+}
+
+// decodeDocument decodes and validates a patched patient document. Members
+// other than the patchable fields, such as id or version, are rejected.
+func decodeDocument(data []byte) (*models.PatientDocument, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var document models.PatientDocument
+	if err := decoder.Decode(&document); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, problem.Binding(err)
+		}
+		return nil, apperror.Validation(apperror.CodeInvalidPatch, "patched patient is invalid: "+strings.TrimPrefix(err.Error(), "json: "))
+	}
+	if err := binding.Validator.ValidateStruct(&document); err != nil {
+		return nil, problem.Binding(err)
+	}
+	return &document, nil
+}
+
+// writeUpdateError writes the error of a conditional update. A version
+// conflict that slipped past the If-Match check is answered with the
+// patient as it is now.
+func (c *PatientController) writeUpdateError(ctx *gin.Context, id uint, err error) {
+	if apperror.KindOf(err) != apperror.KindPreconditionFailed {
+		problem.Write(ctx, err)
+		return
+	}
+
+	current, getErr := c.patientService.GetByID(ctx.Request.Context(), id)
+	if getErr != nil {
+		problem.Write(ctx, getErr)
+		return
+	}
+	writePreconditionFailed(ctx, current, err)
+}
+
 // RegisterRoutes registers the patient routes
 func (c *PatientController) RegisterRoutes(router *gin.Engine) {
 	patients := router.Group("/api/patients")
-	patients.Use(c.authMiddleware.Authenticate())
+	patients.Use(c.authMiddleware.Authenticate(), c.rateLimiter.Limit(middleware.RateLimitDefault))
 	{
 		// Routes for both receptionist and doctor
 		patients.GET("", c.ListPatients)
 		patients.GET("/:id", c.GetPatient)
+		patients.GET("/export", c.ExportPatients)
+		patients.GET("/search", c.rateLimiter.Limit(middleware.RateLimitSearch), c.SearchPatients)
 
 		// Routes for receptionist only
 		receptionistRoutes := patients.Group("")
 		receptionistRoutes.Use(c.authMiddleware.RequireRole(models.RoleReceptionist))
 		{
-			receptionistRoutes.POST("", c.CreatePatient)
+			receptionistRoutes.POST("", c.idempotency.Handle(), c.CreatePatient)
 			receptionistRoutes.PUT("/:id", c.UpdatePatient)
+			receptionistRoutes.PATCH("/:id", c.PatchPatient)
 			receptionistRoutes.DELETE("/:id", c.DeletePatient)
-			receptionistRoutes.GET("/search", c.SearchPatients)
 		}
 
 		// Routes for doctor only
@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/problem"
+	"hospital-project/internal/services"
+)
+
+// SavedSearchController handles requests for saved patient searches and
+// running them as worklists
+type SavedSearchController struct {
+	savedSearchService services.SavedSearchService
+	authMiddleware     *middleware.AuthMiddleware
+	rateLimiter        *middleware.RateLimiter
+	idempotency        *middleware.IdempotencyMiddleware
+}
+
+// NewSavedSearchController creates a new saved search controller
+func NewSavedSearchController(savedSearchService services.SavedSearchService, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter, idempotency *middleware.IdempotencyMiddleware) *SavedSearchController {
+	return &SavedSearchController{
+		savedSearchService: savedSearchService,
+		authMiddleware:     authMiddleware,
+		rateLimiter:        rateLimiter,
+		idempotency:        idempotency,
+	}
+}
+
+// @Summary Save search
+// @Description Save a named patient search to run again, optionally shared with every user of a role (Both Receptionist and Doctor)
+// @Tags worklists
+// @Accept json
+// @Produce json
+// @Param request body models.SavedSearchRequest true "Saved search details"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original response"
+// @Success 201 {object} models.SavedSearchResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/worklists [post]
+// @Security Bearer
+func (c *SavedSearchController) CreateSavedSearch(ctx *gin.Context) {
+	var request models.SavedSearchRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	search, err := c.savedSearchService.Create(ctx.Request.Context(), request, currentUser.ID)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, search.ToResponse())
+}
+
+// @Summary List saved searches
+// @Description List the saved searches of the current user and those shared with their role, by name (Both Receptionist and Doctor). Pages are selected like the patient list, by page number or cursor.
+// @Tags worklists
+// @Produce json
+// @Param pinned query bool false "List only the searches the current user pinned as worklists"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Number of items per page (default: 10, max: 100)"
+// @Param cursor query string false "Cursor from a previous page, used instead of page"
+// @Param include_total query bool false "Count the total when paging by cursor"
+// @Success 200 {object} models.PaginatedResponse[models.SavedSearchResponse]
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/worklists [get]
+// @Security Bearer
+func (c *SavedSearchController) ListSavedSearches(ctx *gin.Context) {
+	var query models.SavedSearchListQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+	request, err := pagination.NewRequest(query.Page, query.Limit, query.Cursor, query.IncludeTotal)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	page, err := c.savedSearchService.List(ctx.Request.Context(), currentUser.ID, currentUser.Role, query.Pinned, request)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pageResponse(ctx, page, (*models.SavedSearch).ToResponse))
+}
+
+// @Summary Get saved search
+// @Description Get a saved search of the current user or one shared with their role (Both Receptionist and Doctor)
+// @Tags worklists
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Success 200 {object} models.SavedSearchResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/worklists/{id} [get]
+// @Security Bearer
+func (c *SavedSearchController) GetSavedSearch(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "saved search ID")
+	if !ok {
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	search, err := c.savedSearchService.GetByID(ctx.Request.Context(), id, currentUser.ID, currentUser.Role)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, search.ToResponse())
+}
+
+// @Summary Update saved search
+// @Description Replace the name, search, sort or sharing of a saved search (owner only)
+// @Tags worklists
+// @Accept json
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Param request body models.SavedSearchRequest true "Saved search details"
+// @Success 200 {object} models.SavedSearchResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/worklists/{id} [put]
+// @Security Bearer
+func (c *SavedSearchController) UpdateSavedSearch(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "saved search ID")
+	if !ok {
+		return
+	}
+
+	var request models.SavedSearchRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	search, err := c.savedSearchService.Update(ctx.Request.Context(), id, request, currentUser.ID, currentUser.Role)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, search.ToResponse())
+}
+
+// @Summary Delete saved search
+// @Description Delete a saved search (owner only)
+// @Tags worklists
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/worklists/{id} [delete]
+// @Security Bearer
+func (c *SavedSearchController) DeleteSavedSearch(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "saved search ID")
+	if !ok {
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	if err := c.savedSearchService.Delete(ctx.Request.Context(), id, currentUser.ID, currentUser.Role); err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
+
+// @Summary Pin saved search
+// @Description Pin a saved search as one of the current user's worklists. Pins belong to each user, so a search shared with the user's role can be pinned too (Both Receptionist and Doctor).
+// @Tags worklists
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Success 200 {object} models.SavedSearchResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/worklists/{id}/pin [put]
+// @Security Bearer
+func (c *SavedSearchController) PinSavedSearch(ctx *gin.Context) {
+	c.setPinned(ctx, true)
+}
+
+// @Summary Unpin saved search
+// @Description Remove a saved search from the current user's worklists (Both Receptionist and Doctor)
+// @Tags worklists
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Success 200 {object} models.SavedSearchResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/worklists/{id}/pin [delete]
+// @Security Bearer
+func (c *SavedSearchController) UnpinSavedSearch(ctx *gin.Context) {
+	c.setPinned(ctx, false)
+}
+
+// setPinned pins or unpins the saved search in the path for the current user
+func (c *SavedSearchController) setPinned(ctx *gin.Context, pinned bool) {
+	id, ok := pathID(ctx, "id", "saved search ID")
+	if !ok {
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	search, err := c.savedSearchService.SetPinned(ctx.Request.Context(), id, currentUser.ID, currentUser.Role, pinned)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, search.ToResponse())
+}
+
+// @Summary Run worklist
+// @Description Run a saved search and return a page of the patients it finds, with their total (Both Receptionist and Doctor). Results are paged like a patient search, by page number or cursor.
+// @Tags worklists
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Param fields query string false "Comma-separated response fields to return; id is always included"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Number of items per page (default: 10, max: 100)"
+// @Param cursor query string false "Cursor from a previous page, used instead of page"
+// @Success 200 {object} models.PaginatedResponse[models.PatientSearchResult]
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/worklists/{id}/patients [get]
+// @Security Bearer
+func (c *SavedSearchController) RunWorklist(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "saved search ID")
+	if !ok {
+		return
+	}
+
+	var query models.WorklistQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+	fields, err := sparseFields[models.PatientSearchResult](query.Fields)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+	request, err := pagination.NewRequest(query.Page, query.Limit, query.Cursor, query.IncludeTotal)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(ctx)
+	if !ok {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	page, err := c.savedSearchService.Run(ctx.Request.Context(), id, currentUser.ID, currentUser.Role, request)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pageResponse(ctx, page, func(match *models.PatientMatch) interface{} {
+		return selectFields(match.ToResult(), fields)
+	}))
+}
+
+// RegisterRoutes registers the saved search and worklist routes
+func (c *SavedSearchController) RegisterRoutes(router *gin.Engine) {
+	worklists := router.Group("/api/worklists")
+	worklists.Use(c.authMiddleware.Authenticate(), c.rateLimiter.Limit(middleware.RateLimitDefault))
+	{
+		worklists.POST("", c.idempotency.Handle(), c.CreateSavedSearch)
+		worklists.GET("", c.ListSavedSearches)
+		worklists.GET("/:id", c.GetSavedSearch)
+		worklists.PUT("/:id", c.UpdateSavedSearch)
+		worklists.DELETE("/:id", c.DeleteSavedSearch)
+		worklists.PUT("/:id/pin", c.PinSavedSearch)
+		worklists.DELETE("/:id/pin", c.UnpinSavedSearch)
+		worklists.GET("/:id/patients", c.rateLimiter.Limit(middleware.RateLimitSearch), c.RunWorklist)
+	}
+}
@@ -2,12 +2,12 @@ package controllers
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"hospital-project/internal/middleware"
 	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
 	"hospital-project/internal/services"
 )
 
@@ -15,13 +15,17 @@ import (
 type UserController struct {
 	userService    services.UserService
 	authMiddleware *middleware.AuthMiddleware
+	rateLimiter    *middleware.RateLimiter
+	idempotency    *middleware.IdempotencyMiddleware
 }
 
 // NewUserController creates a new user controller
-func NewUserController(userService services.UserService, authMiddleware *middleware.AuthMiddleware) *UserController {
+func NewUserController(userService services.UserService, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter, idempotency *middleware.IdempotencyMiddleware) *UserController {
 	return &UserController{
 		userService:    userService,
 		authMiddleware: authMiddleware,
+		rateLimiter:    rateLimiter,
+		idempotency:    idempotency,
 	}
 }
 
@@ -30,10 +34,11 @@ func NewUserController(userService services.UserService, authMiddleware *middlew
 // @Tags users
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original response"
 // @Success 201 {object} models.UserResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/users [post]
 // @Security Bearer
 func (c *UserController) CreateUser(ctx *gin.Context) {
@@ -45,14 +50,14 @@ func (c *UserController) CreateUser(ctx *gin.Context) {
 
 	// Bind request body
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		problem.Write(ctx, problem.Binding(err))
 		return
 	}
 
 	// Create user
-	user, err := c.userService.Create(request.Username, request.Password, request.Role)
+	user, err := c.userService.Create(ctx.Request.Context(), request.Username, request.Password, request.Role)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Write(ctx, err)
 		return
 	}
 
@@ -65,25 +70,23 @@ func (c *UserController) CreateUser(ctx *gin.Context) {
 // @Produce json
 // @Param id path int true "User ID"
 // @Success 200 {object} models.UserResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/users/{id} [get]
 // @Security Bearer
 func (c *UserController) GetUser(ctx *gin.Context) {
 	// Get ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	id, ok := pathID(ctx, "id", "user ID")
+	if !ok {
 		return
 	}
 
 	// Get user
-	user, err := c.userService.GetByID(uint(id))
+	user, err := c.userService.GetByID(ctx.Request.Context(), id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		problem.Write(ctx, err)
 		return
 	}
 
@@ -95,15 +98,15 @@ func (c *UserController) GetUser(ctx *gin.Context) {
 // @Tags users
 // @Produce json
 // @Success 200 {object} models.UserResponse
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/users/me [get]
 // @Security Bearer
 func (c *UserController) GetCurrentUser(ctx *gin.Context) {
 	// Get user from context
 	user, ok := middleware.GetCurrentUser(ctx)
 	if !ok {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		problem.Write(ctx, errUnauthenticated())
 		return
 	}
 
@@ -113,9 +116,9 @@ func (c *UserController) GetCurrentUser(ctx *gin.Context) {
 // RegisterRoutes registers the user routes
 func (c *UserController) RegisterRoutes(router *gin.Engine) {
 	users := router.Group("/api/users")
-	users.Use(c.authMiddleware.Authenticate())
+	users.Use(c.authMiddleware.Authenticate(), c.rateLimiter.Limit(middleware.RateLimitDefault))
 	{
-		users.POST("", c.CreateUser)
+		users.POST("", c.idempotency.Handle(), c.CreateUser)
 		users.GET("/:id", c.GetUser)
 		users.GET("/me", c.GetCurrentUser)
 	}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
+	"hospital-project/internal/services"
+)
+
+// WebhookController handles webhook subscription requests
+type WebhookController struct {
+	webhookService services.WebhookService
+	authMiddleware *middleware.AuthMiddleware
+	rateLimiter    *middleware.RateLimiter
+	idempotency    *middleware.IdempotencyMiddleware
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(webhookService services.WebhookService, authMiddleware *middleware.AuthMiddleware, rateLimiter *middleware.RateLimiter, idempotency *middleware.IdempotencyMiddleware) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+		authMiddleware: authMiddleware,
+		rateLimiter:    rateLimiter,
+		idempotency:    idempotency,
+	}
+}
+
+// @Summary Create webhook
+// @Description Subscribe an endpoint to patient lifecycle events. Endpoints must not resolve to loopback, private, link-local or unspecified addresses unless their host is allowed by WEBHOOK_ALLOWED_HOSTS. The signing secret is only returned here, and is left out when a retry with the same Idempotency-Key is replayed (Receptionist only)
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param request body models.CreateWebhookRequest true "Webhook details"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original response"
+// @Success 201 {object} models.WebhookSubscriptionResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/webhooks [post]
+// @Security Bearer
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	var request models.CreateWebhookRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	currentUser, exists := middleware.GetCurrentUser(ctx)
+	if !exists {
+		problem.Write(ctx, errUnauthenticated())
+		return
+	}
+
+	subscription, err := c.webhookService.CreateSubscription(ctx.Request.Context(), request, currentUser.ID)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	response := subscription.ToResponse()
+	response.Secret = subscription.Secret
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// @Summary List webhooks
+// @Description List webhook subscriptions (Receptionist only)
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} models.WebhookSubscriptionResponse
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/webhooks [get]
+// @Security Bearer
+func (c *WebhookController) ListWebhooks(ctx *gin.Context) {
+	subscriptions, err := c.webhookService.ListSubscriptions(ctx.Request.Context())
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	response := make([]models.WebhookSubscriptionResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		response = append(response, subscription.ToResponse())
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary Get webhook
+// @Description Get a webhook subscription by ID (Receptionist only)
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.WebhookSubscriptionResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/webhooks/{id} [get]
+// @Security Bearer
+func (c *WebhookController) GetWebhook(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "webhook ID")
+	if !ok {
+		return
+	}
+
+	subscription, err := c.webhookService.GetSubscription(ctx.Request.Context(), id)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscription.ToResponse())
+}
+
+// @Summary Update webhook
+// @Description Change the URL or events of a webhook, or re-enable it after it was disabled (Receptionist only)
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param request body models.UpdateWebhookRequest true "Webhook changes"
+// @Success 200 {object} models.WebhookSubscriptionResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/webhooks/{id} [put]
+// @Security Bearer
+func (c *WebhookController) UpdateWebhook(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "webhook ID")
+	if !ok {
+		return
+	}
+
+	var request models.UpdateWebhookRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		problem.Write(ctx, problem.Binding(err))
+		return
+	}
+
+	subscription, err := c.webhookService.UpdateSubscription(ctx.Request.Context(), id, request)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscription.ToResponse())
+}
+
+// @Summary Delete webhook
+// @Description Delete a webhook subscription (Receptionist only)
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/webhooks/{id} [delete]
+// @Security Bearer
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := c.webhookService.DeleteSubscription(ctx.Request.Context(), id); err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// @Summary List webhook deliveries
+// @Description List the delivery log of a webhook, newest first (Receptionist only)
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Number of items per page (default: 10, max: 100)"
+// @Success 200 {object} models.PaginatedResponse[models.WebhookDeliveryResponse]
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/webhooks/{id}/deliveries [get]
+// @Security Bearer
+func (c *WebhookController) ListDeliveries(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "webhook ID")
+	if !ok {
+		return
+	}
+
+	// Get pagination parameters
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	deliveries, total, err := c.webhookService.ListDeliveries(ctx.Request.Context(), id, page, limit)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	// Convert to response
+	responseData := make([]models.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		responseData = append(responseData, delivery.ToResponse())
+	}
+
+	ctx.JSON(http.StatusOK, models.PaginatedResponse[models.WebhookDeliveryResponse]{
+		Data:       responseData,
+		Page:       page,
+		Limit:      limit,
+		Total:      &total,
+		TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+	})
+}
+
+// @Summary Replay webhook delivery
+// @Description Queue a delivery to be sent again (Receptionist only)
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param deliveryId path int true "Delivery ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original response"
+// @Success 202 {object} models.WebhookDeliveryResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/webhooks/{id}/deliveries/{deliveryId}/replay [post]
+// @Security Bearer
+func (c *WebhookController) ReplayDelivery(ctx *gin.Context) {
+	id, ok := pathID(ctx, "id", "webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, ok := pathID(ctx, "deliveryId", "delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := c.webhookService.ReplayDelivery(ctx.Request.Context(), id, deliveryID)
+	if err != nil {
+		problem.Write(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, delivery.ToResponse())
+}
+
+// RegisterRoutes registers the webhook routes
+func (c *WebhookController) RegisterRoutes(router *gin.Engine) {
+	webhooks := router.Group("/api/webhooks")
+	webhooks.Use(c.authMiddleware.Authenticate(), c.rateLimiter.Limit(middleware.RateLimitDefault), c.authMiddleware.RequireRole(models.RoleReceptionist))
+	{
+		// The signing secret is not stored with the response for replays
+		webhooks.POST("", c.idempotency.Handle("secret"), c.CreateWebhook)
+		webhooks.GET("", c.ListWebhooks)
+		webhooks.GET("/:id", c.GetWebhook)
+		webhooks.PUT("/:id", c.UpdateWebhook)
+		webhooks.DELETE("/:id", c.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", c.ListDeliveries)
+		webhooks.POST("/:id/deliveries/:deliveryId/replay", c.idempotency.Handle(), c.ReplayDelivery)
+	}
+}
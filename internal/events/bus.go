@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Handler reacts to published events. Delivery is at-least-once, so
+// handlers must tolerate seeing the same event ID more than once.
+type Handler interface {
+	HandleEvent(ctx context.Context, event Event) error
+}
+
+// HandlerFunc adapts a function to the Handler interface
+type HandlerFunc func(ctx context.Context, event Event) error
+
+// HandleEvent calls f(ctx, event)
+func (f HandlerFunc) HandleEvent(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+// Bus delivers events to in-process subscribers
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []subscriber
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler under a name used in error messages
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, subscriber{name: name, handler: handler})
+}
+
+// Publish calls every subscriber in registration order and returns the
+// combined errors of those that failed
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := s.handler.HandleEvent(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"hospital-project/internal/config"
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+// Dispatcher publishes outbox events to the bus. An event stays in the
+// outbox until every subscriber has handled it, and a failed event holds
+// back the later events of the same patient so they are seen in order.
+// Events are locked while they are published, so several dispatchers can
+// share an outbox.
+type Dispatcher struct {
+	transactions repositories.TransactionManager
+	bus          *Bus
+	config       *config.Outbox
+}
+
+// NewDispatcher creates a new outbox dispatcher
+func NewDispatcher(transactions repositories.TransactionManager, bus *Bus, cfg *config.Outbox) *Dispatcher {
+	return &Dispatcher{
+		transactions: transactions,
+		bus:          bus,
+		config:       cfg,
+	}
+}
+
+// DispatchPending publishes up to one batch of pending events
+func (d *Dispatcher) DispatchPending(ctx context.Context) error {
+	return d.transactions.WithinTransaction(ctx, func(txCtx context.Context, repos *repositories.Repositories) error {
+		// Each round lists the next event of every patient, so the later
+		// events of a patient follow in the same batch once it is published
+		for handled := 0; handled < d.config.BatchSize; {
+			now := time.Now()
+			pending, err := repos.Outbox.ListPending(txCtx, now, d.config.BatchSize-handled)
+			if err != nil || len(pending) == 0 {
+				return err
+			}
+
+			for i := range pending {
+				row := &pending[i]
+				// Subscribers run outside the transaction, which only holds
+				// the locks on the events
+				if err := d.dispatch(ctx, row); err != nil {
+					row.Attempts++
+					row.LastError = err.Error()
+					next := now.Add(d.backoff(row.Attempts))
+					row.NextAttemptAt = &next
+					log.Printf("outbox: event %d (%s) failed, attempt %d: %v", row.ID, row.EventType, row.Attempts, err)
+				} else {
+					row.PublishedAt = &now
+					row.LastError = ""
+				}
+
+				if err := repos.Outbox.Update(txCtx, row); err != nil {
+					return err
+				}
+			}
+			handled += len(pending)
+		}
+		return nil
+	})
+}
+
+// PurgePublished deletes the events published longer ago than the
+// retention period
+func (d *Dispatcher) PurgePublished(ctx context.Context) error {
+	return d.transactions.WithinTransaction(ctx, func(ctx context.Context, repos *repositories.Repositories) error {
+		purged, err := repos.Outbox.PurgePublished(ctx, time.Now().Add(-d.config.Retention))
+		if purged > 0 {
+			log.Printf("outbox: purged %d published events", purged)
+		}
+		return err
+	})
+}
+
+// Run dispatches pending events every poll interval, and purges published
+// events every cleanup interval, until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(d.config.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.DispatchPending(ctx); err != nil {
+				log.Printf("outbox: failed to dispatch events: %v", err)
+			}
+		case <-cleanup.C:
+			if err := d.PurgePublished(ctx); err != nil {
+				log.Printf("outbox: failed to purge published events: %v", err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, row *models.OutboxEvent) error {
+	event, err := FromOutbox(row)
+	if err != nil {
+		return err
+	}
+	return d.bus.Publish(ctx, event)
+}
+
+// backoff returns the delay before the next attempt, doubling each time
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxBackoff {
+		delay = d.config.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"hospital-project/internal/models"
+)
+
+// AggregatePatient is the aggregate type of patient events
+const AggregatePatient = "patient"
+
+// Domain events raised by patient changes. The names are the wire names used
+// by webhooks.
+const (
+	PatientRegistered   = models.PatientEventCreated
+	PatientUpdated      = models.PatientEventUpdated
+	MedicalNotesUpdated = models.PatientEventMedicalNotesUpdated
+	PatientDeleted      = models.PatientEventDeleted
+)
+
+// Event is a published domain event
+type Event struct {
+	// ID is the outbox row ID; it increases with every event of a patient
+	ID         uint
+	Type       models.PatientEvent
+	PatientID  uint
+	OccurredAt time.Time
+	// Patient is the state of the patient after the change, including
+	// medical notes. Subscribers that send it elsewhere must redact it.
+	Patient models.PatientResponse
+}
+
+// NewOutboxEvent records a patient change for the outbox
+func NewOutboxEvent(eventType models.PatientEvent, patient *models.Patient, now time.Time) (*models.OutboxEvent, error) {
+	payload, err := json.Marshal(patient.ToResponse())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OutboxEvent{
+		AggregateType: AggregatePatient,
+		AggregateID:   patient.ID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		OccurredAt:    now,
+	}, nil
+}
+
+// FromOutbox decodes an outbox row into an Event
+func FromOutbox(row *models.OutboxEvent) (Event, error) {
+	event := Event{
+		ID:         row.ID,
+		Type:       row.EventType,
+		PatientID:  row.AggregateID,
+		OccurredAt: row.OccurredAt,
+	}
+	if err := json.Unmarshal([]byte(row.Payload), &event.Patient); err != nil {
+		return Event{}, fmt.Errorf("decode outbox event %d: %w", row.ID, err)
+	}
+	return event, nil
+}
@@ -0,0 +1,125 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"hospital-project/internal/models"
+)
+
+// Format type for export file formats
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Writer streams patient rows to an underlying io.Writer
+type Writer interface {
+	Write(patient models.PatientResponse) error
+	Close() error
+}
+
+// columns is the column order used by the tabular formats
+var columns = []string{
+	"id", "name", "age", "gender", "contact_info", "medical_notes", "created_at", "updated_at",
+}
+
+// NewWriter creates a writer for the given format
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// record flattens a patient into the tabular column order
+func record(p models.PatientResponse) []string {
+	return []string{
+		strconv.FormatUint(uint64(p.ID), 10),
+		p.Name,
+		strconv.Itoa(p.Age),
+		string(p.Gender),
+		p.ContactInfo,
+		p.MedicalNotes,
+		p.CreatedAt.UTC().Format(time.RFC3339),
+		p.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// csvWriter writes RFC 4180 CSV
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+// Write writes one patient row
+func (w *csvWriter) Write(patient models.PatientResponse) error {
+	fields := record(patient)
+	for i, field := range fields {
+		fields[i] = escapeFormula(field)
+	}
+	return w.writer.Write(fields)
+}
+
+// Close flushes any buffered rows
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// escapeFormula prevents spreadsheet applications from evaluating
+// user-supplied text as a formula when the CSV is opened
+func escapeFormula(field string) string {
+	if field != "" && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+		return "'" + field
+	}
+	return field
+}
+
+// ndjsonWriter writes one JSON object per line
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+// Write writes one patient object
+func (w *ndjsonWriter) Write(patient models.PatientResponse) error {
+	return w.encoder.Encode(patient)
+}
+
+// Close is a no-op, every row is written as soon as it is encoded
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
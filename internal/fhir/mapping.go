@@ -0,0 +1,366 @@
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+)
+
+// Default and maximum page sizes for Patient searches
+const (
+	DefaultCount = 20
+	MaxCount     = 100
+)
+
+// FromPatient maps a patient model to a FHIR Patient resource. If only the
+// age is known, the birth date is reported as the year the patient was born
+// in if their birthday has passed this year.
+func FromPatient(p *models.Patient, now time.Time) Patient {
+	updated := p.UpdatedAt.UTC()
+	active := true
+
+	resource := Patient{
+		ResourceType: "Patient",
+		ID:           strconv.FormatUint(uint64(p.ID), 10),
+		Meta:         &Meta{VersionID: strconv.FormatUint(uint64(p.Version), 10), LastUpdated: &updated},
+		Active:       &active,
+		Name:         []HumanName{splitName(p.Name)},
+		Gender:       string(p.Gender),
+		BirthDate:    strconv.Itoa(now.Year() - p.Age),
+	}
+	if p.BirthDate != nil {
+		resource.BirthDate = models.FormatDate(p.BirthDate)
+	}
+	if p.ContactInfo != "" {
+		resource.Telecom = []ContactPoint{contactPoint(p.ContactInfo)}
+	}
+	return resource
+}
+
+// ApplyToPatient validates a FHIR Patient resource and copies its
+// demographics onto the patient model
+func ApplyToPatient(r Patient, p *models.Patient, now time.Time) error {
+	if r.ResourceType != "Patient" {
+		return fmt.Errorf("resourceType must be Patient, got %q", r.ResourceType)
+	}
+
+	name := joinName(r.Name)
+	if name == "" {
+		return errors.New("Patient.name is required")
+	}
+
+	gender := models.Gender(r.Gender)
+	if gender != models.GenderMale && gender != models.GenderFemale && gender != models.GenderOther {
+		return errors.New("Patient.gender must be one of male, female or other")
+	}
+
+	age, err := ageFromBirthDate(r.BirthDate, now)
+	if err != nil {
+		return err
+	}
+
+	contact := ""
+	for _, telecom := range r.Telecom {
+		if telecom.Value != "" {
+			contact = telecom.Value
+			break
+		}
+	}
+	if contact == "" {
+		return errors.New("Patient.telecom is required")
+	}
+
+	p.Name = name
+	p.Gender = gender
+	p.Age = age
+	p.ContactInfo = contact
+	// A partial date only tells the age
+	p.BirthDate = nil
+	if len(r.BirthDate) == len(models.DateLayout) {
+		p.BirthDate, _ = models.ParseDate(r.BirthDate)
+	}
+	return nil
+}
+
+// splitName maps a single display name to a HumanName, treating the last word
+// as the family name
+func splitName(name string) HumanName {
+	human := HumanName{Use: "official", Text: name}
+	parts := strings.Fields(name)
+	if len(parts) > 0 {
+		human.Family = parts[len(parts)-1]
+		if len(parts) > 1 {
+			human.Given = parts[:len(parts)-1]
+		}
+	}
+	return human
+}
+
+// joinName picks the official name, or the first one, and renders it as text
+func joinName(names []HumanName) string {
+	if len(names) == 0 {
+		return ""
+	}
+	chosen := names[0]
+	for _, name := range names {
+		if name.Use == "official" {
+			chosen = name
+			break
+		}
+	}
+	if text := strings.TrimSpace(chosen.Text); text != "" {
+		return text
+	}
+	parts := append([]string{}, chosen.Given...)
+	if chosen.Family != "" {
+		parts = append(parts, chosen.Family)
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// contactPoint guesses the telecom system of free-text contact info
+func contactPoint(contact string) ContactPoint {
+	if strings.Contains(contact, "@") {
+		return ContactPoint{System: "email", Value: contact}
+	}
+	return ContactPoint{System: "phone", Value: contact}
+}
+
+// ageFromBirthDate computes the age in whole years from a FHIR date, which
+// may be a year, a year and month, or a full date
+func ageFromBirthDate(birthDate string, now time.Time) (int, error) {
+	if birthDate == "" {
+		return 0, errors.New("Patient.birthDate is required")
+	}
+
+	var born time.Time
+	var err error
+	switch len(birthDate) {
+	case 4:
+		born, err = time.Parse("2006", birthDate)
+	case 7:
+		born, err = time.Parse("2006-01", birthDate)
+	default:
+		born, err = time.Parse("2006-01-02", birthDate)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Patient.birthDate %q is not a valid date", birthDate)
+	}
+
+	age := now.Year() - born.Year()
+	if len(birthDate) > 4 && (now.Month() < born.Month() || (now.Month() == born.Month() && now.Day() < born.Day())) {
+		age--
+	}
+	if age < 0 || age > 150 {
+		return 0, errors.New("Patient.birthDate must give an age between 0 and 150")
+	}
+	return age, nil
+}
+
+// SearchQuery is a parsed Patient search
+type SearchQuery struct {
+	Params models.PatientSearchRequest
+	Count  int
+	// Sort lists the PatientSortFields the _sort parameters order by
+	Sort []pagination.SortKey
+
+	// bornFrom and bornTo are the first and last birth dates the birthdate
+	// parameters allow, which are searched with a filter expression over the
+	// birth date, or the age of patients whose birth date is not known
+	bornFrom *time.Time
+	bornTo   *time.Time
+}
+
+// sortFields maps _sort parameters to the PatientSortFields they order by
+var sortFields = map[string]string{
+	"_id":          "id",
+	"_lastUpdated": "updated_at",
+	"name":         "name",
+	"gender":       "gender",
+	"birthdate":    "birth_date",
+}
+
+// ParseSearch parses Patient search parameters. Unknown parameters are
+// ignored, as allowed by lenient FHIR search handling.
+func ParseSearch(values url.Values, now time.Time) (*SearchQuery, error) {
+	query := &SearchQuery{Count: DefaultCount}
+
+	if name := values.Get("name"); name != "" {
+		query.Params.Name = name
+	}
+
+	if gender := values.Get("gender"); gender != "" {
+		g := models.Gender(gender)
+		if g != models.GenderMale && g != models.GenderFemale && g != models.GenderOther {
+			return nil, fmt.Errorf("unsupported gender %q", gender)
+		}
+		query.Params.Gender = g
+	}
+
+	for _, value := range values["birthdate"] {
+		if err := query.addBirthDate(value, now); err != nil {
+			return nil, err
+		}
+	}
+	query.Params.Filter = query.birthDateFilter(now)
+
+	if count := values.Get("_count"); count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("_count %q must be a non-negative integer", count)
+		}
+		if n > MaxCount {
+			n = MaxCount
+		}
+		query.Count = n
+	}
+
+	if sortParam := values.Get("_sort"); sortParam != "" {
+		for _, field := range strings.Split(sortParam, ",") {
+			name := strings.TrimSpace(field)
+			descending := strings.HasPrefix(name, "-")
+			column, ok := sortFields[strings.TrimPrefix(name, "-")]
+			if !ok {
+				return nil, fmt.Errorf("unsupported _sort parameter %q", field)
+			}
+			query.Sort = append(query.Sort, pagination.SortKey{Field: column, Descending: descending})
+		}
+	}
+
+	return query, nil
+}
+
+// addBirthDate narrows the birth dates by a birthdate parameter such as
+// "1980", "ge1950-06" or "lt2000-06-01". A partial date stands for every day
+// of its year or month.
+func (q *SearchQuery) addBirthDate(value string, now time.Time) error {
+	prefix := "eq"
+	if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+		prefix, value = value[:2], value[2:]
+	}
+
+	var first time.Time
+	var err error
+	switch len(value) {
+	case 4:
+		first, err = time.Parse("2006", value)
+	case 7:
+		first, err = time.Parse("2006-01", value)
+	default:
+		first, err = time.Parse("2006-01-02", value)
+	}
+	if err != nil {
+		return fmt.Errorf("birthdate %q is not a valid date", value)
+	}
+	last := first
+	switch len(value) {
+	case 4:
+		last = first.AddDate(1, 0, -1)
+	case 7:
+		last = first.AddDate(0, 1, -1)
+	}
+
+	switch prefix {
+	case "eq":
+		q.raiseFrom(first)
+		q.lowerTo(last)
+	case "ge":
+		q.raiseFrom(first)
+	case "gt":
+		q.raiseFrom(last.AddDate(0, 0, 1))
+	case "le":
+		q.lowerTo(last)
+	case "lt":
+		q.lowerTo(first.AddDate(0, 0, -1))
+	default:
+		return fmt.Errorf("unsupported birthdate prefix %q", prefix)
+	}
+	return nil
+}
+
+func (q *SearchQuery) raiseFrom(date time.Time) {
+	if q.bornFrom == nil || date.After(*q.bornFrom) {
+		q.bornFrom = &date
+	}
+}
+
+func (q *SearchQuery) lowerTo(date time.Time) {
+	if q.bornTo == nil || date.Before(*q.bornTo) {
+		q.bornTo = &date
+	}
+}
+
+// birthDateFilter returns the filter expression of the birthdate bounds, or
+// an empty one if there are none. Patients without a birth date are matched
+// by their age instead.
+func (q *SearchQuery) birthDateFilter(now time.Time) string {
+	var bounds []string
+	if q.bornFrom != nil {
+		bounds = append(bounds, "birth_date >= "+q.bornFrom.Format(models.DateLayout))
+	}
+	if q.bornTo != nil {
+		bounds = append(bounds, "birth_date <= "+q.bornTo.Format(models.DateLayout))
+	}
+	if len(bounds) == 0 {
+		return ""
+	}
+	return strings.Join(bounds, " and ") + " or birth_date = null and " + q.ageFilter(now)
+}
+
+// ageFilter returns the filter expression of the ages patients born between
+// the birthdate bounds can have now, or an empty one if there are no bounds
+func (q *SearchQuery) ageFilter(now time.Time) string {
+	var bounds []string
+	if q.bornTo != nil {
+		// Nobody is younger than a newborn
+		bounds = append(bounds, fmt.Sprintf("age >= %d", max(models.AgeAt(*q.bornTo, now), 0)))
+	}
+	if q.bornFrom != nil {
+		bounds = append(bounds, fmt.Sprintf("age <= %d", models.AgeAt(*q.bornFrom, now)))
+	}
+	return strings.Join(bounds, " and ")
+}
+
+// Capabilities returns the CapabilityStatement of this server
+func Capabilities(now time.Time) CapabilityStatement {
+	return CapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         now.UTC().Format("2006-01-02"),
+		Kind:         "instance",
+		Software:     CapabilityStatementSoftware{Name: "Hospital Management System API", Version: "1.0"},
+		FHIRVersion:  Version,
+		Format:       []string{"application/fhir+json", "json"},
+		Rest: []CapabilityStatementRest{
+			{
+				Mode:     "server",
+				Security: &CapabilityStatementSecurity{Description: "Bearer JWT issued by POST /api/auth/login"},
+				Resource: []CapabilityStatementResource{
+					{
+						Type:    "Patient",
+						Profile: "http://hl7.org/fhir/StructureDefinition/Patient",
+						Interaction: []CapabilityStatementInteraction{
+							{Code: "read"},
+							{Code: "search-type"},
+							{Code: "create"},
+							{Code: "update"},
+						},
+						SearchParam: []CapabilityStatementSearchParam{
+							{Name: "name", Type: "string", Definition: "http://hl7.org/fhir/SearchParameter/individual-name"},
+							{Name: "gender", Type: "token", Definition: "http://hl7.org/fhir/SearchParameter/individual-gender"},
+							{Name: "birthdate", Type: "date", Definition: "http://hl7.org/fhir/SearchParameter/individual-birthdate"},
+							{Name: "_count", Type: "number"},
+							{Name: "_sort", Type: "string"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,143 @@
+package fhir
+
+import "time"
+
+// ContentType is the media type for FHIR JSON resources
+const ContentType = "application/fhir+json; charset=utf-8"
+
+// Version is the FHIR release implemented by this server
+const Version = "4.0.1"
+
+// Meta is the metadata carried by every resource
+type Meta struct {
+	VersionID   string     `json:"versionId,omitempty"`
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+// HumanName is a name of a person
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+// ContactPoint is a phone number or email address
+type ContactPoint struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+// Patient is the FHIR R4 Patient resource
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id,omitempty"`
+	Meta         *Meta          `json:"meta,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+	Gender       string         `json:"gender,omitempty"`
+	BirthDate    string         `json:"birthDate,omitempty"`
+}
+
+// BundleLink is a link related to a bundle
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+// BundleSearch carries search-specific information of an entry
+type BundleSearch struct {
+	Mode string `json:"mode,omitempty"`
+}
+
+// BundleEntry is an entry in a bundle
+type BundleEntry struct {
+	FullURL  string        `json:"fullUrl,omitempty"`
+	Resource interface{}   `json:"resource,omitempty"`
+	Search   *BundleSearch `json:"search,omitempty"`
+}
+
+// Bundle is a container for a collection of resources
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Total        *int          `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+// OperationOutcomeIssue is a single issue in an OperationOutcome
+type OperationOutcomeIssue struct {
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+	Expression  string `json:"expression,omitempty"`
+}
+
+// OperationOutcome is a collection of error, warning or information messages
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+// NewOperationOutcome creates an OperationOutcome with a single error issue
+func NewOperationOutcome(code, diagnostics string) OperationOutcome {
+	return OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue: []OperationOutcomeIssue{
+			{Severity: "error", Code: code, Diagnostics: diagnostics},
+		},
+	}
+}
+
+// CapabilityStatementSearchParam is a search parameter supported by a resource
+type CapabilityStatementSearchParam struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Definition string `json:"definition,omitempty"`
+}
+
+// CapabilityStatementInteraction is an operation supported by a resource
+type CapabilityStatementInteraction struct {
+	Code string `json:"code"`
+}
+
+// CapabilityStatementResource describes how a resource type is supported
+type CapabilityStatementResource struct {
+	Type        string                           `json:"type"`
+	Profile     string                           `json:"profile,omitempty"`
+	Interaction []CapabilityStatementInteraction `json:"interaction"`
+	SearchParam []CapabilityStatementSearchParam `json:"searchParam,omitempty"`
+}
+
+// CapabilityStatementSecurity describes the security of the REST endpoint
+type CapabilityStatementSecurity struct {
+	Description string `json:"description,omitempty"`
+}
+
+// CapabilityStatementRest describes the RESTful capabilities of the server
+type CapabilityStatementRest struct {
+	Mode     string                        `json:"mode"`
+	Security *CapabilityStatementSecurity  `json:"security,omitempty"`
+	Resource []CapabilityStatementResource `json:"resource"`
+}
+
+// CapabilityStatementSoftware describes the software exposing the API
+type CapabilityStatementSoftware struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// CapabilityStatement describes the capabilities of this FHIR server
+type CapabilityStatement struct {
+	ResourceType string                      `json:"resourceType"`
+	Status       string                      `json:"status"`
+	Date         string                      `json:"date"`
+	Kind         string                      `json:"kind"`
+	Software     CapabilityStatementSoftware `json:"software"`
+	FHIRVersion  string                      `json:"fhirVersion"`
+	Format       []string                    `json:"format"`
+	Rest         []CapabilityStatementRest   `json:"rest"`
+}
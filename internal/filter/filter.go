@@ -0,0 +1,558 @@
+// Package filter parses filter expressions such as
+//
+//	age >= 65 and gender = female and created_at > 2026-01-01
+//
+// into parameterized SQL conditions over a whitelisted set of fields.
+//
+// The grammar is:
+//
+//	expression = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "not" factor | "(" expression ")" | condition
+//	condition  = field operator value | field "in" "(" value { "," value } ")"
+//	operator   = "=" | "!=" | "<" | "<=" | ">" | ">=" | "~"
+//	value      = word | quoted string | "null"
+//
+// Keywords are case-insensitive. A word is a run of characters other than
+// spaces, quotes, parentheses, commas and operators; strings with those
+// characters are quoted with " or ', and a backslash escapes the next
+// character. "~" matches strings containing the value, ignoring case.
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"hospital-project/internal/apperror"
+)
+
+// Limits on the size of a filter, so that parsing and the resulting query
+// stay cheap
+const (
+	MaxLength     = 2000
+	MaxConditions = 50
+	MaxDepth      = 20
+)
+
+// Type of a field, which decides the values and operators it accepts
+type Type int
+
+const (
+	// String fields accept any value and can be matched with "~"
+	String Type = iota
+	// Integer fields accept whole numbers
+	Integer
+	// Enum fields accept one of the field's Values
+	Enum
+	// Date fields accept dates as YYYY-MM-DD
+	Date
+	// Timestamp fields accept dates, which stand for the whole day in UTC,
+	// and RFC 3339 timestamps
+	Timestamp
+)
+
+// operators lists the operators each type of field accepts
+var operators = map[Type][]string{
+	String:    {"=", "!=", "~", "in"},
+	Integer:   {"=", "!=", "<", "<=", ">", ">=", "in"},
+	Enum:      {"=", "!=", "in"},
+	Date:      {"=", "!=", "<", "<=", ">", ">="},
+	Timestamp: {"=", "!=", "<", "<=", ">", ">="},
+}
+
+// Field is a field filters can name
+type Field struct {
+	// Column is the SQL column the field is stored in
+	Column string
+	Type   Type
+	// Values are the values of an Enum field
+	Values []string
+	// Nullable fields can be compared to null
+	Nullable bool
+}
+
+// Fields are the fields a filter can name, by name
+type Fields map[string]Field
+
+// names returns the names of the fields, sorted
+func (f Fields) names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Filter is a parsed filter expression
+type Filter struct {
+	root node
+}
+
+// Parse parses a filter expression over the given fields. Errors are
+// validation errors that give the position of the offending text.
+func Parse(input string, fields Fields) (*Filter, error) {
+	if len(input) > MaxLength {
+		return nil, invalid(fmt.Sprintf("filter is longer than %d characters", MaxLength))
+	}
+	if !utf8.ValidString(input) {
+		return nil, invalid("filter is not valid UTF-8")
+	}
+
+	p := &parser{lexer: lexer{input: input}, fields: fields}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenEnd {
+		return nil, invalid("filter is empty")
+	}
+	root, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenEnd {
+		return nil, p.unexpected("\"and\", \"or\" or the end of the filter")
+	}
+	return &Filter{root: root}, nil
+}
+
+// SQL returns the filter as an SQL condition with a ? placeholder for each
+// of the returned values
+func (f *Filter) SQL() (string, []interface{}) {
+	var b strings.Builder
+	var vars []interface{}
+	f.root.sql(&b, &vars)
+	return b.String(), vars
+}
+
+// String returns the filter in canonical form, which parses to the same
+// filter
+func (f *Filter) String() string {
+	var b strings.Builder
+	f.root.format(&b)
+	return b.String()
+}
+
+// node is a node of the syntax tree of a filter
+type node interface {
+	sql(b *strings.Builder, vars *[]interface{})
+	format(b *strings.Builder)
+	// precedence is how tightly the node binds in the canonical form
+	precedence() int
+}
+
+// logical joins two conditions with "and" or "or"
+type logical struct {
+	operator    string
+	left, right node
+}
+
+func (n *logical) sql(b *strings.Builder, vars *[]interface{}) {
+	b.WriteString("(")
+	n.left.sql(b, vars)
+	b.WriteString(" " + strings.ToUpper(n.operator) + " ")
+	n.right.sql(b, vars)
+	b.WriteString(")")
+}
+
+func (n *logical) format(b *strings.Builder) {
+	// Parentheses are only written where the tree differs from the one
+	// precedence and left associativity would give, so that the canonical
+	// form is nested no deeper than the input
+	formatOperand(b, n.left, n.left.precedence() < n.precedence())
+	b.WriteString(" " + n.operator + " ")
+	formatOperand(b, n.right, n.right.precedence() <= n.precedence())
+}
+
+func (n *logical) precedence() int {
+	if n.operator == "or" {
+		return 1
+	}
+	return 2
+}
+
+// negation negates a condition
+type negation struct {
+	operand node
+}
+
+func (n *negation) sql(b *strings.Builder, vars *[]interface{}) {
+	b.WriteString("NOT ")
+	n.operand.sql(b, vars)
+}
+
+func (n *negation) format(b *strings.Builder) {
+	b.WriteString("not ")
+	formatOperand(b, n.operand, n.operand.precedence() < n.precedence())
+}
+
+func (n *negation) precedence() int {
+	return 3
+}
+
+// formatOperand formats an operand, in parentheses if parenthesize is set
+func formatOperand(b *strings.Builder, operand node, parenthesize bool) {
+	if parenthesize {
+		b.WriteString("(")
+	}
+	operand.format(b)
+	if parenthesize {
+		b.WriteString(")")
+	}
+}
+
+// value is a value of a condition, both as written and as passed to SQL
+type value struct {
+	text string
+	// quoted values are formatted as strings
+	quoted bool
+	// data is nil for null
+	data interface{}
+	// day is set for dates compared to a timestamp field
+	day bool
+}
+
+// condition compares a field to one or more values
+type condition struct {
+	name     string
+	field    Field
+	operator string
+	values   []value
+}
+
+func (n *condition) sql(b *strings.Builder, vars *[]interface{}) {
+	column := n.field.Column
+	v := n.values[0]
+	switch {
+	case n.operator == "in":
+		placeholders := make([]string, len(n.values))
+		for i, v := range n.values {
+			placeholders[i] = "?"
+			*vars = append(*vars, v.data)
+		}
+		fmt.Fprintf(b, "%s IN (%s)", column, strings.Join(placeholders, ", "))
+	case v.data == nil:
+		if n.operator == "=" {
+			fmt.Fprintf(b, "%s IS NULL", column)
+		} else {
+			fmt.Fprintf(b, "%s IS NOT NULL", column)
+		}
+	case n.operator == "~":
+		fmt.Fprintf(b, "%s ILIKE ?", column)
+		*vars = append(*vars, "%"+escapeLike(v.data.(string))+"%")
+	case v.day:
+		// A date compared to a timestamp stands for the whole day
+		start := v.data.(time.Time)
+		end := start.AddDate(0, 0, 1)
+		switch n.operator {
+		case "=":
+			fmt.Fprintf(b, "(%[1]s >= ? AND %[1]s < ?)", column)
+			*vars = append(*vars, start, end)
+		case "!=":
+			fmt.Fprintf(b, "(%[1]s < ? OR %[1]s >= ?)", column)
+			*vars = append(*vars, start, end)
+		case "<":
+			fmt.Fprintf(b, "%s < ?", column)
+			*vars = append(*vars, start)
+		case "<=":
+			fmt.Fprintf(b, "%s < ?", column)
+			*vars = append(*vars, end)
+		case ">":
+			fmt.Fprintf(b, "%s >= ?", column)
+			*vars = append(*vars, end)
+		case ">=":
+			fmt.Fprintf(b, "%s >= ?", column)
+			*vars = append(*vars, start)
+		}
+	case n.operator == "!=":
+		// Unlike <>, IS DISTINCT FROM keeps the rows where the column is null
+		fmt.Fprintf(b, "%s IS DISTINCT FROM ?", column)
+		*vars = append(*vars, v.data)
+	default:
+		fmt.Fprintf(b, "%s %s ?", column, n.operator)
+		*vars = append(*vars, v.data)
+	}
+}
+
+func (n *condition) precedence() int {
+	return 4
+}
+
+func (n *condition) format(b *strings.Builder) {
+	b.WriteString(n.name)
+	if n.operator == "in" {
+		b.WriteString(" in (")
+		for i, v := range n.values {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			v.format(b)
+		}
+		b.WriteString(")")
+		return
+	}
+	b.WriteString(" " + n.operator + " ")
+	n.values[0].format(b)
+}
+
+func (v value) format(b *strings.Builder) {
+	if !v.quoted {
+		b.WriteString(v.text)
+		return
+	}
+	b.WriteString(`"`)
+	for _, r := range v.text {
+		if r == '"' || r == '\\' {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString(`"`)
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// parser is a recursive descent parser of filter expressions
+type parser struct {
+	lexer      lexer
+	fields     Fields
+	token      token
+	conditions int
+}
+
+// advance reads the next token
+func (p *parser) advance() error {
+	token, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = token
+	return nil
+}
+
+// keyword reports whether the current token is the given keyword
+func (p *parser) keyword(word string) bool {
+	return p.token.kind == tokenWord && strings.EqualFold(p.token.text, word)
+}
+
+func (p *parser) expression(depth int) (node, error) {
+	left, err := p.term(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.term(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{operator: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) term(depth int) (node, error) {
+	left, err := p.factor(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.factor(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{operator: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor(depth int) (node, error) {
+	if depth >= MaxDepth {
+		return nil, p.errorf("filter is nested more than %d levels deep", MaxDepth)
+	}
+
+	switch {
+	case p.keyword("not"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		operand, err := p.factor(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &negation{operand: operand}, nil
+	case p.token.kind == tokenOpen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		inner, err := p.expression(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.token.kind != tokenClose {
+			return nil, p.unexpected("\")\"")
+		}
+		return inner, p.advance()
+	default:
+		return p.condition()
+	}
+}
+
+func (p *parser) condition() (node, error) {
+	if p.token.kind != tokenWord {
+		return nil, p.unexpected("a field name")
+	}
+	name := p.token.text
+	field, ok := p.fields[name]
+	if !ok {
+		return nil, p.errorf("unknown field %q; filterable fields are %s", name, strings.Join(p.fields.names(), ", "))
+	}
+	p.conditions++
+	if p.conditions > MaxConditions {
+		return nil, p.errorf("filter has more than %d conditions", MaxConditions)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	operator := p.token.text
+	if p.keyword("in") {
+		operator = "in"
+	} else if p.token.kind != tokenOperator {
+		return nil, p.unexpected("an operator")
+	}
+	if !slices.Contains(operators[field.Type], operator) {
+		return nil, p.errorf("field %q does not support %q; use one of %s", name, operator, strings.Join(operators[field.Type], " "))
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	n := &condition{name: name, field: field, operator: operator}
+	if operator != "in" {
+		v, err := p.value(name, field, operator)
+		if err != nil {
+			return nil, err
+		}
+		n.values = []value{v}
+		return n, nil
+	}
+
+	if p.token.kind != tokenOpen {
+		return nil, p.unexpected("\"(\"")
+	}
+	for {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v, err := p.value(name, field, operator)
+		if err != nil {
+			return nil, err
+		}
+		n.values = append(n.values, v)
+		if p.token.kind == tokenClose {
+			return n, p.advance()
+		}
+		if p.token.kind != tokenComma {
+			return nil, p.unexpected("\",\" or \")\"")
+		}
+	}
+}
+
+// value parses the value of a condition on a field
+func (p *parser) value(name string, field Field, operator string) (value, error) {
+	if p.token.kind != tokenWord && p.token.kind != tokenString {
+		return value{}, p.unexpected("a value")
+	}
+	text, quoted := p.token.text, p.token.kind == tokenString
+	v := value{text: text, quoted: quoted}
+
+	if !quoted && strings.EqualFold(text, "null") {
+		if !field.Nullable {
+			return value{}, p.errorf("field %q is never null; quote \"null\" to match the word", name)
+		}
+		if operator != "=" && operator != "!=" {
+			return value{}, p.errorf("null can only be compared with = or !=")
+		}
+		v.text = "null"
+		return v, p.advance()
+	}
+
+	var err error
+	switch field.Type {
+	case String:
+		// String values are formatted quoted, so that a value such as "and"
+		// is not read as a keyword
+		v.data, v.quoted = text, true
+	case Integer:
+		var number int64
+		number, err = strconv.ParseInt(text, 10, 32)
+		v.data, v.text, v.quoted = number, strconv.FormatInt(number, 10), false
+	case Enum:
+		if !slices.Contains(field.Values, text) {
+			return value{}, p.errorf("field %q must be one of %s", name, strings.Join(field.Values, " "))
+		}
+		v.data, v.quoted = text, false
+	case Date:
+		var date time.Time
+		date, err = time.Parse(time.DateOnly, text)
+		v.data, v.text, v.quoted = date, date.Format(time.DateOnly), false
+	case Timestamp:
+		var at time.Time
+		if at, err = time.Parse(time.DateOnly, text); err == nil {
+			v.data, v.text, v.day = at, at.Format(time.DateOnly), true
+		} else if at, err = time.Parse(time.RFC3339Nano, text); err == nil {
+			v.data, v.text = at, at.Format(time.RFC3339Nano)
+		}
+		v.quoted = false
+	}
+	if err != nil {
+		return value{}, p.errorf("invalid value %q for field %q: %s", text, name, typeHint[field.Type])
+	}
+	return v, p.advance()
+}
+
+// typeHint describes the values each type of field accepts
+var typeHint = map[Type]string{
+	Integer:   "expected a whole number",
+	Date:      "expected a date as YYYY-MM-DD",
+	Timestamp: "expected a date as YYYY-MM-DD or an RFC 3339 timestamp",
+}
+
+// unexpected reports the current token where something else was expected
+func (p *parser) unexpected(expected string) error {
+	found := "end of filter"
+	if p.token.kind != tokenEnd {
+		found = strconv.Quote(p.token.text)
+	}
+	return p.errorf("expected %s but found %s", expected, found)
+}
+
+// errorf returns an error at the position of the current token
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return errorAt(p.lexer.input, p.token.offset, fmt.Sprintf(format, args...))
+}
+
+// errorAt returns an error at a byte offset of the input, reported as a
+// 1-based character position
+func errorAt(input string, offset int, message string) error {
+	position := utf8.RuneCountInString(input[:offset]) + 1
+	return invalid(fmt.Sprintf("%s at position %d", message, position))
+}
+
+func invalid(message string) error {
+	return apperror.Validation(apperror.CodeInvalidFilter, "invalid filter: "+message)
+}
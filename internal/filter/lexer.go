@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a token of a filter expression
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenComma
+)
+
+// token is a token of a filter expression
+type token struct {
+	kind tokenKind
+	// text is the word, operator or unquoted string
+	text string
+	// offset is the byte offset of the token in the input
+	offset int
+}
+
+// delimiters end a word
+const delimiters = `()=!<>~,"'`
+
+// lexer splits a filter expression into tokens
+type lexer struct {
+	input  string
+	offset int
+}
+
+// next returns the next token, or a token of kind tokenEnd at the end of
+// the input
+func (l *lexer) next() (token, error) {
+	for l.offset < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.offset:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.offset += size
+	}
+	start := l.offset
+	if start == len(l.input) {
+		return token{kind: tokenEnd, offset: start}, nil
+	}
+
+	switch c := l.input[start]; c {
+	case '(':
+		l.offset++
+		return token{kind: tokenOpen, text: "(", offset: start}, nil
+	case ')':
+		l.offset++
+		return token{kind: tokenClose, text: ")", offset: start}, nil
+	case ',':
+		l.offset++
+		return token{kind: tokenComma, text: ",", offset: start}, nil
+	case '=', '~':
+		l.offset++
+		return token{kind: tokenOperator, text: string(c), offset: start}, nil
+	case '<', '>', '!':
+		l.offset++
+		if l.offset < len(l.input) && l.input[l.offset] == '=' {
+			l.offset++
+		} else if c == '!' {
+			return token{}, errorAt(l.input, start, `unexpected "!"; expected "!="`)
+		}
+		return token{kind: tokenOperator, text: l.input[start:l.offset], offset: start}, nil
+	case '"', '\'':
+		return l.quoted(c)
+	}
+
+	for l.offset < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.offset:])
+		if unicode.IsSpace(r) || strings.ContainsRune(delimiters, r) {
+			break
+		}
+		l.offset += size
+	}
+	return token{kind: tokenWord, text: l.input[start:l.offset], offset: start}, nil
+}
+
+// quoted reads a string quoted with quote, in which a backslash escapes the
+// next character
+func (l *lexer) quoted(quote byte) (token, error) {
+	start := l.offset
+	l.offset++
+
+	var text strings.Builder
+	for l.offset < len(l.input) {
+		c := l.input[l.offset]
+		switch {
+		case c == quote:
+			l.offset++
+			return token{kind: tokenString, text: text.String(), offset: start}, nil
+		case c == '\\' && l.offset+1 < len(l.input):
+			_, size := utf8.DecodeRuneInString(l.input[l.offset+1:])
+			text.WriteString(l.input[l.offset+1 : l.offset+1+size])
+			l.offset += 1 + size
+		default:
+			text.WriteByte(c)
+			l.offset++
+		}
+	}
+	return token{}, errorAt(l.input, start, "unterminated string")
+}
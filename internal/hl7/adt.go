@@ -0,0 +1,181 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"hospital-project/internal/models"
+)
+
+// ADT trigger events handled by the interface
+const (
+	EventRegister = "A04"
+	EventUpdate   = "A08"
+	EventMerge    = "A40"
+)
+
+// DefaultIdentifierSystem is used when PID-3 carries no assigning authority
+const DefaultIdentifierSystem = "MR"
+
+// Identifier is a patient identifier from a CX field
+type Identifier struct {
+	System string
+	Value  string
+}
+
+// Visit is the subset of PV1 recorded with each message
+type Visit struct {
+	PatientClass    string
+	Location        string
+	AttendingDoctor string
+	VisitNumber     string
+}
+
+// ADT is the content of an ADT^A04, A08 or A40 message
+type ADT struct {
+	Event       string
+	ControlID   string
+	Identifier  Identifier
+	Name        string
+	BirthDate   time.Time
+	Gender      models.Gender
+	ContactInfo string
+	Visit       Visit
+
+	// PriorIdentifier is the identifier being merged away by an A40
+	PriorIdentifier Identifier
+}
+
+// ParseADT extracts the patient demographics, visit and merge information
+// from an ADT message
+func ParseADT(msg *Message) (*ADT, error) {
+	code, event := msg.Type()
+	if code != "ADT" {
+		return nil, fmt.Errorf("unsupported message type %s", code)
+	}
+	if event != EventRegister && event != EventUpdate && event != EventMerge {
+		return nil, fmt.Errorf("unsupported ADT event %s", event)
+	}
+
+	pid, ok := msg.Segment("PID")
+	if !ok {
+		return nil, errors.New("PID segment is required")
+	}
+
+	adt := &ADT{Event: event, ControlID: msg.ControlID()}
+
+	adt.Identifier = identifier(msg, pid.Repetitions(3))
+	if adt.Identifier.Value == "" {
+		return nil, errors.New("PID-3 patient identifier is required")
+	}
+
+	adt.Name = name(pid)
+	if adt.Name == "" && event != EventMerge {
+		return nil, errors.New("PID-5 patient name is required")
+	}
+
+	if dob := pid.Component(7, 1); dob != "" {
+		if len(dob) < 8 {
+			return nil, fmt.Errorf("PID-7 birth date %q is not a valid date", dob)
+		}
+		born, err := time.Parse("20060102", dob[:8])
+		if err != nil {
+			return nil, fmt.Errorf("PID-7 birth date %q is not a valid date", dob)
+		}
+		adt.BirthDate = born
+	}
+
+	switch pid.Component(8, 1) {
+	case "M":
+		adt.Gender = models.GenderMale
+	case "F":
+		adt.Gender = models.GenderFemale
+	case "":
+	default:
+		adt.Gender = models.GenderOther
+	}
+
+	adt.ContactInfo = contact(msg, pid.Repetitions(13))
+	if adt.ContactInfo == "" {
+		adt.ContactInfo = contact(msg, pid.Repetitions(14))
+	}
+
+	if pv1, ok := msg.Segment("PV1"); ok {
+		adt.Visit = Visit{
+			PatientClass:    pv1.Component(2, 1),
+			Location:        strings.Trim(strings.Join([]string{pv1.Component(3, 1), pv1.Component(3, 2), pv1.Component(3, 3)}, "-"), "-"),
+			AttendingDoctor: strings.TrimSpace(pv1.Component(7, 3) + " " + pv1.Component(7, 2)),
+			VisitNumber:     pv1.Component(19, 1),
+		}
+	}
+
+	if event == EventMerge {
+		mrg, ok := msg.Segment("MRG")
+		if !ok {
+			return nil, errors.New("MRG segment is required for A40")
+		}
+		adt.PriorIdentifier = identifier(msg, mrg.Repetitions(1))
+		if adt.PriorIdentifier.Value == "" {
+			return nil, errors.New("MRG-1 prior patient identifier is required")
+		}
+	}
+
+	return adt, nil
+}
+
+// Age returns the patient's age in whole years at the given time
+func (a *ADT) Age(now time.Time) int {
+	if a.BirthDate.IsZero() {
+		return 0
+	}
+	return models.AgeAt(a.BirthDate, now)
+}
+
+// identifier picks the medical record number from a CX list, falling back to
+// the first repetition
+func identifier(msg *Message, repetitions []string) Identifier {
+	var chosen string
+	for _, repetition := range repetitions {
+		if msg.component(repetition, 5) == "MR" {
+			chosen = repetition
+			break
+		}
+	}
+	if chosen == "" && len(repetitions) > 0 {
+		chosen = repetitions[0]
+	}
+
+	id := Identifier{
+		Value:  msg.component(chosen, 1),
+		System: msg.component(chosen, 4),
+	}
+	if id.System == "" {
+		id.System = DefaultIdentifierSystem
+	}
+	return id
+}
+
+// name renders the first XPN repetition of PID-5 as "Given Middle Family"
+func name(pid Segment) string {
+	parts := []string{pid.Component(5, 2), pid.Component(5, 3), pid.Component(5, 1)}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// contact returns the first usable phone number or email address of an XTN
+// list
+func contact(msg *Message, repetitions []string) string {
+	for _, repetition := range repetitions {
+		if value := msg.component(repetition, 1); value != "" {
+			return value
+		}
+		if email := msg.component(repetition, 4); email != "" {
+			return email
+		}
+		if number := msg.component(repetition, 7); number != "" {
+			return msg.component(repetition, 6) + number
+		}
+	}
+	return ""
+}
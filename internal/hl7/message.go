@@ -0,0 +1,207 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Acknowledgment codes used in MSA-1
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// Message is a parsed HL7 v2 message
+type Message struct {
+	Segments []Segment
+
+	fieldSeparator        string
+	componentSeparator    string
+	repetitionSeparator   string
+	escapeCharacter       string
+	subcomponentSeparator string
+}
+
+// Segment is a single segment of a message
+type Segment struct {
+	Name   string
+	fields []string
+	msg    *Message
+}
+
+// Parse parses a pipe-delimited HL7 v2 message. Segments may be terminated
+// by carriage returns or newlines.
+func Parse(raw string) (*Message, error) {
+	raw = strings.TrimSpace(strings.ReplaceAll(raw, "\r\n", "\r"))
+	raw = strings.ReplaceAll(raw, "\n", "\r")
+	if !strings.HasPrefix(raw, "MSH") || len(raw) < 8 {
+		return nil, errors.New("message must start with an MSH segment")
+	}
+
+	msg := &Message{
+		fieldSeparator:        raw[3:4],
+		componentSeparator:    raw[4:5],
+		repetitionSeparator:   raw[5:6],
+		escapeCharacter:       raw[6:7],
+		subcomponentSeparator: raw[7:8],
+	}
+
+	for _, line := range strings.Split(raw, "\r") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, msg.fieldSeparator)
+		if len(fields[0]) != 3 {
+			return nil, fmt.Errorf("invalid segment name %q", fields[0])
+		}
+		msg.Segments = append(msg.Segments, Segment{Name: fields[0], fields: fields, msg: msg})
+	}
+
+	return msg, nil
+}
+
+// Segment returns the first segment with the given name
+func (m *Message) Segment(name string) (Segment, bool) {
+	for _, segment := range m.Segments {
+		if segment.Name == name {
+			return segment, true
+		}
+	}
+	return Segment{}, false
+}
+
+// Header returns the MSH segment
+func (m *Message) Header() Segment {
+	header, _ := m.Segment("MSH")
+	return header
+}
+
+// ControlID returns MSH-10, the message control ID
+func (m *Message) ControlID() string {
+	return m.Header().Field(10)
+}
+
+// Type returns the message code and trigger event from MSH-9, e.g. ADT, A04
+func (m *Message) Type() (string, string) {
+	header := m.Header()
+	return header.Component(9, 1), header.Component(9, 2)
+}
+
+// Field returns field n, counted as in the HL7 specification. For MSH the
+// field separator itself is MSH-1.
+func (s Segment) Field(n int) string {
+	if s.Name == "MSH" {
+		if n == 1 {
+			return s.msg.fieldSeparator
+		}
+		n--
+	}
+	if n < 1 || n >= len(s.fields) {
+		return ""
+	}
+	return s.fields[n]
+}
+
+// Repetitions returns every repetition of field n
+func (s Segment) Repetitions(n int) []string {
+	field := s.Field(n)
+	if field == "" {
+		return nil
+	}
+	if s.Name == "MSH" && n == 2 {
+		return []string{field}
+	}
+	return strings.Split(field, s.msg.repetitionSeparator)
+}
+
+// Component returns component c of the first repetition of field n, with
+// escape sequences decoded
+func (s Segment) Component(n, c int) string {
+	repetitions := s.Repetitions(n)
+	if len(repetitions) == 0 {
+		return ""
+	}
+	return s.msg.component(repetitions[0], c)
+}
+
+// component returns component c of a single field repetition
+func (m *Message) component(value string, c int) string {
+	components := strings.Split(value, m.componentSeparator)
+	if c < 1 || c > len(components) {
+		return ""
+	}
+	component := components[c-1]
+	if i := strings.Index(component, m.subcomponentSeparator); i >= 0 {
+		component = component[:i]
+	}
+	return m.unescape(component)
+}
+
+// unescape decodes the standard delimiter escape sequences
+func (m *Message) unescape(value string) string {
+	if !strings.Contains(value, m.escapeCharacter) {
+		return value
+	}
+	e := m.escapeCharacter
+	return strings.NewReplacer(
+		e+"F"+e, m.fieldSeparator,
+		e+"S"+e, m.componentSeparator,
+		e+"R"+e, m.repetitionSeparator,
+		e+"T"+e, m.subcomponentSeparator,
+		e+"E"+e, m.escapeCharacter,
+		e+".br"+e, "\n",
+	).Replace(value)
+}
+
+// escape encodes delimiters appearing in free text
+func escape(value string) string {
+	return strings.NewReplacer(
+		`\`, `\E\`,
+		"|", `\F\`,
+		"^", `\S\`,
+		"~", `\R\`,
+		"&", `\T\`,
+		"\r", `\.br\`,
+		"\n", `\.br\`,
+	).Replace(value)
+}
+
+// BuildACK builds an acknowledgment for the given message. The original
+// control ID is echoed in MSA-2 so the sender can correlate the response.
+func BuildACK(msg *Message, code, text string, now time.Time) string {
+	header := msg.Header()
+	_, trigger := msg.Type()
+	version := header.Field(12)
+	if version == "" {
+		version = "2.5.1"
+	}
+	processingID := header.Field(11)
+	if processingID == "" {
+		processingID = "P"
+	}
+
+	ack := strings.Join([]string{
+		"MSH", `^~\&`,
+		header.Field(5), header.Field(6),
+		header.Field(3), header.Field(4),
+		now.UTC().Format("20060102150405"), "",
+		"ACK^" + trigger + "^ACK",
+		"ACK" + now.UTC().Format("20060102150405.000000"),
+		processingID, version,
+	}, "|")
+	ack += "\rMSA|" + code + "|" + escape(msg.ControlID())
+	if text != "" {
+		ack += "|" + escape(text)
+	}
+	return ack + "\r"
+}
+
+// BuildReject builds an AR acknowledgment for input that could not be parsed
+// far enough to echo its header
+func BuildReject(text string, now time.Time) string {
+	return "MSH|^~\\&|||||" + now.UTC().Format("20060102150405") + "||ACK^^ACK|ACK" +
+		now.UTC().Format("20060102150405.000000") + "|P|2.5.1\rMSA|" + AckReject + "||" + escape(text) + "\r"
+}
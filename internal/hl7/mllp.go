@@ -0,0 +1,69 @@
+package hl7
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// MLLP framing characters
+const (
+	startBlock     = 0x0b
+	endBlock       = 0x1c
+	carriageReturn = 0x0d
+)
+
+// MaxMessageSize bounds the size of a single framed message
+const MaxMessageSize = 1 << 20
+
+// ErrMessageTooLarge is returned when a frame exceeds MaxMessageSize
+var ErrMessageTooLarge = errors.New("hl7 message exceeds maximum size")
+
+// ReadFrame reads one MLLP-framed message. Bytes before the start block are
+// discarded.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == startBlock {
+			break
+		}
+	}
+
+	var frame []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if b == endBlock {
+			next, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if next != carriageReturn {
+				return nil, errors.New("mllp end block not followed by carriage return")
+			}
+			return frame, nil
+		}
+		if len(frame) >= MaxMessageSize {
+			return nil, ErrMessageTooLarge
+		}
+		frame = append(frame, b)
+	}
+}
+
+// WriteFrame writes one message wrapped in MLLP framing characters
+func WriteFrame(w io.Writer, message []byte) error {
+	frame := make([]byte, 0, len(message)+3)
+	frame = append(frame, startBlock)
+	frame = append(frame, message...)
+	frame = append(frame, endBlock, carriageReturn)
+	_, err := w.Write(frame)
+	return err
+}
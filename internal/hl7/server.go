@@ -0,0 +1,204 @@
+package hl7
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Handler processes a raw message and returns the acknowledgment to send
+type Handler interface {
+	HandleMessage(ctx context.Context, raw []byte) []byte
+}
+
+// HandlerFunc adapts a function to the Handler interface
+type HandlerFunc func(ctx context.Context, raw []byte) []byte
+
+// HandleMessage calls f(ctx, raw)
+func (f HandlerFunc) HandleMessage(ctx context.Context, raw []byte) []byte {
+	return f(ctx, raw)
+}
+
+// Server is an MLLP listener. Messages on a connection are handled one at a
+// time, in the order they were received.
+type Server struct {
+	Addr        string
+	Handler     Handler
+	IdleTimeout time.Duration
+	// MessageTimeout bounds the time spent handling one message
+	MessageTimeout time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener
+	// conns maps open connections to whether a message is being handled
+	conns  map[net.Conn]bool
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// ErrServerClosed is returned by Serve after Close
+var ErrServerClosed = errors.New("hl7: server closed")
+
+// ListenAndServe listens on Addr and serves connections until Close
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on the listener until Close
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return ErrServerClosed
+	}
+	s.listener = listener
+	s.conns = make(map[net.Conn]bool)
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = false
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops accepting connections and closes open ones at once. Messages
+// being handled are still applied, but their acknowledgments are lost.
+func (s *Server) Close() error {
+	err := s.stop(true)
+	s.wg.Wait()
+	return err
+}
+
+// Shutdown stops accepting connections and closes idle ones, then waits for
+// in-flight messages to be handled and acknowledged before closing their
+// connections. Connections still open when ctx is done are closed, and
+// Shutdown returns without waiting for their messages.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.stop(false)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		s.stop(true)
+		return ctx.Err()
+	}
+}
+
+// stop closes the listener and the idle connections, or all of them
+func (s *Server) stop(all bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn, busy := range s.conns {
+		if all || !busy {
+			conn.Close()
+		}
+	}
+	return err
+}
+
+// setBusy records whether a message is being handled on a connection. It
+// reports false once the server is closed, when the connection must not
+// start another message.
+func (s *Server) setBusy(conn net.Conn, busy bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = busy
+	return true
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	reader := bufio.NewReader(conn)
+	for {
+		if s.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		}
+
+		frame, err := ReadFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("hl7: closing connection from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		// A message received after shutdown began is left unacknowledged,
+		// so that the sender sends it again once the server is back
+		if !s.setBusy(conn, true) {
+			return
+		}
+
+		ctx, cancel := s.messageContext()
+		ack := s.Handler.HandleMessage(ctx, frame)
+		cancel()
+		if err := WriteFrame(conn, ack); err != nil {
+			log.Printf("hl7: failed to acknowledge message from %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+
+		if !s.setBusy(conn, false) {
+			return
+		}
+	}
+}
+
+// messageContext returns the context a message is handled with
+func (s *Server) messageContext() (context.Context, context.CancelFunc) {
+	if s.MessageTimeout > 0 {
+		return context.WithTimeout(context.Background(), s.MessageTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
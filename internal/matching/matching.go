@@ -0,0 +1,283 @@
+// Package matching scores how likely two patient records are to describe the
+// same person, so that a near-duplicate registration can be told apart from a
+// namesake or a relative sharing a phone number.
+package matching
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/phonetic"
+)
+
+// Names of the features a score is made of
+const (
+	FeatureName      = "name"
+	FeatureBirthDate = "birth_date"
+	FeatureAge       = "age"
+	FeatureContact   = "contact"
+	FeatureAddress   = "address"
+	FeatureGender    = "gender"
+)
+
+// feature is a compared attribute and how much it weighs in a score
+type feature struct {
+	name   string
+	weight float64
+}
+
+// features are the compared attributes, in the order they are summed. A
+// birth date is much stronger evidence than an age, which is only compared
+// when either patient has no birth date.
+var features = []feature{
+	{FeatureName, 0.40},
+	{FeatureBirthDate, 0.25},
+	{FeatureAge, 0.10},
+	{FeatureContact, 0.25},
+	{FeatureAddress, 0.10},
+	{FeatureGender, 0.05},
+}
+
+// Result is how well two patients match
+type Result struct {
+	// Score is between 0 for nothing in common and 1 for identical records
+	Score float64
+	// Features holds the score of each compared feature, by name
+	Features map[string]float64
+}
+
+// Score compares two patients feature by feature. The score is the mean of
+// the features both patients have, weighted by how much each tells people
+// apart, so a missing address neither raises nor lowers it.
+func Score(a, b *models.Patient) Result {
+	scores := map[string]float64{
+		FeatureName:   nameSimilarity(a.Name, b.Name),
+		FeatureGender: equal(a.Gender == b.Gender),
+	}
+	if a.BirthDate != nil && b.BirthDate != nil {
+		scores[FeatureBirthDate] = birthDateSimilarity(*a.BirthDate, *b.BirthDate)
+	} else {
+		scores[FeatureAge] = ageSimilarity(a.Age, b.Age)
+	}
+	if keysA, keysB := ContactKeys(a.ContactInfo), ContactKeys(b.ContactInfo); len(keysA) > 0 && len(keysB) > 0 {
+		scores[FeatureContact] = equal(slices.ContainsFunc(keysA, func(key string) bool { return slices.Contains(keysB, key) }))
+	} else if a.ContactInfo != "" && b.ContactInfo != "" {
+		scores[FeatureContact] = equal(strings.EqualFold(strings.TrimSpace(a.ContactInfo), strings.TrimSpace(b.ContactInfo)))
+	}
+	if a.Address != "" && b.Address != "" {
+		scores[FeatureAddress] = addressSimilarity(a.Address, b.Address)
+	}
+
+	var total, weights float64
+	for _, f := range features {
+		score, ok := scores[f.name]
+		if !ok {
+			continue
+		}
+		scores[f.name] = round(score)
+		total += f.weight * score
+		weights += f.weight
+	}
+	return Result{Score: round(total / weights), Features: scores}
+}
+
+// nameSimilarity compares names word by word, ignoring case, accents and
+// word order. Each word scores its best match among the other name's words,
+// and the similarity is the mean over the words of both names, so a missing
+// middle name costs less than a different first name.
+func nameSimilarity(a, b string) float64 {
+	wordsA, wordsB := phonetic.Words(a), phonetic.Words(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, word := range wordsA {
+		total += bestMatch(word, wordsB)
+	}
+	for _, word := range wordsB {
+		total += bestMatch(word, wordsA)
+	}
+	return total / float64(len(wordsA)+len(wordsB))
+}
+
+// bestMatch returns the similarity of word to the most similar of words
+func bestMatch(word string, words []string) float64 {
+	var best float64
+	for _, other := range words {
+		best = max(best, wordSimilarity(word, other))
+	}
+	return best
+}
+
+// soundAlike is the similarity of different words that sound alike
+const soundAlike = 0.9
+
+// wordSimilarity is 1 for equal words, soundAlike for words sharing a
+// phonetic key, or the bigram similarity of the words if it is higher, which
+// tolerates typos that change how a word sounds
+func wordSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	similarity := dice(a, b)
+	if slices.ContainsFunc(phonetic.Keys(a), func(key string) bool { return slices.Contains(phonetic.Keys(b), key) }) {
+		similarity = max(similarity, soundAlike)
+	}
+	return similarity
+}
+
+// dice returns the Sørensen–Dice coefficient of the letter pairs of a and b
+func dice(a, b string) float64 {
+	pairsA, pairsB := bigrams(a), bigrams(b)
+	if len(pairsA) == 0 || len(pairsB) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(pairsA))
+	for _, pair := range pairsA {
+		counts[pair]++
+	}
+	shared := 0
+	for _, pair := range pairsB {
+		if counts[pair] > 0 {
+			counts[pair]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(pairsA)+len(pairsB))
+}
+
+// bigrams returns the pairs of adjacent letters of a word
+func bigrams(word string) []string {
+	letters := []rune(word)
+	pairs := make([]string, 0, len(letters))
+	for i := 1; i < len(letters); i++ {
+		pairs = append(pairs, string(letters[i-1:i+1]))
+	}
+	return pairs
+}
+
+// birthDateSimilarity is 1 for the same date, and partial for the typing
+// mistakes dates are commonly registered with: day and month swapped, a
+// wrong year or a wrong day
+func birthDateSimilarity(a, b time.Time) float64 {
+	switch {
+	case a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day():
+		return 1
+	case a.Year() == b.Year() && int(a.Month()) == b.Day() && a.Day() == int(b.Month()):
+		return 0.8
+	case a.Month() == b.Month() && a.Day() == b.Day():
+		return 0.6
+	case a.Year() == b.Year() && a.Month() == b.Month():
+		return 0.4
+	default:
+		return 0
+	}
+}
+
+// ageSimilarity is 1 for the same age, and half that for ages a year apart,
+// as a birthday may have passed between two registrations
+func ageSimilarity(a, b int) float64 {
+	switch a - b {
+	case 0:
+		return 1
+	case -1, 1:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// addressSimilarity is the share of the words of two addresses that both
+// have, ignoring case and punctuation
+func addressSimilarity(a, b string) float64 {
+	wordsA, wordsB := words(a), words(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for _, word := range wordsA {
+		if slices.Contains(wordsB, word) {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+// words returns the distinct lower-case words and numbers of s
+func words(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	slices.Sort(fields)
+	return slices.Compact(fields)
+}
+
+// Patterns of the email addresses and phone numbers in contact info
+var (
+	emailPattern = regexp.MustCompile(`[^\s,;:<>()\[\]]+@[^\s,;:<>()\[\]]+\.[^\s,;:<>()\[\]]+`)
+	phonePattern = regexp.MustCompile(`\+?[0-9][0-9\s().\-/]*[0-9]`)
+)
+
+// Bounds on the digits of a phone number. Only the last maxPhoneDigits
+// are compared, so that the same number matches with or without a country
+// code.
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 10
+)
+
+// ContactKeys returns the distinct email addresses, in lower case, and phone
+// numbers, as digits, in free-text contact info
+func ContactKeys(contactInfo string) []string {
+	var keys []string
+	add := func(key string) {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, email := range emailPattern.FindAllString(contactInfo, -1) {
+		add(strings.TrimRight(strings.ToLower(email), "."))
+	}
+	rest := emailPattern.ReplaceAllString(contactInfo, " ")
+	for _, phone := range phonePattern.FindAllString(rest, -1) {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, phone)
+		if len(digits) < minPhoneDigits {
+			continue
+		}
+		add(digits[max(0, len(digits)-maxPhoneDigits):])
+	}
+	return keys
+}
+
+// EncodeContactKeys returns the keys of contact info separated by spaces, as
+// they are stored
+func EncodeContactKeys(contactInfo string) string {
+	return strings.Join(ContactKeys(contactInfo), " ")
+}
+
+// equal scores a feature that either matches or does not
+func equal(matches bool) float64 {
+	if matches {
+		return 1
+	}
+	return 0
+}
+
+// round rounds a score to three decimals, which is all that is reported
+func round(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
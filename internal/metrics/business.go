@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeTimeout bounds the queries run on each scrape
+const scrapeTimeout = 5 * time.Second
+
+// PatientCounter counts patients registered since a point in time
+type PatientCounter interface {
+	CountCreatedSince(ctx context.Context, since time.Time) (int64, error)
+}
+
+// businessCollector queries business gauges from the database on each
+// scrape, so they are correct across restarts and multiple instances
+type businessCollector struct {
+	patients        PatientCounter
+	registeredToday *prometheus.Desc
+}
+
+// NewBusinessCollector creates a collector for the business gauges
+func NewBusinessCollector(patients PatientCounter) prometheus.Collector {
+	return &businessCollector{
+		patients: patients,
+		registeredToday: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "patients", "registered_today"),
+			"Patients registered since midnight, server local time.",
+			nil, nil,
+		),
+	}
+}
+
+// Describe sends the descriptors of the business gauges
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.registeredToday
+}
+
+// Collect queries the current values of the business gauges
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	count, err := c.patients.CountCreatedSince(ctx, midnight)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.registeredToday, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.registeredToday, prometheus.GaugeValue, float64(count))
+}
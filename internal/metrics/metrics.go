@@ -0,0 +1,104 @@
+// Package metrics exposes Prometheus metrics for the HTTP API, the database
+// connection pool and the hospital's own activity.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "hospital"
+
+// Login outcomes counted by RecordLogin
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+	LoginError   = "error"
+)
+
+// unmatchedRoute labels requests that did not match any route, so that
+// arbitrary paths cannot create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors served on /metrics
+type Metrics struct {
+	registry        *prometheus.Registry
+	requestDuration *prometheus.HistogramVec
+	loginAttempts   *prometheus.CounterVec
+}
+
+// New creates the metrics with their own registry, including the Go
+// runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests by route template, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		loginAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "auth",
+			Name:      "login_attempts_total",
+			Help:      "Login attempts by result: success, failure (rejected credentials) or error.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.loginAttempts,
+	)
+
+	// Report every outcome from the start rather than only once it happens
+	for _, result := range []string{LoginSuccess, LoginFailure, LoginError} {
+		m.loginAttempts.WithLabelValues(result)
+	}
+
+	return m
+}
+
+// Register adds collectors to the registry
+func (m *Metrics) Register(cs ...prometheus.Collector) {
+	m.registry.MustRegister(cs...)
+}
+
+// RegisterDB reports the statistics of a database connection pool
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveRequest records a handled HTTP request. route is the route
+// template, such as /api/patients/:id, or empty if no route matched.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = unmatchedRoute
+	}
+	m.requestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// RecordLogin counts a login attempt with one of the Login* results
+func (m *Metrics) RecordLogin(result string) {
+	m.loginAttempts.WithLabelValues(result).Inc()
+}
+
+// Handler serves the metrics in the Prometheus exposition format. A
+// collector that fails, such as a business gauge while the database is
+// down, is left out instead of failing the whole scrape.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		Registry:      m.registry,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
@@ -1,13 +1,16 @@
 package middleware
 
 import (
-	"net/http"
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"hospital-project/internal/apperror"
 	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
 	"hospital-project/internal/services"
+	"hospital-project/internal/tracing"
 )
 
 // AuthMiddleware is a middleware for authentication
@@ -28,15 +31,13 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		// Get authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
-			c.Abort()
+			problem.Write(c, apperror.Unauthorized(apperror.CodeUnauthorized, "authorization header is required"))
 			return
 		}
 
 		// Check if the header has the Bearer prefix
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
-			c.Abort()
+			problem.Write(c, apperror.Unauthorized(apperror.CodeUnauthorized, "invalid authorization header format"))
 			return
 		}
 
@@ -46,21 +47,20 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		// Validate token
 		token, err := m.authService.ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+			problem.Write(c, apperror.Unauthorized(apperror.CodeInvalidToken, "invalid token"))
 			return
 		}
 
 		// Get user from token
-		user, err := m.authService.GetUserFromToken(token)
+		user, err := m.authService.GetUserFromToken(c.Request.Context(), token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+			problem.Write(c, apperror.Unauthorized(apperror.CodeInvalidToken, "invalid token"))
 			return
 		}
 
 		// Set user in context
 		c.Set("user", user)
+		tracing.SetRole(c.Request.Context(), string(user.Role))
 		c.Next()
 	}
 }
@@ -71,23 +71,20 @@ func (m *AuthMiddleware) RequireRole(role models.Role) gin.HandlerFunc {
 		// Get user from context
 		userInterface, exists := c.Get("user")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			c.Abort()
+			problem.Write(c, apperror.Unauthorized(apperror.CodeUnauthorized, "unauthorized"))
 			return
 		}
 
 		// Type assertion
 		user, ok := userInterface.(*models.User)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-			c.Abort()
+			problem.Write(c, errors.New("user in context has unexpected type"))
 			return
 		}
 
 		// Check role
 		if user.Role != role {
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-			c.Abort()
+			problem.Write(c, apperror.Forbidden(apperror.CodeForbidden, "forbidden"))
 			return
 		}
 
@@ -101,16 +98,14 @@ func (m *AuthMiddleware) RequireAnyRole(roles ...models.Role) gin.HandlerFunc {
 		// Get user from context
 		userInterface, exists := c.Get("user")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			c.Abort()
+			problem.Write(c, apperror.Unauthorized(apperror.CodeUnauthorized, "unauthorized"))
 			return
 		}
 
 		// Type assertion
 		user, ok := userInterface.(*models.User)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-			c.Abort()
+			problem.Write(c, errors.New("user in context has unexpected type"))
 			return
 		}
 
@@ -124,8 +119,7 @@ func (m *AuthMiddleware) RequireAnyRole(roles ...models.Role) gin.HandlerFunc {
 		}
 
 		if !hasRole {
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-			c.Abort()
+			problem.Write(c, apperror.Forbidden(apperror.CodeForbidden, "forbidden"))
 			return
 		}
 
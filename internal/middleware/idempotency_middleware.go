@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
+	"hospital-project/internal/services"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client's key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed from a stored key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// IdempotencyMiddleware replays the stored response of a request retried
+// with the same Idempotency-Key instead of processing it again
+type IdempotencyMiddleware struct {
+	idempotencyService services.IdempotencyService
+}
+
+// NewIdempotencyMiddleware creates a new idempotency middleware
+func NewIdempotencyMiddleware(idempotencyService services.IdempotencyService) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		idempotencyService: idempotencyService,
+	}
+}
+
+// Handle makes a route idempotent for requests with an Idempotency-Key
+// header. Keys are scoped to the user, so it must run after Authenticate.
+// The redacted members of a JSON response object, such as secrets shown only
+// once, are left out of the stored response and so of replays.
+func (m *IdempotencyMiddleware) Handle(redacted ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		user, exists := GetCurrentUser(c)
+		if !exists {
+			problem.Write(c, apperror.Unauthorized(apperror.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			problem.Write(c, apperror.Validation(apperror.CodeMalformedBody, "Request body could not be read"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := m.idempotencyService.Begin(c.Request.Context(), user.ID, key, c.Request.Method, c.Request.URL.Path, body)
+		if err != nil {
+			problem.Write(c, err)
+			return
+		}
+		if record.Completed() {
+			replay(c, record)
+			return
+		}
+
+		// The outcome is stored even if the request's deadline has passed
+		storeCtx := context.WithoutCancel(c.Request.Context())
+
+		// Forget the key if the handler panics so the client can retry
+		completed := false
+		defer func() {
+			if !completed {
+				if err := m.idempotencyService.Release(storeCtx, record); err != nil {
+					log.Printf("idempotency: failed to release key %d: %v", record.ID, err)
+				}
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Server errors are not stored, so a retry gets another chance
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+		stored := redact(recorder.body.Bytes(), redacted)
+		if err := m.idempotencyService.Complete(storeCtx, record, c.Writer.Status(), c.Writer.Header(), stored); err != nil {
+			log.Printf("idempotency: failed to store response for key %d: %v", record.ID, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replay writes the response stored for a key
+func replay(c *gin.Context, record *models.IdempotencyKey) {
+	var headers map[string]string
+	if record.ResponseHeaders != "" {
+		if err := json.Unmarshal([]byte(record.ResponseHeaders), &headers); err != nil {
+			problem.Write(c, err)
+			return
+		}
+	}
+	for name, value := range headers {
+		c.Header(name, value)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+
+	c.Status(record.StatusCode)
+	_, _ = c.Writer.Write(record.ResponseBody)
+	c.Abort()
+}
+
+// redact removes members from a JSON object. Bodies that are not objects are
+// returned as they are.
+func redact(body []byte, members []string) []byte {
+	if len(members) == 0 {
+		return body
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil {
+		return body
+	}
+	for _, member := range members {
+		delete(object, member)
+	}
+	redacted, err := json.Marshal(object)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// responseRecorder copies the response body while it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes the data to the client and the copy
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the string to the client and the copy
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
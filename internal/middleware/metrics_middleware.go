@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/metrics"
+)
+
+// Metrics records the duration and status of every request. Requests are
+// labelled with the route template from FullPath, never the raw URL, so
+// that IDs in paths do not create a series per patient.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		m.ObserveRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
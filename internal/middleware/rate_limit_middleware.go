@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/problem"
+	"hospital-project/internal/ratelimit"
+)
+
+// Rate limit policies applied to route groups
+const (
+	RateLimitDefault = "default"
+	// RateLimitAuth guards login and registration against password guessing
+	RateLimitAuth = "auth"
+	// RateLimitSearch guards the search endpoints against scraping
+	RateLimitSearch = "search"
+)
+
+// Rate limit response headers
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// RateLimiter limits how often each user, or each client IP for
+// unauthenticated requests, may call a group of routes
+type RateLimiter struct {
+	store  ratelimit.Store
+	limits map[string]ratelimit.Limit
+}
+
+// NewRateLimiter creates a new rate limiter with a limit per policy. A nil
+// store disables rate limiting.
+func NewRateLimiter(store ratelimit.Store, limits map[string]ratelimit.Limit) *RateLimiter {
+	return &RateLimiter{
+		store:  store,
+		limits: limits,
+	}
+}
+
+// Limit applies the limit of a policy. Requests are counted per user when
+// it runs after Authenticate and per client IP otherwise.
+func (m *RateLimiter) Limit(policy string) gin.HandlerFunc {
+	limit, ok := m.limits[policy]
+	if m.store == nil || !ok {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		key := policy + ":ip:" + c.ClientIP()
+		if user, exists := GetCurrentUser(c); exists {
+			key = policy + ":user:" + strconv.FormatUint(uint64(user.ID), 10)
+		}
+
+		result, err := m.store.Take(c.Request.Context(), key, limit)
+		if err != nil {
+			// An unavailable store must not take the API down with it
+			log.Printf("ratelimit: failed to take token for %s: %v", key, err)
+			c.Next()
+			return
+		}
+
+		c.Header(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		c.Header(RateLimitResetHeader, strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Header(RateLimitPolicyHeader, fmt.Sprintf("%d;w=%d", limit.Burst, ceilSeconds(limit.Window())))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			problem.Write(c, apperror.New(apperror.KindTooManyRequests, apperror.CodeRateLimited,
+				"rate limit exceeded, retry later"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"hospital-project/internal/problem"
+)
+
+// Recovery recovers from panics in handlers and answers them with an internal
+// error. A panic with http.ErrAbortHandler is passed on to net/http, which
+// resets the connection, so that a handler that fails after its response has
+// started can tell the client that the response is incomplete.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			problem.Write(c, fmt.Errorf("panic: %v\n%s", err, debug.Stack()))
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout sets a deadline on the request context so that database queries
+// are cancelled once it passes or the client disconnects. Routes listed in
+// overrides, keyed by their full path, get their own deadline instead, and
+// the server's write timeout is extended to match it.
+func Timeout(timeout time.Duration, overrides map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deadline := timeout
+		if override, ok := overrides[c.FullPath()]; ok {
+			deadline = override
+			extendWriteDeadline(c, override)
+		}
+		if deadline <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), deadline)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// extendWriteDeadline lets a long-running response outlive the server's
+// write timeout. A timeout of zero removes the deadline.
+func extendWriteDeadline(c *gin.Context, timeout time.Duration) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	// Writers that do not support deadlines, such as test recorders, have no
+	// write timeout to extend
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(deadline)
+}
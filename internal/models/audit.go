@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditAction type for audit trail actions
+type AuditAction string
+
+const (
+	AuditActionPatientExport  AuditAction = "patient.export"
+	AuditActionPatientMerge   AuditAction = "patient.merge"
+	AuditActionPatientUnmerge AuditAction = "patient.unmerge"
+)
+
+// AuditLog represents an entry in the audit trail
+type AuditLog struct {
+	gorm.Model
+	UserID     uint        `gorm:"not null;index"`
+	Action     AuditAction `gorm:"not null;index"`
+	Resource   string      `gorm:"not null"`
+	ResourceID uint        `gorm:"index"`
+	Details    string
+}
+
+// TableName overrides the table name
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditLogResponse is the DTO for audit log responses
+type AuditLogResponse struct {
+	ID         uint        `json:"id"`
+	UserID     uint        `json:"user_id"`
+	Action     AuditAction `json:"action"`
+	Resource   string      `json:"resource"`
+	ResourceID uint        `json:"resource_id,omitempty"`
+	Details    string      `json:"details,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// ToResponse converts an AuditLog to an AuditLogResponse
+func (a *AuditLog) ToResponse() AuditLogResponse {
+	return AuditLogResponse{
+		ID:         a.ID,
+		UserID:     a.UserID,
+		Action:     a.Action,
+		Resource:   a.Resource,
+		ResourceID: a.ResourceID,
+		Details:    a.Details,
+		CreatedAt:  a.CreatedAt,
+	}
+}
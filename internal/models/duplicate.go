@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DuplicateStatus type for the review status of a suspected duplicate
+type DuplicateStatus string
+
+const (
+	DuplicateStatusPending   DuplicateStatus = "pending"
+	DuplicateStatusConfirmed DuplicateStatus = "confirmed"
+	DuplicateStatusDismissed DuplicateStatus = "dismissed"
+	// DuplicateStatusMerged closes a suspected duplicate of a patient that
+	// was merged into another one
+	DuplicateStatusMerged DuplicateStatus = "merged"
+)
+
+// PatientDuplicate is a pair of patients suspected to be the same person,
+// queued for review. PatientID is the patient that was being registered and
+// CandidateID the existing patient it matched.
+type PatientDuplicate struct {
+	gorm.Model
+	PatientID   uint    `gorm:"not null;uniqueIndex:idx_patient_duplicates_pair"`
+	CandidateID uint    `gorm:"not null;uniqueIndex:idx_patient_duplicates_pair;index"`
+	Score       float64 `gorm:"not null"`
+	// Features holds the score of each compared feature, by name
+	Features   map[string]float64 `gorm:"type:text;serializer:json"`
+	Status     DuplicateStatus    `gorm:"not null;default:pending;index"`
+	ReviewedBy *uint
+	ReviewedAt *time.Time
+}
+
+// TableName overrides the table name
+func (PatientDuplicate) TableName() string {
+	return "patient_duplicates"
+}
+
+// PatientDuplicateResponse is the DTO for suspected duplicate responses
+type PatientDuplicateResponse struct {
+	ID          uint               `json:"id"`
+	PatientID   uint               `json:"patient_id"`
+	CandidateID uint               `json:"candidate_id"`
+	Score       float64            `json:"score"`
+	Features    map[string]float64 `json:"features"`
+	Status      DuplicateStatus    `json:"status"`
+	ReviewedBy  *uint              `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time         `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// ToResponse converts a PatientDuplicate to a PatientDuplicateResponse
+func (d *PatientDuplicate) ToResponse() PatientDuplicateResponse {
+	return PatientDuplicateResponse{
+		ID:          d.ID,
+		PatientID:   d.PatientID,
+		CandidateID: d.CandidateID,
+		Score:       d.Score,
+		Features:    d.Features,
+		Status:      d.Status,
+		ReviewedBy:  d.ReviewedBy,
+		ReviewedAt:  d.ReviewedAt,
+		CreatedAt:   d.CreatedAt,
+	}
+}
+
+// ReviewDuplicateRequest is the DTO for reviewing a suspected duplicate
+type ReviewDuplicateRequest struct {
+	Status DuplicateStatus `json:"status" binding:"required,oneof=confirmed dismissed"`
+}
+
+// DuplicateListQuery is the DTO for listing suspected duplicates
+type DuplicateListQuery struct {
+	PageQuery
+	// Status selects the duplicates in one review status, pending unless set
+	Status DuplicateStatus `form:"status" binding:"omitempty,oneof=pending confirmed dismissed merged"`
+}
+
+// DuplicateMatch is an existing patient that another one may duplicate,
+// with how well the two match
+type DuplicateMatch struct {
+	Patient Patient
+	// Score is between 0 and 1, 1 being a certain duplicate
+	Score float64
+	// Features holds the score of each compared feature, by name
+	Features map[string]float64
+}
+
+// DuplicateMatchResponse is the DTO for a possible duplicate
+type DuplicateMatchResponse struct {
+	Patient  PatientResponse    `json:"patient"`
+	Score    float64            `json:"score"`
+	Features map[string]float64 `json:"features"`
+}
+
+// ToResponse converts a match to its DTO, redacting the patient for role
+func (m *DuplicateMatch) ToResponse(role Role) DuplicateMatchResponse {
+	return DuplicateMatchResponse{
+		Patient:  m.Patient.ToResponse().RedactFor(role),
+		Score:    m.Score,
+		Features: m.Features,
+	}
+}
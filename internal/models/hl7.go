@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HL7MessageStatus type for the processing state of an inbound HL7 message
+type HL7MessageStatus string
+
+const (
+	HL7MessageReceived  HL7MessageStatus = "received"
+	HL7MessageProcessed HL7MessageStatus = "processed"
+	HL7MessageFailed    HL7MessageStatus = "failed"
+)
+
+// HL7Message is a raw inbound HL7 v2 message kept for auditing and replay
+type HL7Message struct {
+	gorm.Model
+	ControlID       string           `gorm:"index"`
+	MessageType     string           `gorm:"index"`
+	Raw             string           `gorm:"type:text;not null"`
+	Status          HL7MessageStatus `gorm:"not null;index"`
+	AckCode         string
+	Error           string
+	PatientID       uint `gorm:"index"`
+	PatientClass    string
+	Location        string
+	AttendingDoctor string
+	VisitNumber     string
+}
+
+// TableName overrides the table name
+func (HL7Message) TableName() string {
+	return "hl7_messages"
+}
+
+// HL7MessageResponse is the DTO for HL7 message responses
+type HL7MessageResponse struct {
+	ID              uint             `json:"id"`
+	ControlID       string           `json:"control_id"`
+	MessageType     string           `json:"message_type"`
+	Raw             string           `json:"raw"`
+	Status          HL7MessageStatus `json:"status"`
+	AckCode         string           `json:"ack_code"`
+	Error           string           `json:"error,omitempty"`
+	PatientID       uint             `json:"patient_id,omitempty"`
+	PatientClass    string           `json:"patient_class,omitempty"`
+	Location        string           `json:"location,omitempty"`
+	AttendingDoctor string           `json:"attending_doctor,omitempty"`
+	VisitNumber     string           `json:"visit_number,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// ToResponse converts an HL7Message to an HL7MessageResponse
+func (m *HL7Message) ToResponse() HL7MessageResponse {
+	return HL7MessageResponse{
+		ID:              m.ID,
+		ControlID:       m.ControlID,
+		MessageType:     m.MessageType,
+		Raw:             m.Raw,
+		Status:          m.Status,
+		AckCode:         m.AckCode,
+		Error:           m.Error,
+		PatientID:       m.PatientID,
+		PatientClass:    m.PatientClass,
+		Location:        m.Location,
+		AttendingDoctor: m.AttendingDoctor,
+		VisitNumber:     m.VisitNumber,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
+
+// PatientIdentifier links an identifier issued by an external system, such as
+// a medical record number, to a patient
+type PatientIdentifier struct {
+	gorm.Model
+	System    string `gorm:"not null;uniqueIndex:idx_patient_identifiers_system_value"`
+	Value     string `gorm:"not null;uniqueIndex:idx_patient_identifiers_system_value"`
+	PatientID uint   `gorm:"not null;index"`
+}
+
+// TableName overrides the table name
+func (PatientIdentifier) TableName() string {
+	return "patient_identifiers"
+}
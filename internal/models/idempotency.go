@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// IdempotencyKey records a request made with an Idempotency-Key header so
+// that retries of it get the original response instead of repeating it
+type IdempotencyKey struct {
+	ID     uint   `gorm:"primarykey"`
+	UserID uint   `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Key    string `gorm:"not null;size:255;uniqueIndex:idx_idempotency_keys_user_key"`
+	Method string `gorm:"not null"`
+	Path   string `gorm:"not null"`
+	// Fingerprint is a hash of the method, path and body of the request
+	Fingerprint string `gorm:"not null"`
+	// StatusCode is zero while the original request is still being processed
+	StatusCode      int
+	ResponseHeaders string `gorm:"type:text"`
+	ResponseBody    []byte
+	CompletedAt     *time.Time
+	ExpiresAt       time.Time `gorm:"not null;index"`
+	CreatedAt       time.Time
+}
+
+// TableName overrides the table name
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// Completed reports whether the response of the original request was stored
+func (k *IdempotencyKey) Completed() bool {
+	return k.CompletedAt != nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PatientMerge records a patient folded into another. Until it is undone it
+// is a tombstone: the merged patient's ID resolves to the survivor.
+type PatientMerge struct {
+	gorm.Model
+	SurvivorID uint `gorm:"not null;index"`
+	MergedID   uint `gorm:"not null;index"`
+	MergedBy   uint `gorm:"not null"`
+	// SurvivorBefore is the survivor as it was before the merge, restored
+	// when the merge is undone
+	SurvivorBefore PatientDocument `gorm:"type:text;serializer:json"`
+	// SurvivorVersion is the survivor's version right after the merge. The
+	// merge cannot be undone once the survivor has been changed again.
+	SurvivorVersion uint `gorm:"not null"`
+	// Relinked holds the IDs of the rows moved from the merged patient to
+	// the survivor, by table
+	Relinked map[string][]uint `gorm:"type:text;serializer:json"`
+	// ClosedDuplicates holds the IDs of the pending suspected duplicates the
+	// merge confirmed or closed, which go back to the queue when it is undone
+	ClosedDuplicates []uint `gorm:"type:text;serializer:json"`
+	// UnmergeBefore is when the merge can no longer be undone
+	UnmergeBefore time.Time `gorm:"not null"`
+	UnmergedAt    *time.Time
+	UnmergedBy    *uint
+}
+
+// TableName overrides the table name
+func (PatientMerge) TableName() string {
+	return "patient_merges"
+}
+
+// PatientMergeResponse is the DTO for patient merge responses
+type PatientMergeResponse struct {
+	ID         uint `json:"id"`
+	SurvivorID uint `json:"survivor_id"`
+	MergedID   uint `json:"merged_id"`
+	MergedBy   uint `json:"merged_by"`
+	// Relinked counts the rows moved to the survivor, by table
+	Relinked      map[string]int `json:"relinked"`
+	UnmergeBefore time.Time      `json:"unmerge_before"`
+	UnmergedAt    *time.Time     `json:"unmerged_at,omitempty"`
+	UnmergedBy    *uint          `json:"unmerged_by,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// ToResponse converts a PatientMerge to a PatientMergeResponse
+func (m *PatientMerge) ToResponse() PatientMergeResponse {
+	relinked := make(map[string]int, len(m.Relinked))
+	for table, ids := range m.Relinked {
+		relinked[table] = len(ids)
+	}
+	return PatientMergeResponse{
+		ID:            m.ID,
+		SurvivorID:    m.SurvivorID,
+		MergedID:      m.MergedID,
+		MergedBy:      m.MergedBy,
+		Relinked:      relinked,
+		UnmergeBefore: m.UnmergeBefore,
+		UnmergedAt:    m.UnmergedAt,
+		UnmergedBy:    m.UnmergedBy,
+		CreatedAt:     m.CreatedAt,
+	}
+}
+
+// MergePatientRequest is the DTO for merging a duplicate into a patient
+type MergePatientRequest struct {
+	// DuplicateID is the patient folded into the one in the path
+	DuplicateID uint `json:"duplicate_id" binding:"required"`
+}
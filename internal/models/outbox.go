@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change that caused it, waiting to be published to in-process subscribers
+type OutboxEvent struct {
+	gorm.Model
+	AggregateType string       `gorm:"not null"`
+	AggregateID   uint         `gorm:"not null;index"`
+	EventType     PatientEvent `gorm:"not null"`
+	Payload       string       `gorm:"type:text;not null"`
+	OccurredAt    time.Time    `gorm:"not null"`
+	PublishedAt   *time.Time   `gorm:"index"`
+	Attempts      int          `gorm:"not null;default:0"`
+	NextAttemptAt *time.Time
+	LastError     string
+}
+
+// TableName overrides the table name
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
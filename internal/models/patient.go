@@ -1,9 +1,13 @@
 package models
 
 import (
+	"html"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+
+	"hospital-project/internal/filter"
 )
 
 // Gender type for patient gender
@@ -24,6 +28,54 @@ type Patient struct {
 	ContactInfo  string `gorm:"not null" json:"contact_info" binding:"required"`
 	MedicalNotes string `json:"medical_notes"`
 	CreatedBy    uint   `gorm:"not null" json:"created_by" binding:"required"`
+	// BirthDate and Address are optional, but make duplicates easier to tell
+	// apart from namesakes
+	BirthDate *time.Time `gorm:"type:date" json:"birth_date"`
+	Address   string     `gorm:"not null;default:''" json:"address"`
+	// Version is incremented by every update and is used as the ETag
+	Version uint `gorm:"not null;default:1" json:"version"`
+	// NamePhonetic holds the phonetic keys of Name separated by spaces. It
+	// is computed by the repository whenever the name is saved; rows
+	// backfilled at startup without any key hold "-".
+	NamePhonetic string `gorm:"not null;default:''" json:"-"`
+	// ContactKeys holds the normalized phone numbers and email addresses of
+	// ContactInfo separated by spaces. It is computed by the repository
+	// whenever the contact info is saved; rows backfilled at startup without
+	// any key hold "-".
+	ContactKeys string `gorm:"not null;default:''" json:"-"`
+}
+
+// DateLayout is the format of dates without a time, such as birth dates
+const DateLayout = "2006-01-02"
+
+// ParseDate parses a date in DateLayout, returning nil for an empty string
+func ParseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// FormatDate formats a date in DateLayout, returning an empty string for nil
+func FormatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(DateLayout)
+}
+
+// AgeAt returns the age in whole years, at the given time, of someone born
+// on the birth date
+func AgeAt(birthDate, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
 }
 
 // TableName overrides the table name
@@ -38,7 +90,10 @@ type PatientResponse struct {
 	Age          int       `json:"age"`
 	Gender       Gender    `json:"gender"`
 	ContactInfo  string    `json:"contact_info"`
+	BirthDate    string    `json:"birth_date,omitempty"`
+	Address      string    `json:"address,omitempty"`
 	MedicalNotes string    `json:"medical_notes"`
+	Version      uint      `json:"version"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -51,19 +106,49 @@ func (p *Patient) ToResponse() PatientResponse {
 		Age:          p.Age,
 		Gender:       p.Gender,
 		ContactInfo:  p.ContactInfo,
+		BirthDate:    FormatDate(p.BirthDate),
+		Address:      p.Address,
 		MedicalNotes: p.MedicalNotes,
+		Version:      p.Version,
 		CreatedAt:    p.CreatedAt,
 		UpdatedAt:    p.UpdatedAt,
 	}
 }
 
+// CanReadMedicalNotes reports whether a role may see clinical notes across
+// patients, in bulk extracts or by searching them
+func CanReadMedicalNotes(role Role) bool {
+	return role == RoleDoctor
+}
+
+// RedactFor removes the fields the given role is not allowed to see in bulk
+// extracts. Clinical notes are only released to doctors.
+func (r PatientResponse) RedactFor(role Role) PatientResponse {
+	if !CanReadMedicalNotes(role) {
+		r.MedicalNotes = ""
+	}
+	return r
+}
+
 // CreatePatientRequest is the DTO for creating a patient
 type CreatePatientRequest struct {
 	Name         string `json:"name" binding:"required"`
 	Age          int    `json:"age" binding:"required,min=0,max=150"`
 	Gender       Gender `json:"gender" binding:"required"`
 	ContactInfo  string `json:"contact_info" binding:"required"`
+	BirthDate    string `json:"birth_date" binding:"omitempty,datetime=2006-01-02"`
+	Address      string `json:"address"`
 	MedicalNotes string `json:"medical_notes"`
+	// OverrideDuplicates registers the patient even if it probably
+	// duplicates an existing one. The pair is queued for review instead.
+	OverrideDuplicates bool `json:"override_duplicates"`
+}
+
+// CreatePatientResponse is the DTO for a registered patient, with the
+// existing patients it may duplicate, which have been queued for review
+type CreatePatientResponse struct {
+	PatientResponse
+	DuplicateCandidates []DuplicateMatchResponse `json:"duplicate_candidates,omitempty"`
 }
 
 // UpdatePatientRequest is the DTO for updating a patient
@@ -72,9 +157,50 @@ type UpdatePatientRequest struct {
 	Age          int    `json:"age" binding:"omitempty,min=0,max=150"`
 	Gender       Gender `json:"gender"`
 	ContactInfo  string `json:"contact_info"`
+	BirthDate    string `json:"birth_date" binding:"omitempty,datetime=2006-01-02"`
+	Address      string `json:"address"`
 	MedicalNotes string `json:"medical_notes"`
 }
 
+// PatientDocument is the representation of a patient that PATCH requests
+// are applied to. Unlike UpdatePatientRequest, a missing or null member
+// clears the field, so the patched document is validated as a whole.
+type PatientDocument struct {
+	Name         string `json:"name" binding:"required"`
+	Age          *int   `json:"age" binding:"required,min=0,max=150"`
+	Gender       Gender `json:"gender" binding:"required,oneof=male female other"`
+	ContactInfo  string `json:"contact_info"`
+	BirthDate    string `json:"birth_date" binding:"omitempty,datetime=2006-01-02"`
+	Address      string `json:"address"`
+	MedicalNotes string `json:"medical_notes"`
+}
+
+// ToDocument converts a Patient to the document PATCH requests apply to
+func (p *Patient) ToDocument() PatientDocument {
+	age := p.Age
+	return PatientDocument{
+		Name:         p.Name,
+		Age:          &age,
+		Gender:       p.Gender,
+		ContactInfo:  p.ContactInfo,
+		BirthDate:    FormatDate(p.BirthDate),
+		Address:      p.Address,
+		MedicalNotes: p.MedicalNotes,
+	}
+}
+
+// ApplyTo copies a validated document onto a patient
+func (d PatientDocument) ApplyTo(patient *Patient) {
+	patient.Name = d.Name
+	patient.Age = *d.Age
+	patient.Gender = d.Gender
+	patient.ContactInfo = d.ContactInfo
+	// The birth date has been validated, so it parses
+	patient.BirthDate, _ = ParseDate(d.BirthDate)
+	patient.Address = d.Address
+	patient.MedicalNotes = d.MedicalNotes
+}
+
 // UpdateMedicalNotesRequest is the DTO for updating medical notes
 type UpdateMedicalNotesRequest struct {
 	MedicalNotes string `json:"medical_notes" binding:"required"`
@@ -82,18 +208,175 @@ type UpdateMedicalNotesRequest struct {
 
 // PatientSearchRequest is the DTO for searching patients
 type PatientSearchRequest struct {
-	Name        string `form:"name" binding:"omitempty"`
-	AgeMin      int    `form:"age_min" binding:"omitempty,min=0,max=150"`
-	AgeMax      int    `form:"age_max" binding:"omitempty,min=0,max=150,gtefield=AgeMin"`
-	Gender      Gender `form:"gender" binding:"omitempty,oneof=male female other"`
-	ContactInfo string `form:"contact_info" binding:"omitempty"`
+	Name        string `json:"name,omitempty" form:"name" binding:"omitempty"`
+	AgeMin      int    `json:"age_min,omitempty" form:"age_min" binding:"omitempty,min=0,max=150"`
+	AgeMax      int    `json:"age_max,omitempty" form:"age_max" binding:"omitempty,min=0,max=150,gtefield=AgeMin"`
+	Gender      Gender `json:"gender,omitempty" form:"gender" binding:"omitempty,oneof=male female other"`
+	ContactInfo string `json:"contact_info,omitempty" form:"contact_info" binding:"omitempty"`
+	// Threshold is the least similarity, between 0 and 1, a name or contact
+	// info must have to the searched one to match it when it is not a
+	// substring of it
+	Threshold float64 `json:"threshold,omitempty" form:"threshold" binding:"omitempty,gt=0,lte=1"`
+	// Match is how names are matched, MatchFuzzy unless set
+	Match string `json:"match,omitempty" form:"match" binding:"omitempty,oneof=fuzzy phonetic"`
+	// Filter is a filter expression over PatientFilterFields, such as
+	// "age >= 65 and gender = female", applied with the other parameters
+	Filter string `json:"filter,omitempty" form:"filter" binding:"omitempty"`
+}
+
+// Ways of matching names in a patient search
+const (
+	// MatchFuzzy matches names by substring or trigram similarity
+	MatchFuzzy = "fuzzy"
+	// MatchPhonetic also matches names that sound alike, and ranks them by
+	// both their similarity and how many of the searched words sound alike
+	MatchPhonetic = "phonetic"
+)
+
+// DefaultSimilarityThreshold is the Threshold of searches that set none. It
+// tolerates a typo or two in a name.
+const DefaultSimilarityThreshold = 0.3
+
+// Fuzzy reports whether the search matches names or contact info by
+// similarity
+func (r *PatientSearchRequest) Fuzzy() bool {
+	return r.Name != "" || r.ContactInfo != ""
+}
+
+// Phonetic reports whether the search also matches names that sound like
+// the searched one
+func (r *PatientSearchRequest) Phonetic() bool {
+	return r.Name != "" && r.Match == MatchPhonetic
+}
+
+// PatientFilterFields are the fields the filter of a patient search can name
+var PatientFilterFields = filter.Fields{
+	"id":           {Column: "id", Type: filter.Integer},
+	"name":         {Column: "name", Type: filter.String},
+	"age":          {Column: "age", Type: filter.Integer},
+	"gender":       {Column: "gender", Type: filter.Enum, Values: []string{string(GenderMale), string(GenderFemale), string(GenderOther)}},
+	"contact_info": {Column: "contact_info", Type: filter.String},
+	"address":      {Column: "address", Type: filter.String},
+	"birth_date":   {Column: "birth_date", Type: filter.Date, Nullable: true},
+	"created_at":   {Column: "created_at", Type: filter.Timestamp},
+	"updated_at":   {Column: "updated_at", Type: filter.Timestamp},
+}
+
+// ParseFilter parses Filter, returning nil if it is empty
+func (r *PatientSearchRequest) ParseFilter() (*filter.Filter, error) {
+	if strings.TrimSpace(r.Filter) == "" {
+		return nil, nil
+	}
+	return filter.Parse(r.Filter, PatientFilterFields)
+}
+
+// SimilarityThreshold returns Threshold, or DefaultSimilarityThreshold if it
+// is not set
+func (r *PatientSearchRequest) SimilarityThreshold() float64 {
+	if r.Threshold > 0 {
+		return r.Threshold
+	}
+	return DefaultSimilarityThreshold
+}
+
+// PatientSortFields are the fields patient searches can be sorted by
+var PatientSortFields = []string{"id", "name", "age", "gender", "created_at", "updated_at"}
+
+// PatientSearchQuery is the DTO for a page of patient search results
+type PatientSearchQuery struct {
+	PatientSearchRequest
+	PageQuery
+	// Q is a full-text query over medical notes, in web search syntax
+	Q string `form:"q"`
+	// Sort lists PatientSortFields, each prefixed with "-" to sort descending
+	Sort string `form:"sort"`
+	// Fields lists the PatientResponse members to return
+	Fields string `form:"fields"`
+	// Facets lists PatientFacets to count the matching patients by
+	Facets string `form:"facets"`
+}
+
+// Facets patient listings can be counted by
+const (
+	FacetGender       = "gender"
+	FacetAgeBand      = "age_band"
+	FacetCreatedMonth = "created_month"
+)
+
+// PatientFacets are the facets patient listings can be counted by
+var PatientFacets = []string{FacetGender, FacetAgeBand, FacetCreatedMonth}
+
+// FacetCount is the number of patients with one value of a facet
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Markers around the matched words of a PatientMatch snippet
+const (
+	SnippetStart = "\x02"
+	SnippetStop  = "\x03"
+)
+
+// PatientMatch is a patient found by a search, with how well it matched
+type PatientMatch struct {
+	Patient
+	// Rank is the relevance of the medical notes to a full-text query, or
+	// the similarity of the name and contact info to the searched ones
+	Rank float32
+	// Snippet is an excerpt of the notes matching a full-text query, with
+	// the matched words between SnippetStart and SnippetStop
+	Snippet string `gorm:"-"`
+}
+
+// PatientSearchResult is the DTO for patient search results. Rank is only
+// set for full-text and fuzzy searches, and Snippet for full-text searches.
+type PatientSearchResult struct {
+	PatientResponse
+	Rank float32 `json:"rank,omitempty"`
+	// Snippet is an HTML excerpt of the medical notes with the matched
+	// words in <mark> elements
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// ToResult converts a match to a search result
+func (m *PatientMatch) ToResult() PatientSearchResult {
+	snippet := html.EscapeString(m.Snippet)
+	snippet = strings.ReplaceAll(snippet, SnippetStart, "<mark>")
+	snippet = strings.ReplaceAll(snippet, SnippetStop, "</mark>")
+
+	return PatientSearchResult{
+		PatientResponse: m.Patient.ToResponse(),
+		Rank:            m.Rank,
+		Snippet:         snippet,
+	}
+}
+
+// PatientExportRequest is the DTO for exporting patients
+type PatientExportRequest struct {
+	PatientSearchRequest
+	Format string `form:"format" binding:"omitempty,oneof=csv ndjson xlsx"`
+}
+
+// PageQuery is the DTO for selecting a page of a listing, either by page
+// number or by a cursor from a previous response
+type PageQuery struct {
+	Page         int    `form:"page"`
+	Limit        int    `form:"limit"`
+	Cursor       string `form:"cursor"`
+	IncludeTotal bool   `form:"include_total"`
 }
 
-// PaginatedResponse is a generic struct for paginated responses
+// PaginatedResponse is a generic struct for paginated responses. Page and
+// TotalPages are omitted for pages selected by cursor, Total unless it was
+// counted, and Facets unless they were asked for.
 type PaginatedResponse[T any] struct {
-	Data       []T   `json:"data"`
-	Page       int   `json:"page"`
-	Limit      int   `json:"limit"`
-	Total      int64 `json:"total"`
-	TotalPages int   `json:"total_pages"`
+	Data       []T                     `json:"data"`
+	Page       int                     `json:"page,omitempty"`
+	Limit      int                     `json:"limit"`
+	Total      *int64                  `json:"total,omitempty"`
+	TotalPages int                     `json:"total_pages,omitempty"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+	PrevCursor string                  `json:"prev_cursor,omitempty"`
+	Facets     map[string][]FacetCount `json:"facets,omitempty"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RateLimitBucket is the shared token bucket of one rate limit key
+type RateLimitBucket struct {
+	Key    string  `gorm:"primaryKey;size:255"`
+	Tokens float64 `gorm:"not null"`
+	// RefilledAt is when Tokens was last brought up to date
+	RefilledAt time.Time `gorm:"not null;index"`
+}
+
+// TableName overrides the table name
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
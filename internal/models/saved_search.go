@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SavedSearch is a named patient search a user keeps to run again. A search
+// shared with a role can be run, but not changed, by every user with that
+// role, and each user pins the searches that make up their worklists.
+type SavedSearch struct {
+	gorm.Model
+	OwnerID uint   `gorm:"not null;uniqueIndex:idx_saved_searches_owner_name,where:deleted_at IS NULL"`
+	Name    string `gorm:"not null;uniqueIndex:idx_saved_searches_owner_name,where:deleted_at IS NULL"`
+	// Search holds the search parameters, including any filter expression
+	Search PatientSearchRequest `gorm:"type:text;serializer:json"`
+	// Sort lists PatientSortFields, each prefixed with "-" to sort descending
+	Sort string
+	// SharedWith is the role the search is shared with, if any
+	SharedWith Role `gorm:"index"`
+	// Pinned reports whether the user the search was loaded for pinned it
+	Pinned bool `gorm:"->;-:migration"`
+}
+
+// TableName overrides the table name
+func (SavedSearch) TableName() string {
+	return "saved_searches"
+}
+
+// SavedSearchPin marks a saved search as one of a user's worklists. Pins
+// belong to the user, so users a search is shared with pin it for
+// themselves.
+type SavedSearchPin struct {
+	UserID        uint `gorm:"primaryKey;autoIncrement:false"`
+	SavedSearchID uint `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt     time.Time
+}
+
+// TableName overrides the table name
+func (SavedSearchPin) TableName() string {
+	return "saved_search_pins"
+}
+
+// VisibleTo reports whether a user may see and run the search
+func (s *SavedSearch) VisibleTo(userID uint, role Role) bool {
+	return s.OwnerID == userID || (s.SharedWith != "" && s.SharedWith == role)
+}
+
+// SavedSearchResponse is the DTO for saved search responses
+type SavedSearchResponse struct {
+	ID         uint                 `json:"id"`
+	OwnerID    uint                 `json:"owner_id"`
+	Name       string               `json:"name"`
+	Search     PatientSearchRequest `json:"search"`
+	Sort       string               `json:"sort,omitempty"`
+	Pinned     bool                 `json:"pinned"`
+	SharedWith Role                 `json:"shared_with,omitempty"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+// ToResponse converts a SavedSearch to a SavedSearchResponse
+func (s *SavedSearch) ToResponse() SavedSearchResponse {
+	return SavedSearchResponse{
+		ID:         s.ID,
+		OwnerID:    s.OwnerID,
+		Name:       s.Name,
+		Search:     s.Search,
+		Sort:       s.Sort,
+		Pinned:     s.Pinned,
+		SharedWith: s.SharedWith,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+// SavedSearchRequest is the DTO for saving a search or replacing a saved one
+type SavedSearchRequest struct {
+	Name   string               `json:"name" binding:"required,max=100"`
+	Search PatientSearchRequest `json:"search"`
+	Sort   string               `json:"sort"`
+	// SharedWith is the role to share the search with, or empty to keep it
+	// private
+	SharedWith Role `json:"shared_with" binding:"omitempty,oneof=doctor receptionist"`
+}
+
+// SavedSearchListQuery is the DTO for listing saved searches
+type SavedSearchListQuery struct {
+	PageQuery
+	// Pinned lists only the searches the user pinned as worklists
+	Pinned bool `form:"pinned"`
+}
+
+// WorklistQuery is the DTO for a page of the patients of a worklist
+type WorklistQuery struct {
+	PageQuery
+	// Fields lists the PatientResponse members to return
+	Fields string `form:"fields"`
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PatientEvent type for patient lifecycle events
+type PatientEvent string
+
+const (
+	PatientEventCreated             PatientEvent = "patient.created"
+	PatientEventUpdated             PatientEvent = "patient.updated"
+	PatientEventMedicalNotesUpdated PatientEvent = "patient.medical_notes_updated"
+	PatientEventDeleted             PatientEvent = "patient.deleted"
+)
+
+// PatientEvents lists every patient lifecycle event
+var PatientEvents = []PatientEvent{
+	PatientEventCreated,
+	PatientEventUpdated,
+	PatientEventMedicalNotesUpdated,
+	PatientEventDeleted,
+}
+
+// IsValid reports whether the event is a known patient lifecycle event
+func (e PatientEvent) IsValid() bool {
+	for _, event := range PatientEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus type for the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookSubscription is an endpoint that receives patient lifecycle events
+type WebhookSubscription struct {
+	gorm.Model
+	URL                 string `gorm:"not null"`
+	Events              string `gorm:"not null"`
+	Secret              string `gorm:"not null"`
+	Active              bool   `gorm:"not null;default:true;index"`
+	ConsecutiveFailures int    `gorm:"not null;default:0"`
+	DisabledAt          *time.Time
+	CreatedBy           uint `gorm:"not null"`
+}
+
+// TableName overrides the table name
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// EventList returns the subscribed events
+func (w *WebhookSubscription) EventList() []PatientEvent {
+	var events []PatientEvent
+	for _, event := range strings.Split(w.Events, ",") {
+		if event != "" {
+			events = append(events, PatientEvent(event))
+		}
+	}
+	return events
+}
+
+// SetEvents stores the subscribed events
+func (w *WebhookSubscription) SetEvents(events []PatientEvent) {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	w.Events = strings.Join(names, ",")
+}
+
+// Subscribes reports whether the subscription wants the given event
+func (w *WebhookSubscription) Subscribes(event PatientEvent) bool {
+	for _, subscribed := range w.EventList() {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscriptionResponse is the DTO for webhook subscription responses.
+// The secret is only returned when the subscription is created.
+type WebhookSubscriptionResponse struct {
+	ID                  uint           `json:"id"`
+	URL                 string         `json:"url"`
+	Events              []PatientEvent `json:"events"`
+	Secret              string         `json:"secret,omitempty"`
+	Active              bool           `json:"active"`
+	ConsecutiveFailures int            `json:"consecutive_failures"`
+	DisabledAt          *time.Time     `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+}
+
+// ToResponse converts a WebhookSubscription to a WebhookSubscriptionResponse
+func (w *WebhookSubscription) ToResponse() WebhookSubscriptionResponse {
+	return WebhookSubscriptionResponse{
+		ID:                  w.ID,
+		URL:                 w.URL,
+		Events:              w.EventList(),
+		Active:              w.Active,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		DisabledAt:          w.DisabledAt,
+		CreatedAt:           w.CreatedAt,
+		UpdatedAt:           w.UpdatedAt,
+	}
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a subscription
+type WebhookDelivery struct {
+	gorm.Model
+	SubscriptionID uint                  `gorm:"not null;index"`
+	EventID        string                `gorm:"not null;index"`
+	Event          PatientEvent          `gorm:"not null"`
+	Payload        string                `gorm:"type:text;not null"`
+	Status         WebhookDeliveryStatus `gorm:"not null;index"`
+	Attempts       int                   `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time             `gorm:"not null;index"`
+	ResponseStatus int
+	LastError      string
+	DeliveredAt    *time.Time
+}
+
+// TableName overrides the table name
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryResponse is the DTO for webhook delivery responses
+type WebhookDeliveryResponse struct {
+	ID             uint                  `json:"id"`
+	SubscriptionID uint                  `json:"subscription_id"`
+	EventID        string                `json:"event_id"`
+	Event          PatientEvent          `json:"event"`
+	Payload        string                `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	ResponseStatus int                   `json:"response_status,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+// ToResponse converts a WebhookDelivery to a WebhookDeliveryResponse
+func (d *WebhookDelivery) ToResponse() WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		Event:          d.Event,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+	}
+}
+
+// WebhookPayload is the JSON body POSTed to subscribers
+type WebhookPayload struct {
+	ID         string          `json:"id"`
+	Event      PatientEvent    `json:"event"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       PatientResponse `json:"data"`
+}
+
+// CreateWebhookRequest is the DTO for creating a webhook subscription
+type CreateWebhookRequest struct {
+	URL    string         `json:"url" binding:"required,url"`
+	Events []PatientEvent `json:"events" binding:"required,min=1"`
+	Secret string         `json:"secret" binding:"omitempty,min=16"`
+}
+
+// UpdateWebhookRequest is the DTO for updating a webhook subscription
+type UpdateWebhookRequest struct {
+	URL    string         `json:"url" binding:"omitempty,url"`
+	Events []PatientEvent `json:"events"`
+	Active *bool          `json:"active"`
+}
@@ -0,0 +1,179 @@
+package pagination
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column is a column of a keyset ordering over rows of type T
+type Column[T any] struct {
+	Name       string
+	Descending bool
+	// Key formats the column's value of a row for a cursor
+	Key func(row *T) string
+	// Parse reads a value formatted by Key back into a query argument
+	Parse func(key string) (interface{}, error)
+}
+
+// Ordering is a total order over rows of type T. Its last column must be
+// unique so that no two rows compare equal.
+type Ordering[T any] []Column[T]
+
+// String describes the ordering, such as "-created_at,id"
+func (o Ordering[T]) String() string {
+	names := make([]string, len(o))
+	for i, column := range o {
+		names[i] = column.Name
+		if column.Descending {
+			names[i] = "-" + column.Name
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// OrderBy returns the ORDER BY clause, reversed when paging backward
+func (o Ordering[T]) OrderBy(backward bool) string {
+	terms := make([]string, len(o))
+	for i, column := range o {
+		terms[i] = column.Name
+		if column.Descending != backward {
+			terms[i] += " DESC"
+		}
+	}
+	return strings.Join(terms, ", ")
+}
+
+// Seek returns the condition selecting the rows after the cursor, or before
+// it when it pages backward
+func (o Ordering[T]) Seek(cursor *Cursor) (string, []interface{}, error) {
+	if cursor.Sort != o.String() || len(cursor.Keys) != len(o) {
+		return "", nil, errInvalidCursor()
+	}
+	values := make([]interface{}, len(o))
+	for i, column := range o {
+		value, err := column.Parse(cursor.Keys[i])
+		if err != nil {
+			return "", nil, errInvalidCursor()
+		}
+		values[i] = value
+	}
+
+	// Rows compare as tuples when every column has the same direction,
+	// which lets Postgres use a composite index
+	if o.uniform() {
+		op := ">"
+		if o[0].Descending != cursor.Backward {
+			op = "<"
+		}
+		names := make([]string, len(o))
+		placeholders := make([]string, len(o))
+		for i, column := range o {
+			names[i] = column.Name
+			placeholders[i] = "?"
+		}
+		return "(" + strings.Join(names, ", ") + ") " + op + " (" + strings.Join(placeholders, ", ") + ")", values, nil
+	}
+
+	// Otherwise a row comes after the cursor if it is equal on a prefix of
+	// the columns and after it on the next one
+	var terms []string
+	var args []interface{}
+	for i, column := range o {
+		op := ">"
+		if column.Descending != cursor.Backward {
+			op = "<"
+		}
+		var parts []string
+		for _, equal := range o[:i] {
+			parts = append(parts, equal.Name+" = ?")
+		}
+		parts = append(parts, column.Name+" "+op+" ?")
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+		args = append(args, values[:i+1]...)
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args, nil
+}
+
+func (o Ordering[T]) uniform() bool {
+	for _, column := range o {
+		if column.Descending != o[0].Descending {
+			return false
+		}
+	}
+	return true
+}
+
+// Page builds the page of a request from the rows fetched for it, which
+// must be ordered by OrderBy and include one row more than the limit so
+// that it is known whether there is a further page
+func (o Ordering[T]) Page(request Request, rows []T, total *int64) *Page[T] {
+	page := &Page[T]{Limit: request.Limit, Total: total}
+	if request.Cursor == nil {
+		page.Number = request.Number
+	}
+
+	more := len(rows) > request.Limit
+	if more {
+		rows = rows[:request.Limit]
+	}
+	backward := request.Cursor != nil && request.Cursor.Backward
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	page.Items = rows
+
+	if len(rows) == 0 {
+		// A page past either end leads back to the rows on the other side
+		if request.Cursor != nil {
+			turned := *request.Cursor
+			turned.Backward = !backward
+			if backward {
+				page.Next = &turned
+			} else {
+				page.Prev = &turned
+			}
+		}
+		return page
+	}
+
+	// The rows a cursor was made from lie behind it
+	if more || backward {
+		page.Next = o.cursor(&rows[len(rows)-1], false)
+	}
+	switch {
+	case backward:
+		if more {
+			page.Prev = o.cursor(&rows[0], true)
+		}
+	case request.Cursor != nil || request.Number > 1:
+		page.Prev = o.cursor(&rows[0], true)
+	}
+	return page
+}
+
+// cursor points at a row
+func (o Ordering[T]) cursor(row *T, backward bool) *Cursor {
+	keys := make([]string, len(o))
+	for i, column := range o {
+		keys[i] = column.Key(row)
+	}
+	return &Cursor{Sort: o.String(), Keys: keys, Backward: backward}
+}
+
+// ParseTime parses a time formatted with FormatTime
+func ParseTime(key string) (interface{}, error) {
+	return time.Parse(time.RFC3339Nano, key)
+}
+
+// FormatTime formats a time for a cursor without losing precision
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ParseUint parses an unsigned integer key, such as an ID
+func ParseUint(key string) (interface{}, error) {
+	return strconv.ParseUint(key, 10, 64)
+}
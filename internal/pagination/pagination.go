@@ -0,0 +1,119 @@
+// Package pagination pages through ordered listings, either by page number
+// or by keyset cursors that point at the last row a client has seen.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"hospital-project/internal/apperror"
+)
+
+// Page sizes
+const (
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+// Request selects a page of a listing. A cursor takes precedence over the
+// page number.
+type Request struct {
+	// Number is the 1-based page number of page-number pagination
+	Number int
+	Limit  int
+	Cursor *Cursor
+	// IncludeTotal counts every matching row, which page-number pagination
+	// always does
+	IncludeTotal bool
+}
+
+// NewRequest builds a request from client input, clamping the page number
+// and size to their valid ranges
+func NewRequest(number, limit int, cursor string, includeTotal bool) (Request, error) {
+	if number < 1 {
+		number = 1
+	}
+	if limit < 1 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	request := Request{Number: number, Limit: limit, IncludeTotal: includeTotal}
+	if cursor != "" {
+		decoded, err := Decode(cursor)
+		if err != nil {
+			return Request{}, err
+		}
+		request.Cursor = decoded
+	}
+	return request, nil
+}
+
+// Counted reports whether the total number of rows should be counted
+func (r Request) Counted() bool {
+	return r.Cursor == nil || r.IncludeTotal
+}
+
+// Offset is the number of rows before the requested page
+func (r Request) Offset() int {
+	if r.Cursor != nil {
+		return 0
+	}
+	return (r.Number - 1) * r.Limit
+}
+
+// Page is one page of a listing
+type Page[T any] struct {
+	Items []T
+	// Number is the page number, or 0 when the page was selected by cursor
+	Number int
+	Limit  int
+	// Total is the number of matching rows, if they were counted
+	Total *int64
+	// Next and Prev select the neighbouring pages, and are nil at either end
+	Next *Cursor
+	Prev *Cursor
+}
+
+// TotalPages is the number of pages of the listing, or 0 if it was not counted
+func (p *Page[T]) TotalPages() int {
+	if p.Total == nil {
+		return 0
+	}
+	return int((*p.Total + int64(p.Limit) - 1) / int64(p.Limit))
+}
+
+// Cursor is a position in an ordered listing
+type Cursor struct {
+	// Sort is the ordering the cursor belongs to
+	Sort string `json:"s"`
+	// Keys are the sort key values of the row the page starts after
+	Keys []string `json:"k"`
+	// Backward selects the rows before the keys instead
+	Backward bool `json:"b,omitempty"`
+}
+
+// Encode formats the cursor as an opaque URL-safe token
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a token made by Encode
+func Decode(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor()
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || len(cursor.Keys) == 0 {
+		return nil, errInvalidCursor()
+	}
+	return &cursor, nil
+}
+
+func errInvalidCursor() *apperror.Error {
+	return apperror.Validation(apperror.CodeInvalidCursor, "cursor is not valid for this listing")
+}
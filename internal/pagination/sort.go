@@ -0,0 +1,74 @@
+package pagination
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hospital-project/internal/apperror"
+)
+
+// SortKey is one criterion of a sort parameter
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// ParseSort parses a sort parameter such as "name,-created_at", where a
+// leading "-" sorts descending. Only the allowed fields may be used.
+func ParseSort(value string, allowed []string) ([]SortKey, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, term := range strings.Split(value, ",") {
+		key := SortKey{Field: strings.TrimSpace(term)}
+		if strings.HasPrefix(key.Field, "-") {
+			key.Field = key.Field[1:]
+			key.Descending = true
+		}
+		if !slices.Contains(allowed, key.Field) || seen[key.Field] {
+			return nil, apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+				Field:   "sort",
+				Code:    "oneof",
+				Message: fmt.Sprintf("must list distinct fields of %s", strings.Join(allowed, ", ")),
+			})
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Order builds the ordering of the sort keys from the columns they name,
+// ending with the unique column so that it is total. Without keys the
+// default ordering is returned.
+func Order[T any](keys []SortKey, columns map[string]Column[T], unique string, fallback Ordering[T]) Ordering[T] {
+	if len(keys) == 0 {
+		return fallback
+	}
+
+	ordering := make(Ordering[T], 0, len(keys)+1)
+	for _, key := range keys {
+		column := columns[key.Field]
+		column.Descending = key.Descending
+		ordering = append(ordering, column)
+		if key.Field == unique {
+			return ordering
+		}
+	}
+	return append(ordering, columns[unique])
+}
+
+// ParseInt parses an integer key
+func ParseInt(key string) (interface{}, error) {
+	return strconv.ParseInt(key, 10, 64)
+}
+
+// ParseString returns a text key as it is
+func ParseString(key string) (interface{}, error) {
+	return key, nil
+}
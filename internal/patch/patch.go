@@ -0,0 +1,420 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"hospital-project/internal/apperror"
+)
+
+// Media types of the supported patch formats
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// Apply applies a patch of the given media type to doc
+func Apply(contentType string, doc, patch []byte) ([]byte, error) {
+	switch contentType {
+	case MergePatchContentType:
+		return MergePatch(doc, patch)
+	case JSONPatchContentType:
+		return JSONPatch(doc, patch)
+	default:
+		return nil, apperror.New(apperror.KindUnsupportedMediaType, apperror.CodeUnsupportedMediaType,
+			fmt.Sprintf("Content-Type must be %s or %s", MergePatchContentType, JSONPatchContentType))
+	}
+}
+
+// MergePatch applies an RFC 7396 merge patch to doc. Members set to null in
+// the patch are removed from the document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, invalid("patch is not valid JSON")
+	}
+
+	return json.Marshal(merge(target, changes))
+}
+
+// merge implements the MergePatch algorithm of RFC 7396 section 2
+func merge(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = merge(object[name], value)
+		}
+	}
+	return object
+}
+
+// JSONPatch applies an RFC 6902 patch to doc. The operations are applied in
+// order and the patch fails as a whole if any of them fails.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, invalid("patch must be a JSON array of operations")
+	}
+
+	for i, operation := range operations {
+		target, err = applyOperation(target, operation)
+		if err != nil {
+			if appErr, ok := apperror.As(err); ok {
+				appErr.Message = fmt.Sprintf("operation %d: %s", i, appErr.Message)
+			}
+			return nil, err
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+// applyOperation applies a single JSON Patch operation and returns the new document
+func applyOperation(doc interface{}, operation map[string]json.RawMessage) (interface{}, error) {
+	var op string
+	if err := json.Unmarshal(operation["op"], &op); err != nil {
+		return nil, invalid(`"op" must be a string`)
+	}
+	path, err := pointerMember(operation, "path")
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "add", "replace", "test":
+		raw, ok := operation["value"]
+		if !ok {
+			return nil, invalid(fmt.Sprintf(`%q requires "value"`, op))
+		}
+		value, err := decode(raw)
+		if err != nil {
+			return nil, invalid(`"value" is not valid JSON`)
+		}
+
+		switch op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if doc, _, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, apperror.Conflict(apperror.CodePatchTestFailed, fmt.Sprintf("test failed at %q", pointerString(path)))
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		from, err := pointerMember(operation, "from")
+		if err != nil {
+			return nil, err
+		}
+
+		if op == "copy" {
+			value, err := get(doc, from)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, deepCopy(value))
+		}
+
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, invalid("cannot move a value into one of its children")
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	default:
+		return nil, invalid(fmt.Sprintf("unknown op %q", op))
+	}
+}
+
+// pointerMember parses the JSON Pointer held in an operation member
+func pointerMember(operation map[string]json.RawMessage, name string) ([]string, error) {
+	var pointer string
+	if err := json.Unmarshal(operation[name], &pointer); err != nil {
+		return nil, invalid(fmt.Sprintf("%q must be a JSON Pointer string", name))
+	}
+	return parsePointer(pointer)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, invalid(fmt.Sprintf("%q is not a JSON Pointer", pointer))
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// pointerString formats reference tokens back into a JSON Pointer
+func pointerString(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+// get returns the value a pointer refers to
+func get(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for i, token := range path {
+		switch container := current.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, notFound(path[:i+1])
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1, path[:i+1])
+			if err != nil {
+				return nil, err
+			}
+			current = container[index]
+		default:
+			return nil, notFound(path[:i+1])
+		}
+	}
+	return current, nil
+}
+
+// add sets the value at a pointer, inserting into arrays and creating or
+// replacing object members
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return doc, nil
+	case []interface{}:
+		index := len(container)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(container), path); err != nil {
+				return nil, err
+			}
+		}
+		container = append(container, nil)
+		copy(container[index+1:], container[index:])
+		container[index] = value
+		return set(doc, path[:len(path)-1], container)
+	default:
+		return nil, notFound(path)
+	}
+}
+
+// remove deletes the value at a pointer and returns it
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, invalid("cannot remove the whole document")
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		value, ok := container[token]
+		if !ok {
+			return nil, nil, notFound(path)
+		}
+		delete(container, token)
+		return doc, value, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container)-1, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := container[index]
+		container = append(container[:index], container[index+1:]...)
+		doc, err = set(doc, path[:len(path)-1], container)
+		return doc, value, err
+	default:
+		return nil, nil, notFound(path)
+	}
+}
+
+// set replaces the value at an existing pointer, which is needed because
+// growing or shrinking an array yields a new slice
+func set(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+	case []interface{}:
+		index, err := arrayIndex(token, len(container)-1, path)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = value
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index token, which must be between 0 and max
+func arrayIndex(token string, max int, path []string) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, invalid(fmt.Sprintf("%q is not a valid array index", pointerString(path)))
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, invalid(fmt.Sprintf("%q is not a valid array index", pointerString(path)))
+	}
+	if index > max {
+		return 0, notFound(path)
+	}
+	return index, nil
+}
+
+// equal compares two JSON values, treating numbers by value
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		xf, xErr := x.Float64()
+		yf, yErr := y.Float64()
+		return xErr == nil && yErr == nil && xf == yf
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for name, value := range x {
+			other, ok := y[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// deepCopy copies a decoded JSON value so that copies do not share containers
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for name, member := range v {
+			object[name] = deepCopy(member)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, element := range v {
+			array[i] = deepCopy(element)
+		}
+		return array
+	default:
+		return value
+	}
+}
+
+// decode parses a JSON value, keeping numbers exact
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, invalid("document is not valid JSON")
+	}
+	if decoder.More() {
+		return nil, invalid("document has trailing data")
+	}
+	return value, nil
+}
+
+func invalid(message string) error {
+	return apperror.Validation(apperror.CodeInvalidPatch, message)
+}
+
+func notFound(path []string) error {
+	return invalid(fmt.Sprintf("path %q does not exist", pointerString(path)))
+}
@@ -0,0 +1,572 @@
+package phonetic
+
+import "strings"
+
+// MaxCodeLength is the length Double Metaphone codes are cut to
+const MaxCodeLength = 4
+
+// DoubleMetaphone returns the primary and alternate Double Metaphone codes
+// of a word, following Lawrence Philips' algorithm. The alternate code
+// captures a second common pronunciation, such as the Germanic one of
+// "Schmidt", and equals the primary code when there is none.
+func DoubleMetaphone(word string) (primary, alternate string) {
+	w := newWord(word)
+	if len(w.value) == 0 {
+		return "", ""
+	}
+
+	r := &result{}
+	i := 0
+	if w.contains(0, 2, "GN", "KN", "PN", "WR", "PS") {
+		// The first letter is silent
+		i = 1
+	}
+
+	for !r.complete() && i < len(w.value) {
+		switch w.at(i) {
+		case 'A', 'E', 'I', 'O', 'U', 'Y':
+			if i == 0 {
+				r.add("A")
+			}
+			i++
+		case 'B':
+			r.add("P")
+			i = w.skip(i, "B")
+		case 'Ç':
+			r.add("S")
+			i++
+		case 'C':
+			i = w.c(r, i)
+		case 'D':
+			i = w.d(r, i)
+		case 'F':
+			r.add("F")
+			i = w.skip(i, "F")
+		case 'G':
+			i = w.g(r, i)
+		case 'H':
+			i = w.h(r, i)
+		case 'J':
+			i = w.j(r, i)
+		case 'K':
+			r.add("K")
+			i = w.skip(i, "K")
+		case 'L':
+			i = w.l(r, i)
+		case 'M':
+			r.add("M")
+			if w.at(i+1) == 'M' || (w.contains(i-1, 3, "UMB") && (i+1 == w.last() || w.contains(i+2, 2, "ER"))) {
+				// "dumb", "thumb"
+				i += 2
+			} else {
+				i++
+			}
+		case 'N':
+			r.add("N")
+			i = w.skip(i, "N")
+		case 'Ñ':
+			r.add("N")
+			i++
+		case 'P':
+			if w.at(i+1) == 'H' {
+				r.add("F")
+				i += 2
+			} else {
+				r.add("P")
+				i = w.skip(i, "P", "B")
+			}
+		case 'Q':
+			r.add("K")
+			i = w.skip(i, "Q")
+		case 'R':
+			// French "Rogier" drops the final r
+			if i == w.last() && !w.slavoGermanic && w.contains(i-2, 2, "IE") && !w.contains(i-4, 2, "ME", "MA") {
+				r.addAlternate("R")
+			} else {
+				r.add("R")
+			}
+			i = w.skip(i, "R")
+		case 'S':
+			i = w.s(r, i)
+		case 'T':
+			i = w.t(r, i)
+		case 'V':
+			r.add("F")
+			i = w.skip(i, "V")
+		case 'W':
+			i = w.w(r, i)
+		case 'X':
+			i = w.x(r, i)
+		case 'Z':
+			i = w.z(r, i)
+		default:
+			i++
+		}
+	}
+
+	return r.primary.String(), r.alternate.String()
+}
+
+// word is an upper-case word being encoded
+type word struct {
+	value         []rune
+	slavoGermanic bool
+}
+
+func newWord(value string) *word {
+	upper := strings.ToUpper(strings.TrimSpace(value))
+	return &word{
+		value:         []rune(upper),
+		slavoGermanic: strings.ContainsAny(upper, "WK") || strings.Contains(upper, "CZ") || strings.Contains(upper, "WITZ"),
+	}
+}
+
+// at returns the letter at i, or zero outside the word
+func (w *word) at(i int) rune {
+	if i < 0 || i >= len(w.value) {
+		return 0
+	}
+	return w.value[i]
+}
+
+// last returns the index of the last letter
+func (w *word) last() int {
+	return len(w.value) - 1
+}
+
+// contains reports whether the length letters from start are one of options
+func (w *word) contains(start, length int, options ...string) bool {
+	if start < 0 || start+length > len(w.value) {
+		return false
+	}
+	target := string(w.value[start : start+length])
+	for _, option := range options {
+		if target == option {
+			return true
+		}
+	}
+	return false
+}
+
+// skip returns the index after the letter at i and one of letters
+// following it, which sound as one
+func (w *word) skip(i int, letters ...string) int {
+	if w.contains(i+1, 1, letters...) {
+		return i + 2
+	}
+	return i + 1
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("AEIOUY", r)
+}
+
+func (w *word) c(r *result, i int) int {
+	switch {
+	case w.germanicCH(i):
+		// "Bacher", "Macher"
+		r.add("K")
+		return i + 2
+	case i == 0 && w.contains(i, 6, "CAESAR"):
+		r.add("S")
+		return i + 2
+	case w.contains(i, 2, "CH"):
+		return w.ch(r, i)
+	case w.contains(i, 2, "CZ") && !w.contains(i-2, 4, "WICZ"):
+		// "Czerny"
+		r.add2("S", "X")
+		return i + 2
+	case w.contains(i+1, 3, "CIA"):
+		// "focaccia"
+		r.add("X")
+		return i + 3
+	case w.contains(i, 2, "CC") && !(i == 1 && w.at(0) == 'M'):
+		// Double c, but not "McClelland"
+		if w.contains(i+2, 1, "I", "E", "H") && !w.contains(i+2, 2, "HU") {
+			if (i == 1 && w.at(i-1) == 'A') || w.contains(i-1, 5, "UCCEE", "UCCES") {
+				// "accident", "succeed"
+				r.add("KS")
+			} else {
+				// "bacci", "bertucci"
+				r.add("X")
+			}
+			return i + 3
+		}
+		r.add("K")
+		return i + 2
+	case w.contains(i, 2, "CK", "CG", "CQ"):
+		r.add("K")
+		return i + 2
+	case w.contains(i, 2, "CI", "CE", "CY"):
+		// Italian or English
+		if w.contains(i, 3, "CIO", "CIE", "CIA") {
+			r.add2("S", "X")
+		} else {
+			r.add("S")
+		}
+		return i + 2
+	default:
+		r.add("K")
+		if w.contains(i+1, 1, "C", "K", "Q") && !w.contains(i+1, 2, "CE", "CI") {
+			return i + 2
+		}
+		return i + 1
+	}
+}
+
+// germanicCH reports whether the c at i is part of a Germanic "ach" or
+// starts "chia"
+func (w *word) germanicCH(i int) bool {
+	switch {
+	case w.contains(i, 4, "CHIA"):
+		return true
+	case i <= 1, isVowel(w.at(i - 2)), !w.contains(i-1, 3, "ACH"):
+		return false
+	default:
+		next := w.at(i + 2)
+		return (next != 'I' && next != 'E') || w.contains(i-2, 6, "BACHER", "MACHER")
+	}
+}
+
+func (w *word) ch(r *result, i int) int {
+	switch {
+	case i > 0 && w.contains(i, 4, "CHAE"):
+		// "Michael"
+		r.add2("K", "X")
+	case i == 0 && (w.contains(i+1, 5, "HARAC", "HARIS") || w.contains(i+1, 3, "HOR", "HYM", "HIA", "HEM")) && !w.contains(0, 5, "CHORE"):
+		// Greek roots: "chemistry", "chorus"
+		r.add("K")
+	case w.contains(0, 3, "SCH") ||
+		w.contains(i-2, 6, "ORCHES", "ARCHIT", "ORCHID") ||
+		w.contains(i+2, 1, "T", "S") ||
+		((i == 0 || w.contains(i-1, 1, "A", "O", "U", "E")) && (w.contains(i+2, 1, "L", "R", "N", "M", "B", "H", "F", "V", "W") || i+1 == w.last())):
+		// Germanic, Greek or otherwise a "kh" sound
+		r.add("K")
+	case i > 0:
+		if w.contains(0, 2, "MC") {
+			r.add("K")
+		} else {
+			r.add2("X", "K")
+		}
+	default:
+		r.add("X")
+	}
+	return i + 2
+}
+
+func (w *word) d(r *result, i int) int {
+	switch {
+	case w.contains(i, 2, "DG"):
+		if w.contains(i+2, 1, "I", "E", "Y") {
+			// "edge"
+			r.add("J")
+			return i + 3
+		}
+		// "Edgar"
+		r.add("TK")
+		return i + 2
+	case w.contains(i, 2, "DT", "DD"):
+		r.add("T")
+		return i + 2
+	default:
+		r.add("T")
+		return i + 1
+	}
+}
+
+func (w *word) g(r *result, i int) int {
+	switch {
+	case w.at(i+1) == 'H':
+		return w.gh(r, i)
+	case w.at(i+1) == 'N':
+		switch {
+		case i == 1 && isVowel(w.at(0)) && !w.slavoGermanic:
+			r.add2("KN", "N")
+		case !w.contains(i+2, 2, "EY") && w.at(i+1) != 'Y' && !w.slavoGermanic:
+			r.add2("N", "KN")
+		default:
+			r.add("KN")
+		}
+		return i + 2
+	case w.contains(i+1, 2, "LI") && !w.slavoGermanic:
+		// "tagliaro"
+		r.add2("KL", "L")
+		return i + 2
+	case i == 0 && (w.at(i+1) == 'Y' || w.contains(i+1, 2, "ES", "EP", "EB", "EL", "EY", "IB", "IL", "IN", "IE", "EI", "ER")):
+		// -ges-, -gep-, -gel-, -gie- at the beginning
+		r.add2("K", "J")
+		return i + 2
+	case (w.contains(i+1, 2, "ER") || w.at(i+1) == 'Y') &&
+		!w.contains(0, 6, "DANGER", "RANGER", "MANGER") &&
+		!w.contains(i-1, 1, "E", "I") &&
+		!w.contains(i-1, 3, "RGY", "OGY"):
+		// -ger-, -gy-
+		r.add2("K", "J")
+		return i + 2
+	case w.contains(i+1, 1, "E", "I", "Y") || w.contains(i-1, 4, "AGGI", "OGGI"):
+		// Italian "biaggi"
+		switch {
+		case w.contains(0, 3, "SCH") || w.contains(i+1, 2, "ET"):
+			// Obviously Germanic
+			r.add("K")
+		case w.contains(i+1, 3, "IER"):
+			r.add("J")
+		default:
+			r.add2("J", "K")
+		}
+		return i + 2
+	case w.at(i+1) == 'G':
+		r.add("K")
+		return i + 2
+	default:
+		r.add("K")
+		return i + 1
+	}
+}
+
+func (w *word) gh(r *result, i int) int {
+	switch {
+	case i > 0 && !isVowel(w.at(i-1)):
+		r.add("K")
+	case i == 0:
+		// "ghislane", "ghiradelli"
+		if w.at(i+2) == 'I' {
+			r.add("J")
+		} else {
+			r.add("K")
+		}
+	case (i > 1 && w.contains(i-2, 1, "B", "H", "D")) ||
+		(i > 2 && w.contains(i-3, 1, "B", "H", "D")) ||
+		(i > 3 && w.contains(i-4, 1, "B", "H")):
+		// Parker's rule: "hugh", "bough", "broughton"
+	case i > 2 && w.at(i-1) == 'U' && w.contains(i-3, 1, "C", "G", "L", "R", "T"):
+		// "laugh", "McLaughlin", "cough", "rough", "tough"
+		r.add("F")
+	case w.at(i-1) != 'I':
+		r.add("K")
+	}
+	return i + 2
+}
+
+func (w *word) h(r *result, i int) int {
+	// Only kept first or between vowels, and then also covers "hh"
+	if (i == 0 || isVowel(w.at(i-1))) && isVowel(w.at(i+1)) {
+		r.add("H")
+		return i + 2
+	}
+	return i + 1
+}
+
+func (w *word) j(r *result, i int) int {
+	if w.contains(i, 4, "JOSE") {
+		// Spanish "Jose"
+		if i == 0 && len(w.value) == 4 {
+			r.add("H")
+		} else {
+			r.add2("J", "H")
+		}
+		return i + 1
+	}
+
+	switch {
+	case i == 0:
+		r.add2("J", "A")
+	case isVowel(w.at(i-1)) && !w.slavoGermanic && (w.at(i+1) == 'A' || w.at(i+1) == 'O'):
+		// Spanish "bajador"
+		r.add2("J", "H")
+	case i == w.last():
+		r.add2("J", "")
+	case !w.contains(i+1, 1, "L", "T", "K", "S", "N", "M", "B", "Z") && !w.contains(i-1, 1, "S", "K", "L"):
+		r.add("J")
+	}
+	return w.skip(i, "J")
+}
+
+func (w *word) l(r *result, i int) int {
+	if w.at(i+1) != 'L' {
+		r.add("L")
+		return i + 1
+	}
+
+	// Spanish "cabrillo", "gallegos" drop the double l
+	n := len(w.value)
+	if (i == n-3 && w.contains(i-1, 4, "ILLO", "ILLA", "ALLE")) ||
+		((w.contains(n-2, 2, "AS", "OS") || w.contains(n-1, 1, "A", "O")) && w.contains(i-1, 4, "ALLE")) {
+		r.addPrimary("L")
+	} else {
+		r.add("L")
+	}
+	return i + 2
+}
+
+func (w *word) s(r *result, i int) int {
+	switch {
+	case w.contains(i-1, 3, "ISL", "YSL"):
+		// "island", "isle", "carlisle"
+		return i + 1
+	case i == 0 && w.contains(i, 5, "SUGAR"):
+		r.add2("X", "S")
+		return i + 1
+	case w.contains(i, 2, "SH"):
+		if w.contains(i+1, 4, "HEIM", "HOEK", "HOLM", "HOLZ") {
+			// Germanic
+			r.add("S")
+		} else {
+			r.add("X")
+		}
+		return i + 2
+	case w.contains(i, 3, "SIO", "SIA") || w.contains(i, 4, "SIAN"):
+		// Italian and Armenian
+		if w.slavoGermanic {
+			r.add("S")
+		} else {
+			r.add2("S", "X")
+		}
+		return i + 3
+	case (i == 0 && w.contains(i+1, 1, "M", "N", "L", "W")) || w.contains(i+1, 1, "Z"):
+		// Anglicisations, so "Smith" matches "Schmidt" and "Snider"
+		// matches "Schneider", and Slavic -sz-
+		r.add2("S", "X")
+		return w.skip(i, "Z")
+	case w.contains(i, 2, "SC"):
+		return w.sc(r, i)
+	default:
+		if i == w.last() && w.contains(i-2, 2, "AI", "OI") {
+			// French "resnais", "artois"
+			r.addAlternate("S")
+		} else {
+			r.add("S")
+		}
+		return w.skip(i, "S", "Z")
+	}
+}
+
+func (w *word) sc(r *result, i int) int {
+	switch {
+	case w.at(i+2) == 'H':
+		// Schlesinger's rule
+		switch {
+		case w.contains(i+3, 2, "ER", "EN"):
+			// Dutch "schermerhorn", "schenker"
+			r.add2("X", "SK")
+		case w.contains(i+3, 2, "OO", "UY", "ED", "EM"):
+			// Dutch "school", "schooner"
+			r.add("SK")
+		case i == 0 && !isVowel(w.at(3)) && w.at(3) != 'W':
+			r.add2("X", "S")
+		default:
+			r.add("X")
+		}
+	case w.contains(i+2, 1, "I", "E", "Y"):
+		r.add("S")
+	default:
+		r.add("SK")
+	}
+	return i + 3
+}
+
+func (w *word) t(r *result, i int) int {
+	switch {
+	case w.contains(i, 4, "TION"), w.contains(i, 3, "TIA", "TCH"):
+		r.add("X")
+		return i + 3
+	case w.contains(i, 2, "TH"), w.contains(i, 3, "TTH"):
+		if w.contains(i+2, 2, "OM", "AM") || w.contains(0, 3, "SCH") {
+			// "Thomas", "Thames" or Germanic
+			r.add("T")
+		} else {
+			r.add2("0", "T")
+		}
+		return i + 2
+	default:
+		r.add("T")
+		return w.skip(i, "T", "D")
+	}
+}
+
+func (w *word) w(r *result, i int) int {
+	switch {
+	case w.contains(i, 2, "WR"):
+		r.add("R")
+		return i + 2
+	case i == 0 && isVowel(w.at(i+1)):
+		// "Wasserman" matches "Vasserman"
+		r.add2("A", "F")
+		return i + 1
+	case i == 0 && w.contains(i, 2, "WH"):
+		r.add("A")
+		return i + 1
+	case (i == w.last() && isVowel(w.at(i-1))) ||
+		w.contains(i-1, 5, "EWSKI", "EWSKY", "OWSKI", "OWSKY") ||
+		w.contains(0, 3, "SCH"):
+		// "Arnow" matches "Arnoff"
+		r.addAlternate("F")
+		return i + 1
+	case w.contains(i, 4, "WICZ", "WITZ"):
+		// Polish "filipowicz"
+		r.add2("TS", "FX")
+		return i + 4
+	default:
+		return i + 1
+	}
+}
+
+func (w *word) x(r *result, i int) int {
+	if i == 0 {
+		r.add("S")
+		return i + 1
+	}
+	if !(i == w.last() && (w.contains(i-3, 3, "IAU", "EAU") || w.contains(i-2, 2, "AU", "OU"))) {
+		// But not French "breaux"
+		r.add("KS")
+	}
+	return w.skip(i, "C", "X")
+}
+
+func (w *word) z(r *result, i int) int {
+	if w.at(i+1) == 'H' {
+		// Chinese "Zhao"
+		r.add("J")
+		return i + 2
+	}
+	if w.contains(i+1, 2, "ZO", "ZI", "ZA") || (w.slavoGermanic && i > 0 && w.at(i-1) != 'T') {
+		r.add2("S", "TS")
+	} else {
+		r.add("S")
+	}
+	return w.skip(i, "Z")
+}
+
+// result accumulates the two codes of a word, each cut to MaxCodeLength
+type result struct {
+	primary   strings.Builder
+	alternate strings.Builder
+}
+
+func (r *result) add(code string) {
+	r.add2(code, code)
+}
+
+func (r *result) add2(primary, alternate string) {
+	r.addPrimary(primary)
+	r.addAlternate(alternate)
+}
+
+func (r *result) addPrimary(code string) {
+	appendCode(&r.primary, code)
+}
+
+func (r *result) addAlternate(code string) {
+	appendCode(&r.alternate, code)
+}
+
+func (r *result) complete() bool {
+	return r.primary.Len() >= MaxCodeLength && r.alternate.Len() >= MaxCodeLength
+}
+
+func appendCode(b *strings.Builder, code string) {
+	if room := MaxCodeLength - b.Len(); len(code) > room {
+		code = code[:max(room, 0)]
+	}
+	b.WriteString(code)
+}
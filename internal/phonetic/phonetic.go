@@ -0,0 +1,55 @@
+// Package phonetic encodes names by how they sound, so that a name spelt
+// the way it was heard, such as "Kathryn" for "Catherine", can be matched to
+// the registered one.
+package phonetic
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Words splits a name into the upper-case words that are encoded, folding
+// accents other than those Double Metaphone knows (Ç and Ñ) and dropping
+// apostrophes, so that "O'Brien" is one word
+func Words(name string) []string {
+	var folded strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		switch {
+		case r == 'Ç' || r == 'Ñ':
+			folded.WriteRune(r)
+		case r == '\'' || r == '’':
+		default:
+			for _, d := range norm.NFD.String(string(r)) {
+				if !unicode.Is(unicode.Mn, d) {
+					folded.WriteRune(d)
+				}
+			}
+		}
+	}
+
+	return strings.FieldsFunc(folded.String(), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// Keys returns the distinct Double Metaphone codes of the words of a name
+func Keys(name string) []string {
+	var keys []string
+	for _, word := range Words(name) {
+		primary, alternate := DoubleMetaphone(word)
+		for _, key := range []string{primary, alternate} {
+			if key != "" && !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// Encode returns the keys of a name separated by spaces, as they are stored
+func Encode(name string) string {
+	return strings.Join(Keys(name), " ")
+}
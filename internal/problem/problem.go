@@ -0,0 +1,242 @@
+// Package problem renders errors as RFC 7807 problem details.
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"hospital-project/internal/apperror"
+)
+
+// ContentType is the media type of problem details
+const ContentType = "application/problem+json"
+
+// StatusClientClosedRequest is the non-standard status, borrowed from nginx,
+// recorded for requests whose client disconnected. The client never sees it.
+const StatusClientClosedRequest = 499
+
+// Problem is an RFC 7807 problem details object. Code is a stable,
+// machine-readable extension member; clients should branch on it rather than
+// on Title or Detail.
+type Problem struct {
+	Type     string                `json:"type"`
+	Title    string                `json:"title"`
+	Status   int                   `json:"status"`
+	Detail   string                `json:"detail,omitempty"`
+	Instance string                `json:"instance,omitempty"`
+	Code     string                `json:"code"`
+	Errors   []apperror.FieldError `json:"errors,omitempty"`
+	// Current is the current representation of the resource when a
+	// precondition failed, so the client can merge and retry
+	Current interface{} `json:"current,omitempty"`
+	// Candidates are the existing resources a new one conflicts with, such
+	// as the patients a registration probably duplicates
+	Candidates interface{} `json:"candidates,omitempty"`
+}
+
+func init() {
+	// Report validation failures by JSON/query field name rather than Go field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+// Status returns the HTTP status for an error kind
+func Status(kind apperror.Kind) int {
+	switch kind {
+	case apperror.KindValidation:
+		return http.StatusBadRequest
+	case apperror.KindNotFound:
+		return http.StatusNotFound
+	case apperror.KindConflict:
+		return http.StatusConflict
+	case apperror.KindForbidden:
+		return http.StatusForbidden
+	case apperror.KindUnauthorized:
+		return http.StatusUnauthorized
+	case apperror.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case apperror.KindPreconditionRequired:
+		return http.StatusPreconditionRequired
+	case apperror.KindUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case apperror.KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case apperror.KindTimeout:
+		return http.StatusGatewayTimeout
+	case apperror.KindClientClosed:
+		return StatusClientClosedRequest
+	case apperror.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case apperror.KindTooManyRequests:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// New builds the problem details for err. Untyped errors are reported as
+// internal errors without exposing their message.
+func New(err error, instance string) Problem {
+	if isTimeout(err) {
+		err = apperror.New(apperror.KindTimeout, apperror.CodeRequestTimeout, "The request took too long and was cancelled").WithCause(err)
+	} else if errors.Is(err, context.Canceled) {
+		err = apperror.New(apperror.KindClientClosed, apperror.CodeClientClosed, "The client closed the request").WithCause(err)
+	}
+
+	appErr, ok := apperror.As(err)
+	if !ok || appErr.Kind == apperror.KindInternal {
+		return Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(http.StatusInternalServerError),
+			Status:   http.StatusInternalServerError,
+			Detail:   "An unexpected error occurred",
+			Instance: instance,
+			Code:     apperror.CodeInternal,
+		}
+	}
+
+	status := Status(appErr.Kind)
+	title := http.StatusText(status)
+	if status == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+	return Problem{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   status,
+		Detail:   appErr.Message,
+		Instance: instance,
+		Code:     appErr.Code,
+		Errors:   appErr.Fields,
+	}
+}
+
+// Write aborts the request with the problem details for err
+func Write(ctx *gin.Context, err error) {
+	p := New(err, ctx.Request.URL.Path)
+	Log(ctx, p, err)
+
+	ctx.Header("Content-Type", ContentType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
+// Log records the errors worth an operator's attention: internal errors
+// with their cause, and requests abandoned by their client
+func Log(ctx *gin.Context, p Problem, err error) {
+	switch p.Status {
+	case http.StatusInternalServerError:
+		log.Printf("%s %s: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+	case StatusClientClosedRequest:
+		log.Printf("%s %s: client disconnected", ctx.Request.Method, ctx.Request.URL.Path)
+	}
+}
+
+// WriteCurrent aborts the request with the problem details for err and the
+// current representation of the resource it concerns
+func WriteCurrent(ctx *gin.Context, err error, current interface{}) {
+	p := New(err, ctx.Request.URL.Path)
+	p.Current = current
+
+	ctx.Header("Content-Type", ContentType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
+// WriteCandidates aborts the request with the problem details for err and
+// the existing resources the request conflicts with
+func WriteCandidates(ctx *gin.Context, err error, candidates interface{}) {
+	p := New(err, ctx.Request.URL.Path)
+	p.Candidates = candidates
+
+	ctx.Header("Content-Type", ContentType)
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
+// queryCanceled is the SQLSTATE of a query cancelled by statement_timeout
+const queryCanceled = "57014"
+
+// isTimeout reports whether err comes from a request context past its
+// deadline or a query stopped by the database's statement_timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == queryCanceled
+}
+
+// Binding converts an error from ShouldBindJSON or ShouldBindQuery into a
+// validation error with one entry per invalid field
+func Binding(err error) *apperror.Error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]apperror.FieldError, 0, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			fields = append(fields, apperror.FieldError{
+				Field:   fieldErr.Field(),
+				Code:    fieldErr.Tag(),
+				Message: fieldMessage(fieldErr),
+			})
+		}
+		return apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", fields...).WithCause(err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be a " + typeErr.Type.String(),
+		}).WithCause(err)
+	}
+
+	if errors.Is(err, io.EOF) {
+		return apperror.Validation(apperror.CodeMalformedBody, "The request body is empty").WithCause(err)
+	}
+	return apperror.Validation(apperror.CodeMalformedBody, "The request could not be parsed").WithCause(err)
+}
+
+// fieldMessage describes a failed validation rule
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		return "must be at most " + fieldErr.Param()
+	case "oneof":
+		return "must be one of: " + fieldErr.Param()
+	case "url":
+		return "must be a valid URL"
+	case "datetime":
+		return "must be a date formatted as " + fieldErr.Param()
+	default:
+		return "failed the " + fieldErr.Tag() + " rule"
+	}
+}
+
+// fieldName returns the name a struct field is known by in requests
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,99 @@
+// Package ratelimit implements token bucket rate limiting with pluggable
+// bucket stores.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Burst requests at once, refilled at Rate requests per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerPeriod allows n requests per period, all of which may be made at once
+func PerPeriod(n int, period time.Duration) Limit {
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: n}
+}
+
+// Parse reads a limit written as "<requests>/<period>", such as "100/1m"
+func Parse(s string) (Limit, error) {
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must be <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("rate limit %q must allow at least one request", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid period", s)
+	}
+	return PerPeriod(n, d), nil
+}
+
+// Window is the time an empty bucket takes to refill completely
+func (l Limit) Window() time.Duration {
+	return seconds(float64(l.Burst) / l.Rate)
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	// Limit is the bucket's capacity
+	Limit int
+	// Remaining is the number of requests that may still be made at once
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, if this one was not
+	RetryAfter time.Duration
+}
+
+// Store keeps one bucket per key and takes tokens from them atomically
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Bucket is the state of a token bucket. A zero Bucket is full.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Take refills the bucket for the time elapsed since it was last updated
+// and takes one token if there is one. Rejected requests take nothing, so
+// a client that keeps retrying is let through as soon as a token is back.
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	burst := float64(limit.Burst)
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = burst
+		b.UpdatedAt = now
+	} else if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		// A clock behind UpdatedAt, such as another instance's, refills nothing
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*limit.Rate)
+		b.UpdatedAt = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.Tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.Tokens)
+	result.Reset = seconds((burst - b.Tokens) / limit.Rate)
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Sweeper is a store that can drop buckets nobody has used for a while
+type Sweeper interface {
+	DeleteIdle(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Sweep returns a worker that deletes the buckets of store left idle
+// longer than idle every interval. A bucket idle for longer than its
+// limit's Window is full, which is the same as having no bucket at all.
+func Sweep(store Sweeper, interval, idle time.Duration) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := store.DeleteIdle(ctx, time.Now().Add(-idle)); err != nil {
+					log.Printf("ratelimit: failed to delete idle buckets: %v", err)
+				}
+			}
+		}
+	}
+}
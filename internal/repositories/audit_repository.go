@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/models"
+)
+
+// AuditRepository interface defines methods for audit repository
+type AuditRepository interface {
+	Create(ctx context.Context, entry *models.AuditLog) error
+	ListByResource(ctx context.Context, resource string, resourceID uint) ([]models.AuditLog, error)
+}
+
+// auditRepository implements AuditRepository interface
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Create creates a new audit log entry
+func (r *auditRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	return conn(ctx, r.db).Create(entry).Error
+}
+
+// ListByResource returns the audit trail of a resource, oldest first
+func (r *auditRepository) ListByResource(ctx context.Context, resource string, resourceID uint) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	err := conn(ctx, r.db).Where("resource = ? AND resource_id = ?", resource, resourceID).
+		Order("created_at, id").
+		Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+)
+
+// DuplicateRepository interface defines methods for the queue of suspected
+// duplicate patients
+type DuplicateRepository interface {
+	Queue(ctx context.Context, duplicates []models.PatientDuplicate) error
+	FindByID(ctx context.Context, id uint) (*models.PatientDuplicate, error)
+	List(ctx context.Context, status models.DuplicateStatus, request pagination.Request) (*pagination.Page[models.PatientDuplicate], error)
+	Review(ctx context.Context, id uint, status models.DuplicateStatus, reviewerID uint, at time.Time) (*models.PatientDuplicate, error)
+	ConfirmPair(ctx context.Context, patientID, otherID, reviewerID uint, at time.Time) ([]uint, error)
+	CloseMerged(ctx context.Context, patientID, reviewerID uint, at time.Time) ([]uint, error)
+	Reopen(ctx context.Context, ids []uint) error
+}
+
+// duplicateRepository implements DuplicateRepository interface
+type duplicateRepository struct {
+	db *gorm.DB
+}
+
+// NewDuplicateRepository creates a new duplicate repository
+func NewDuplicateRepository(db *gorm.DB) DuplicateRepository {
+	return &duplicateRepository{db: db}
+}
+
+// Queue adds suspected duplicates to the review queue. A pair that is
+// already queued keeps its status, so a dismissed pair is not raised again.
+func (r *duplicateRepository) Queue(ctx context.Context, duplicates []models.PatientDuplicate) error {
+	if len(duplicates) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(&duplicates).Error
+}
+
+// FindByID finds a suspected duplicate by ID
+func (r *duplicateRepository) FindByID(ctx context.Context, id uint) (*models.PatientDuplicate, error) {
+	var duplicate models.PatientDuplicate
+	err := conn(ctx, r.db).First(&duplicate, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errDuplicateNotFound().WithCause(err)
+		}
+		return nil, err
+	}
+	return &duplicate, nil
+}
+
+// duplicateOrder lists the most probable duplicates first
+var duplicateOrder = pagination.Ordering[models.PatientDuplicate]{
+	{
+		Name:       "score",
+		Descending: true,
+		Key:        func(d *models.PatientDuplicate) string { return strconv.FormatFloat(d.Score, 'g', -1, 64) },
+		Parse: func(key string) (interface{}, error) {
+			return strconv.ParseFloat(key, 64)
+		},
+	},
+	{
+		Name:  "id",
+		Key:   func(d *models.PatientDuplicate) string { return strconv.FormatUint(uint64(d.ID), 10) },
+		Parse: pagination.ParseUint,
+	},
+}
+
+// List returns a page of the suspected duplicates in a review status
+func (r *duplicateRepository) List(ctx context.Context, status models.DuplicateStatus, request pagination.Request) (*pagination.Page[models.PatientDuplicate], error) {
+	query := conn(ctx, r.db).Model(&models.PatientDuplicate{}).Where("status = ?", status)
+	return paginate(query, duplicateOrder, request)
+}
+
+// Review records the decision on a pending suspected duplicate
+func (r *duplicateRepository) Review(ctx context.Context, id uint, status models.DuplicateStatus, reviewerID uint, at time.Time) (*models.PatientDuplicate, error) {
+	result := conn(ctx, r.db).Model(&models.PatientDuplicate{}).
+		Where("id = ? AND status = ?", id, models.DuplicateStatusPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewerID,
+			"reviewed_at": at,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// Tell a missing duplicate apart from one already reviewed
+	duplicate, err := r.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.Conflict(apperror.CodeDuplicateReviewed, "suspected duplicate has already been reviewed")
+	}
+	return duplicate, nil
+}
+
+// ConfirmPair confirms the pending suspected duplicate between two patients,
+// whichever of them was registered first, if one is queued, and returns the
+// IDs of the confirmed rows
+func (r *duplicateRepository) ConfirmPair(ctx context.Context, patientID, otherID, reviewerID uint, at time.Time) ([]uint, error) {
+	return r.closePending(ctx, models.DuplicateStatusConfirmed, reviewerID, at,
+		"(patient_id = ? AND candidate_id = ?) OR (patient_id = ? AND candidate_id = ?)", patientID, otherID, otherID, patientID)
+}
+
+// CloseMerged closes the pending suspected duplicates of a patient merged
+// into another, so that the queue does not point at a deleted patient, and
+// returns the IDs of the closed rows
+func (r *duplicateRepository) CloseMerged(ctx context.Context, patientID, reviewerID uint, at time.Time) ([]uint, error) {
+	return r.closePending(ctx, models.DuplicateStatusMerged, reviewerID, at,
+		"patient_id = ? OR candidate_id = ?", patientID, patientID)
+}
+
+// closePending records a status for the pending suspected duplicates that
+// match the condition and returns their IDs
+func (r *duplicateRepository) closePending(ctx context.Context, status models.DuplicateStatus, reviewerID uint, at time.Time, condition string, args ...interface{}) ([]uint, error) {
+	var closed []models.PatientDuplicate
+	err := conn(ctx, r.db).Model(&closed).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where(condition, args...).
+		Where("status = ?", models.DuplicateStatusPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewerID,
+			"reviewed_at": at,
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(closed))
+	for i, duplicate := range closed {
+		ids[i] = duplicate.ID
+	}
+	return ids, nil
+}
+
+// Reopen puts suspected duplicates back in the review queue as pending
+func (r *duplicateRepository) Reopen(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Model(&models.PatientDuplicate{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"status":      models.DuplicateStatusPending,
+			"reviewed_by": nil,
+			"reviewed_at": nil,
+		}).Error
+}
+
+// errDuplicateNotFound is returned when no suspected duplicate has the requested ID
+func errDuplicateNotFound() *apperror.Error {
+	return apperror.NotFound(apperror.CodeDuplicateNotFound, "suspected duplicate not found")
+}
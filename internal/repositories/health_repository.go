@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// HealthRepository interface defines methods for checking the database
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	MissingTables(ctx context.Context, models []interface{}) ([]string, error)
+}
+
+// healthRepository implements HealthRepository interface
+type healthRepository struct {
+	db *gorm.DB
+}
+
+// NewHealthRepository creates a new health repository
+func NewHealthRepository(db *gorm.DB) HealthRepository {
+	return &healthRepository{db: db}
+}
+
+// Ping checks that a database connection can be established
+func (r *healthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// MissingTables returns the tables of the given models that do not exist
+func (r *healthRepository) MissingTables(ctx context.Context, models []interface{}) ([]string, error) {
+	db := conn(ctx, r.db)
+
+	var missing []string
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		if !db.Migrator().HasTable(stmt.Table) {
+			missing = append(missing, stmt.Table)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+)
+
+// HL7MessageRepository interface defines methods for HL7 message repository
+type HL7MessageRepository interface {
+	Create(ctx context.Context, message *models.HL7Message) error
+	FindByID(ctx context.Context, id uint) (*models.HL7Message, error)
+	Update(ctx context.Context, message *models.HL7Message) error
+	List(ctx context.Context, page, limit int) ([]models.HL7Message, int64, error)
+}
+
+// hl7MessageRepository implements HL7MessageRepository interface
+type hl7MessageRepository struct {
+	db *gorm.DB
+}
+
+// NewHL7MessageRepository creates a new HL7 message repository
+func NewHL7MessageRepository(db *gorm.DB) HL7MessageRepository {
+	return &hl7MessageRepository{db: db}
+}
+
+// Create stores a new message
+func (r *hl7MessageRepository) Create(ctx context.Context, message *models.HL7Message) error {
+	return conn(ctx, r.db).Create(message).Error
+}
+
+// FindByID finds a message by ID
+func (r *hl7MessageRepository) FindByID(ctx context.Context, id uint) (*models.HL7Message, error) {
+	var message models.HL7Message
+	err := conn(ctx, r.db).First(&message, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound(apperror.CodeHL7MessageNotFound, "HL7 message not found").WithCause(err)
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Update updates a message
+func (r *hl7MessageRepository) Update(ctx context.Context, message *models.HL7Message) error {
+	return conn(ctx, r.db).Save(message).Error
+}
+
+// List returns messages with pagination, newest first
+func (r *hl7MessageRepository) List(ctx context.Context, page, limit int) ([]models.HL7Message, int64, error) {
+	var messages []models.HL7Message
+	var total int64
+
+	if err := conn(ctx, r.db).Model(&models.HL7Message{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := conn(ctx, r.db).Order("id DESC").Offset(offset).Limit(limit).Find(&messages).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
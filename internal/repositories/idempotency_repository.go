@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"hospital-project/internal/models"
+)
+
+// IdempotencyRepository interface defines methods for idempotency key repository
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record *models.IdempotencyKey) (bool, error)
+	FindByKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, record *models.IdempotencyKey) error
+	Delete(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// idempotencyRepository implements IdempotencyRepository interface
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency key repository
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve inserts a key that is being processed. It returns false without
+// an error if the user already used the key.
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyKey) (bool, error) {
+	result := conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindByKey finds a user's key, returning nil if there is none
+func (r *idempotencyRepository) FindByKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := conn(ctx, r.db).Where("user_id = ? AND key = ?", userID, key).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete stores the response of the request made with a key
+func (r *idempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyKey) error {
+	return conn(ctx, r.db).Model(record).Updates(map[string]interface{}{
+		"status_code":      record.StatusCode,
+		"response_headers": record.ResponseHeaders,
+		"response_body":    record.ResponseBody,
+		"completed_at":     record.CompletedAt,
+	}).Error
+}
+
+// Delete deletes a key so that it can be used again
+func (r *idempotencyRepository) Delete(ctx context.Context, id uint) error {
+	return conn(ctx, r.db).Delete(&models.IdempotencyKey{}, id).Error
+}
+
+// DeleteExpired deletes the keys that expired before now
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
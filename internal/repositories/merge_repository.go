@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+)
+
+// MergeRepository interface defines methods for patient merges and the
+// records that move with them
+type MergeRepository interface {
+	Create(ctx context.Context, merge *models.PatientMerge) error
+	FindByID(ctx context.Context, id uint) (*models.PatientMerge, error)
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.PatientMerge, error)
+	FindActiveByMergedID(ctx context.Context, mergedID uint) (*models.PatientMerge, error)
+	Update(ctx context.Context, merge *models.PatientMerge) error
+	Relink(ctx context.Context, fromPatientID, toPatientID uint) (map[string][]uint, error)
+	Restore(ctx context.Context, relinked map[string][]uint, fromPatientID, toPatientID uint) error
+}
+
+// mergeRepository implements MergeRepository interface
+type mergeRepository struct {
+	db *gorm.DB
+}
+
+// NewMergeRepository creates a new merge repository
+func NewMergeRepository(db *gorm.DB) MergeRepository {
+	return &mergeRepository{db: db}
+}
+
+// patientReference is a column of another table that refers to a patient
+type patientReference struct {
+	Table  string
+	Column string
+}
+
+// patientReferences are the records a merge moves to the survivor. A new
+// table that belongs to a patient must be added here, or its rows stay with
+// the merged patient.
+var patientReferences = []patientReference{
+	{Table: "patient_identifiers", Column: "patient_id"},
+	{Table: "hl7_messages", Column: "patient_id"},
+}
+
+// Create creates a new patient merge
+func (r *mergeRepository) Create(ctx context.Context, merge *models.PatientMerge) error {
+	return conn(ctx, r.db).Create(merge).Error
+}
+
+// FindByID finds a patient merge by ID
+func (r *mergeRepository) FindByID(ctx context.Context, id uint) (*models.PatientMerge, error) {
+	return r.findByID(conn(ctx, r.db), id)
+}
+
+// FindByIDForUpdate finds a patient merge by ID and locks its row until the
+// surrounding transaction ends
+func (r *mergeRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.PatientMerge, error) {
+	return r.findByID(forUpdate(conn(ctx, r.db)), id)
+}
+
+func (r *mergeRepository) findByID(db *gorm.DB, id uint) (*models.PatientMerge, error) {
+	var merge models.PatientMerge
+	err := db.First(&merge, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errMergeNotFound().WithCause(err)
+		}
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// FindActiveByMergedID finds the merge, not undone, that folded a patient
+// into another
+func (r *mergeRepository) FindActiveByMergedID(ctx context.Context, mergedID uint) (*models.PatientMerge, error) {
+	var merge models.PatientMerge
+	err := conn(ctx, r.db).Where("merged_id = ? AND unmerged_at IS NULL", mergedID).
+		Order("id DESC").
+		First(&merge).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errMergeNotFound().WithCause(err)
+		}
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// Update saves a patient merge
+func (r *mergeRepository) Update(ctx context.Context, merge *models.PatientMerge) error {
+	return conn(ctx, r.db).Save(merge).Error
+}
+
+// Relink moves every record of one patient to another, and returns the IDs
+// of the moved rows by table
+func (r *mergeRepository) Relink(ctx context.Context, fromPatientID, toPatientID uint) (map[string][]uint, error) {
+	relinked := make(map[string][]uint)
+	for _, ref := range patientReferences {
+		var ids []uint
+		statement := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ? RETURNING id", ref.Table, ref.Column, ref.Column)
+		if err := conn(ctx, r.db).Raw(statement, toPatientID, fromPatientID).Scan(&ids).Error; err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			relinked[ref.Table] = ids
+		}
+	}
+	return relinked, nil
+}
+
+// Restore moves the relinked rows that still belong to fromPatientID back
+// to toPatientID. Rows added to fromPatientID since they were relinked stay.
+func (r *mergeRepository) Restore(ctx context.Context, relinked map[string][]uint, fromPatientID, toPatientID uint) error {
+	for _, ref := range patientReferences {
+		ids := relinked[ref.Table]
+		if len(ids) == 0 {
+			continue
+		}
+		statement := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ? AND id IN ?", ref.Table, ref.Column, ref.Column)
+		if err := conn(ctx, r.db).Exec(statement, toPatientID, fromPatientID, ids).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errMergeNotFound is returned when no patient merge has the requested ID
+func errMergeNotFound() *apperror.Error {
+	return apperror.NotFound(apperror.CodeMergeNotFound, "patient merge not found")
+}
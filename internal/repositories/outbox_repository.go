@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"hospital-project/internal/models"
+)
+
+// OutboxRepository interface defines methods for outbox repository
+type OutboxRepository interface {
+	Append(ctx context.Context, event *models.OutboxEvent) error
+	ListPending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error)
+	Update(ctx context.Context, event *models.OutboxEvent) error
+	PurgePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// outboxRepository implements OutboxRepository interface
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Append adds an event to the outbox
+func (r *outboxRepository) Append(ctx context.Context, event *models.OutboxEvent) error {
+	return conn(ctx, r.db).Create(event).Error
+}
+
+// unblocked matches events with no earlier unpublished event of the same
+// aggregate, so that an aggregate's events are published in order
+const unblocked = `NOT EXISTS (
+	SELECT 1 FROM outbox_events earlier
+	WHERE earlier.aggregate_type = outbox_events.aggregate_type
+		AND earlier.aggregate_id = outbox_events.aggregate_id
+		AND earlier.published_at IS NULL
+		AND earlier.deleted_at IS NULL
+		AND earlier.id < outbox_events.id
+)`
+
+// ListPending returns unpublished events that are due, in the order they
+// were written, at most one per aggregate: the earliest, and only if it is
+// not waiting to be retried. Inside a transaction the events stay locked
+// until it ends, and events locked by another dispatcher are skipped.
+func (r *outboxRepository) ListPending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := conn(ctx, r.db).
+		Where("published_at IS NULL").
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+		Where(unblocked).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// Update updates an event
+func (r *outboxRepository) Update(ctx context.Context, event *models.OutboxEvent) error {
+	return conn(ctx, r.db).Save(event).Error
+}
+
+// PurgePublished deletes the events published before the given time and
+// returns how many were deleted
+func (r *outboxRepository) PurgePublished(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Unscoped().Where("published_at < ?", before).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
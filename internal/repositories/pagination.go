@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"hospital-project/internal/pagination"
+)
+
+// paginate fetches the page of query selected by request, ordered by order
+func paginate[T any](query *gorm.DB, order pagination.Ordering[T], request pagination.Request) (*pagination.Page[T], error) {
+	// Count and Find each start from the filters without affecting the other
+	query = query.Session(&gorm.Session{})
+
+	var total *int64
+	if request.Counted() {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, err
+		}
+		total = &count
+	}
+
+	backward := false
+	if request.Cursor != nil {
+		condition, args, err := order.Seek(request.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition, args...)
+		backward = request.Cursor.Backward
+	}
+
+	// One row more than the limit tells whether there is a further page
+	var rows []T
+	err := query.Order(order.OrderBy(backward)).Offset(request.Offset()).Limit(request.Limit + 1).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return order.Page(request, rows, total), nil
+}
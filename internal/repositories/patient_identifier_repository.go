@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+)
+
+// PatientIdentifierRepository interface defines methods for patient identifier repository
+type PatientIdentifierRepository interface {
+	Create(ctx context.Context, identifier *models.PatientIdentifier) error
+	FindByValue(ctx context.Context, system, value string) (*models.PatientIdentifier, error)
+	Relink(ctx context.Context, id, patientID uint) error
+}
+
+// patientIdentifierRepository implements PatientIdentifierRepository interface
+type patientIdentifierRepository struct {
+	db *gorm.DB
+}
+
+// NewPatientIdentifierRepository creates a new patient identifier repository
+func NewPatientIdentifierRepository(db *gorm.DB) PatientIdentifierRepository {
+	return &patientIdentifierRepository{db: db}
+}
+
+// Create creates a new identifier
+func (r *patientIdentifierRepository) Create(ctx context.Context, identifier *models.PatientIdentifier) error {
+	return conn(ctx, r.db).Create(identifier).Error
+}
+
+// FindByValue finds an identifier by its issuing system and value
+func (r *patientIdentifierRepository) FindByValue(ctx context.Context, system, value string) (*models.PatientIdentifier, error) {
+	var identifier models.PatientIdentifier
+	err := conn(ctx, r.db).Where("system = ? AND value = ?", system, value).First(&identifier).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound(apperror.CodePatientIdentifierNotFound, "patient identifier not found").WithCause(err)
+		}
+		return nil, err
+	}
+	return &identifier, nil
+}
+
+// Relink points an identifier at another patient
+func (r *patientIdentifierRepository) Relink(ctx context.Context, id, patientID uint) error {
+	return conn(ctx, r.db).Model(&models.PatientIdentifier{}).Where("id = ?", id).Update("patient_id", patientID).Error
+}
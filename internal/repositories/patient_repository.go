@@ -1,21 +1,39 @@
 package repositories
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/matching"
 	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/phonetic"
 )
 
 // PatientRepository interface defines methods for patient repository
 type PatientRepository interface {
-	Create(patient *models.Patient) error
-	FindByID(id uint) (*models.Patient, error)
-	Update(patient *models.Patient) error
-	UpdateMedicalNotes(id uint, medicalNotes string) error
-	Delete(id uint) error
-	List(page, limit int) ([]models.Patient, int64, error)
-	Search(params models.PatientSearchRequest) ([]models.Patient, error)
-	ExistsByNameOrContact(name, contactInfo string) (bool, error)
+	Create(ctx context.Context, patient *models.Patient) error
+	FindByID(ctx context.Context, id uint) (*models.Patient, error)
+	FindByIDForUpdate(ctx context.Context, id uint) (*models.Patient, error)
+	Update(ctx context.Context, patient *models.Patient) error
+	UpdateMedicalNotes(ctx context.Context, id uint, medicalNotes string, version uint) error
+	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) error
+	List(ctx context.Context, request pagination.Request) (*pagination.Page[models.Patient], error)
+	Search(ctx context.Context, params models.PatientSearchRequest, sort []pagination.SortKey, request pagination.Request) (*pagination.Page[models.PatientMatch], error)
+	SearchNotes(ctx context.Context, query string, params models.PatientSearchRequest, request pagination.Request) (*pagination.Page[models.PatientMatch], error)
+	Export(ctx context.Context, params models.PatientSearchRequest, fn func(*models.Patient) error) error
+	Facets(ctx context.Context, query string, params models.PatientSearchRequest, facets []string) (map[string][]models.FacetCount, error)
+	FindMatchCandidates(ctx context.Context, patient *models.Patient, limit int) ([]models.Patient, error)
+	CountCreatedSince(ctx context.Context, since time.Time) (int64, error)
 }
 
 // patientRepository implements PatientRepository interface
@@ -31,64 +49,464 @@ func NewPatientRepository(db *gorm.DB) PatientRepository {
 }
 
 // Create creates a new patient
-func (r *patientRepository) Create(patient *models.Patient) error {
-	return r.db.Create(patient).Error
+func (r *patientRepository) Create(ctx context.Context, patient *models.Patient) error {
+	if patient.Version == 0 {
+		patient.Version = 1
+	}
+	patient.NamePhonetic = phonetic.Encode(patient.Name)
+	patient.ContactKeys = matching.EncodeContactKeys(patient.ContactInfo)
+	return conn(ctx, r.db).Create(patient).Error
 }
 
 // FindByID finds a patient by ID
-func (r *patientRepository) FindByID(id uint) (*models.Patient, error) {
+func (r *patientRepository) FindByID(ctx context.Context, id uint) (*models.Patient, error) {
+	return r.findByID(conn(ctx, r.db), id)
+}
+
+// FindByIDForUpdate finds a patient by ID and locks its row until the
+// surrounding transaction ends, so a read-modify-write cannot interleave
+// with another writer
+func (r *patientRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Patient, error) {
+	return r.findByID(forUpdate(conn(ctx, r.db)), id)
+}
+
+func (r *patientRepository) findByID(db *gorm.DB, id uint) (*models.Patient, error) {
 	var patient models.Patient
-	err := r.db.First(&patient, id).Error
+	err := db.First(&patient, id).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errPatientNotFound().WithCause(err)
+		}
 		return nil, err
 	}
 	return &patient, nil
 }
 
-// Update updates a patient
-func (r *patientRepository) Update(patient *models.Patient) error {
-	return r.db.Save(patient).Error
+// Update updates a patient if it is still at patient.Version, and
+// increments the version on success
+func (r *patientRepository) Update(ctx context.Context, patient *models.Patient) error {
+	err := r.updateVersion(ctx, patient.ID, patient.Version, map[string]interface{}{
+		"name":          patient.Name,
+		"name_phonetic": phonetic.Encode(patient.Name),
+		"age":           patient.Age,
+		"gender":        patient.Gender,
+		"contact_info":  patient.ContactInfo,
+		"contact_keys":  matching.EncodeContactKeys(patient.ContactInfo),
+		"birth_date":    patient.BirthDate,
+		"address":       patient.Address,
+		"medical_notes": patient.MedicalNotes,
+	})
+	if err != nil {
+		return err
+	}
+	patient.NamePhonetic = phonetic.Encode(patient.Name)
+	patient.ContactKeys = matching.EncodeContactKeys(patient.ContactInfo)
+	patient.Version++
+	return nil
 }
 
-// UpdateMedicalNotes updates medical notes
-func (r *patientRepository) UpdateMedicalNotes(id uint, medicalNotes string) error {
-	return r.db.Model(&models.Patient{}).Where("id = ?", id).Update("medical_notes", medicalNotes).Error
+// UpdateMedicalNotes updates medical notes if the patient is still at the given version
+func (r *patientRepository) UpdateMedicalNotes(ctx context.Context, id uint, medicalNotes string, version uint) error {
+	return r.updateVersion(ctx, id, version, map[string]interface{}{"medical_notes": medicalNotes})
+}
+
+// updateVersion applies the changes only if the stored version matches,
+// so concurrent writers cannot silently overwrite each other
+func (r *patientRepository) updateVersion(ctx context.Context, id, version uint, changes map[string]interface{}) error {
+	changes["version"] = gorm.Expr("version + 1")
+
+	result := conn(ctx, r.db).Model(&models.Patient{}).Where("id = ? AND version = ?", id, version).Updates(changes)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// Tell a missing patient apart from a stale version
+	var count int64
+	if err := conn(ctx, r.db).Model(&models.Patient{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errPatientNotFound()
+	}
+	return apperror.New(apperror.KindPreconditionFailed, apperror.CodeVersionMismatch, "patient has been modified by someone else")
 }
 
 // Delete deletes a patient
-func (r *patientRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Patient{}, id).Error
+func (r *patientRepository) Delete(ctx context.Context, id uint) error {
+	result := conn(ctx, r.db).Delete(&models.Patient{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errPatientNotFound()
+	}
+	return nil
+}
+
+// Restore brings back a deleted patient
+func (r *patientRepository) Restore(ctx context.Context, id uint) error {
+	result := conn(ctx, r.db).Unscoped().Model(&models.Patient{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errPatientNotFound()
+	}
+	return nil
+}
+
+// patientColumns are the columns patients can be sorted by
+var patientColumns = map[string]pagination.Column[models.Patient]{
+	"id": {
+		Name:  "id",
+		Key:   func(p *models.Patient) string { return strconv.FormatUint(uint64(p.ID), 10) },
+		Parse: pagination.ParseUint,
+	},
+	"name": {
+		Name:  "name",
+		Key:   func(p *models.Patient) string { return p.Name },
+		Parse: pagination.ParseString,
+	},
+	"age": {
+		Name:  "age",
+		Key:   func(p *models.Patient) string { return strconv.Itoa(p.Age) },
+		Parse: pagination.ParseInt,
+	},
+	"gender": {
+		Name:  "gender",
+		Key:   func(p *models.Patient) string { return string(p.Gender) },
+		Parse: pagination.ParseString,
+	},
+	"created_at": {
+		Name:  "created_at",
+		Key:   func(p *models.Patient) string { return pagination.FormatTime(p.CreatedAt) },
+		Parse: pagination.ParseTime,
+	},
+	"updated_at": {
+		Name:  "updated_at",
+		Key:   func(p *models.Patient) string { return pagination.FormatTime(p.UpdatedAt) },
+		Parse: pagination.ParseTime,
+	},
+	// Patients known only by their age sort as if they turned it today
+	"birth_date": {
+		Name:  "COALESCE(birth_date, (CURRENT_DATE - make_interval(years => age))::date)",
+		Key:   func(p *models.Patient) string { return models.FormatDate(estimatedBirthDate(p)) },
+		Parse: pagination.ParseString,
+	},
+}
+
+// estimatedBirthDate returns the birth date of a patient, or the latest one
+// their age allows if it is not known
+func estimatedBirthDate(p *models.Patient) *time.Time {
+	if p.BirthDate != nil {
+		return p.BirthDate
+	}
+	born := time.Now().AddDate(-p.Age, 0, 0)
+	return &born
+}
+
+// patientOrder lists patients in the order they were registered. Unlike an
+// offset, a cursor into it stays put when patients are added while paging.
+var patientOrder = pagination.Ordering[models.Patient]{patientColumns["created_at"], patientColumns["id"]}
+
+// List returns a page of all patients
+func (r *patientRepository) List(ctx context.Context, request pagination.Request) (*pagination.Page[models.Patient], error) {
+	return paginate(conn(ctx, r.db).Model(&models.Patient{}), patientOrder, request)
+}
+
+// Search returns a page of the patients matching the search parameters,
+// sorted by the given keys and then by ID. Without sort keys, fuzzy searches
+// list the most similar patients first and others list them in the order
+// they were registered.
+func (r *patientRepository) Search(ctx context.Context, params models.PatientSearchRequest, sort []pagination.SortKey, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	fallback := matchOrder
+	if params.Fuzzy() {
+		fallback = matchRankOrder
+	}
+
+	var page *pagination.Page[models.PatientMatch]
+	err := r.search(ctx, params, func(db *gorm.DB) error {
+		matches, err := applySearchFilters(db.Model(&models.Patient{}), params)
+		if err != nil {
+			return err
+		}
+		// The rank is computed in a subquery so that pages can seek on it
+		matches = matches.Select("patients.*, ? AS rank", similarity(params))
+
+		page, err = paginate(db.Table("(?) AS matches", matches), pagination.Order(sort, matchColumns, "id", fallback), request)
+		return err
+	})
+	return page, err
+}
+
+// matchColumns are the columns matches can be sorted by
+var matchColumns = matchColumnsOf(patientColumns)
+
+// matchOrder lists matches in the order the patients were registered
+var matchOrder = pagination.Ordering[models.PatientMatch]{matchColumns["created_at"], matchColumns["id"]}
+
+// matchColumnsOf adapts patient columns to the patients of matches
+func matchColumnsOf(columns map[string]pagination.Column[models.Patient]) map[string]pagination.Column[models.PatientMatch] {
+	adapted := make(map[string]pagination.Column[models.PatientMatch], len(columns))
+	for field, column := range columns {
+		key := column.Key
+		adapted[field] = pagination.Column[models.PatientMatch]{
+			Name:       column.Name,
+			Descending: column.Descending,
+			Key:        func(m *models.PatientMatch) string { return key(&m.Patient) },
+			Parse:      column.Parse,
+		}
+	}
+	return adapted
+}
+
+// snippetOptions select the excerpts of the notes returned with a match
+var snippetOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=20, MinWords=8, MaxFragments=2", models.SnippetStart, models.SnippetStop)
+
+// matchRankOrder lists the most relevant or similar matches first
+var matchRankOrder = pagination.Ordering[models.PatientMatch]{
+	{
+		Name:       "rank",
+		Descending: true,
+		Key:        func(m *models.PatientMatch) string { return strconv.FormatFloat(float64(m.Rank), 'g', -1, 32) },
+		Parse: func(key string) (interface{}, error) {
+			rank, err := strconv.ParseFloat(key, 32)
+			return float32(rank), err
+		},
+	},
+	{
+		Name:  "id",
+		Key:   func(m *models.PatientMatch) string { return strconv.FormatUint(uint64(m.ID), 10) },
+		Parse: pagination.ParseUint,
+	},
+}
+
+// SearchNotes returns a page of the patients matching the search parameters
+// whose medical notes match a full-text query, most relevant first, each
+// with a highlighted snippet of the notes
+func (r *patientRepository) SearchNotes(ctx context.Context, query string, params models.PatientSearchRequest, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	tsquery := notesQuery(query)
+
+	var page *pagination.Page[models.PatientMatch]
+	err := r.search(ctx, params, func(db *gorm.DB) error {
+		matches, err := applySearchFilters(db.Model(&models.Patient{}), params)
+		if err != nil {
+			return err
+		}
+		// The rank is computed in a subquery so that pages can seek on it
+		matches = matches.Select("patients.*, ts_rank(notes_tsv, ?) AS rank", tsquery).
+			Where("notes_tsv @@ ?", tsquery)
+
+		page, err = paginate(db.Table("(?) AS matches", matches), matchRankOrder, request)
+		return err
+	})
+	if err != nil || len(page.Items) == 0 {
+		return page, err
+	}
+
+	// Snippets are only worth computing for the rows on the page
+	ids := make([]uint, len(page.Items))
+	for i := range page.Items {
+		ids[i] = page.Items[i].ID
+	}
+	var snippets []struct {
+		ID      uint
+		Snippet string
+	}
+	err = conn(ctx, r.db).Model(&models.Patient{}).
+		Select("id, ts_headline('english', coalesce(medical_notes, ''), ?, ?) AS snippet", tsquery, snippetOptions).
+		Where("id IN ?", ids).
+		Scan(&snippets).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, snippet := range snippets {
+		for i := range page.Items {
+			if page.Items[i].ID == snippet.ID {
+				page.Items[i].Snippet = snippet.Snippet
+			}
+		}
+	}
+	return page, nil
 }
 
-// List returns all patients with pagination
-func (r *patientRepository) List(page, limit int) ([]models.Patient, int64, error) {
-	var patients []models.Patient
-	var total int64
+// notesQuery parses a full-text query over medical notes in web search
+// syntax. The notes are indexed with the english text search configuration.
+func notesQuery(query string) clause.Expr {
+	return gorm.Expr("websearch_to_tsquery('english', ?)", query)
+}
 
-	// Count total records
-	if err := r.db.Model(&models.Patient{}).Count(&total).Error; err != nil {
-		return nil, 0, err
+// facetValues are the expressions whose values patients are counted by for
+// each facet. Ages are banded by life stage and registrations by UTC month.
+var facetValues = map[string]string{
+	models.FacetGender: "gender",
+	models.FacetAgeBand: `CASE WHEN age < 18 THEN '0-17' WHEN age < 35 THEN '18-34' WHEN age < 50 THEN '35-49'
+		WHEN age < 65 THEN '50-64' WHEN age < 80 THEN '65-79' ELSE '80+' END`,
+	models.FacetCreatedMonth: "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM')",
+}
+
+// Facets counts the patients matching the search parameters, and the
+// full-text query over medical notes if one is given, by each value of each
+// facet. Values without patients are left out.
+func (r *patientRepository) Facets(ctx context.Context, query string, params models.PatientSearchRequest, facets []string) (map[string][]models.FacetCount, error) {
+	if len(facets) == 0 {
+		return nil, nil
 	}
 
-	// Calculate offset
-	offset := (page - 1) * limit
+	counts := make(map[string][]models.FacetCount, len(facets))
+	err := r.search(ctx, params, func(db *gorm.DB) error {
+		matches, err := applySearchFilters(db.Model(&models.Patient{}), params)
+		if err != nil {
+			return err
+		}
+		if query != "" {
+			matches = matches.Where("notes_tsv @@ ?", notesQuery(query))
+		}
 
-	// Get paginated records
-	err := r.db.Offset(offset).Limit(limit).Find(&patients).Error
+		for _, facet := range facets {
+			value, ok := facetValues[facet]
+			if !ok {
+				return fmt.Errorf("unknown facet %q", facet)
+			}
+			facetCounts := []models.FacetCount{}
+			err := matches.Session(&gorm.Session{}).
+				Select(value + " AS value, count(*) AS count").
+				Group("value").
+				Order("value").
+				Scan(&facetCounts).Error
+			if err != nil {
+				return err
+			}
+			counts[facet] = facetCounts
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
+	return counts, nil
+}
+
+// Export streams every patient matching the search parameters to fn, one row
+// at a time, using a database cursor so memory use does not grow with the
+// size of the result set
+func (r *patientRepository) Export(ctx context.Context, params models.PatientSearchRequest, fn func(*models.Patient) error) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// The cursor stays open while rows are written to the client, so the
+		// request deadline bounds an export rather than statement_timeout
+		if err := tx.Exec("SET LOCAL statement_timeout = 0").Error; err != nil {
+			return err
+		}
+		if err := setSimilarityThreshold(tx, params); err != nil {
+			return err
+		}
+
+		query, err := applySearchFilters(tx.Model(&models.Patient{}), params)
+		if err != nil {
+			return err
+		}
+		rows, err := query.Order("id").Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
 
-	return patients, total, nil
+		for rows.Next() {
+			var patient models.Patient
+			if err := tx.ScanRows(rows, &patient); err != nil {
+				return err
+			}
+			if err := fn(&patient); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	})
 }
 
-// Search searches for patients
-func (r *patientRepository) Search(params models.PatientSearchRequest) ([]models.Patient, error) {
-	var patients []models.Patient
-	query := r.db.Model(&models.Patient{})
+// search runs fn with a connection set up for the fuzzy filters of params.
+// The similarity threshold is a setting of the transaction, so fuzzy
+// searches run in one.
+func (r *patientRepository) search(ctx context.Context, params models.PatientSearchRequest, fn func(db *gorm.DB) error) error {
+	if !params.Fuzzy() {
+		return fn(conn(ctx, r.db))
+	}
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := setSimilarityThreshold(tx, params); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
 
+// setSimilarityThreshold sets the similarity the <% operator of pg_trgm
+// requires for the rest of the transaction
+func setSimilarityThreshold(tx *gorm.DB, params models.PatientSearchRequest) error {
+	if !params.Fuzzy() {
+		return nil
+	}
+	threshold := strconv.FormatFloat(params.SimilarityThreshold(), 'f', -1, 64)
+	return tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)", threshold).Error
+}
+
+// fuzzyMatch matches a column containing the searched text, or a word of it
+// similar enough to the text, ignoring case and accents. Both conditions
+// can use the column's trigram index.
+func fuzzyMatch(column string) string {
+	return fmt.Sprintf("(f_unaccent(%[1]s) ILIKE '%%' || f_unaccent(?) || '%%' OR f_unaccent(?) <%% f_unaccent(%[1]s))", column)
+}
+
+// phoneticMatch matches a name sharing a phonetic key with the given ones,
+// which can use the index on the keys
+const phoneticMatch = "string_to_array(name_phonetic, ' ') && string_to_array(?, ' ')"
+
+// contactMatch matches contact info sharing a phone number or email address
+// with the given keys, which can use the index on the keys
+const contactMatch = "string_to_array(contact_keys, ' ') && string_to_array(?, ' ')"
+
+// similarity returns the mean similarity of the fuzzy filters of params to
+// the columns they search, or zero if there are none. In phonetic searches
+// the name's similarity is the mean of its trigram similarity and the share
+// of the searched words that sound like one of its words.
+func similarity(params models.PatientSearchRequest) clause.Expr {
+	var terms []string
+	var values []interface{}
 	if params.Name != "" {
-		query = query.Where("name ILIKE ?", "%"+params.Name+"%")
+		term := "word_similarity(f_unaccent(?), f_unaccent(name))"
+		values = append(values, params.Name)
+		if words := phonetic.Words(params.Name); params.Phonetic() && len(words) > 0 {
+			sounds := make([]string, len(words))
+			for i, word := range words {
+				sounds[i] = "(" + phoneticMatch + ")::int"
+				values = append(values, phonetic.Encode(word))
+			}
+			term = fmt.Sprintf("(%s + (%s)::real / %d) / 2", term, strings.Join(sounds, " + "), len(words))
+		}
+		terms = append(terms, term)
+	}
+	if params.ContactInfo != "" {
+		terms = append(terms, "word_similarity(f_unaccent(?), f_unaccent(contact_info))")
+		values = append(values, params.ContactInfo)
+	}
+
+	if len(terms) == 0 {
+		return gorm.Expr("0::real")
+	}
+	return gorm.Expr(fmt.Sprintf("((%s) / %d)::real", strings.Join(terms, " + "), len(terms)), values...)
+}
+
+// applySearchFilters adds the WHERE clauses for the given search parameters
+func applySearchFilters(query *gorm.DB, params models.PatientSearchRequest) (*gorm.DB, error) {
+	if params.Phonetic() {
+		query = query.Where("("+fuzzyMatch("name")+" OR "+phoneticMatch+")", params.Name, params.Name, phonetic.Encode(params.Name))
+	} else if params.Name != "" {
+		query = query.Where(fuzzyMatch("name"), params.Name, params.Name)
 	}
 	if params.AgeMin > 0 {
 		query = query.Where("age >= ?", params.AgeMin)
@@ -100,18 +518,53 @@ func (r *patientRepository) Search(params models.PatientSearchRequest) ([]models
 		query = query.Where("gender = ?", params.Gender)
 	}
 	if params.ContactInfo != "" {
-		query = query.Where("contact_info ILIKE ?", "%"+params.ContactInfo+"%")
+		query = query.Where(fuzzyMatch("contact_info"), params.ContactInfo, params.ContactInfo)
 	}
 
-	err := query.Find(&patients).Error
-	return patients, err
+	parsed, err := params.ParseFilter()
+	if err != nil {
+		return nil, err
+	}
+	if parsed != nil {
+		condition, vars := parsed.SQL()
+		query = query.Where(condition, vars...)
+	}
+	return query, nil
 }
 
-// ExistsByNameOrContact checks if a patient exists with the given name or contact info
-func (r *patientRepository) ExistsByNameOrContact(name, contactInfo string) (bool, error) {
+// FindMatchCandidates returns up to limit other patients that may be the
+// same person as patient: those with a name that sounds like a word of its
+// name, or sharing a phone number or email address. Both conditions can use
+// an index, so candidates are found without scoring every patient. Patients
+// sharing contact info come first, then the most recently registered, so a
+// common surname does not crowd out a likelier duplicate.
+func (r *patientRepository) FindMatchCandidates(ctx context.Context, patient *models.Patient, limit int) ([]models.Patient, error) {
+	names := phonetic.Encode(patient.Name)
+	contacts := matching.EncodeContactKeys(patient.ContactInfo)
+	if names == "" && contacts == "" {
+		return nil, nil
+	}
+
+	var candidates []models.Patient
+	err := conn(ctx, r.db).
+		Where("("+phoneticMatch+" OR "+contactMatch+")", names, contacts).
+		Where("id <> ?", patient.ID).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: contactMatch + " DESC, id DESC", Vars: []interface{}{contacts}}}).
+		Limit(limit).
+		Find(&candidates).Error
+	return candidates, err
+}
+
+// CountCreatedSince counts the patients registered at or after since
+func (r *patientRepository) CountCreatedSince(ctx context.Context, since time.Time) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Patient{}).
-		Where("name = ? OR contact_info = ?", name, contactInfo).
+	err := conn(ctx, r.db).Model(&models.Patient{}).
+		Where("created_at >= ?", since).
 		Count(&count).Error
-	return count > 0, err
+	return count, err
+}
+
+// errPatientNotFound is returned when no patient has the requested ID
+func errPatientNotFound() *apperror.Error {
+	return apperror.NotFound(apperror.CodePatientNotFound, "patient not found")
 }
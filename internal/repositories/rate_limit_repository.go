@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/ratelimit"
+)
+
+// RateLimitRepository interface defines methods for the shared rate limit store
+type RateLimitRepository interface {
+	ratelimit.Store
+	DeleteIdle(ctx context.Context, before time.Time) (int64, error)
+}
+
+// rateLimitRepository implements RateLimitRepository interface
+type rateLimitRepository struct {
+	db *gorm.DB
+}
+
+// NewRateLimitRepository creates a new rate limit repository
+func NewRateLimitRepository(db *gorm.DB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+// Take takes a token from the bucket of key. The bucket row is locked while
+// it is refilled, so instances sharing the database never both spend the
+// same token.
+func (r *rateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	var result ratelimit.Result
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		row := models.RateLimitBucket{Key: key, Tokens: float64(limit.Burst), RefilledAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		if err := forUpdate(tx).Where("key = ?", key).First(&row).Error; err != nil {
+			return err
+		}
+
+		bucket := ratelimit.Bucket{Tokens: row.Tokens, UpdatedAt: row.RefilledAt}
+		result = bucket.Take(limit, now)
+
+		return tx.Model(&row).Updates(map[string]interface{}{
+			"tokens":      bucket.Tokens,
+			"refilled_at": bucket.UpdatedAt,
+		}).Error
+	})
+	return result, err
+}
+
+// DeleteIdle deletes the buckets last used before the given time
+func (r *rateLimitRepository) DeleteIdle(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("refilled_at < ?", before).Delete(&models.RateLimitBucket{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+)
+
+// SavedSearchRepository interface defines methods for saved patient searches
+type SavedSearchRepository interface {
+	Create(ctx context.Context, search *models.SavedSearch) error
+	FindByID(ctx context.Context, id, userID uint) (*models.SavedSearch, error)
+	Update(ctx context.Context, search *models.SavedSearch) error
+	Delete(ctx context.Context, id uint) error
+	ListVisible(ctx context.Context, userID uint, role models.Role, pinnedOnly bool, request pagination.Request) (*pagination.Page[models.SavedSearch], error)
+	SetPinned(ctx context.Context, id, userID uint, pinned bool) error
+}
+
+// savedSearchRepository implements SavedSearchRepository interface
+type savedSearchRepository struct {
+	db *gorm.DB
+}
+
+// NewSavedSearchRepository creates a new saved search repository
+func NewSavedSearchRepository(db *gorm.DB) SavedSearchRepository {
+	return &savedSearchRepository{db: db}
+}
+
+// Create saves a new search. A name the owner already uses is reported as a
+// conflict by the unique index.
+func (r *savedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	err := conn(ctx, r.db).Create(search).Error
+	if isUniqueViolation(err) {
+		return errSavedSearchNameTaken().WithCause(err)
+	}
+	return err
+}
+
+// pinnedBy matches the saved searches a user pinned
+const pinnedBy = `EXISTS (
+	SELECT 1 FROM saved_search_pins
+	WHERE saved_search_pins.saved_search_id = saved_searches.id
+	AND saved_search_pins.user_id = ?
+)`
+
+// withPinned selects saved searches along with whether the user pinned them
+func withPinned(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.SavedSearch{}).Select("saved_searches.*, "+pinnedBy+" AS pinned", userID)
+}
+
+// FindByID finds a saved search by ID, along with whether the user pinned it
+func (r *savedSearchRepository) FindByID(ctx context.Context, id, userID uint) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := withPinned(conn(ctx, r.db), userID).First(&search, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errSavedSearchNotFound().WithCause(err)
+		}
+		return nil, err
+	}
+	return &search, nil
+}
+
+// Update updates a saved search
+func (r *savedSearchRepository) Update(ctx context.Context, search *models.SavedSearch) error {
+	err := conn(ctx, r.db).Save(search).Error
+	if isUniqueViolation(err) {
+		return errSavedSearchNameTaken().WithCause(err)
+	}
+	return err
+}
+
+// Delete deletes a saved search
+func (r *savedSearchRepository) Delete(ctx context.Context, id uint) error {
+	result := conn(ctx, r.db).Delete(&models.SavedSearch{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errSavedSearchNotFound()
+	}
+	return nil
+}
+
+// savedSearchOrder lists saved searches by name
+var savedSearchOrder = pagination.Ordering[models.SavedSearch]{
+	{
+		Name:  "name",
+		Key:   func(s *models.SavedSearch) string { return s.Name },
+		Parse: pagination.ParseString,
+	},
+	{
+		Name:  "id",
+		Key:   func(s *models.SavedSearch) string { return strconv.FormatUint(uint64(s.ID), 10) },
+		Parse: pagination.ParseUint,
+	},
+}
+
+// ListVisible returns a page of the searches a user owns or that are shared
+// with their role, optionally only the ones the user pinned
+func (r *savedSearchRepository) ListVisible(ctx context.Context, userID uint, role models.Role, pinnedOnly bool, request pagination.Request) (*pagination.Page[models.SavedSearch], error) {
+	query := withPinned(conn(ctx, r.db), userID).
+		Where("owner_id = ? OR shared_with = ?", userID, role)
+	if pinnedOnly {
+		query = query.Where(pinnedBy, userID)
+	}
+	return paginate(query, savedSearchOrder, request)
+}
+
+// SetPinned pins a saved search for a user or removes the pin. Pinning a
+// search twice, or unpinning one that is not pinned, changes nothing.
+func (r *savedSearchRepository) SetPinned(ctx context.Context, id, userID uint, pinned bool) error {
+	pin := &models.SavedSearchPin{UserID: userID, SavedSearchID: id}
+	if !pinned {
+		return conn(ctx, r.db).Delete(pin).Error
+	}
+	return conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(pin).Error
+}
+
+// errSavedSearchNotFound is returned when no saved search has the requested ID
+func errSavedSearchNotFound() *apperror.Error {
+	return apperror.NotFound(apperror.CodeSavedSearchNotFound, "saved search not found")
+}
+
+// errSavedSearchNameTaken is returned when the owner already has a saved
+// search with the name
+func errSavedSearchNameTaken() *apperror.Error {
+	return apperror.Conflict(apperror.CodeSavedSearchNameTaken, "a saved search with this name already exists")
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// uniqueViolation is the Postgres SQLSTATE raised when a unique constraint fails
+const uniqueViolation = "23505"
+
+// Repositories groups the repositories available inside a transaction
+type Repositories struct {
+	Patients    PatientRepository
+	Outbox      OutboxRepository
+	Users       UserRepository
+	Audit       AuditRepository
+	Identifiers PatientIdentifierRepository
+	Duplicates  DuplicateRepository
+	Merges      MergeRepository
+}
+
+// TransactionManager runs several repository calls atomically
+type TransactionManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos *Repositories) error) error
+}
+
+// transactionManager implements TransactionManager interface
+type transactionManager struct {
+	db *gorm.DB
+}
+
+// NewTransactionManager creates a new transaction manager
+func NewTransactionManager(db *gorm.DB) TransactionManager {
+	return &transactionManager{db: db}
+}
+
+// txKey is the context key of the transaction a unit of work runs in
+type txKey struct{}
+
+// WithinTransaction calls fn with repositories bound to one transaction,
+// committing if fn returns nil and rolling back otherwise.
+//
+// The transaction is also carried by the context passed to fn, so any
+// repository called with that context joins it. A nested call runs in a
+// savepoint of the outer transaction, which lets services that open their
+// own unit of work be composed into a larger one.
+func (m *transactionManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos *Repositories) error) error {
+	return conn(ctx, m.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx), &Repositories{
+			Patients:    NewPatientRepository(tx),
+			Outbox:      NewOutboxRepository(tx),
+			Users:       NewUserRepository(tx),
+			Audit:       NewAuditRepository(tx),
+			Identifiers: NewPatientIdentifierRepository(tx),
+			Duplicates:  NewDuplicateRepository(tx),
+			Merges:      NewMergeRepository(tx),
+		})
+	})
+}
+
+// conn returns the transaction carried by ctx, or db if there is none
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// forUpdate locks the selected rows until the end of the transaction
+func forUpdate(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
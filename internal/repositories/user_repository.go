@@ -1,21 +1,23 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 
 	"gorm.io/gorm"
 
+	"hospital-project/internal/apperror"
 	"hospital-project/internal/models"
 )
 
 // UserRepository interface defines methods for user repository
 type UserRepository interface {
-	Create(user *models.User) error
-	FindByID(id uint) (*models.User, error)
-	FindByUsername(username string) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
-	List() ([]models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context) ([]models.User, error)
 }
 
 // userRepository implements UserRepository interface
@@ -28,18 +30,23 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-// Create creates a new user
-func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+// Create creates a new user. A concurrent insert of the same username is
+// reported as a conflict by the unique index.
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	err := conn(ctx, r.db).Create(user).Error
+	if isUniqueViolation(err) {
+		return apperror.Conflict(apperror.CodeUsernameTaken, "username already exists").WithCause(err)
+	}
+	return err
 }
 
 // FindByID finds a user by ID
-func (r *userRepository) FindByID(id uint) (*models.User, error) {
+func (r *userRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	result := r.db.First(&user, id)
+	result := conn(ctx, r.db).First(&user, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, apperror.NotFound(apperror.CodeUserNotFound, "user not found").WithCause(result.Error)
 		}
 		return nil, result.Error
 	}
@@ -47,12 +54,12 @@ func (r *userRepository) FindByID(id uint) (*models.User, error) {
 }
 
 // FindByUsername finds a user by username
-func (r *userRepository) FindByUsername(username string) (*models.User, error) {
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	result := r.db.Where("username = ?", username).First(&user)
+	result := conn(ctx, r.db).Where("username = ?", username).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, apperror.NotFound(apperror.CodeUserNotFound, "user not found").WithCause(result.Error)
 		}
 		return nil, result.Error
 	}
@@ -60,18 +67,18 @@ func (r *userRepository) FindByUsername(username string) (*models.User, error) {
 }
 
 // Update updates a user
-func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return conn(ctx, r.db).Save(user).Error
 }
 
 // Delete deletes a user
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	return conn(ctx, r.db).Delete(&models.User{}, id).Error
 }
 
 // List returns all users
-func (r *userRepository) List() ([]models.User, error) {
+func (r *userRepository) List(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	result := r.db.Find(&users)
+	result := conn(ctx, r.db).Find(&users)
 	return users, result.Error
 }
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+)
+
+// WebhookRepository interface defines methods for webhook repository
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	FindSubscriptionByID(ctx context.Context, id uint) (*models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id uint) error
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	ListActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	DeliveryExists(ctx context.Context, subscriptionID uint, eventID string) (bool, error)
+	FindDeliveryByID(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, subscriptionID uint, page, limit int) ([]models.WebhookDelivery, int64, error)
+	ClaimDueDeliveries(ctx context.Context, now, claimedUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+}
+
+// webhookRepository implements WebhookRepository interface
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// CreateSubscription creates a new subscription
+func (r *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return conn(ctx, r.db).Create(subscription).Error
+}
+
+// FindSubscriptionByID finds a subscription by ID
+func (r *webhookRepository) FindSubscriptionByID(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := conn(ctx, r.db).First(&subscription, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound(apperror.CodeWebhookNotFound, "webhook not found").WithCause(err)
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// UpdateSubscription updates a subscription
+func (r *webhookRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return conn(ctx, r.db).Save(subscription).Error
+}
+
+// DeleteSubscription deletes a subscription
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id uint) error {
+	result := conn(ctx, r.db).Delete(&models.WebhookSubscription{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperror.NotFound(apperror.CodeWebhookNotFound, "webhook not found")
+	}
+	return nil
+}
+
+// ListSubscriptions returns all subscriptions
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := conn(ctx, r.db).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ListActiveSubscriptions returns the subscriptions that receive events
+func (r *webhookRepository) ListActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := conn(ctx, r.db).Where("active = ?", true).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// CreateDelivery creates a new delivery
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return conn(ctx, r.db).Create(delivery).Error
+}
+
+// DeliveryExists checks if an event has already been queued for a subscription
+func (r *webhookRepository) DeliveryExists(ctx context.Context, subscriptionID uint, eventID string) (bool, error) {
+	var count int64
+	err := conn(ctx, r.db).Model(&models.WebhookDelivery{}).
+		Where("subscription_id = ? AND event_id = ?", subscriptionID, eventID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindDeliveryByID finds a delivery by ID
+func (r *webhookRepository) FindDeliveryByID(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := conn(ctx, r.db).First(&delivery, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound(apperror.CodeWebhookDeliveryNotFound, "webhook delivery not found").WithCause(err)
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// UpdateDelivery updates a delivery
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return conn(ctx, r.db).Save(delivery).Error
+}
+
+// ListDeliveries returns the deliveries of a subscription with pagination, newest first
+func (r *webhookRepository) ListDeliveries(ctx context.Context, subscriptionID uint, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	query := conn(ctx, r.db).Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// ClaimDueDeliveries claims pending deliveries whose next attempt is due,
+// oldest first, by moving their next attempt to claimedUntil. Deliveries
+// being claimed by another instance are skipped, and a delivery that is not
+// updated before claimedUntil is due again then.
+func (r *webhookRepository) ClaimDueDeliveries(ctx context.Context, now, claimedUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	due := conn(ctx, r.db).Model(&models.WebhookDelivery{}).
+		Select("id").
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Order("next_attempt_at, id").
+		Limit(limit)
+
+	var deliveries []models.WebhookDelivery
+	err := conn(ctx, r.db).Model(&deliveries).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Update("next_attempt_at", claimedUntil).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING lists the rows in no particular order
+	slices.SortFunc(deliveries, func(a, b models.WebhookDelivery) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return deliveries, nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+// AuditService interface defines methods for audit service
+type AuditService interface {
+	Record(ctx context.Context, userID uint, action models.AuditAction, resource string, resourceID uint, details string) error
+	History(ctx context.Context, resource string, resourceID uint) ([]models.AuditLog, error)
+}
+
+// auditService implements AuditService interface
+type auditService struct {
+	auditRepo repositories.AuditRepository
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(auditRepo repositories.AuditRepository) AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// Record writes an entry to the audit trail
+func (s *auditService) Record(ctx context.Context, userID uint, action models.AuditAction, resource string, resourceID uint, details string) error {
+	ctx, span := tracer.Start(ctx, "AuditService.Record")
+	defer span.End()
+
+	if userID == 0 {
+		return errors.New("audit entry requires a user")
+	}
+	if action == "" || resource == "" {
+		return errors.New("audit entry requires an action and a resource")
+	}
+
+	return s.auditRepo.Create(ctx, &models.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Details:    details,
+	})
+}
+
+// History returns the audit trail of a resource
+func (s *auditService) History(ctx context.Context, resource string, resourceID uint) ([]models.AuditLog, error) {
+	ctx, span := tracer.Start(ctx, "AuditService.History")
+	defer span.End()
+
+	return s.auditRepo.ListByResource(ctx, resource, resourceID)
+}
@@ -1,7 +1,7 @@
 package services
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -9,18 +9,19 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 
+	"hospital-project/internal/apperror"
 	"hospital-project/internal/models"
 	"hospital-project/internal/repositories"
 )
 
 // AuthService interface defines methods for authentication service
 type AuthService interface {
-	Login(request models.LoginRequest) (*models.LoginResponse, error)
+	Login(ctx context.Context, request models.LoginRequest) (*models.LoginResponse, error)
 	HashPassword(password string) (string, error)
 	VerifyPassword(hashedPassword, password string) error
 	GenerateToken(user *models.User) (string, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
-	GetUserFromToken(token *jwt.Token) (*models.User, error)
+	GetUserFromToken(ctx context.Context, token *jwt.Token) (*models.User, error)
 }
 
 // authService implements AuthService interface
@@ -44,17 +45,23 @@ func NewAuthService(userRepo repositories.UserRepository) AuthService {
 }
 
 // Login authenticates a user and returns a JWT token
-func (s *authService) Login(request models.LoginRequest) (*models.LoginResponse, error) {
+func (s *authService) Login(ctx context.Context, request models.LoginRequest) (*models.LoginResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
 	// Find user by username
-	user, err := s.userRepo.FindByUsername(request.Username)
+	user, err := s.userRepo.FindByUsername(ctx, request.Username)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		if apperror.IsNotFound(err) {
+			return nil, errInvalidCredentials()
+		}
+		return nil, err
 	}
 
 	// Verify password
 	err = s.VerifyPassword(user.PasswordHash, request.Password)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, errInvalidCredentials()
 	}
 
 	// Generate JWT token
@@ -135,25 +142,33 @@ func (s *authService) ValidateToken(tokenString string) (*jwt.Token, error) {
 
 	// Validate token
 	if !token.Valid {
-		return nil, errors.New("invalid token")
+		return nil, apperror.Unauthorized(apperror.CodeInvalidToken, "invalid token")
 	}
 
 	return token, nil
 }
 
 // GetUserFromToken extracts user information from a JWT token
-func (s *authService) GetUserFromToken(token *jwt.Token) (*models.User, error) {
+func (s *authService) GetUserFromToken(ctx context.Context, token *jwt.Token) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.GetUserFromToken")
+	defer span.End()
+
 	// Extract claims
 	claims, ok := token.Claims.(*Claims)
 	if !ok {
-		return nil, errors.New("invalid token claims")
+		return nil, apperror.Unauthorized(apperror.CodeInvalidToken, "invalid token claims")
 	}
 
 	// Find user by ID
-	user, err := s.userRepo.FindByID(claims.UserID)
+	user, err := s.userRepo.FindByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
+
+// errInvalidCredentials does not say whether the username or the password was wrong
+func errInvalidCredentials() *apperror.Error {
+	return apperror.Unauthorized(apperror.CodeInvalidCredentials, "invalid credentials")
+}
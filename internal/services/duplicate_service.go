@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/repositories"
+)
+
+// DuplicateService interface defines methods for reviewing suspected
+// duplicate patients
+type DuplicateService interface {
+	List(ctx context.Context, status models.DuplicateStatus, request pagination.Request) (*pagination.Page[models.PatientDuplicate], error)
+	Review(ctx context.Context, id uint, status models.DuplicateStatus, reviewerID uint) (*models.PatientDuplicate, error)
+}
+
+// duplicateService implements DuplicateService interface
+type duplicateService struct {
+	duplicateRepo repositories.DuplicateRepository
+}
+
+// NewDuplicateService creates a new duplicate service
+func NewDuplicateService(duplicateRepo repositories.DuplicateRepository) DuplicateService {
+	return &duplicateService{duplicateRepo: duplicateRepo}
+}
+
+// List returns a page of the suspected duplicates in a review status, most
+// probable first. Pending duplicates are listed if no status is given.
+func (s *duplicateService) List(ctx context.Context, status models.DuplicateStatus, request pagination.Request) (*pagination.Page[models.PatientDuplicate], error) {
+	ctx, span := tracer.Start(ctx, "DuplicateService.List")
+	defer span.End()
+
+	if status == "" {
+		status = models.DuplicateStatusPending
+	}
+	return s.duplicateRepo.List(ctx, status, request)
+}
+
+// Review confirms or dismisses a pending suspected duplicate
+func (s *duplicateService) Review(ctx context.Context, id uint, status models.DuplicateStatus, reviewerID uint) (*models.PatientDuplicate, error) {
+	ctx, span := tracer.Start(ctx, "DuplicateService.Review")
+	defer span.End()
+
+	if id == 0 {
+		return nil, apperror.Validation(apperror.CodeInvalidID, "invalid duplicate ID")
+	}
+	if status != models.DuplicateStatusConfirmed && status != models.DuplicateStatusDismissed {
+		return nil, apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+			Field:   "status",
+			Code:    "oneof",
+			Message: "must be one of: confirmed dismissed",
+		})
+	}
+	return s.duplicateRepo.Review(ctx, id, status, reviewerID, time.Now())
+}
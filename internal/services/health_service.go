@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/repositories"
+)
+
+// HealthService interface defines methods for readiness checks
+type HealthService interface {
+	Ready(ctx context.Context) error
+	Drain()
+}
+
+// healthService implements HealthService interface
+type healthService struct {
+	healthRepo repositories.HealthRepository
+	models     []interface{}
+	draining   atomic.Bool
+	// migrated is set once every table has been found, which stays true
+	// while the server runs
+	migrated atomic.Bool
+}
+
+// NewHealthService creates a new health service. The server is ready once
+// the database answers and the tables of the given models exist.
+func NewHealthService(healthRepo repositories.HealthRepository, models []interface{}) HealthService {
+	return &healthService{
+		healthRepo: healthRepo,
+		models:     models,
+	}
+}
+
+// Ready returns nil if the server can handle requests
+func (s *healthService) Ready(ctx context.Context) error {
+	if s.draining.Load() {
+		return notReady("server is shutting down")
+	}
+
+	// The causes are logged rather than wrapped, so that a ping cut short by
+	// the probe's deadline is still reported as not ready instead of a timeout
+	if err := s.healthRepo.Ping(ctx); err != nil {
+		log.Printf("health: database ping failed: %v", err)
+		return notReady("database is unreachable")
+	}
+
+	if s.migrated.Load() {
+		return nil
+	}
+	missing, err := s.healthRepo.MissingTables(ctx, s.models)
+	if err != nil {
+		log.Printf("health: failed to check database schema: %v", err)
+		return notReady("database schema could not be checked")
+	}
+	if len(missing) > 0 {
+		return notReady("database migrations have not been applied: missing " + strings.Join(missing, ", "))
+	}
+
+	s.migrated.Store(true)
+	return nil
+}
+
+// Drain marks the server as not ready, so load balancers stop routing new
+// requests to it while in-flight ones finish
+func (s *healthService) Drain() {
+	s.draining.Store(true)
+}
+
+func notReady(message string) *apperror.Error {
+	return apperror.New(apperror.KindUnavailable, apperror.CodeNotReady, message)
+}
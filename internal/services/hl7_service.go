@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/hl7"
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+// HL7Service interface defines methods for HL7 ADT ingestion
+type HL7Service interface {
+	HandleMessage(ctx context.Context, raw []byte) []byte
+	Replay(ctx context.Context, id uint) (*models.HL7Message, error)
+	ListMessages(ctx context.Context, page, limit int) ([]models.HL7Message, int64, error)
+}
+
+// hl7Service implements HL7Service interface
+type hl7Service struct {
+	patientService PatientService
+	mergeService   MergeService
+	messageRepo    repositories.HL7MessageRepository
+	identifierRepo repositories.PatientIdentifierRepository
+	transactions   repositories.TransactionManager
+	systemUserID   uint
+}
+
+// NewHL7Service creates a new HL7 service. Patients registered over HL7 are
+// recorded as created by the given system user.
+func NewHL7Service(patientService PatientService, mergeService MergeService, messageRepo repositories.HL7MessageRepository, identifierRepo repositories.PatientIdentifierRepository, transactions repositories.TransactionManager, systemUserID uint) HL7Service {
+	return &hl7Service{
+		patientService: patientService,
+		mergeService:   mergeService,
+		messageRepo:    messageRepo,
+		identifierRepo: identifierRepo,
+		transactions:   transactions,
+		systemUserID:   systemUserID,
+	}
+}
+
+// rejection is an error that is acknowledged with AR rather than AE, because
+// resending the same message can never succeed
+type rejection struct {
+	err error
+}
+
+func (r rejection) Error() string {
+	return r.err.Error()
+}
+
+// HandleMessage stores, processes and acknowledges a raw ADT message
+func (s *hl7Service) HandleMessage(ctx context.Context, raw []byte) []byte {
+	ctx, span := tracer.Start(ctx, "HL7Service.HandleMessage")
+	defer span.End()
+
+	now := time.Now()
+
+	msg, err := hl7.Parse(string(raw))
+	if err != nil {
+		return []byte(hl7.BuildReject(err.Error(), now))
+	}
+
+	// Persist the raw message before acting on it so it can be replayed
+	code, event := msg.Type()
+	record := &models.HL7Message{
+		ControlID:   msg.ControlID(),
+		MessageType: strings.Trim(code+"^"+event, "^"),
+		Raw:         string(raw),
+		Status:      models.HL7MessageReceived,
+	}
+	if err := s.messageRepo.Create(ctx, record); err != nil {
+		log.Printf("hl7: failed to store message %s: %v", record.ControlID, err)
+		return []byte(hl7.BuildACK(msg, hl7.AckError, "message could not be stored", now))
+	}
+
+	ackCode, text := s.process(ctx, record, msg)
+	return []byte(hl7.BuildACK(msg, ackCode, text, now))
+}
+
+// Replay processes a stored message again
+func (s *hl7Service) Replay(ctx context.Context, id uint) (*models.HL7Message, error) {
+	ctx, span := tracer.Start(ctx, "HL7Service.Replay")
+	defer span.End()
+
+	if id == 0 {
+		return nil, apperror.Validation(apperror.CodeInvalidID, "invalid message ID")
+	}
+
+	record, err := s.messageRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := hl7.Parse(record.Raw)
+	if err != nil {
+		return nil, apperror.Validation(apperror.CodeHL7MessageInvalid, "stored message cannot be parsed: "+err.Error()).WithCause(err)
+	}
+
+	s.process(ctx, record, msg)
+	return record, nil
+}
+
+// ListMessages returns stored messages with pagination
+func (s *hl7Service) ListMessages(ctx context.Context, page, limit int) ([]models.HL7Message, int64, error) {
+	ctx, span := tracer.Start(ctx, "HL7Service.ListMessages")
+	defer span.End()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	return s.messageRepo.List(ctx, page, limit)
+}
+
+// process applies a message and records the outcome on the stored record.
+// A message is applied in one transaction, so a merge or registration that
+// fails halfway leaves no partial changes behind.
+func (s *hl7Service) process(ctx context.Context, record *models.HL7Message, msg *hl7.Message) (string, string) {
+	var patientID uint
+	err := s.transactions.WithinTransaction(ctx, func(ctx context.Context, _ *repositories.Repositories) error {
+		var err error
+		patientID, err = s.apply(ctx, record, msg)
+		return err
+	})
+
+	ackCode := hl7.AckAccept
+	record.Status = models.HL7MessageProcessed
+	record.Error = ""
+	record.PatientID = patientID
+	if err != nil {
+		var rejected rejection
+		if errors.As(err, &rejected) {
+			ackCode = hl7.AckReject
+		} else {
+			ackCode = hl7.AckError
+		}
+		record.Status = models.HL7MessageFailed
+		record.Error = err.Error()
+	}
+	record.AckCode = ackCode
+
+	if updateErr := s.messageRepo.Update(ctx, record); updateErr != nil {
+		log.Printf("hl7: failed to record outcome of message %d: %v", record.ID, updateErr)
+	}
+
+	return ackCode, record.Error
+}
+
+// apply dispatches an ADT event and returns the ID of the affected patient
+func (s *hl7Service) apply(ctx context.Context, record *models.HL7Message, msg *hl7.Message) (uint, error) {
+	code, event := msg.Type()
+	if code != "ADT" || (event != hl7.EventRegister && event != hl7.EventUpdate && event != hl7.EventMerge) {
+		return 0, rejection{fmt.Errorf("unsupported message type %s^%s", code, event)}
+	}
+
+	adt, err := hl7.ParseADT(msg)
+	if err != nil {
+		return 0, rejection{err}
+	}
+
+	record.PatientClass = adt.Visit.PatientClass
+	record.Location = adt.Visit.Location
+	record.AttendingDoctor = adt.Visit.AttendingDoctor
+	record.VisitNumber = adt.Visit.VisitNumber
+
+	if adt.Event == hl7.EventMerge {
+		return s.merge(ctx, adt)
+	}
+	return s.upsert(ctx, adt)
+}
+
+// upsert registers or updates the patient identified by PID-3. A04 and A08
+// are both idempotent so that resent messages do not create duplicates.
+func (s *hl7Service) upsert(ctx context.Context, adt *hl7.ADT) (uint, error) {
+	now := time.Now()
+
+	identifier, patient, err := s.findPatient(ctx, adt.Identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	if patient != nil {
+		applyDemographics(patient, adt, now)
+		if err := s.patientService.Update(ctx, patient); err != nil {
+			return patient.ID, err
+		}
+		return patient.ID, nil
+	}
+
+	if adt.Gender == "" {
+		return 0, rejection{errors.New("PID-8 administrative sex is required to register a patient")}
+	}
+	if adt.BirthDate.IsZero() {
+		return 0, rejection{errors.New("PID-7 birth date is required to register a patient")}
+	}
+	if adt.ContactInfo == "" {
+		return 0, rejection{errors.New("PID-13 phone number is required to register a patient")}
+	}
+
+	// The sending system has already registered the patient, so a probable
+	// duplicate is queued for review rather than refused
+	patient = &models.Patient{CreatedBy: s.systemUserID}
+	applyDemographics(patient, adt, now)
+	if _, err := s.patientService.Create(ctx, patient, true); err != nil {
+		return 0, err
+	}
+
+	// An identifier of a patient deleted through the API now names the new one
+	if err := s.link(ctx, identifier, adt.Identifier, patient.ID); err != nil {
+		return 0, fmt.Errorf("identifier could not be stored: %w", err)
+	}
+
+	return patient.ID, nil
+}
+
+// merge folds the patient identified by MRG-1 into the one identified by
+// PID-3, the same way a merge requested over the API does
+func (s *hl7Service) merge(ctx context.Context, adt *hl7.ADT) (uint, error) {
+	_, prior, err := s.findPatient(ctx, adt.PriorIdentifier)
+	if err != nil {
+		return 0, err
+	}
+	if prior == nil {
+		return 0, fmt.Errorf("prior patient %s^%s is not known", adt.PriorIdentifier.Value, adt.PriorIdentifier.System)
+	}
+
+	identifier, survivor, err := s.findPatient(ctx, adt.Identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	// The surviving identifier is new, or its patient was deleted: it simply
+	// becomes an alias of the prior record
+	if survivor == nil {
+		return prior.ID, s.link(ctx, identifier, adt.Identifier, prior.ID)
+	}
+
+	if survivor.ID == prior.ID {
+		return survivor.ID, nil
+	}
+
+	if _, err := s.mergeService.Merge(ctx, survivor.ID, prior.ID, s.systemUserID); err != nil {
+		return survivor.ID, err
+	}
+
+	return survivor.ID, nil
+}
+
+// findPatient resolves an external identifier. The identifier is nil if it
+// is unknown, and the patient is nil if the identifier is unknown or its
+// patient has been deleted.
+func (s *hl7Service) findPatient(ctx context.Context, id hl7.Identifier) (*models.PatientIdentifier, *models.Patient, error) {
+	identifier, err := s.identifierRepo.FindByValue(ctx, id.System, id.Value)
+	if err != nil {
+		if apperror.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	patient, err := s.patientService.GetByID(ctx, identifier.PatientID)
+	if err != nil {
+		if apperror.IsNotFound(err) {
+			return identifier, nil, nil
+		}
+		return nil, nil, err
+	}
+	return identifier, patient, nil
+}
+
+// link points an external identifier at a patient, relinking the identifier
+// found for it if there is one
+func (s *hl7Service) link(ctx context.Context, identifier *models.PatientIdentifier, id hl7.Identifier, patientID uint) error {
+	if identifier != nil {
+		return s.identifierRepo.Relink(ctx, identifier.ID, patientID)
+	}
+	return s.identifierRepo.Create(ctx, &models.PatientIdentifier{
+		System:    id.System,
+		Value:     id.Value,
+		PatientID: patientID,
+	})
+}
+
+// applyDemographics copies the fields present in the message onto a patient
+func applyDemographics(patient *models.Patient, adt *hl7.ADT, now time.Time) {
+	if adt.Name != "" {
+		patient.Name = adt.Name
+	}
+	if adt.Gender != "" {
+		patient.Gender = adt.Gender
+	}
+	if !adt.BirthDate.IsZero() {
+		born := adt.BirthDate
+		patient.BirthDate = &born
+		patient.Age = adt.Age(now)
+	}
+	if adt.ContactInfo != "" {
+		patient.ContactInfo = adt.ContactInfo
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key accepted
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers stored with an idempotency key
+// and sent again when the request is replayed
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyService interface defines methods for idempotency key service
+type IdempotencyService interface {
+	Begin(ctx context.Context, userID uint, key, method, path string, body []byte) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, record *models.IdempotencyKey, status int, header http.Header, body []byte) error
+	Release(ctx context.Context, record *models.IdempotencyKey) error
+	PurgeExpired(ctx context.Context) (int64, error)
+	Run(ctx context.Context)
+}
+
+// idempotencyService implements IdempotencyService interface
+type idempotencyService struct {
+	idempotencyRepo repositories.IdempotencyRepository
+	config          *config.Idempotency
+}
+
+// NewIdempotencyService creates a new idempotency key service
+func NewIdempotencyService(idempotencyRepo repositories.IdempotencyRepository, cfg *config.Idempotency) IdempotencyService {
+	return &idempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		config:          cfg,
+	}
+}
+
+// Begin claims a key for a request. If the key was already used for the
+// same request, the returned record is completed and holds the response to
+// replay; otherwise the caller must Complete or Release it.
+func (s *idempotencyService) Begin(ctx context.Context, userID uint, key, method, path string, body []byte) (*models.IdempotencyKey, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, apperror.Validation(apperror.CodeInvalidIdempotencyKey, "Idempotency-Key must be between 1 and 255 characters",
+			apperror.FieldError{Field: "Idempotency-Key", Code: "max", Message: "must be between 1 and 255 characters"})
+	}
+
+	now := time.Now()
+	record := &models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Method:      method,
+		Path:        path,
+		Fingerprint: fingerprint(method, path, body),
+		ExpiresAt:   now.Add(s.config.TTL),
+		CreatedAt:   now,
+	}
+
+	// A second attempt is only needed if an expired key was in the way
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.idempotencyRepo.Reserve(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return record, nil
+		}
+
+		existing, err := s.idempotencyRepo.FindByKey(ctx, userID, key)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			// Deleted after the conflicting insert; try again
+			continue
+		}
+		if !existing.ExpiresAt.After(now) {
+			if err := s.idempotencyRepo.Delete(ctx, existing.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if existing.Fingerprint != record.Fingerprint {
+			return nil, apperror.New(apperror.KindUnprocessable, apperror.CodeIdempotencyKeyReused,
+				"Idempotency-Key was already used for a different request")
+		}
+		if !existing.Completed() {
+			return nil, apperror.Conflict(apperror.CodeIdempotencyKeyInProgress,
+				"a request with this Idempotency-Key is still being processed")
+		}
+		return existing, nil
+	}
+
+	return nil, apperror.Conflict(apperror.CodeIdempotencyKeyInProgress,
+		"a request with this Idempotency-Key is still being processed")
+}
+
+// Complete stores the response to a request so that retries replay it
+func (s *idempotencyService) Complete(ctx context.Context, record *models.IdempotencyKey, status int, header http.Header, body []byte) error {
+	headers := make(map[string]string)
+	for _, name := range replayedHeaders {
+		if value := header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	completedAt := time.Now()
+	record.StatusCode = status
+	record.ResponseHeaders = string(encoded)
+	record.ResponseBody = body
+	record.CompletedAt = &completedAt
+	return s.idempotencyRepo.Complete(ctx, record)
+}
+
+// Release forgets a key whose request failed without a response worth
+// replaying, so that the client can retry it
+func (s *idempotencyService) Release(ctx context.Context, record *models.IdempotencyKey) error {
+	return s.idempotencyRepo.Delete(ctx, record.ID)
+}
+
+// PurgeExpired deletes expired keys and their stored responses
+func (s *idempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.idempotencyRepo.DeleteExpired(ctx, time.Now())
+}
+
+// Run purges expired keys every cleanup interval until ctx is cancelled
+func (s *idempotencyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.PurgeExpired(ctx); err != nil {
+				log.Printf("idempotency: failed to purge expired keys: %v", err)
+			}
+		}
+	}
+}
+
+// fingerprint identifies a request by its method, path and body
+func fingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
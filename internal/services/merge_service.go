@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
+	"hospital-project/internal/events"
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+// maxMergeChain bounds how many merges Survivor follows, in case of a cycle
+const maxMergeChain = 16
+
+// MergeService interface defines methods for merging duplicate patients
+type MergeService interface {
+	Merge(ctx context.Context, survivorID, mergedID, userID uint) (*models.PatientMerge, error)
+	Unmerge(ctx context.Context, id, userID uint) (*models.PatientMerge, error)
+	GetByID(ctx context.Context, id uint) (*models.PatientMerge, error)
+	Survivor(ctx context.Context, patientID uint) (uint, error)
+}
+
+// mergeService implements MergeService interface
+type mergeService struct {
+	mergeRepo    repositories.MergeRepository
+	transactions repositories.TransactionManager
+	config       *config.Merge
+}
+
+// NewMergeService creates a new merge service
+func NewMergeService(mergeRepo repositories.MergeRepository, transactions repositories.TransactionManager, cfg *config.Merge) MergeService {
+	return &mergeService{
+		mergeRepo:    mergeRepo,
+		transactions: transactions,
+		config:       cfg,
+	}
+}
+
+// Merge folds a duplicate patient into the survivor in one transaction. The
+// duplicate's notes are appended to the survivor's, the fields the survivor
+// lacks are taken from it, its records are moved to the survivor, its
+// pending suspected duplicates are closed and it is deleted. The merge is
+// kept as a tombstone and can be undone until the configured window has
+// passed.
+func (s *mergeService) Merge(ctx context.Context, survivorID, mergedID, userID uint) (*models.PatientMerge, error) {
+	ctx, span := tracer.Start(ctx, "MergeService.Merge")
+	defer span.End()
+
+	if survivorID == 0 {
+		return nil, apperror.Validation(apperror.CodeInvalidID, "invalid patient ID")
+	}
+	if mergedID == survivorID {
+		return nil, apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+			Field:   "duplicate_id",
+			Code:    "nefield",
+			Message: "must not be the surviving patient",
+		})
+	}
+
+	var merge *models.PatientMerge
+	err := s.transactions.WithinTransaction(ctx, func(ctx context.Context, repos *repositories.Repositories) error {
+		survivor, merged, err := lockPair(ctx, repos.Patients, survivorID, mergedID)
+		if err != nil {
+			return err
+		}
+
+		before := survivor.ToDocument()
+		foldInto(survivor, merged)
+		if err := repos.Patients.Update(ctx, survivor); err != nil {
+			return err
+		}
+
+		relinked, err := repos.Merges.Relink(ctx, merged.ID, survivor.ID)
+		if err != nil {
+			return err
+		}
+		if err := repos.Patients.Delete(ctx, merged.ID); err != nil {
+			return err
+		}
+
+		// The pair is confirmed, and the other suspected duplicates of the
+		// merged patient are closed so the queue does not point at it
+		now := time.Now()
+		confirmed, err := repos.Duplicates.ConfirmPair(ctx, survivor.ID, merged.ID, userID, now)
+		if err != nil {
+			return err
+		}
+		closed, err := repos.Duplicates.CloseMerged(ctx, merged.ID, userID, now)
+		if err != nil {
+			return err
+		}
+
+		merge = &models.PatientMerge{
+			SurvivorID:       survivor.ID,
+			MergedID:         merged.ID,
+			MergedBy:         userID,
+			SurvivorBefore:   before,
+			SurvivorVersion:  survivor.Version,
+			Relinked:         relinked,
+			ClosedDuplicates: append(confirmed, closed...),
+			UnmergeBefore:    now.Add(s.config.UnmergeWindow),
+		}
+		if err := repos.Merges.Create(ctx, merge); err != nil {
+			return err
+		}
+
+		if err := audit(ctx, repos.Audit, userID, models.AuditActionPatientMerge, merge); err != nil {
+			return err
+		}
+		if err := record(ctx, repos.Outbox, events.PatientUpdated, survivor); err != nil {
+			return err
+		}
+		deleted := &models.Patient{}
+		deleted.ID = merged.ID
+		return record(ctx, repos.Outbox, events.PatientDeleted, deleted)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// Unmerge undoes a merge within its window: the survivor is restored as it
+// was before the merge, the merged patient comes back, the records moved
+// from it are moved back and the suspected duplicates the merge closed are
+// pending again. A survivor changed since the merge is not restored, since
+// the changes would be lost.
+func (s *mergeService) Unmerge(ctx context.Context, id, userID uint) (*models.PatientMerge, error) {
+	ctx, span := tracer.Start(ctx, "MergeService.Unmerge")
+	defer span.End()
+
+	if id == 0 {
+		return nil, apperror.Validation(apperror.CodeInvalidID, "invalid merge ID")
+	}
+
+	var merge *models.PatientMerge
+	err := s.transactions.WithinTransaction(ctx, func(ctx context.Context, repos *repositories.Repositories) error {
+		var err error
+		merge, err = repos.Merges.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if merge.UnmergedAt != nil {
+			return apperror.Conflict(apperror.CodeMergeUndone, "merge has already been undone")
+		}
+		now := time.Now()
+		if now.After(merge.UnmergeBefore) {
+			return apperror.Conflict(apperror.CodeMergeExpired, "merge can no longer be undone")
+		}
+
+		survivor, err := repos.Patients.FindByIDForUpdate(ctx, merge.SurvivorID)
+		if err != nil {
+			if apperror.IsNotFound(err) {
+				return apperror.Conflict(apperror.CodeMergeSurvivorChanged, "surviving patient has since been deleted or merged")
+			}
+			return err
+		}
+		if survivor.Version != merge.SurvivorVersion {
+			return apperror.Conflict(apperror.CodeMergeSurvivorChanged, "surviving patient has been modified since the merge")
+		}
+
+		merge.SurvivorBefore.ApplyTo(survivor)
+		if err := repos.Patients.Update(ctx, survivor); err != nil {
+			return err
+		}
+		if err := repos.Patients.Restore(ctx, merge.MergedID); err != nil {
+			return err
+		}
+		if err := repos.Merges.Restore(ctx, merge.Relinked, merge.SurvivorID, merge.MergedID); err != nil {
+			return err
+		}
+		if err := repos.Duplicates.Reopen(ctx, merge.ClosedDuplicates); err != nil {
+			return err
+		}
+
+		merge.UnmergedAt = &now
+		merge.UnmergedBy = &userID
+		if err := repos.Merges.Update(ctx, merge); err != nil {
+			return err
+		}
+
+		restored, err := repos.Patients.FindByID(ctx, merge.MergedID)
+		if err != nil {
+			return err
+		}
+		if err := audit(ctx, repos.Audit, userID, models.AuditActionPatientUnmerge, merge); err != nil {
+			return err
+		}
+		if err := record(ctx, repos.Outbox, events.PatientUpdated, survivor); err != nil {
+			return err
+		}
+		return record(ctx, repos.Outbox, events.PatientRegistered, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// GetByID gets a patient merge by ID
+func (s *mergeService) GetByID(ctx context.Context, id uint) (*models.PatientMerge, error) {
+	ctx, span := tracer.Start(ctx, "MergeService.GetByID")
+	defer span.End()
+
+	if id == 0 {
+		return nil, apperror.Validation(apperror.CodeInvalidID, "invalid merge ID")
+	}
+	return s.mergeRepo.FindByID(ctx, id)
+}
+
+// Survivor returns the patient a merged patient now lives on as, following
+// merges of the survivor in turn. A patient that has not been merged is not
+// found.
+func (s *mergeService) Survivor(ctx context.Context, patientID uint) (uint, error) {
+	ctx, span := tracer.Start(ctx, "MergeService.Survivor")
+	defer span.End()
+
+	merge, err := s.mergeRepo.FindActiveByMergedID(ctx, patientID)
+	if err != nil {
+		return 0, err
+	}
+	survivorID := merge.SurvivorID
+	for i := 0; i < maxMergeChain; i++ {
+		merge, err := s.mergeRepo.FindActiveByMergedID(ctx, survivorID)
+		if apperror.IsNotFound(err) {
+			return survivorID, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		survivorID = merge.SurvivorID
+	}
+	return survivorID, nil
+}
+
+// lockPair locks two patients in ID order, so that two merges of the same
+// patients cannot deadlock, and returns them as survivor and merged
+func lockPair(ctx context.Context, patients repositories.PatientRepository, survivorID, mergedID uint) (*models.Patient, *models.Patient, error) {
+	first, second := survivorID, mergedID
+	if first > second {
+		first, second = second, first
+	}
+	locked := make(map[uint]*models.Patient, 2)
+	for _, id := range []uint{first, second} {
+		patient, err := patients.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		locked[id] = patient
+	}
+	return locked[survivorID], locked[mergedID], nil
+}
+
+// foldInto appends the notes of a merged patient to the survivor's and fills
+// in the fields the survivor lacks
+func foldInto(survivor, merged *models.Patient) {
+	if merged.MedicalNotes != "" {
+		if survivor.MedicalNotes != "" {
+			survivor.MedicalNotes += "\n\n"
+		}
+		survivor.MedicalNotes += fmt.Sprintf("[Merged from patient %d]\n%s", merged.ID, merged.MedicalNotes)
+	}
+	if survivor.ContactInfo == "" {
+		survivor.ContactInfo = merged.ContactInfo
+	}
+	if survivor.BirthDate == nil {
+		survivor.BirthDate = merged.BirthDate
+	}
+	if survivor.Address == "" {
+		survivor.Address = merged.Address
+	}
+}
+
+// audit records a merge or unmerge in the audit trail of both patients
+func audit(ctx context.Context, auditRepo repositories.AuditRepository, userID uint, action models.AuditAction, merge *models.PatientMerge) error {
+	details := fmt.Sprintf(`{"merge_id":%d,"survivor_id":%d,"merged_id":%d}`, merge.ID, merge.SurvivorID, merge.MergedID)
+	for _, patientID := range []uint{merge.SurvivorID, merge.MergedID} {
+		err := auditRepo.Create(ctx, &models.AuditLog{
+			UserID:     userID,
+			Action:     action,
+			Resource:   "patients",
+			ResourceID: patientID,
+			Details:    details,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,112 +1,259 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"sort"
+	"strings"
+	"time"
 
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
+	"hospital-project/internal/events"
+	"hospital-project/internal/matching"
 	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
 	"hospital-project/internal/repositories"
 )
 
 // PatientService interface defines methods for patient service
 type PatientService interface {
-	Create(patient *models.Patient) error
-	GetByID(id uint) (*models.Patient, error)
-	Update(patient *models.Patient) error
-	UpdateMedicalNotes(id uint, medicalNotes string) error
-	Delete(id uint) error
-	List(page, limit int) ([]models.Patient, int64, error)
-	Search(params models.PatientSearchRequest) ([]models.Patient, error)
+	Create(ctx context.Context, patient *models.Patient, overrideDuplicates bool) ([]models.DuplicateMatch, error)
+	GetByID(ctx context.Context, id uint) (*models.Patient, error)
+	Update(ctx context.Context, patient *models.Patient) error
+	UpdateMedicalNotes(ctx context.Context, id uint, medicalNotes string, version uint) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context, request pagination.Request) (*pagination.Page[models.Patient], error)
+	Search(ctx context.Context, params models.PatientSearchRequest, sort []pagination.SortKey, request pagination.Request) (*pagination.Page[models.PatientMatch], error)
+	SearchNotes(ctx context.Context, query string, params models.PatientSearchRequest, request pagination.Request) (*pagination.Page[models.PatientMatch], error)
+	Export(ctx context.Context, params models.PatientSearchRequest, fn func(*models.Patient) error) error
+	Facets(ctx context.Context, query string, params models.PatientSearchRequest, facets []string) (map[string][]models.FacetCount, error)
 }
 
 // patientService implements PatientService interface
 type patientService struct {
-	patientRepo repositories.PatientRepository
+	patientRepo  repositories.PatientRepository
+	transactions repositories.TransactionManager
+	duplicates   *config.Duplicates
 }
 
-// NewPatientService creates a new patient service
-func NewPatientService(patientRepo repositories.PatientRepository) PatientService {
+// NewPatientService creates a new patient service. Changes are written
+// together with their domain events through the transaction manager.
+func NewPatientService(patientRepo repositories.PatientRepository, transactions repositories.TransactionManager, duplicates *config.Duplicates) PatientService {
 	return &patientService{
-		patientRepo: patientRepo,
+		patientRepo:  patientRepo,
+		transactions: transactions,
+		duplicates:   duplicates,
 	}
 }
 
-// Create creates a new patient
-func (s *patientService) Create(patient *models.Patient) error {
-	exists, err := s.patientRepo.ExistsByNameOrContact(patient.Name, patient.ContactInfo)
+// record appends a domain event for the patient to the outbox
+func record(ctx context.Context, outbox repositories.OutboxRepository, eventType models.PatientEvent, patient *models.Patient) error {
+	event, err := events.NewOutboxEvent(eventType, patient, time.Now())
 	if err != nil {
 		return err
 	}
-	if exists {
-		return errors.New("patient with this name or contact info already exists")
-	}
-	// Save patient to database
-	return s.patientRepo.Create(patient)
+	return outbox.Append(ctx, event)
 }
 
-// GetByID gets a patient by ID
-func (s *patientService) GetByID(id uint) (*models.Patient, error) {
-	return s.patientRepo.FindByID(id)
+// Create creates a new patient and returns the existing patients it may
+// duplicate, most probable first, which are queued for review with it. If
+// one of them is a probable duplicate, the patient is not created and a
+// conflict is returned along with them, unless overrideDuplicates is set.
+func (s *patientService) Create(ctx context.Context, patient *models.Patient, overrideDuplicates bool) ([]models.DuplicateMatch, error) {
+	ctx, span := tracer.Start(ctx, "PatientService.Create")
+	defer span.End()
+
+	var matches []models.DuplicateMatch
+	err := s.transactions.WithinTransaction(ctx, func(ctx context.Context, repos *repositories.Repositories) error {
+		var err error
+		matches, err = s.findDuplicates(ctx, repos.Patients, patient)
+		if err != nil {
+			return err
+		}
+		if len(matches) > 0 && matches[0].Score >= s.duplicates.BlockThreshold && !overrideDuplicates {
+			return apperror.Conflict(apperror.CodePatientDuplicate, "patient probably duplicates an existing patient; review the candidates or override the check")
+		}
+
+		// Save patient to database
+		if err := repos.Patients.Create(ctx, patient); err != nil {
+			return err
+		}
+
+		suspected := make([]models.PatientDuplicate, len(matches))
+		for i, match := range matches {
+			suspected[i] = models.PatientDuplicate{
+				PatientID:   patient.ID,
+				CandidateID: match.Patient.ID,
+				Score:       match.Score,
+				Features:    match.Features,
+				Status:      models.DuplicateStatusPending,
+			}
+		}
+		if err := repos.Duplicates.Queue(ctx, suspected); err != nil {
+			return err
+		}
+
+		return record(ctx, repos.Outbox, events.PatientRegistered, patient)
+	})
+	return matches, err
 }
 
-// Update updates a patient
-func (s *patientService) Update(patient *models.Patient) error {
-	// Check if patient exists
-	existingPatient, err := s.patientRepo.FindByID(patient.ID)
+// findDuplicates scores the candidates for being the same person as patient
+// and returns those scoring at least the review threshold, most probable
+// first
+func (s *patientService) findDuplicates(ctx context.Context, patients repositories.PatientRepository, patient *models.Patient) ([]models.DuplicateMatch, error) {
+	candidates, err := patients.FindMatchCandidates(ctx, patient, s.duplicates.Candidates)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if existingPatient == nil {
-		return errors.New("patient not found")
+
+	var matches []models.DuplicateMatch
+	for _, candidate := range candidates {
+		result := matching.Score(patient, &candidate)
+		if result.Score >= s.duplicates.ReviewThreshold {
+			matches = append(matches, models.DuplicateMatch{Patient: candidate, Score: result.Score, Features: result.Features})
+		}
 	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches, nil
+}
 
-	// Update patient in database
-	return s.patientRepo.Update(patient)
+// GetByID gets a patient by ID
+func (s *patientService) GetByID(ctx context.Context, id uint) (*models.Patient, error) {
+	ctx, span := tracer.Start(ctx, "PatientService.GetByID")
+	defer span.End()
+
+	return s.patientRepo.FindByID(ctx, id)
+}
+
+// Update updates a patient
+func (s *patientService) Update(ctx context.Context, patient *models.Patient) error {
+	ctx, span := tracer.Start(ctx, "PatientService.Update")
+	defer span.End()
+
+	return s.transactions.WithinTransaction(ctx, func(ctx context.Context, repos *repositories.Repositories) error {
+		// Lock the row so the check and the write see the same patient
+		existingPatient, err := repos.Patients.FindByIDForUpdate(ctx, patient.ID)
+		if err != nil {
+			return err
+		}
+
+		// The age follows a changed birth date, as it does for HL7 updates
+		if patient.BirthDate != nil && models.FormatDate(patient.BirthDate) != models.FormatDate(existingPatient.BirthDate) {
+			patient.Age = models.AgeAt(*patient.BirthDate, time.Now())
+		}
+
+		// Update patient in database
+		if err := repos.Patients.Update(ctx, patient); err != nil {
+			return err
+		}
+
+		return record(ctx, repos.Outbox, events.PatientUpdated, patient)
+	})
 }
 
-// UpdateMedicalNotes updates only the medical notes of a patient
-func (s *patientService) UpdateMedicalNotes(id uint, medicalNotes string) error {
+// UpdateMedicalNotes updates only the medical notes of a patient, provided
+// it is still at the given version
+func (s *patientService) UpdateMedicalNotes(ctx context.Context, id uint, medicalNotes string, version uint) error {
+	ctx, span := tracer.Start(ctx, "PatientService.UpdateMedicalNotes")
+	defer span.End()
+
 	if id == 0 {
-		return errors.New("invalid patient ID")
+		return apperror.Validation(apperror.CodeInvalidID, "invalid patient ID")
 	}
 
-	// Check if patient exists
-	existingPatient, err := s.patientRepo.FindByID(id)
-	if err != nil {
-		return err
-	}
-	if existingPatient == nil {
-		return errors.New("patient not found")
-	}
+	return s.transactions.WithinTransaction(ctx, func(ctx context.Context, repos *repositories.Repositories) error {
+		// Lock the row so the event carries the notes as written
+		existingPatient, err := repos.Patients.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Update medical notes in database
+		if err := repos.Patients.UpdateMedicalNotes(ctx, id, medicalNotes, version); err != nil {
+			return err
+		}
 
-	// Update medical notes in database
-	return s.patientRepo.UpdateMedicalNotes(id, medicalNotes)
+		existingPatient.MedicalNotes = medicalNotes
+		existingPatient.Version = version + 1
+		return record(ctx, repos.Outbox, events.MedicalNotesUpdated, existingPatient)
+	})
 }
 
 // Delete deletes a patient
-func (s *patientService) Delete(id uint) error {
+func (s *patientService) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracer.Start(ctx, "PatientService.Delete")
+	defer span.End()
+
 	if id == 0 {
-		return errors.New("invalid patient ID")
+		return apperror.Validation(apperror.CodeInvalidID, "invalid patient ID")
 	}
-	return s.patientRepo.Delete(id)
+	return s.transactions.WithinTransaction(ctx, func(ctx context.Context, repos *repositories.Repositories) error {
+		if err := repos.Patients.Delete(ctx, id); err != nil {
+			return err
+		}
+
+		deleted := &models.Patient{}
+		deleted.ID = id
+		return record(ctx, repos.Outbox, events.PatientDeleted, deleted)
+	})
 }
 
-// List returns all patients with pagination
-func (s *patientService) List(page, limit int) ([]models.Patient, int64, error) {
-	// Validate pagination parameters
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
-	}
-	if limit > 100 {
-		limit = 100
+// List returns a page of all patients
+func (s *patientService) List(ctx context.Context, request pagination.Request) (*pagination.Page[models.Patient], error) {
+	ctx, span := tracer.Start(ctx, "PatientService.List")
+	defer span.End()
+
+	return s.patientRepo.List(ctx, request)
+}
+
+// Search returns a page of the patients matching the search parameters
+func (s *patientService) Search(ctx context.Context, params models.PatientSearchRequest, sort []pagination.SortKey, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	ctx, span := tracer.Start(ctx, "PatientService.Search")
+	defer span.End()
+
+	return s.patientRepo.Search(ctx, params, sort, request)
+}
+
+// SearchNotes returns a page of the patients matching the search parameters
+// whose medical notes match a full-text query, most relevant first. Callers
+// must check that the user may read medical notes.
+func (s *patientService) SearchNotes(ctx context.Context, query string, params models.PatientSearchRequest, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	ctx, span := tracer.Start(ctx, "PatientService.SearchNotes")
+	defer span.End()
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+			Field:   "q",
+			Code:    "required",
+			Message: "is required",
+		})
 	}
+	return s.patientRepo.SearchNotes(ctx, query, params, request)
+}
+
+// Export streams every patient matching the search parameters to fn
+func (s *patientService) Export(ctx context.Context, params models.PatientSearchRequest, fn func(*models.Patient) error) error {
+	ctx, span := tracer.Start(ctx, "PatientService.Export")
+	defer span.End()
 
-	return s.patientRepo.List(page, limit)
+	if fn == nil {
+		return errors.New("export requires a row handler")
+	}
+	return s.patientRepo.Export(ctx, params, fn)
 }
 
-// Search searches for patients based on search parameters
-func (s *patientService) Search(params models.PatientSearchRequest) ([]models.Patient, error) {
-	return s.patientRepo.Search(params)
+// Facets counts the patients matching the search parameters, and the
+// full-text query over medical notes if one is given, by each value of the
+// given facets. Callers must check that the user may read medical notes
+// before passing a query.
+func (s *patientService) Facets(ctx context.Context, query string, params models.PatientSearchRequest, facets []string) (map[string][]models.FacetCount, error) {
+	ctx, span := tracer.Start(ctx, "PatientService.Facets")
+	defer span.End()
+
+	return s.patientRepo.Facets(ctx, strings.TrimSpace(query), params, facets)
 }
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/repositories"
+)
+
+// SavedSearchService interface defines methods for saved patient searches
+// and the worklists they make up
+type SavedSearchService interface {
+	Create(ctx context.Context, request models.SavedSearchRequest, userID uint) (*models.SavedSearch, error)
+	GetByID(ctx context.Context, id, userID uint, role models.Role) (*models.SavedSearch, error)
+	List(ctx context.Context, userID uint, role models.Role, pinnedOnly bool, request pagination.Request) (*pagination.Page[models.SavedSearch], error)
+	Update(ctx context.Context, id uint, request models.SavedSearchRequest, userID uint, role models.Role) (*models.SavedSearch, error)
+	Delete(ctx context.Context, id, userID uint, role models.Role) error
+	Run(ctx context.Context, id, userID uint, role models.Role, request pagination.Request) (*pagination.Page[models.PatientMatch], error)
+	SetPinned(ctx context.Context, id, userID uint, role models.Role, pinned bool) (*models.SavedSearch, error)
+}
+
+// savedSearchService implements SavedSearchService interface
+type savedSearchService struct {
+	savedSearchRepo repositories.SavedSearchRepository
+	patientService  PatientService
+}
+
+// NewSavedSearchService creates a new saved search service
+func NewSavedSearchService(savedSearchRepo repositories.SavedSearchRepository, patientService PatientService) SavedSearchService {
+	return &savedSearchService{
+		savedSearchRepo: savedSearchRepo,
+		patientService:  patientService,
+	}
+}
+
+// Create saves a search for a user
+func (s *savedSearchService) Create(ctx context.Context, request models.SavedSearchRequest, userID uint) (*models.SavedSearch, error) {
+	ctx, span := tracer.Start(ctx, "SavedSearchService.Create")
+	defer span.End()
+
+	search := &models.SavedSearch{OwnerID: userID}
+	if err := applySavedSearch(search, request); err != nil {
+		return nil, err
+	}
+	if err := s.savedSearchRepo.Create(ctx, search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// GetByID gets a saved search the user owns or that is shared with their role
+func (s *savedSearchService) GetByID(ctx context.Context, id, userID uint, role models.Role) (*models.SavedSearch, error) {
+	ctx, span := tracer.Start(ctx, "SavedSearchService.GetByID")
+	defer span.End()
+
+	return s.findVisible(ctx, id, userID, role)
+}
+
+// List returns a page of the searches the user owns or that are shared with
+// their role, by name
+func (s *savedSearchService) List(ctx context.Context, userID uint, role models.Role, pinnedOnly bool, request pagination.Request) (*pagination.Page[models.SavedSearch], error) {
+	ctx, span := tracer.Start(ctx, "SavedSearchService.List")
+	defer span.End()
+
+	return s.savedSearchRepo.ListVisible(ctx, userID, role, pinnedOnly, request)
+}
+
+// Update replaces a saved search. Only its owner may change it.
+func (s *savedSearchService) Update(ctx context.Context, id uint, request models.SavedSearchRequest, userID uint, role models.Role) (*models.SavedSearch, error) {
+	ctx, span := tracer.Start(ctx, "SavedSearchService.Update")
+	defer span.End()
+
+	search, err := s.findOwned(ctx, id, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySavedSearch(search, request); err != nil {
+		return nil, err
+	}
+	if err := s.savedSearchRepo.Update(ctx, search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// Delete deletes a saved search. Only its owner may delete it.
+func (s *savedSearchService) Delete(ctx context.Context, id, userID uint, role models.Role) error {
+	ctx, span := tracer.Start(ctx, "SavedSearchService.Delete")
+	defer span.End()
+
+	if _, err := s.findOwned(ctx, id, userID, role); err != nil {
+		return err
+	}
+	return s.savedSearchRepo.Delete(ctx, id)
+}
+
+// Run returns a page of the patients a saved search finds, always with the
+// total number of them
+func (s *savedSearchService) Run(ctx context.Context, id, userID uint, role models.Role, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	ctx, span := tracer.Start(ctx, "SavedSearchService.Run")
+	defer span.End()
+
+	search, err := s.findVisible(ctx, id, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	sort, err := pagination.ParseSort(search.Sort, models.PatientSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	request.IncludeTotal = true
+	return s.patientService.Search(ctx, search.Search, sort, request)
+}
+
+// SetPinned pins a saved search as one of the user's worklists or removes the
+// pin. Any user who may see a search may pin it for themselves.
+func (s *savedSearchService) SetPinned(ctx context.Context, id, userID uint, role models.Role, pinned bool) (*models.SavedSearch, error) {
+	ctx, span := tracer.Start(ctx, "SavedSearchService.SetPinned")
+	defer span.End()
+
+	search, err := s.findVisible(ctx, id, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.savedSearchRepo.SetPinned(ctx, id, userID, pinned); err != nil {
+		return nil, err
+	}
+	search.Pinned = pinned
+	return search, nil
+}
+
+// findVisible finds a saved search the user may see. Searches of other users
+// that are not shared with the user's role are reported as not found.
+func (s *savedSearchService) findVisible(ctx context.Context, id, userID uint, role models.Role) (*models.SavedSearch, error) {
+	if id == 0 {
+		return nil, apperror.Validation(apperror.CodeInvalidID, "invalid saved search ID")
+	}
+	search, err := s.savedSearchRepo.FindByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !search.VisibleTo(userID, role) {
+		return nil, apperror.NotFound(apperror.CodeSavedSearchNotFound, "saved search not found")
+	}
+	return search, nil
+}
+
+// findOwned finds a saved search the user owns
+func (s *savedSearchService) findOwned(ctx context.Context, id, userID uint, role models.Role) (*models.SavedSearch, error) {
+	search, err := s.findVisible(ctx, id, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if search.OwnerID != userID {
+		return nil, apperror.Forbidden(apperror.CodeForbidden, "Only the owner may change a saved search")
+	}
+	return search, nil
+}
+
+// applySavedSearch validates a saved search request and copies it to search
+func applySavedSearch(search *models.SavedSearch, request models.SavedSearchRequest) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return apperror.Validation(apperror.CodeValidationFailed, "The request has invalid fields", apperror.FieldError{
+			Field:   "name",
+			Code:    "required",
+			Message: "is required",
+		})
+	}
+	if _, err := request.Search.ParseFilter(); err != nil {
+		return err
+	}
+	if _, err := pagination.ParseSort(request.Sort, models.PatientSortFields); err != nil {
+		return err
+	}
+
+	search.Name = name
+	search.Search = request.Search
+	search.Sort = request.Sort
+	search.SharedWith = request.SharedWith
+	return nil
+}
@@ -0,0 +1,7 @@
+package services
+
+import "go.opentelemetry.io/otel"
+
+// tracer starts the spans of service methods. It resolves the global
+// tracer provider lazily, so spans are recorded once tracing is set up.
+var tracer = otel.Tracer("hospital-project/internal/services")
@@ -1,20 +1,20 @@
 package services
 
 import (
-	"errors"
-
+	"context"
+	"hospital-project/internal/apperror"
 	"hospital-project/internal/models"
 	"hospital-project/internal/repositories"
 )
 
 // UserService interface defines methods for user service
 type UserService interface {
-	Create(username, password string, role models.Role) (*models.User, error)
-	GetByID(id uint) (*models.User, error)
-	GetByUsername(username string) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
-	List() ([]models.User, error)
+	Create(ctx context.Context, username, password string, role models.Role) (*models.User, error)
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context) ([]models.User, error)
 }
 
 // userService implements UserService interface
@@ -31,12 +31,17 @@ func NewUserService(userRepo repositories.UserRepository, authService AuthServic
 	}
 }
 
-// Create creates a new user
-func (s *userService) Create(username, password string, role models.Role) (*models.User, error) {
+// Create creates a new user. A concurrent insert of the same username passes
+// the check but fails on the unique index, and is reported as the same
+// conflict.
+func (s *userService) Create(ctx context.Context, username, password string, role models.Role) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Create")
+	defer span.End()
+
 	// Check if username already exists
-	existingUser, err := s.userRepo.FindByUsername(username)
+	existingUser, err := s.userRepo.FindByUsername(ctx, username)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("username already exists")
+		return nil, apperror.Conflict(apperror.CodeUsernameTaken, "username already exists")
 	}
 
 	// Hash password
@@ -53,7 +58,7 @@ func (s *userService) Create(username, password string, role models.Role) (*mode
 	}
 
 	// Save user to database
-	err = s.userRepo.Create(user)
+	err = s.userRepo.Create(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -62,26 +67,41 @@ func (s *userService) Create(username, password string, role models.Role) (*mode
 }
 
 // GetByID gets a user by ID
-func (s *userService) GetByID(id uint) (*models.User, error) {
-	return s.userRepo.FindByID(id)
+func (s *userService) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetByID")
+	defer span.End()
+
+	return s.userRepo.FindByID(ctx, id)
 }
 
 // GetByUsername gets a user by username
-func (s *userService) GetByUsername(username string) (*models.User, error) {
-	return s.userRepo.FindByUsername(username)
+func (s *userService) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetByUsername")
+	defer span.End()
+
+	return s.userRepo.FindByUsername(ctx, username)
 }
 
 // Update updates a user
-func (s *userService) Update(user *models.User) error {
-	return s.userRepo.Update(user)
+func (s *userService) Update(ctx context.Context, user *models.User) error {
+	ctx, span := tracer.Start(ctx, "UserService.Update")
+	defer span.End()
+
+	return s.userRepo.Update(ctx, user)
 }
 
 // Delete deletes a user
-func (s *userService) Delete(id uint) error {
-	return s.userRepo.Delete(id)
+func (s *userService) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracer.Start(ctx, "UserService.Delete")
+	defer span.End()
+
+	return s.userRepo.Delete(ctx, id)
 }
 
 // List returns all users
-func (s *userService) List() ([]models.User, error) {
-	return s.userRepo.List()
+func (s *userService) List(ctx context.Context) ([]models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.List")
+	defer span.End()
+
+	return s.userRepo.List(ctx)
 }
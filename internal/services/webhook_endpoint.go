@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+
+	"hospital-project/internal/config"
+)
+
+// errBlockedAddress is returned for webhook endpoints at addresses inside
+// the server's own network
+var errBlockedAddress = errors.New("webhook endpoint resolves to a loopback, private, link-local or unspecified address")
+
+// blockedAddress reports whether deliveries must not be sent to an address,
+// so that webhooks cannot reach the server itself, cloud metadata services
+// or other internal hosts
+func blockedAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// allowedHost reports whether a host is one of the configured AllowedHosts,
+// which may resolve to any address
+func allowedHost(cfg *config.Webhook, host string) bool {
+	return slices.ContainsFunc(cfg.AllowedHosts, func(allowed string) bool {
+		return strings.EqualFold(allowed, host)
+	})
+}
+
+// checkEndpoint resolves the host of an endpoint and reports an error if any
+// of its addresses is blocked
+func checkEndpoint(ctx context.Context, cfg *config.Webhook, endpoint *url.URL) error {
+	host := endpoint.Hostname()
+	if allowedHost(cfg, host) {
+		return nil
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		if blockedAddress(address.IP) {
+			return errBlockedAddress
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns the HTTP client deliveries are sent with. Addresses
+// are checked when connecting, after the host is resolved again, so a host
+// that is later pointed at an internal address is refused as well.
+func newWebhookClient(cfg *config.Webhook) *http.Client {
+	direct := &net.Dialer{Timeout: cfg.Timeout}
+	guarded := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedAddress(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Endpoints are dialed directly, as the address of a proxy says nothing
+	// about the endpoint behind it
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil && allowedHost(cfg, host) {
+			return direct.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
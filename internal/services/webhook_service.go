@@ -0,0 +1,430 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
+	"hospital-project/internal/events"
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+// WebhookService interface defines methods for webhook service
+type WebhookService interface {
+	events.Handler
+	CreateSubscription(ctx context.Context, request models.CreateWebhookRequest, userID uint) (*models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, id uint, request models.UpdateWebhookRequest) (*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uint) error
+	ListDeliveries(ctx context.Context, subscriptionID uint, page, limit int) ([]models.WebhookDelivery, int64, error)
+	ReplayDelivery(ctx context.Context, subscriptionID, deliveryID uint) (*models.WebhookDelivery, error)
+	ProcessDue(ctx context.Context) error
+	Run(ctx context.Context)
+}
+
+// webhookService implements WebhookService interface
+type webhookService struct {
+	webhookRepo repositories.WebhookRepository
+	config      *config.Webhook
+	client      *http.Client
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(webhookRepo repositories.WebhookRepository, cfg *config.Webhook) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		config:      cfg,
+		client:      newWebhookClient(cfg),
+	}
+}
+
+// CreateSubscription creates a new subscription, generating a secret if none is given
+func (s *webhookService) CreateSubscription(ctx context.Context, request models.CreateWebhookRequest, userID uint) (*models.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateSubscription")
+	defer span.End()
+
+	if err := validateWebhook(ctx, s.config, request.URL, request.Events); err != nil {
+		return nil, err
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		generated, err := randomHex(32)
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:       request.URL,
+		Secret:    secret,
+		Active:    true,
+		CreatedBy: userID,
+	}
+	subscription.SetEvents(request.Events)
+
+	if err := s.webhookRepo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// GetSubscription gets a subscription by ID
+func (s *webhookService) GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetSubscription")
+	defer span.End()
+
+	return s.webhookRepo.FindSubscriptionByID(ctx, id)
+}
+
+// ListSubscriptions returns all subscriptions
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListSubscriptions")
+	defer span.End()
+
+	return s.webhookRepo.ListSubscriptions(ctx)
+}
+
+// UpdateSubscription changes the URL or events of a subscription, or
+// re-enables one that was disabled after repeated failures
+func (s *webhookService) UpdateSubscription(ctx context.Context, id uint, request models.UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.UpdateSubscription")
+	defer span.End()
+
+	subscription, err := s.webhookRepo.FindSubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.URL != "" {
+		subscription.URL = request.URL
+	}
+	if request.Events != nil {
+		subscription.SetEvents(request.Events)
+	}
+	if err := validateWebhook(ctx, s.config, subscription.URL, subscription.EventList()); err != nil {
+		return nil, err
+	}
+
+	if request.Active != nil {
+		subscription.Active = *request.Active
+		if subscription.Active {
+			subscription.ConsecutiveFailures = 0
+			subscription.DisabledAt = nil
+		}
+	}
+
+	if err := s.webhookRepo.UpdateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription deletes a subscription
+func (s *webhookService) DeleteSubscription(ctx context.Context, id uint) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteSubscription")
+	defer span.End()
+
+	if id == 0 {
+		return apperror.Validation(apperror.CodeInvalidID, "invalid subscription ID")
+	}
+	return s.webhookRepo.DeleteSubscription(ctx, id)
+}
+
+// ListDeliveries returns the delivery log of a subscription with pagination
+func (s *webhookService) ListDeliveries(ctx context.Context, subscriptionID uint, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	return s.webhookRepo.ListDeliveries(ctx, subscriptionID, page, limit)
+}
+
+// ReplayDelivery queues a delivery to be sent again as soon as possible
+func (s *webhookService) ReplayDelivery(ctx context.Context, subscriptionID, deliveryID uint) (*models.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ReplayDelivery")
+	defer span.End()
+
+	delivery, err := s.webhookRepo.FindDeliveryByID(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.SubscriptionID != subscriptionID {
+		return nil, apperror.NotFound(apperror.CodeWebhookDeliveryNotFound, "webhook delivery not found")
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.LastError = ""
+
+	if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// HandleEvent queues a delivery of the event for every active subscription
+// that wants it. Deliveries already queued for the event are skipped, so an
+// event published again by the outbox is not sent twice.
+func (s *webhookService) HandleEvent(ctx context.Context, event events.Event) error {
+	subscriptions, err := s.webhookRepo.ListActiveSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	eventID := strconv.FormatUint(uint64(event.ID), 10)
+	payload, err := json.Marshal(models.WebhookPayload{
+		ID:         eventID,
+		Event:      event.Type,
+		OccurredAt: event.OccurredAt.UTC(),
+		// Clinical notes never leave the system through webhooks
+		Data: event.Patient.RedactFor(models.RoleReceptionist),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(event.Type) {
+			continue
+		}
+
+		exists, err := s.webhookRepo.DeliveryExists(ctx, subscription.ID, eventID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		delivery := &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        eventID,
+			Event:          event.Type,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		}
+		if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliveryBatch is the number of deliveries claimed at each poll
+const deliveryBatch = 50
+
+// ProcessDue claims and attempts the deliveries whose next attempt is due.
+// The claim lasts as long as sending the whole batch may take, so that no
+// other instance sends the same deliveries meanwhile, and expires if this
+// one stops before it has recorded the outcome.
+func (s *webhookService) ProcessDue(ctx context.Context) error {
+	now := time.Now()
+	deliveries, err := s.webhookRepo.ClaimDueDeliveries(ctx, now, now.Add(deliveryBatch*s.config.Timeout), deliveryBatch)
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		s.attempt(ctx, &deliveries[i])
+	}
+	return nil
+}
+
+// Run processes due deliveries every poll interval until ctx is cancelled
+func (s *webhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ProcessDue(ctx); err != nil {
+				log.Printf("webhooks: failed to process deliveries: %v", err)
+			}
+		}
+	}
+}
+
+// attempt sends a delivery once and schedules a retry, gives up, or
+// disables the subscription depending on the outcome
+func (s *webhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	now := time.Now()
+
+	subscription, err := s.webhookRepo.FindSubscriptionByID(ctx, delivery.SubscriptionID)
+	if err != nil && !apperror.IsNotFound(err) {
+		// The delivery stays pending and is tried again at the next poll
+		log.Printf("webhooks: failed to load subscription of delivery %d: %v", delivery.ID, err)
+		delivery.NextAttemptAt = now
+		s.saveDelivery(ctx, delivery)
+		return
+	}
+	if err != nil || !subscription.Active {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = "subscription is deleted or disabled"
+		s.saveDelivery(ctx, delivery)
+		return
+	}
+
+	delivery.Attempts++
+	status, err := s.send(ctx, subscription, delivery, now)
+	delivery.ResponseStatus = status
+
+	if err == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		s.saveDelivery(ctx, delivery)
+
+		if subscription.ConsecutiveFailures > 0 {
+			subscription.ConsecutiveFailures = 0
+			s.saveSubscription(ctx, subscription)
+		}
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= s.config.MaxAttempts {
+		delivery.Status = models.WebhookDeliveryFailed
+	} else {
+		delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+	}
+	s.saveDelivery(ctx, delivery)
+
+	subscription.ConsecutiveFailures++
+	if subscription.ConsecutiveFailures >= s.config.DisableAfter {
+		subscription.Active = false
+		subscription.DisabledAt = &now
+		log.Printf("webhooks: disabled subscription %d after %d consecutive failures", subscription.ID, subscription.ConsecutiveFailures)
+	}
+	s.saveSubscription(ctx, subscription)
+}
+
+// send POSTs the payload signed with the subscription secret
+func (s *webhookService) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "hospital-webhooks/1.0")
+	request.Header.Set("X-Webhook-ID", delivery.EventID)
+	request.Header.Set("X-Webhook-Event", string(delivery.Event))
+	request.Header.Set("X-Webhook-Signature", "t="+timestamp+",v1="+Sign(subscription.Secret, timestamp, body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("endpoint responded with %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt, doubling each time
+func (s *webhookService) backoff(attempts int) time.Duration {
+	delay := s.config.BaseBackoff
+	for i := 1; i < attempts && delay < s.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.config.MaxBackoff {
+		delay = s.config.MaxBackoff
+	}
+	return delay
+}
+
+func (s *webhookService) saveDelivery(ctx context.Context, delivery *models.WebhookDelivery) {
+	if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("webhooks: failed to update delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (s *webhookService) saveSubscription(ctx context.Context, subscription *models.WebhookSubscription) {
+	if err := s.webhookRepo.UpdateSubscription(ctx, subscription); err != nil {
+		log.Printf("webhooks: failed to update subscription %d: %v", subscription.ID, err)
+	}
+}
+
+// Sign computes the hex HMAC-SHA256 of "timestamp.body" with the secret.
+// Receivers recompute it to verify the X-Webhook-Signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateWebhook checks the endpoint URL, including that its host does not
+// resolve to an internal address, and the subscribed events
+func validateWebhook(ctx context.Context, cfg *config.Webhook, rawURL string, events []models.PatientEvent) error {
+	var fields []apperror.FieldError
+
+	endpoint, err := url.Parse(rawURL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		fields = append(fields, apperror.FieldError{Field: "url", Code: "url", Message: "must be an absolute http or https URL"})
+	} else if err := checkEndpoint(ctx, cfg, endpoint); errors.Is(err, errBlockedAddress) {
+		fields = append(fields, apperror.FieldError{Field: "url", Code: "url", Message: "must not resolve to a loopback, private, link-local or unspecified address"})
+	} else if err != nil {
+		fields = append(fields, apperror.FieldError{Field: "url", Code: "url", Message: "must have a host that can be resolved"})
+	}
+
+	if len(events) == 0 {
+		fields = append(fields, apperror.FieldError{Field: "events", Code: "required", Message: "is required"})
+	}
+	for _, event := range events {
+		if !event.IsValid() {
+			fields = append(fields, apperror.FieldError{Field: "events", Code: "oneof", Message: fmt.Sprintf("unknown event %q", event)})
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation(apperror.CodeValidationFailed, "The webhook has invalid fields", fields...)
+	}
+	return nil
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_audit_logs_resource_id;
+DROP INDEX IF EXISTS idx_audit_logs_action;
+DROP INDEX IF EXISTS idx_audit_logs_user_id;
+
+-- Drop audit_logs table
+DROP TABLE IF EXISTS audit_logs;
@@ -0,0 +1,11 @@
+-- Drop patient_identifiers table
+DROP INDEX IF EXISTS idx_patient_identifiers_patient_id;
+DROP INDEX IF EXISTS idx_patient_identifiers_system_value;
+DROP TABLE IF EXISTS patient_identifiers;
+
+-- Drop hl7_messages table
+DROP INDEX IF EXISTS idx_hl7_messages_patient_id;
+DROP INDEX IF EXISTS idx_hl7_messages_status;
+DROP INDEX IF EXISTS idx_hl7_messages_message_type;
+DROP INDEX IF EXISTS idx_hl7_messages_control_id;
+DROP TABLE IF EXISTS hl7_messages;
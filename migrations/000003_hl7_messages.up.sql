@@ -0,0 +1,37 @@
+-- Create hl7_messages table for raw inbound messages
+CREATE TABLE IF NOT EXISTS hl7_messages (
+    id SERIAL PRIMARY KEY,
+    control_id VARCHAR(255),
+    message_type VARCHAR(50),
+    raw TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    ack_code VARCHAR(2),
+    error TEXT,
+    patient_id INTEGER,
+    patient_class VARCHAR(50),
+    location VARCHAR(255),
+    attending_doctor VARCHAR(255),
+    visit_number VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_hl7_messages_control_id ON hl7_messages(control_id);
+CREATE INDEX IF NOT EXISTS idx_hl7_messages_message_type ON hl7_messages(message_type);
+CREATE INDEX IF NOT EXISTS idx_hl7_messages_status ON hl7_messages(status);
+CREATE INDEX IF NOT EXISTS idx_hl7_messages_patient_id ON hl7_messages(patient_id);
+
+-- Create patient_identifiers table for identifiers issued by external systems
+CREATE TABLE IF NOT EXISTS patient_identifiers (
+    id SERIAL PRIMARY KEY,
+    system VARCHAR(255) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    patient_id INTEGER NOT NULL REFERENCES patients(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_identifiers_system_value ON patient_identifiers(system, value);
+CREATE INDEX IF NOT EXISTS idx_patient_identifiers_patient_id ON patient_identifiers(patient_id);
@@ -0,0 +1,10 @@
+-- Drop webhook_deliveries table
+DROP INDEX IF EXISTS idx_webhook_deliveries_next_attempt_at;
+DROP INDEX IF EXISTS idx_webhook_deliveries_status;
+DROP INDEX IF EXISTS idx_webhook_deliveries_event_id;
+DROP INDEX IF EXISTS idx_webhook_deliveries_subscription_id;
+DROP TABLE IF EXISTS webhook_deliveries;
+
+-- Drop webhook_subscriptions table
+DROP INDEX IF EXISTS idx_webhook_subscriptions_active;
+DROP TABLE IF EXISTS webhook_subscriptions;
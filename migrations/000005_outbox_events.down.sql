@@ -0,0 +1,4 @@
+-- Drop outbox_events table
+DROP INDEX IF EXISTS idx_outbox_events_published_at;
+DROP INDEX IF EXISTS idx_outbox_events_aggregate_id;
+DROP TABLE IF EXISTS outbox_events;
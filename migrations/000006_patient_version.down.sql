@@ -0,0 +1,2 @@
+-- Drop version column from patients
+ALTER TABLE patients DROP COLUMN IF EXISTS version;
@@ -0,0 +1,2 @@
+-- Add version column to patients for optimistic concurrency
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- Drop rate_limit_buckets table
+DROP INDEX IF EXISTS idx_rate_limit_buckets_refilled_at;
+DROP TABLE IF EXISTS rate_limit_buckets;
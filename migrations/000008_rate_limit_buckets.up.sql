@@ -0,0 +1,8 @@
+-- Create rate_limit_buckets table for sharing rate limits between instances
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    refilled_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_refilled_at ON rate_limit_buckets(refilled_at);
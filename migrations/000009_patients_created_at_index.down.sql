@@ -0,0 +1,2 @@
+-- Drop the patient listing index
+DROP INDEX IF EXISTS idx_patients_created_at_id;
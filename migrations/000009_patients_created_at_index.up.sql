@@ -0,0 +1,2 @@
+-- Index the order patients are listed in, so cursor pagination seeks instead of scanning
+CREATE INDEX IF NOT EXISTS idx_patients_created_at_id ON patients(created_at, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+-- Drop the medical notes search vector
+DROP INDEX IF EXISTS idx_patients_notes_tsv;
+DROP TRIGGER IF EXISTS patients_notes_tsv_trigger ON patients;
+DROP FUNCTION IF EXISTS patients_notes_tsv_update();
+ALTER TABLE patients DROP COLUMN IF EXISTS notes_tsv;
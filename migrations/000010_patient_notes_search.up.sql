@@ -0,0 +1,17 @@
+-- Add a full-text search vector over medical notes, kept up to date by a trigger
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS notes_tsv tsvector;
+
+CREATE OR REPLACE FUNCTION patients_notes_tsv_update() RETURNS trigger AS $$
+BEGIN
+    NEW.notes_tsv := to_tsvector('english', coalesce(NEW.medical_notes, ''));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS patients_notes_tsv_trigger ON patients;
+CREATE TRIGGER patients_notes_tsv_trigger BEFORE INSERT OR UPDATE OF medical_notes ON patients
+    FOR EACH ROW EXECUTE FUNCTION patients_notes_tsv_update();
+
+UPDATE patients SET notes_tsv = to_tsvector('english', coalesce(medical_notes, '')) WHERE notes_tsv IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_patients_notes_tsv ON patients USING GIN (notes_tsv);
@@ -0,0 +1,4 @@
+-- Drop the trigram indexes, keeping the extensions other objects may use
+DROP INDEX IF EXISTS idx_patients_contact_info_trgm;
+DROP INDEX IF EXISTS idx_patients_name_trgm;
+DROP FUNCTION IF EXISTS f_unaccent(text);
@@ -0,0 +1,12 @@
+-- Index names and contact info by trigrams for fuzzy, accent-insensitive search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent() is only stable because its dictionary can change, so index
+-- expressions go through an immutable wrapper with the dictionary fixed
+CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text AS $$
+    SELECT public.unaccent('public.unaccent'::regdictionary, $1)
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+CREATE INDEX IF NOT EXISTS idx_patients_name_trgm ON patients USING GIN (f_unaccent(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_patients_contact_info_trgm ON patients USING GIN (f_unaccent(contact_info) gin_trgm_ops);
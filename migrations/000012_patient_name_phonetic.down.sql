@@ -0,0 +1,3 @@
+-- Drop the phonetic keys of patient names
+DROP INDEX IF EXISTS idx_patients_name_phonetic;
+ALTER TABLE patients DROP COLUMN IF EXISTS name_phonetic;
@@ -0,0 +1,6 @@
+-- Store the Double Metaphone keys of patient names for phonetic search. The
+-- keys are computed by the application, which fills them in for existing
+-- patients when it starts.
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS name_phonetic TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_patients_name_phonetic ON patients USING GIN (string_to_array(name_phonetic, ' '));
@@ -0,0 +1,11 @@
+-- Drop patient_duplicates table
+DROP INDEX IF EXISTS idx_patient_duplicates_status;
+DROP INDEX IF EXISTS idx_patient_duplicates_candidate_id;
+DROP INDEX IF EXISTS idx_patient_duplicates_pair;
+DROP TABLE IF EXISTS patient_duplicates;
+
+-- Drop the fields patients are matched on
+DROP INDEX IF EXISTS idx_patients_contact_keys;
+ALTER TABLE patients DROP COLUMN IF EXISTS contact_keys;
+ALTER TABLE patients DROP COLUMN IF EXISTS address;
+ALTER TABLE patients DROP COLUMN IF EXISTS birth_date;
@@ -0,0 +1,28 @@
+-- Record the birth date and address of patients, and the normalized phone
+-- numbers and email addresses of their contact info, to score how likely a
+-- new patient is to duplicate an existing one. The keys are computed by the
+-- application, which fills them in for existing patients when it starts.
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS birth_date DATE;
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS address TEXT NOT NULL DEFAULT '';
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS contact_keys TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_patients_contact_keys ON patients USING GIN (string_to_array(contact_keys, ' '));
+
+-- Create patient_duplicates table for the queue of suspected duplicates
+CREATE TABLE IF NOT EXISTS patient_duplicates (
+    id SERIAL PRIMARY KEY,
+    patient_id INTEGER NOT NULL REFERENCES patients(id),
+    candidate_id INTEGER NOT NULL REFERENCES patients(id),
+    score DOUBLE PRECISION NOT NULL,
+    features TEXT,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    reviewed_by INTEGER REFERENCES users(id),
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_duplicates_pair ON patient_duplicates(patient_id, candidate_id);
+CREATE INDEX IF NOT EXISTS idx_patient_duplicates_candidate_id ON patient_duplicates(candidate_id);
+CREATE INDEX IF NOT EXISTS idx_patient_duplicates_status ON patient_duplicates(status);
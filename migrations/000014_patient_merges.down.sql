@@ -0,0 +1,4 @@
+-- Drop patient_merges table
+DROP INDEX IF EXISTS idx_patient_merges_merged_id;
+DROP INDEX IF EXISTS idx_patient_merges_survivor_id;
+DROP TABLE IF EXISTS patient_merges;
@@ -0,0 +1,21 @@
+-- Create patient_merges table. A merge folds a duplicate patient into a
+-- survivor and is kept as a tombstone that resolves the merged patient's ID,
+-- with what is needed to undo it.
+CREATE TABLE IF NOT EXISTS patient_merges (
+    id SERIAL PRIMARY KEY,
+    survivor_id INTEGER NOT NULL REFERENCES patients(id),
+    merged_id INTEGER NOT NULL REFERENCES patients(id),
+    merged_by INTEGER NOT NULL REFERENCES users(id),
+    survivor_before TEXT,
+    survivor_version INTEGER NOT NULL,
+    relinked TEXT,
+    unmerge_before TIMESTAMP WITH TIME ZONE NOT NULL,
+    unmerged_at TIMESTAMP WITH TIME ZONE,
+    unmerged_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_merges_survivor_id ON patient_merges(survivor_id);
+CREATE INDEX IF NOT EXISTS idx_patient_merges_merged_id ON patient_merges(merged_id);
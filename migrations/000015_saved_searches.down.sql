@@ -0,0 +1,4 @@
+-- Drop saved_searches table
+DROP INDEX IF EXISTS idx_saved_searches_shared_with;
+DROP INDEX IF EXISTS idx_saved_searches_owner_name;
+DROP TABLE IF EXISTS saved_searches;
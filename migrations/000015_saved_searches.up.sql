@@ -0,0 +1,19 @@
+-- Create saved_searches table. A saved search is a named patient search a
+-- user runs again; pinned ones are their worklists, and one shared with a
+-- role can be run by every user with that role.
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL REFERENCES users(id),
+    name VARCHAR(255) NOT NULL,
+    search TEXT,
+    sort VARCHAR(255),
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    shared_with VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Names are unique among the searches a user has not deleted
+CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_searches_owner_name ON saved_searches(owner_id, name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_saved_searches_shared_with ON saved_searches(shared_with);
@@ -0,0 +1,12 @@
+-- Move pins back to saved_searches. Only the owner's pins can be kept.
+ALTER TABLE saved_searches ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE saved_searches SET pinned = TRUE
+WHERE EXISTS (
+    SELECT 1 FROM saved_search_pins
+    WHERE saved_search_pins.saved_search_id = saved_searches.id
+      AND saved_search_pins.user_id = saved_searches.owner_id
+);
+
+DROP INDEX IF EXISTS idx_saved_search_pins_saved_search_id;
+DROP TABLE IF EXISTS saved_search_pins;
@@ -0,0 +1,17 @@
+-- Create saved_search_pins table. Pins belong to a user, so users a saved
+-- search is shared with can pin it as their own worklist.
+CREATE TABLE IF NOT EXISTS saved_search_pins (
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    saved_search_id INTEGER NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, saved_search_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_search_pins_saved_search_id ON saved_search_pins(saved_search_id);
+
+-- Searches pinned so far were pinned by their owner
+INSERT INTO saved_search_pins (user_id, saved_search_id)
+SELECT owner_id, id FROM saved_searches WHERE pinned
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE saved_searches DROP COLUMN IF EXISTS pinned;
@@ -0,0 +1,2 @@
+-- Drop the suspected duplicates closed by merges
+ALTER TABLE patient_merges DROP COLUMN IF EXISTS closed_duplicates;
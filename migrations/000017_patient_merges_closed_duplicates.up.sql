@@ -0,0 +1,3 @@
+-- Record the suspected duplicates a merge confirmed or closed, so that
+-- undoing the merge puts them back in the review queue
+ALTER TABLE patient_merges ADD COLUMN IF NOT EXISTS closed_duplicates TEXT;
@@ -0,0 +1,219 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/config"
+	"hospital-project/internal/events"
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+// memoryOutbox is an in-memory OutboxRepository
+type memoryOutbox struct {
+	rows []models.OutboxEvent
+}
+
+func (o *memoryOutbox) Append(ctx context.Context, event *models.OutboxEvent) error {
+	event.ID = uint(len(o.rows) + 1)
+	o.rows = append(o.rows, *event)
+	return nil
+}
+
+func (o *memoryOutbox) ListPending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var pending []models.OutboxEvent
+	blocked := make(map[uint]bool)
+	for _, row := range o.rows {
+		if row.PublishedAt != nil {
+			continue
+		}
+		due := row.NextAttemptAt == nil || !row.NextAttemptAt.After(now)
+		if due && !blocked[row.AggregateID] && len(pending) < limit {
+			pending = append(pending, row)
+		}
+		blocked[row.AggregateID] = true
+	}
+	return pending, nil
+}
+
+func (o *memoryOutbox) Update(ctx context.Context, event *models.OutboxEvent) error {
+	o.rows[event.ID-1] = *event
+	return nil
+}
+
+func (o *memoryOutbox) PurgePublished(ctx context.Context, before time.Time) (int64, error) {
+	var kept []models.OutboxEvent
+	for _, row := range o.rows {
+		if row.PublishedAt == nil || !row.PublishedAt.Before(before) {
+			kept = append(kept, row)
+		}
+	}
+	purged := int64(len(o.rows) - len(kept))
+	o.rows = kept
+	return purged, nil
+}
+
+// memoryTransactions runs units of work against the in-memory outbox
+type memoryTransactions struct {
+	outbox *memoryOutbox
+}
+
+func (m *memoryTransactions) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos *repositories.Repositories) error) error {
+	return fn(ctx, &repositories.Repositories{Outbox: m.outbox})
+}
+
+func testOutboxConfig() *config.Outbox {
+	return &config.Outbox{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		BaseBackoff:  time.Millisecond,
+		MaxBackoff:   time.Millisecond,
+		Retention:    time.Hour,
+	}
+}
+
+func appendEvent(t *testing.T, outbox *memoryOutbox, eventType models.PatientEvent, patientID uint) {
+	patient := &models.Patient{Name: "John Doe"}
+	patient.ID = patientID
+	row, err := events.NewOutboxEvent(eventType, patient, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, outbox.Append(context.Background(), row))
+}
+
+func TestBus_PublishesToEverySubscriber(t *testing.T) {
+	bus := events.NewBus()
+
+	var calls []string
+	bus.Subscribe("first", events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		calls = append(calls, "first")
+		return errors.New("boom")
+	}))
+	bus.Subscribe("second", events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		calls = append(calls, "second")
+		return nil
+	}))
+
+	err := bus.Publish(context.Background(), events.Event{ID: 1, Type: events.PatientRegistered})
+
+	// A failing subscriber does not stop the others
+	assert.Equal(t, []string{"first", "second"}, calls)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "first: boom")
+}
+
+func TestDispatcher_PublishesInOrder(t *testing.T) {
+	outbox := &memoryOutbox{}
+	appendEvent(t, outbox, events.PatientRegistered, 1)
+	appendEvent(t, outbox, events.MedicalNotesUpdated, 1)
+	appendEvent(t, outbox, events.PatientDeleted, 1)
+
+	bus := events.NewBus()
+	var received []events.Event
+	bus.Subscribe("recorder", events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		received = append(received, event)
+		return nil
+	}))
+
+	dispatcher := events.NewDispatcher(&memoryTransactions{outbox: outbox}, bus, testOutboxConfig())
+	require.NoError(t, dispatcher.DispatchPending(context.Background()))
+
+	require.Len(t, received, 3)
+	assert.Equal(t, events.PatientRegistered, received[0].Type)
+	assert.Equal(t, events.MedicalNotesUpdated, received[1].Type)
+	assert.Equal(t, events.PatientDeleted, received[2].Type)
+	assert.Equal(t, uint(1), received[0].PatientID)
+	assert.Equal(t, "John Doe", received[0].Patient.Name)
+
+	pending, _ := outbox.ListPending(context.Background(), time.Now(), 100)
+	assert.Empty(t, pending)
+}
+
+func TestDispatcher_FailureHoldsBackSamePatient(t *testing.T) {
+	outbox := &memoryOutbox{}
+	appendEvent(t, outbox, events.PatientRegistered, 1)
+	appendEvent(t, outbox, events.PatientRegistered, 2)
+	appendEvent(t, outbox, events.PatientUpdated, 1)
+
+	bus := events.NewBus()
+	fail := true
+	var received []uint
+	bus.Subscribe("flaky", events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		if event.PatientID == 1 && fail {
+			return errors.New("unavailable")
+		}
+		received = append(received, event.ID)
+		return nil
+	}))
+
+	dispatcher := events.NewDispatcher(&memoryTransactions{outbox: outbox}, bus, testOutboxConfig())
+	require.NoError(t, dispatcher.DispatchPending(context.Background()))
+
+	// Patient 2 is unaffected; patient 1's update waits for its registration
+	assert.Equal(t, []uint{2}, received)
+	assert.Equal(t, 1, outbox.rows[0].Attempts)
+	assert.Equal(t, "flaky: unavailable", outbox.rows[0].LastError)
+	assert.Nil(t, outbox.rows[2].PublishedAt)
+
+	// Once the subscriber recovers, the held back events follow in order
+	fail = false
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(t, dispatcher.DispatchPending(context.Background()))
+	assert.Equal(t, []uint{2, 1, 3}, received)
+}
+
+func TestDispatcher_FailingEventsDoNotStallOthers(t *testing.T) {
+	outbox := &memoryOutbox{}
+	appendEvent(t, outbox, events.PatientRegistered, 1)
+	appendEvent(t, outbox, events.PatientRegistered, 2)
+	appendEvent(t, outbox, events.PatientUpdated, 1)
+	appendEvent(t, outbox, events.PatientRegistered, 3)
+
+	bus := events.NewBus()
+	var received []uint
+	bus.Subscribe("flaky", events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		if event.PatientID == 1 {
+			return errors.New("unavailable")
+		}
+		received = append(received, event.ID)
+		return nil
+	}))
+
+	// Batches of two still get past the failing patient
+	cfg := testOutboxConfig()
+	cfg.BatchSize = 2
+	cfg.BaseBackoff, cfg.MaxBackoff = time.Hour, time.Hour
+	dispatcher := events.NewDispatcher(&memoryTransactions{outbox: outbox}, bus, cfg)
+
+	require.NoError(t, dispatcher.DispatchPending(context.Background()))
+	assert.Equal(t, []uint{2}, received)
+	require.NoError(t, dispatcher.DispatchPending(context.Background()))
+	assert.Equal(t, []uint{2, 4}, received)
+
+	// The failed event waits for its retry and holds back its patient
+	assert.Equal(t, 1, outbox.rows[0].Attempts)
+	assert.Nil(t, outbox.rows[2].PublishedAt)
+}
+
+func TestDispatcher_PurgePublished(t *testing.T) {
+	outbox := &memoryOutbox{}
+	appendEvent(t, outbox, events.PatientRegistered, 1)
+	appendEvent(t, outbox, events.PatientRegistered, 2)
+	appendEvent(t, outbox, events.PatientRegistered, 3)
+	old, recent := time.Now().Add(-2*time.Hour), time.Now()
+	outbox.rows[0].PublishedAt = &old
+	outbox.rows[1].PublishedAt = &recent
+
+	dispatcher := events.NewDispatcher(&memoryTransactions{outbox: outbox}, events.NewBus(), testOutboxConfig())
+	require.NoError(t, dispatcher.PurgePublished(context.Background()))
+
+	// Only events published before the retention period are deleted
+	require.Len(t, outbox.rows, 2)
+	assert.Equal(t, uint(2), outbox.rows[0].ID)
+	assert.Nil(t, outbox.rows[1].PublishedAt)
+}
@@ -0,0 +1,97 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/export"
+	"hospital-project/internal/models"
+)
+
+func testPatients() []models.PatientResponse {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []models.PatientResponse{
+		{ID: 1, Name: "John Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "1234567890", MedicalNotes: "Allergic to penicillin", CreatedAt: created, UpdatedAt: created},
+		{ID: 2, Name: "=HYPERLINK(\"x\")", Age: 25, Gender: models.GenderFemale, ContactInfo: "a & b <c>", CreatedAt: created, UpdatedAt: created},
+	}
+}
+
+func writeAll(t *testing.T, format export.Format) []byte {
+	var buf bytes.Buffer
+	writer, err := export.NewWriter(format, &buf)
+	require.NoError(t, err)
+	for _, patient := range testPatients() {
+		require.NoError(t, writer.Write(patient))
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestExport_CSV(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(writeAll(t, export.FormatCSV))).ReadAll()
+	require.NoError(t, err)
+
+	assert.Len(t, records, 3)
+	assert.Equal(t, "id", records[0][0])
+	assert.Equal(t, []string{"1", "John Doe", "30", "male", "1234567890", "Allergic to penicillin", "2026-01-02T03:04:05Z", "2026-01-02T03:04:05Z"}, records[1])
+
+	// Formula-looking values are neutralised
+	assert.Equal(t, "'=HYPERLINK(\"x\")", records[2][1])
+}
+
+func TestExport_NDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(writeAll(t, export.FormatNDJSON))), "\n")
+	require.Len(t, lines, 2)
+
+	var patient models.PatientResponse
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &patient))
+	assert.Equal(t, "John Doe", patient.Name)
+	assert.Equal(t, "Allergic to penicillin", patient.MedicalNotes)
+}
+
+func TestExport_XLSX(t *testing.T) {
+	data := writeAll(t, export.FormatXLSX)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	var sheet string
+	names := map[string]bool{}
+	for _, file := range archive.File {
+		names[file.Name] = true
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			reader, err := file.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			sheet = string(content)
+		}
+	}
+
+	assert.True(t, names["[Content_Types].xml"])
+	assert.True(t, names["xl/workbook.xml"])
+	assert.Contains(t, sheet, `<c r="A2"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<row r="3">`)
+	assert.Contains(t, sheet, "a &amp; b &lt;c&gt;")
+}
+
+func TestExport_UnsupportedFormat(t *testing.T) {
+	_, err := export.NewWriter("pdf", io.Discard)
+	assert.Error(t, err)
+}
+
+func TestPatientResponse_RedactFor(t *testing.T) {
+	patient := testPatients()[0]
+
+	assert.Empty(t, patient.RedactFor(models.RoleReceptionist).MedicalNotes)
+	assert.Equal(t, "Allergic to penicillin", patient.RedactFor(models.RoleDoctor).MedicalNotes)
+}
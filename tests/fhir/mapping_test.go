@@ -0,0 +1,178 @@
+package fhir_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/fhir"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+)
+
+var now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func TestFromPatient(t *testing.T) {
+	patient := &models.Patient{
+		Model:       gorm.Model{ID: 7, UpdatedAt: now},
+		Name:        "Mary Ann Smith",
+		Age:         40,
+		Gender:      models.GenderFemale,
+		ContactInfo: "mary@example.com",
+	}
+
+	resource := fhir.FromPatient(patient, now)
+
+	assert.Equal(t, "Patient", resource.ResourceType)
+	assert.Equal(t, "7", resource.ID)
+	assert.Equal(t, "Smith", resource.Name[0].Family)
+	assert.Equal(t, []string{"Mary", "Ann"}, resource.Name[0].Given)
+	assert.Equal(t, "female", resource.Gender)
+	assert.Equal(t, "1986", resource.BirthDate)
+	assert.Equal(t, "email", resource.Telecom[0].System)
+}
+
+func TestApplyToPatient(t *testing.T) {
+	resource := fhir.Patient{
+		ResourceType: "Patient",
+		Name:         []fhir.HumanName{{Use: "official", Family: "Doe", Given: []string{"John"}}},
+		Gender:       "male",
+		BirthDate:    "1990-12-01",
+		Telecom:      []fhir.ContactPoint{{System: "phone", Value: "1234567890"}},
+	}
+
+	patient := &models.Patient{MedicalNotes: "kept"}
+	err := fhir.ApplyToPatient(resource, patient, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", patient.Name)
+	assert.Equal(t, 35, patient.Age)
+	assert.Equal(t, models.GenderMale, patient.Gender)
+	assert.Equal(t, "1234567890", patient.ContactInfo)
+	assert.Equal(t, "kept", patient.MedicalNotes)
+	assert.Equal(t, "1990-12-01", models.FormatDate(patient.BirthDate))
+
+	// The full birth date is reported back, and a partial one only sets the age
+	assert.Equal(t, "1990-12-01", fhir.FromPatient(patient, now).BirthDate)
+	resource.BirthDate = "1990"
+	require.NoError(t, fhir.ApplyToPatient(resource, patient, now))
+	assert.Nil(t, patient.BirthDate)
+	assert.Equal(t, 36, patient.Age)
+}
+
+func TestApplyToPatient_Invalid(t *testing.T) {
+	valid := fhir.Patient{
+		ResourceType: "Patient",
+		Name:         []fhir.HumanName{{Text: "John Doe"}},
+		Gender:       "male",
+		BirthDate:    "1990",
+		Telecom:      []fhir.ContactPoint{{Value: "1234567890"}},
+	}
+
+	cases := map[string]func(p *fhir.Patient){
+		"wrong resource type": func(p *fhir.Patient) { p.ResourceType = "Observation" },
+		"missing name":        func(p *fhir.Patient) { p.Name = nil },
+		"unknown gender":      func(p *fhir.Patient) { p.Gender = "unknown" },
+		"bad birth date":      func(p *fhir.Patient) { p.BirthDate = "19-90" },
+		"future birth date":   func(p *fhir.Patient) { p.BirthDate = "2030" },
+		"missing telecom":     func(p *fhir.Patient) { p.Telecom = nil },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			resource := valid
+			mutate(&resource)
+			assert.Error(t, fhir.ApplyToPatient(resource, &models.Patient{}, now))
+		})
+	}
+}
+
+func TestParseSearch(t *testing.T) {
+	values := url.Values{
+		"name":      {"smith"},
+		"gender":    {"female"},
+		"birthdate": {"ge1950", "lt1980-06-01"},
+		"_count":    {"500"},
+		"_sort":     {"-birthdate,name"},
+	}
+
+	query, err := fhir.ParseSearch(values, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, "smith", query.Params.Name)
+	assert.Equal(t, models.GenderFemale, query.Params.Gender)
+	assert.Equal(t, "birth_date >= 1950-01-01 and birth_date <= 1980-05-31 or birth_date = null and age >= 46 and age <= 76", query.Params.Filter)
+	_, err = query.Params.ParseFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, fhir.MaxCount, query.Count)
+	assert.Equal(t, []pagination.SortKey{{Field: "birth_date", Descending: true}, {Field: "name"}}, query.Sort)
+}
+
+func TestParseSearch_Sort(t *testing.T) {
+	values := url.Values{"_sort": {"birthdate,-_lastUpdated,_id"}}
+
+	query, err := fhir.ParseSearch(values, now)
+
+	require.NoError(t, err)
+	assert.Empty(t, query.Params.Filter)
+	assert.Equal(t, []pagination.SortKey{{Field: "birth_date"}, {Field: "updated_at", Descending: true}, {Field: "id"}}, query.Sort)
+}
+
+func TestParseSearch_Errors(t *testing.T) {
+	for _, raw := range []string{"gender=unknown", "birthdate=ap1980", "_count=-1", "_sort=contact_info"} {
+		values, err := url.ParseQuery(raw)
+		require.NoError(t, err)
+
+		_, err = fhir.ParseSearch(values, now)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestParseSearch_BirthdayToCome(t *testing.T) {
+	// A patient born on 1980-12-01 is still 45, as their birthday has not
+	// come yet this year
+	resource := fhir.Patient{
+		ResourceType: "Patient",
+		Name:         []fhir.HumanName{{Text: "John Doe"}},
+		Gender:       "male",
+		BirthDate:    "1980-12-01",
+		Telecom:      []fhir.ContactPoint{{Value: "1234567890"}},
+	}
+	patient := &models.Patient{}
+	require.NoError(t, fhir.ApplyToPatient(resource, patient, now))
+	assert.Equal(t, 45, patient.Age)
+	assert.Equal(t, "1980-12-01", fhir.FromPatient(patient, now).BirthDate)
+
+	tests := map[string]string{
+		"1980":         "birth_date >= 1980-01-01 and birth_date <= 1980-12-31 or birth_date = null and age >= 45 and age <= 46",
+		"1980-12":      "birth_date >= 1980-12-01 and birth_date <= 1980-12-31 or birth_date = null and age >= 45 and age <= 45",
+		"1980-12-01":   "birth_date >= 1980-12-01 and birth_date <= 1980-12-01 or birth_date = null and age >= 45 and age <= 45",
+		"ge1980":       "birth_date >= 1980-01-01 or birth_date = null and age <= 46",
+		"gt1980":       "birth_date >= 1981-01-01 or birth_date = null and age <= 45",
+		"le1980":       "birth_date <= 1980-12-31 or birth_date = null and age >= 45",
+		"lt1981":       "birth_date <= 1980-12-31 or birth_date = null and age >= 45",
+		"lt1980-12-02": "birth_date <= 1980-12-01 or birth_date = null and age >= 45",
+	}
+	for birthdate, filter := range tests {
+		t.Run(birthdate, func(t *testing.T) {
+			query, err := fhir.ParseSearch(url.Values{"birthdate": {birthdate}}, now)
+			require.NoError(t, err)
+			assert.Equal(t, filter, query.Params.Filter)
+		})
+	}
+}
+
+func TestParseSearch_Newborn(t *testing.T) {
+	values := url.Values{"birthdate": {"2026"}}
+
+	query, err := fhir.ParseSearch(values, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, "birth_date >= 2026-01-01 and birth_date <= 2026-12-31 or birth_date = null and age >= 0 and age <= 0", query.Params.Filter)
+	_, err = query.Params.ParseFilter()
+	assert.NoError(t, err)
+}
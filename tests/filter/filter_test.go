@@ -0,0 +1,204 @@
+package filter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/filter"
+)
+
+// fields are the fields the tests filter on
+var fields = filter.Fields{
+	"name":       {Column: "name", Type: filter.String},
+	"age":        {Column: "age", Type: filter.Integer},
+	"gender":     {Column: "gender", Type: filter.Enum, Values: []string{"male", "female", "other"}},
+	"birth_date": {Column: "birth_date", Type: filter.Date, Nullable: true},
+	"created_at": {Column: "created_at", Type: filter.Timestamp},
+}
+
+func day(value string) time.Time {
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		sql   string
+		vars  []interface{}
+	}{
+		{
+			input: "age>=65 and gender=female and created_at>2026-01-01",
+			sql:   "((age >= ? AND gender = ?) AND created_at >= ?)",
+			vars:  []interface{}{int64(65), "female", day("2026-01-02")},
+		},
+		{
+			input: "gender = male or gender = other and age < 18",
+			sql:   "(gender = ? OR (gender = ? AND age < ?))",
+			vars:  []interface{}{"male", "other", int64(18)},
+		},
+		{
+			input: "(gender = male OR gender = other) AND NOT age in (1, 2,3)",
+			sql:   "((gender = ? OR gender = ?) AND NOT age IN (?, ?, ?))",
+			vars:  []interface{}{"male", "other", int64(1), int64(2), int64(3)},
+		},
+		{
+			input: `name ~ "50%_o'\"k\\"`,
+			sql:   "name ILIKE ?",
+			vars:  []interface{}{`%50\%\_o'"k\\%`},
+		},
+		{
+			input: "name = 'Mary Ann' and name != doe",
+			sql:   "(name = ? AND name IS DISTINCT FROM ?)",
+			vars:  []interface{}{"Mary Ann", "doe"},
+		},
+		{
+			input: "birth_date = null or birth_date != NULL",
+			sql:   "(birth_date IS NULL OR birth_date IS NOT NULL)",
+		},
+		{
+			input: "birth_date <= 1960-12-31",
+			sql:   "birth_date <= ?",
+			vars:  []interface{}{day("1960-12-31")},
+		},
+		{
+			input: "created_at = 2026-03-01",
+			sql:   "(created_at >= ? AND created_at < ?)",
+			vars:  []interface{}{day("2026-03-01"), day("2026-03-02")},
+		},
+		{
+			input: "created_at <= 2026-03-01",
+			sql:   "created_at < ?",
+			vars:  []interface{}{day("2026-03-02")},
+		},
+		{
+			input: "created_at < 2026-03-01T10:30:00Z",
+			sql:   "created_at < ?",
+			vars:  []interface{}{time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			parsed, err := filter.Parse(tt.input, fields)
+			require.NoError(t, err)
+
+			sql, vars := parsed.SQL()
+			assert.Equal(t, tt.sql, sql)
+			assert.Equal(t, tt.vars, vars)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+	}{
+		{"", "filter is empty"},
+		{"age >=", "expected a value but found end of filter at position 7"},
+		{"age 65", `expected an operator but found "65" at position 5`},
+		{"weight > 80", `unknown field "weight"; filterable fields are age, birth_date, created_at, gender, name at position 1`},
+		{"age > old", `invalid value "old" for field "age": expected a whole number at position 7`},
+		{"gender = unknown", `field "gender" must be one of male female other at position 10`},
+		{"gender > male", `field "gender" does not support ">"; use one of = != in at position 8`},
+		{"name = null", `field "name" is never null; quote "null" to match the word at position 8`},
+		{"birth_date < null", "null can only be compared with = or != at position 14"},
+		{"created_at > yesterday", `invalid value "yesterday" for field "created_at": expected a date as YYYY-MM-DD or an RFC 3339 timestamp at position 14`},
+		{"(age > 1", `expected ")" but found end of filter at position 9`},
+		{"age > 1 age < 5", `expected "and", "or" or the end of the filter but found "age" at position 9`},
+		{"age in 1", `expected "(" but found "1" at position 8`},
+		{"age in (1 2)", `expected "," or ")" but found "2" at position 11`},
+		{"age ! 1", `unexpected "!"; expected "!=" at position 5`},
+		{`name = "doe`, "unterminated string at position 8"},
+		{"nåme = x", `unknown field "nåme"; filterable fields are age, birth_date, created_at, gender, name at position 1`},
+		{"name = é or name = \"x", "unterminated string at position 20"},
+		{strings.Repeat("not ", 30) + "age = 1", "filter is nested more than 20 levels deep at position 81"},
+		{strings.Repeat("age = 1 or ", 50) + "age = 1", "filter has more than 50 conditions at position 551"},
+		{strings.Repeat("x", filter.MaxLength+1), "filter is longer than 2000 characters"},
+		{"name = \xff", "filter is not valid UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := filter.Parse(tt.input, fields)
+
+			appErr, ok := apperror.As(err)
+			require.True(t, ok, "error %v is not an apperror", err)
+			assert.Equal(t, apperror.KindValidation, appErr.Kind)
+			assert.Equal(t, apperror.CodeInvalidFilter, appErr.Code)
+			assert.Equal(t, "invalid filter: "+tt.message, appErr.Message)
+		})
+	}
+}
+
+func TestFilter_String(t *testing.T) {
+	parsed, err := filter.Parse(`NOT (age >= +65 AND name ~ 'O"Brien') or gender in (male,female) or created_at > 2026-01-01`, fields)
+	require.NoError(t, err)
+
+	assert.Equal(t, `not (age >= 65 and name ~ "O\"Brien") or gender in (male, female) or created_at > 2026-01-01`, parsed.String())
+
+	// Parentheses that change the grouping are kept
+	parsed, err = filter.Parse("age = 1 and (age = 2 or (age = 3 and age = 4)) or (age = 5 or age = 6)", fields)
+	require.NoError(t, err)
+	assert.Equal(t, "age = 1 and (age = 2 or age = 3 and age = 4) or (age = 5 or age = 6)", parsed.String())
+}
+
+// FuzzParse checks that any input either fails with a validation error or
+// parses to a filter whose SQL has a placeholder for every value and whose
+// canonical form parses back to the same filter
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"age>=65 and gender=female and created_at>2026-01-01",
+		"(gender = male or gender = other) and not age in (1, 2, 3)",
+		`name ~ "50%_o'\"k\\" or name = 'Mary Ann'`,
+		"birth_date = null or birth_date != 1960-12-31",
+		"created_at < 2026-03-01T10:30:00+02:00",
+		"not not (age < 1)",
+		"age in (",
+		`name = "`,
+		"((((",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		parsed, err := filter.Parse(input, fields)
+		if err != nil {
+			appErr, ok := apperror.As(err)
+			if !ok || appErr.Code != apperror.CodeInvalidFilter {
+				t.Fatalf("Parse(%q) returned %v, not an invalid filter error", input, err)
+			}
+			return
+		}
+
+		sql, vars := parsed.SQL()
+		if strings.Count(sql, "?") != len(vars) {
+			t.Fatalf("Parse(%q) gave %d placeholders for %d values: %s", input, strings.Count(sql, "?"), len(vars), sql)
+		}
+
+		canonical := parsed.String()
+		if len(canonical) > filter.MaxLength {
+			// Spaces between tokens can make the canonical form the longer
+			return
+		}
+		reparsed, err := filter.Parse(canonical, fields)
+		if err != nil {
+			t.Fatalf("canonical form %q of %q does not parse: %v", canonical, input, err)
+		}
+		resql, revars := reparsed.SQL()
+		if resql != sql || !assert.ObjectsAreEqual(vars, revars) {
+			t.Fatalf("canonical form %q of %q parses to %s %v, not %s %v", canonical, input, resql, revars, sql, vars)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package hl7_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/hl7"
+	"hospital-project/internal/models"
+)
+
+const a04 = "MSH|^~\\&|REG|HOSP|EHR|HOSP|20261018120000||ADT^A04^ADT_A01|MSG0001|P|2.5.1\r" +
+	"EVN|A04|20261018120000\r" +
+	"PID|1||12345^^^HOSP^MR~999^^^SSA^SS||Doe^John^Q||19800315|M|||1 Main St^^Springfield||555-0100^PRN^PH\r" +
+	"PV1|1|O|CLINIC^101^A||||1234^House^Gregory||||||||||||V100\r"
+
+var now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	msg, err := hl7.Parse(a04)
+	require.NoError(t, err)
+
+	code, event := msg.Type()
+	assert.Equal(t, "ADT", code)
+	assert.Equal(t, "A04", event)
+	assert.Equal(t, "MSG0001", msg.ControlID())
+	assert.Len(t, msg.Segments, 4)
+	assert.Equal(t, "|", msg.Header().Field(1))
+	assert.Equal(t, `^~\&`, msg.Header().Field(2))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := hl7.Parse("PID|1||12345")
+	assert.Error(t, err)
+}
+
+func TestParse_Escapes(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|A|B|C|D|20260101||ADT^A08|1|P|2.5.1\rPID|1||1||O\\S\\Brien^Pat\\T\\Co")
+	require.NoError(t, err)
+
+	pid, ok := msg.Segment("PID")
+	require.True(t, ok)
+	assert.Equal(t, "O^Brien", pid.Component(5, 1))
+	assert.Equal(t, "Pat&Co", pid.Component(5, 2))
+}
+
+func TestParseADT(t *testing.T) {
+	msg, err := hl7.Parse(a04)
+	require.NoError(t, err)
+
+	adt, err := hl7.ParseADT(msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, hl7.Identifier{System: "HOSP", Value: "12345"}, adt.Identifier)
+	assert.Equal(t, "John Q Doe", adt.Name)
+	assert.Equal(t, models.GenderMale, adt.Gender)
+	assert.Equal(t, 46, adt.Age(now))
+	assert.Equal(t, "555-0100", adt.ContactInfo)
+	assert.Equal(t, "O", adt.Visit.PatientClass)
+	assert.Equal(t, "CLINIC-101-A", adt.Visit.Location)
+	assert.Equal(t, "Gregory House", adt.Visit.AttendingDoctor)
+	assert.Equal(t, "V100", adt.Visit.VisitNumber)
+}
+
+func TestParseADT_Merge(t *testing.T) {
+	raw := "MSH|^~\\&|REG|HOSP|EHR|HOSP|20261018120000||ADT^A40^ADT_A39|MSG0002|P|2.5.1\r" +
+		"PID|1||12345^^^HOSP^MR||Doe^John\r" +
+		"MRG|67890^^^HOSP^MR\r"
+	msg, err := hl7.Parse(raw)
+	require.NoError(t, err)
+
+	adt, err := hl7.ParseADT(msg)
+	require.NoError(t, err)
+	assert.Equal(t, hl7.Identifier{System: "HOSP", Value: "67890"}, adt.PriorIdentifier)
+
+	// MRG is mandatory for A40
+	msg, err = hl7.Parse(strings.Replace(raw, "MRG|67890^^^HOSP^MR\r", "", 1))
+	require.NoError(t, err)
+	_, err = hl7.ParseADT(msg)
+	assert.Error(t, err)
+}
+
+func TestBuildACK(t *testing.T) {
+	msg, err := hl7.Parse(a04)
+	require.NoError(t, err)
+
+	ack, err := hl7.Parse(hl7.BuildACK(msg, hl7.AckError, "name|missing", now))
+	require.NoError(t, err)
+
+	header := ack.Header()
+	assert.Equal(t, "EHR", header.Field(3))
+	assert.Equal(t, "REG", header.Field(5))
+	assert.Equal(t, "ACK^A04^ACK", header.Field(9))
+
+	msa, ok := ack.Segment("MSA")
+	require.True(t, ok)
+	assert.Equal(t, "AE", msa.Field(1))
+	assert.Equal(t, "MSG0001", msa.Field(2))
+	assert.Equal(t, "name|missing", msa.Component(3, 1))
+}
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, hl7.WriteFrame(&buf, []byte("first")))
+	require.NoError(t, hl7.WriteFrame(&buf, []byte("second")))
+
+	reader := bufio.NewReader(bytes.NewReader(append([]byte("noise"), buf.Bytes()...)))
+
+	frame, err := hl7.ReadFrame(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(frame))
+
+	frame, err = hl7.ReadFrame(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(frame))
+}
+
+func TestServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &hl7.Server{
+		Handler: hl7.HandlerFunc(func(ctx context.Context, raw []byte) []byte {
+			msg, err := hl7.Parse(string(raw))
+			if err != nil {
+				return []byte(hl7.BuildReject(err.Error(), now))
+			}
+			return []byte(hl7.BuildACK(msg, hl7.AckAccept, "", now))
+		}),
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(listener) }()
+
+	// Send two messages on one connection with a local MLLP client
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	require.NoError(t, hl7.WriteFrame(conn, []byte(a04)))
+	frame, err := hl7.ReadFrame(reader)
+	require.NoError(t, err)
+	assert.Contains(t, string(frame), "MSA|AA|MSG0001")
+
+	require.NoError(t, hl7.WriteFrame(conn, []byte("garbage")))
+	frame, err = hl7.ReadFrame(reader)
+	require.NoError(t, err)
+	assert.Contains(t, string(frame), "MSA|AR|")
+
+	require.NoError(t, server.Close())
+	assert.ErrorIs(t, <-done, hl7.ErrServerClosed)
+}
+
+func TestServer_ShutdownAcknowledgesInFlightMessages(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	server := &hl7.Server{
+		Handler: hl7.HandlerFunc(func(ctx context.Context, raw []byte) []byte {
+			close(handling)
+			<-release
+			msg, _ := hl7.Parse(string(raw))
+			return []byte(hl7.BuildACK(msg, hl7.AckAccept, "", now))
+		}),
+	}
+	go server.Serve(listener)
+
+	busy, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer busy.Close()
+	idle, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer idle.Close()
+
+	require.NoError(t, hl7.WriteFrame(busy, []byte(a04)))
+	<-handling
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- server.Shutdown(context.Background()) }()
+
+	// Idle connections are closed at once
+	_, err = hl7.ReadFrame(bufio.NewReader(idle))
+	assert.Error(t, err)
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before the message was handled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The message being handled is still acknowledged
+	close(release)
+	reader := bufio.NewReader(busy)
+	frame, err := hl7.ReadFrame(reader)
+	require.NoError(t, err)
+	assert.Contains(t, string(frame), "MSA|AA|MSG0001")
+	require.NoError(t, <-shutdown)
+
+	// and the connection is closed afterwards
+	_, err = hl7.ReadFrame(reader)
+	assert.Error(t, err)
+}
+
+func TestServer_ShutdownDeadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server := &hl7.Server{
+		Handler: hl7.HandlerFunc(func(ctx context.Context, raw []byte) []byte {
+			close(handling)
+			<-release
+			return nil
+		}),
+	}
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, hl7.WriteFrame(conn, []byte(a04)))
+	<-handling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+
+	// The connection is closed without an acknowledgment
+	_, err = hl7.ReadFrame(bufio.NewReader(conn))
+	assert.Error(t, err)
+}
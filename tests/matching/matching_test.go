@@ -0,0 +1,110 @@
+package matching_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"hospital-project/internal/matching"
+	"hospital-project/internal/models"
+)
+
+func date(value string) *time.Time {
+	parsed, err := models.ParseDate(value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func TestContactKeys(t *testing.T) {
+	tests := []struct {
+		contactInfo string
+		want        []string
+	}{
+		{"1234567890", []string{"1234567890"}},
+		{"+1 (123) 456-7890", []string{"1234567890"}},
+		{"555-0100", []string{"5550100"}},
+		{"John.Doe@Example.com", []string{"john.doe@example.com"}},
+		{"john@example.com, 123.456.7890 / 123 456 7890", []string{"john@example.com", "1234567890"}},
+		{"ext. 42", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contactInfo, func(t *testing.T) {
+			assert.Equal(t, tt.want, matching.ContactKeys(tt.contactInfo))
+		})
+	}
+	assert.Equal(t, "john@example.com 1234567890", matching.EncodeContactKeys("john@example.com; 123-456-7890"))
+}
+
+func TestScore(t *testing.T) {
+	john := &models.Patient{Name: "John Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "123-456-7890", BirthDate: date("1994-03-05"), Address: "12 High Street, Springfield"}
+
+	tests := []struct {
+		name     string
+		other    *models.Patient
+		min, max float64
+	}{
+		{
+			name:  "identical record",
+			other: &models.Patient{Name: "John Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "123-456-7890", BirthDate: date("1994-03-05"), Address: "12 High Street, Springfield"},
+			min:   1, max: 1,
+		},
+		{
+			name:  "typo in the name and formatted phone number",
+			other: &models.Patient{Name: "Jon Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "+1 (123) 456 7890", BirthDate: date("1994-03-05")},
+			min:   0.9, max: 1,
+		},
+		{
+			name:  "day and month swapped",
+			other: &models.Patient{Name: "John Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "1234567890", BirthDate: date("1994-05-03")},
+			min:   0.9, max: 1,
+		},
+		{
+			name:  "new contact info",
+			other: &models.Patient{Name: "John Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "john@example.com", BirthDate: date("1994-03-05")},
+			min:   0.7, max: 0.9,
+		},
+		{
+			name:  "namesake",
+			other: &models.Patient{Name: "John Doe", Age: 58, Gender: models.GenderMale, ContactInfo: "987-654-3210", BirthDate: date("1966-11-20"), Address: "3 Mill Lane, Shelbyville"},
+			min:   0, max: 0.5,
+		},
+		{
+			name:  "sibling sharing a phone number",
+			other: &models.Patient{Name: "Mary Doe", Age: 27, Gender: models.GenderFemale, ContactInfo: "123-456-7890", BirthDate: date("1997-08-14"), Address: "12 High Street, Springfield"},
+			min:   0, max: 0.7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := matching.Score(john, tt.other)
+			assert.GreaterOrEqual(t, result.Score, tt.min)
+			assert.LessOrEqual(t, result.Score, tt.max)
+
+			// The score does not depend on which patient is registered first
+			assert.Equal(t, result, matching.Score(tt.other, john))
+		})
+	}
+}
+
+func TestScore_Features(t *testing.T) {
+	a := &models.Patient{Name: "Catherine Price", Age: 40, Gender: models.GenderFemale, ContactInfo: "cathy@example.com"}
+	b := &models.Patient{Name: "Kathryn Price", Age: 41, Gender: models.GenderFemale, ContactInfo: "CATHY@example.com", Address: "1 Main St"}
+
+	result := matching.Score(a, b)
+
+	// Names that sound alike score high, ages a year apart score half and
+	// features only one patient has are not compared
+	assert.InDelta(t, 0.95, result.Features[matching.FeatureName], 0.001)
+	assert.Equal(t, 0.5, result.Features[matching.FeatureAge])
+	assert.Equal(t, 1.0, result.Features[matching.FeatureContact])
+	assert.Equal(t, 1.0, result.Features[matching.FeatureGender])
+	assert.NotContains(t, result.Features, matching.FeatureBirthDate)
+	assert.NotContains(t, result.Features, matching.FeatureAddress)
+	assert.Greater(t, result.Score, 0.85)
+}
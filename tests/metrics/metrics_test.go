@@ -0,0 +1,97 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/metrics"
+	"hospital-project/internal/middleware"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// scrape returns the metrics exposition served by m
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetricsMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	m := metrics.New()
+	router := gin.New()
+	router.Use(middleware.Metrics(m))
+	router.GET("/api/patients/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/api/patients/1", "/api/patients/2", "/unknown/3"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `hospital_http_request_duration_seconds_count{method="GET",route="/api/patients/:id",status="204"} 2`)
+	assert.Contains(t, body, `hospital_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, "/api/patients/1")
+	assert.NotContains(t, body, "/unknown/3")
+}
+
+func TestMetrics_RecordLogin(t *testing.T) {
+	m := metrics.New()
+	m.RecordLogin(metrics.LoginSuccess)
+	m.RecordLogin(metrics.LoginFailure)
+	m.RecordLogin(metrics.LoginFailure)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `hospital_auth_login_attempts_total{result="success"} 1`)
+	assert.Contains(t, body, `hospital_auth_login_attempts_total{result="failure"} 2`)
+	assert.Contains(t, body, `hospital_auth_login_attempts_total{result="error"} 0`)
+}
+
+// fakePatientCounter returns a fixed count and records the requested start
+type fakePatientCounter struct {
+	count int64
+	err   error
+	since time.Time
+}
+
+func (f *fakePatientCounter) CountCreatedSince(ctx context.Context, since time.Time) (int64, error) {
+	f.since = since
+	return f.count, f.err
+}
+
+func TestBusinessCollector_RegisteredToday(t *testing.T) {
+	counter := &fakePatientCounter{count: 7}
+	m := metrics.New()
+	m.Register(metrics.NewBusinessCollector(counter))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, "hospital_patients_registered_today 7")
+
+	now := time.Now()
+	assert.Equal(t, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), counter.since)
+}
+
+func TestBusinessCollector_QueryFailure(t *testing.T) {
+	m := metrics.New()
+	m.Register(metrics.NewBusinessCollector(&fakePatientCounter{err: errors.New("database is down")}))
+
+	// A failing gauge is left out rather than reported as zero, and the
+	// other metrics are still served
+	body := scrape(t, m)
+	assert.NotContains(t, body, "hospital_patients_registered_today ")
+	assert.Contains(t, body, "hospital_auth_login_attempts_total")
+}
@@ -0,0 +1,188 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/config"
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/models"
+	"hospital-project/internal/services"
+)
+
+// memoryIdempotencyRepository keeps idempotency keys in memory
+type memoryIdempotencyRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	keys   map[uint]*models.IdempotencyKey
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{keys: make(map[uint]*models.IdempotencyKey)}
+}
+
+func (r *memoryIdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyKey) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.keys {
+		if existing.UserID == record.UserID && existing.Key == record.Key {
+			return false, nil
+		}
+	}
+	r.nextID++
+	record.ID = r.nextID
+	stored := *record
+	r.keys[record.ID] = &stored
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) FindByKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.keys {
+		if existing.UserID == userID && existing.Key == key {
+			record := *existing
+			return &record, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *record
+	r.keys[record.ID] = &stored
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, id)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for id, existing := range r.keys {
+		if existing.ExpiresAt.Before(now) {
+			delete(r.keys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// setupRouter returns a router whose POST /api/patients handler counts its
+// calls and answers with the given status
+func setupRouter(status int, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	idempotencyService := services.NewIdempotencyService(newMemoryIdempotencyRepository(), &config.Idempotency{TTL: time.Hour, CleanupInterval: time.Hour})
+	idempotency := middleware.NewIdempotencyMiddleware(idempotencyService)
+
+	router := gin.New()
+	router.POST("/api/patients", func(c *gin.Context) {
+		c.Set("user", &models.User{Model: gorm.Model{ID: 1}, Role: models.RoleReceptionist})
+	}, idempotency.Handle(), func(c *gin.Context) {
+		*calls++
+		c.Header("Location", "/api/patients/1")
+		c.JSON(status, gin.H{"call": *calls})
+	})
+	return router
+}
+
+func post(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/patients", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyMiddleware_ReplaysResponse(t *testing.T) {
+	calls := 0
+	router := setupRouter(http.StatusCreated, &calls)
+
+	first := post(router, "key-1", `{"name":"John"}`)
+	retry := post(router, "key-1", `{"name":"John"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "/api/patients/1", retry.Header().Get("Location"))
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+}
+
+func TestIdempotencyMiddleware_KeyReusedWithDifferentBody(t *testing.T) {
+	calls := 0
+	router := setupRouter(http.StatusCreated, &calls)
+
+	post(router, "key-1", `{"name":"John"}`)
+	w := post(router, "key-1", `{"name":"Jane"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "idempotency_key_reused")
+}
+
+func TestIdempotencyMiddleware_WithoutKey(t *testing.T) {
+	calls := 0
+	router := setupRouter(http.StatusCreated, &calls)
+
+	post(router, "", `{"name":"John"}`)
+	post(router, "", `{"name":"John"}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_ServerErrorIsNotStored(t *testing.T) {
+	calls := 0
+	router := setupRouter(http.StatusInternalServerError, &calls)
+
+	post(router, "key-1", `{"name":"John"}`)
+	post(router, "key-1", `{"name":"John"}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_RedactsStoredResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := newMemoryIdempotencyRepository()
+	idempotencyService := services.NewIdempotencyService(repo, &config.Idempotency{TTL: time.Hour, CleanupInterval: time.Hour})
+	idempotency := middleware.NewIdempotencyMiddleware(idempotencyService)
+
+	router := gin.New()
+	router.POST("/api/patients", func(c *gin.Context) {
+		c.Set("user", &models.User{Model: gorm.Model{ID: 1}, Role: models.RoleReceptionist})
+	}, idempotency.Handle("secret"), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 1, "secret": "shown-once"})
+	})
+
+	first := post(router, "key-1", `{"url":"https://example.com"}`)
+	retry := post(router, "key-1", `{"url":"https://example.com"}`)
+
+	assert.Contains(t, first.Body.String(), "shown-once")
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.JSONEq(t, `{"id":1}`, retry.Body.String())
+	for _, record := range repo.keys {
+		assert.NotContains(t, string(record.ResponseBody), "shown-once")
+	}
+}
@@ -0,0 +1,109 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/models"
+	"hospital-project/internal/ratelimit"
+)
+
+// failingStore is a rate limit store whose database is down
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+// setupRateLimitedRouter returns a router whose GET /limited route allows
+// two requests a minute. Requests with an X-User header are authenticated
+// as that user ID.
+func setupRateLimitedRouter(store ratelimit.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	limiter := middleware.NewRateLimiter(store, map[string]ratelimit.Limit{
+		middleware.RateLimitDefault: ratelimit.PerPeriod(2, time.Minute),
+	})
+
+	router := gin.New()
+	router.GET("/limited", func(c *gin.Context) {
+		switch c.GetHeader("X-User") {
+		case "1":
+			c.Set("user", &models.User{Model: gorm.Model{ID: 1}})
+		case "2":
+			c.Set("user", &models.User{Model: gorm.Model{ID: 2}})
+		}
+	}, limiter.Limit(middleware.RateLimitDefault), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func get(router *gin.Engine, user, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	req.RemoteAddr = ip + ":1234"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_LimitsPerUser(t *testing.T) {
+	router := setupRateLimitedRouter(ratelimit.NewMemoryStore())
+
+	first := get(router, "1", "10.0.0.1")
+	assert.Equal(t, http.StatusNoContent, first.Code)
+	assert.Equal(t, "2", first.Header().Get(middleware.RateLimitLimitHeader))
+	assert.Equal(t, "1", first.Header().Get(middleware.RateLimitRemainingHeader))
+	assert.Equal(t, "30", first.Header().Get(middleware.RateLimitResetHeader))
+	assert.Equal(t, "2;w=60", first.Header().Get(middleware.RateLimitPolicyHeader))
+
+	// The same user is limited from another IP
+	assert.Equal(t, http.StatusNoContent, get(router, "1", "10.0.0.2").Code)
+	denied := get(router, "1", "10.0.0.3")
+	assert.Equal(t, http.StatusTooManyRequests, denied.Code)
+	assert.Equal(t, "30", denied.Header().Get("Retry-After"))
+	assert.Equal(t, "0", denied.Header().Get(middleware.RateLimitRemainingHeader))
+	assert.Contains(t, denied.Body.String(), "rate_limited")
+
+	// Another user on the same IP is not
+	assert.Equal(t, http.StatusNoContent, get(router, "2", "10.0.0.1").Code)
+}
+
+func TestRateLimiter_LimitsAnonymousPerIP(t *testing.T) {
+	router := setupRateLimitedRouter(ratelimit.NewMemoryStore())
+
+	get(router, "", "10.0.0.1")
+	get(router, "", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, get(router, "", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusNoContent, get(router, "", "10.0.0.2").Code)
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	router := setupRateLimitedRouter(nil)
+
+	for i := 0; i < 5; i++ {
+		w := get(router, "1", "10.0.0.1")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get(middleware.RateLimitLimitHeader))
+	}
+}
+
+func TestRateLimiter_FailsOpen(t *testing.T) {
+	router := setupRateLimitedRouter(failingStore{})
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusNoContent, get(router, "1", "10.0.0.1").Code)
+	}
+}
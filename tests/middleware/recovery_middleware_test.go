@@ -0,0 +1,44 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"hospital-project/internal/middleware"
+	"hospital-project/internal/problem"
+)
+
+func TestRecovery_AnswersPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/api/patients", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/patients", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.NotContains(t, w.Body.String(), "boom")
+}
+
+func TestRecovery_PassesAbortToServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/api/patients/export", func(c *gin.Context) {
+		c.String(http.StatusOK, "id,name\n")
+		panic(http.ErrAbortHandler)
+	})
+
+	// net/http resets the connection instead of completing the response
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/patients/export", nil))
+	})
+}
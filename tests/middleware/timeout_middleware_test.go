@@ -0,0 +1,52 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/middleware"
+)
+
+// deadlineOf serves a request to path and returns how far away the
+// handler's context deadline was
+func deadlineOf(t *testing.T, router *gin.Engine, path string) (time.Duration, bool) {
+	var remaining time.Duration
+	var ok bool
+	router.GET(path, func(c *gin.Context) {
+		var deadline time.Time
+		deadline, ok = c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	return remaining, ok
+}
+
+func TestTimeout_SetsDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Timeout(time.Second, map[string]time.Duration{"/api/patients/export": time.Hour}))
+
+	remaining, ok := deadlineOf(t, router, "/api/patients")
+	require.True(t, ok)
+	assert.InDelta(t, time.Second, remaining, float64(100*time.Millisecond))
+
+	remaining, ok = deadlineOf(t, router, "/api/patients/export")
+	require.True(t, ok)
+	assert.InDelta(t, time.Hour, remaining, float64(time.Second))
+}
+
+func TestTimeout_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Timeout(0, nil))
+
+	_, ok := deadlineOf(t, router, "/api/patients")
+	assert.False(t, ok)
+}
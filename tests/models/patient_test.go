@@ -0,0 +1,88 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/models"
+)
+
+func TestPatientMatch_ToResult(t *testing.T) {
+	match := &models.PatientMatch{
+		Patient: models.Patient{Model: gorm.Model{ID: 7}, Name: "John Doe", MedicalNotes: "On warfarin"},
+		Rank:    0.25,
+		Snippet: "<b>INR</b> stable on " + models.SnippetStart + "warfarin" + models.SnippetStop + " & aspirin",
+	}
+
+	result := match.ToResult()
+
+	assert.Equal(t, uint(7), result.ID)
+	assert.Equal(t, float32(0.25), result.Rank)
+	assert.Equal(t, "&lt;b&gt;INR&lt;/b&gt; stable on <mark>warfarin</mark> &amp; aspirin", result.Snippet)
+}
+
+func TestPatientDocument_ApplyTo(t *testing.T) {
+	patient := &models.Patient{Name: "John Doe", Address: "1 Main St"}
+	document := patient.ToDocument()
+	assert.Empty(t, document.BirthDate)
+
+	age := 30
+	document.Age = &age
+	document.BirthDate = "1994-03-05"
+	document.Address = ""
+	document.ApplyTo(patient)
+
+	assert.Equal(t, "1994-03-05", models.FormatDate(patient.BirthDate))
+	assert.Empty(t, patient.Address)
+	assert.Equal(t, "1994-03-05", patient.ToResponse().BirthDate)
+}
+
+func TestDuplicateMatch_ToResponse(t *testing.T) {
+	match := &models.DuplicateMatch{
+		Patient:  models.Patient{Model: gorm.Model{ID: 7}, Name: "John Doe", MedicalNotes: "On warfarin"},
+		Score:    0.93,
+		Features: map[string]float64{"name": 0.95},
+	}
+
+	// Candidates are shown to whoever registers a patient, so notes are
+	// only included for doctors
+	assert.Empty(t, match.ToResponse(models.RoleReceptionist).Patient.MedicalNotes)
+	assert.Equal(t, "On warfarin", match.ToResponse(models.RoleDoctor).Patient.MedicalNotes)
+	assert.Equal(t, uint(7), match.ToResponse(models.RoleReceptionist).Patient.ID)
+}
+
+func TestCanReadMedicalNotes(t *testing.T) {
+	assert.True(t, models.CanReadMedicalNotes(models.RoleDoctor))
+	assert.False(t, models.CanReadMedicalNotes(models.RoleReceptionist))
+}
+
+func TestPatientSearchRequest_SimilarityThreshold(t *testing.T) {
+	assert.Equal(t, models.DefaultSimilarityThreshold, (&models.PatientSearchRequest{}).SimilarityThreshold())
+	assert.Equal(t, 0.8, (&models.PatientSearchRequest{Threshold: 0.8}).SimilarityThreshold())
+
+	assert.False(t, (&models.PatientSearchRequest{Gender: models.GenderMale}).Fuzzy())
+	assert.True(t, (&models.PatientSearchRequest{ContactInfo: "555"}).Fuzzy())
+}
+
+func TestPatientSearchRequest_Phonetic(t *testing.T) {
+	assert.True(t, (&models.PatientSearchRequest{Name: "Kathryn", Match: models.MatchPhonetic}).Phonetic())
+	assert.False(t, (&models.PatientSearchRequest{Name: "Kathryn"}).Phonetic())
+	assert.False(t, (&models.PatientSearchRequest{Match: models.MatchPhonetic}).Phonetic())
+}
+
+func TestPatientSearchRequest_ParseFilter(t *testing.T) {
+	parsed, err := (&models.PatientSearchRequest{}).ParseFilter()
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+
+	parsed, err = (&models.PatientSearchRequest{Filter: "age >= 65 and birth_date = null"}).ParseFilter()
+	assert.NoError(t, err)
+	sql, _ := parsed.SQL()
+	assert.Equal(t, "(age >= ? AND birth_date IS NULL)", sql)
+
+	// Medical notes are not filterable
+	_, err = (&models.PatientSearchRequest{Filter: "medical_notes ~ asthma"}).ParseFilter()
+	assert.Error(t, err)
+}
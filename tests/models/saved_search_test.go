@@ -0,0 +1,19 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hospital-project/internal/models"
+)
+
+func TestSavedSearch_VisibleTo(t *testing.T) {
+	private := &models.SavedSearch{OwnerID: 1}
+	assert.True(t, private.VisibleTo(1, models.RoleDoctor))
+	assert.False(t, private.VisibleTo(2, models.RoleDoctor))
+
+	shared := &models.SavedSearch{OwnerID: 1, SharedWith: models.RoleDoctor}
+	assert.True(t, shared.VisibleTo(2, models.RoleDoctor))
+	assert.False(t, shared.VisibleTo(3, models.RoleReceptionist))
+}
@@ -0,0 +1,174 @@
+package pagination_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/pagination"
+)
+
+type row struct {
+	Name string
+	ID   uint
+}
+
+var byID = pagination.Ordering[row]{
+	{
+		Name:  "id",
+		Key:   func(r *row) string { return strconv.FormatUint(uint64(r.ID), 10) },
+		Parse: pagination.ParseUint,
+	},
+}
+
+var byNameDescThenID = pagination.Ordering[row]{
+	{
+		Name:       "name",
+		Descending: true,
+		Key:        func(r *row) string { return r.Name },
+		Parse:      func(key string) (interface{}, error) { return key, nil },
+	},
+	byID[0],
+}
+
+func rows(ids ...uint) []row {
+	result := make([]row, len(ids))
+	for i, id := range ids {
+		result[i] = row{ID: id}
+	}
+	return result
+}
+
+func TestNewRequest_Clamps(t *testing.T) {
+	tests := []struct {
+		number, limit         int
+		wantNumber, wantLimit int
+	}{
+		{0, 0, 1, pagination.DefaultLimit},
+		{-3, 500, 1, pagination.MaxLimit},
+		{4, 25, 4, 25},
+	}
+	for _, tt := range tests {
+		request, err := pagination.NewRequest(tt.number, tt.limit, "", false)
+		require.NoError(t, err)
+		assert.Equal(t, tt.wantNumber, request.Number)
+		assert.Equal(t, tt.wantLimit, request.Limit)
+		assert.True(t, request.Counted())
+	}
+}
+
+func TestNewRequest_InvalidCursor(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		_, err := pagination.NewRequest(1, 10, cursor, false)
+		appErr, ok := apperror.As(err)
+		require.True(t, ok, cursor)
+		assert.Equal(t, apperror.CodeInvalidCursor, appErr.Code)
+	}
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor := &pagination.Cursor{Sort: "-name,id", Keys: []string{"Doe, John", "7"}, Backward: true}
+
+	request, err := pagination.NewRequest(1, 10, cursor.Encode(), false)
+	require.NoError(t, err)
+	assert.Equal(t, cursor, request.Cursor)
+	assert.False(t, request.Counted())
+	assert.Zero(t, request.Offset())
+}
+
+func TestOrdering_OrderBy(t *testing.T) {
+	assert.Equal(t, "name DESC, id", byNameDescThenID.OrderBy(false))
+	assert.Equal(t, "name, id DESC", byNameDescThenID.OrderBy(true))
+	assert.Equal(t, "-name,id", byNameDescThenID.String())
+}
+
+func TestOrdering_Seek(t *testing.T) {
+	condition, args, err := byID.Seek(&pagination.Cursor{Sort: "id", Keys: []string{"5"}})
+	require.NoError(t, err)
+	assert.Equal(t, "(id) > (?)", condition)
+	assert.Equal(t, []interface{}{uint64(5)}, args)
+
+	condition, args, err = byNameDescThenID.Seek(&pagination.Cursor{Sort: "-name,id", Keys: []string{"Doe", "5"}, Backward: true})
+	require.NoError(t, err)
+	assert.Equal(t, "((name > ?) OR (name = ? AND id < ?))", condition)
+	assert.Equal(t, []interface{}{"Doe", "Doe", uint64(5)}, args)
+
+	// A cursor from another ordering is rejected
+	_, _, err = byID.Seek(&pagination.Cursor{Sort: "-name,id", Keys: []string{"Doe", "5"}})
+	assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+
+	_, _, err = byID.Seek(&pagination.Cursor{Sort: "id", Keys: []string{"five"}})
+	assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+}
+
+func TestOrdering_Page(t *testing.T) {
+	total := int64(5)
+
+	// The first page by number has only a next page
+	page := byID.Page(pagination.Request{Number: 1, Limit: 2}, rows(1, 2, 3), &total)
+	assert.Equal(t, rows(1, 2), page.Items)
+	assert.Equal(t, 3, page.TotalPages())
+	require.NotNil(t, page.Next)
+	assert.Equal(t, []string{"2"}, page.Next.Keys)
+	assert.Nil(t, page.Prev)
+
+	// A page reached by cursor leads back to where it came from
+	page = byID.Page(pagination.Request{Limit: 2, Cursor: page.Next}, rows(3, 4), nil)
+	assert.Equal(t, rows(3, 4), page.Items)
+	assert.Zero(t, page.Number)
+	assert.Nil(t, page.Next)
+	require.NotNil(t, page.Prev)
+	assert.Equal(t, []string{"3"}, page.Prev.Keys)
+	assert.True(t, page.Prev.Backward)
+
+	// Rows fetched backward are returned in order
+	page = byID.Page(pagination.Request{Limit: 2, Cursor: page.Prev}, rows(2, 1), nil)
+	assert.Equal(t, rows(1, 2), page.Items)
+	assert.Nil(t, page.Prev)
+	require.NotNil(t, page.Next)
+	assert.Equal(t, []string{"2"}, page.Next.Keys)
+	assert.False(t, page.Next.Backward)
+
+	// An empty page past the end leads back
+	past := &pagination.Cursor{Sort: "id", Keys: []string{"9"}}
+	page = byID.Page(pagination.Request{Limit: 2, Cursor: past}, nil, nil)
+	assert.Empty(t, page.Items)
+	assert.Nil(t, page.Next)
+	require.NotNil(t, page.Prev)
+	assert.True(t, page.Prev.Backward)
+}
+
+func TestParseSort(t *testing.T) {
+	allowed := []string{"id", "name", "created_at"}
+
+	keys, err := pagination.ParseSort("name, -created_at", allowed)
+	require.NoError(t, err)
+	assert.Equal(t, []pagination.SortKey{{Field: "name"}, {Field: "created_at", Descending: true}}, keys)
+
+	keys, err = pagination.ParseSort("", allowed)
+	require.NoError(t, err)
+	assert.Nil(t, keys)
+
+	for _, invalid := range []string{"age", "name,name", "name;drop table patients", "-", "name,"} {
+		_, err := pagination.ParseSort(invalid, allowed)
+		appErr, ok := apperror.As(err)
+		require.True(t, ok, invalid)
+		assert.Equal(t, "sort", appErr.Fields[0].Field)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	columns := map[string]pagination.Column[row]{"id": byID[0], "name": byNameDescThenID[0]}
+
+	ordering := pagination.Order([]pagination.SortKey{{Field: "name", Descending: true}}, columns, "id", byID)
+	assert.Equal(t, "-name,id", ordering.String())
+
+	// The unique column ends the ordering wherever it appears
+	ordering = pagination.Order([]pagination.SortKey{{Field: "id", Descending: true}, {Field: "name"}}, columns, "id", byID)
+	assert.Equal(t, "-id", ordering.String())
+
+	assert.Equal(t, "id", pagination.Order(nil, columns, "id", byID).String())
+}
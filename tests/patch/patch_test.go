@@ -0,0 +1,171 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/patch"
+)
+
+const patientDoc = `{"name":"John Doe","age":30,"gender":"male","contact_info":"555-0100","medical_notes":"Asthma"}`
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"array is replaced", `{"a":["b"]}`, `{"a":["c","d"]}`, `{"a":["c","d"]}`},
+		{"nested merge", `{"a":{"b":"c","d":"e"}}`, `{"a":{"d":null,"f":1}}`, `{"a":{"b":"c","f":1}}`},
+		{"non-object patch replaces", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"zero is kept", `{"age":30}`, `{"age":0}`, `{"age":0}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := patch.MergePatch([]byte(tt.doc), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestMergePatch_InvalidPatch(t *testing.T) {
+	_, err := patch.MergePatch([]byte(patientDoc), []byte(`{"name":`))
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	assert.Equal(t, apperror.CodeInvalidPatch, appErr.Code)
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"append to array", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"baz"}]`, `{"foo":["bar","baz"]}`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"replace with null", `{"baz":"qux"}`, `[{"op":"replace","path":"/baz","value":null}]`, `{"baz":null}`},
+		{"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`},
+		{"test passes", `{"baz":"qux","n":1}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/n","value":1.0}]`, `{"baz":"qux","n":1}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`},
+		{"replace root", `{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := patch.JSONPatch([]byte(tt.doc), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		kind  apperror.Kind
+		code  string
+	}{
+		{"not an array", `{"op":"remove","path":"/name"}`, apperror.KindValidation, apperror.CodeInvalidPatch},
+		{"unknown op", `[{"op":"delete","path":"/name"}]`, apperror.KindValidation, apperror.CodeInvalidPatch},
+		{"missing value", `[{"op":"add","path":"/name"}]`, apperror.KindValidation, apperror.CodeInvalidPatch},
+		{"missing path", `[{"op":"remove","path":"/missing"}]`, apperror.KindValidation, apperror.CodeInvalidPatch},
+		{"invalid pointer", `[{"op":"remove","path":"name"}]`, apperror.KindValidation, apperror.CodeInvalidPatch},
+		{"move into child", `[{"op":"move","from":"","path":"/name"}]`, apperror.KindValidation, apperror.CodeInvalidPatch},
+		{"failed test", `[{"op":"test","path":"/age","value":31}]`, apperror.KindConflict, apperror.CodePatchTestFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := patch.JSONPatch([]byte(patientDoc), []byte(tt.patch))
+			appErr, ok := apperror.As(err)
+			require.True(t, ok)
+			assert.Equal(t, tt.kind, appErr.Kind)
+			assert.Equal(t, tt.code, appErr.Code)
+		})
+	}
+}
+
+func TestJSONPatch_IsAtomic(t *testing.T) {
+	// The first operation must not be visible when a later one fails
+	doc := []byte(patientDoc)
+	_, err := patch.JSONPatch(doc, []byte(`[{"op":"replace","path":"/name","value":"Jane"},{"op":"test","path":"/age","value":99}]`))
+	assert.Error(t, err)
+	assert.JSONEq(t, patientDoc, string(doc))
+}
+
+func TestApply_UnsupportedMediaType(t *testing.T) {
+	_, err := patch.Apply("application/json", []byte(patientDoc), []byte(`{}`))
+	assert.Equal(t, apperror.KindUnsupportedMediaType, apperror.KindOf(err))
+}
+
+func TestPatientDocument_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		valid bool
+	}{
+		{"clear contact info and notes", `{"contact_info":null,"medical_notes":null}`, true},
+		{"newborn", `{"age":0}`, true},
+		{"clear name", `{"name":null}`, false},
+		{"clear age", `{"age":null}`, false},
+		{"invalid gender", `{"gender":"unknown"}`, false},
+		{"age out of range", `{"age":151}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, err := patch.MergePatch([]byte(patientDoc), []byte(tt.patch))
+			require.NoError(t, err)
+
+			var document models.PatientDocument
+			require.NoError(t, json.Unmarshal(patched, &document))
+			err = binding.Validator.ValidateStruct(&document)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPatientDocument_ApplyTo(t *testing.T) {
+	patient := &models.Patient{Name: "John Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "555-0100", MedicalNotes: "Asthma"}
+
+	patched, err := patch.MergePatch(mustMarshal(t, patient.ToDocument()), []byte(`{"age":0,"medical_notes":null}`))
+	require.NoError(t, err)
+
+	var document models.PatientDocument
+	require.NoError(t, json.Unmarshal(patched, &document))
+	document.ApplyTo(patient)
+
+	assert.Equal(t, 0, patient.Age)
+	assert.Equal(t, "", patient.MedicalNotes)
+	assert.Equal(t, "555-0100", patient.ContactInfo)
+}
+
+func mustMarshal(t *testing.T, value interface{}) []byte {
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	return data
+}
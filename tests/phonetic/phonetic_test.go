@@ -0,0 +1,69 @@
+package phonetic_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"hospital-project/internal/phonetic"
+)
+
+func TestDoubleMetaphone(t *testing.T) {
+	tests := []struct {
+		word      string
+		primary   string
+		alternate string
+	}{
+		{"Catherine", "K0RN", "KTRN"},
+		{"Smith", "SM0", "XMT"},
+		{"Schmidt", "XMT", "SMT"},
+		{"Michael", "MKL", "MXL"},
+		{"Thomas", "TMS", "TMS"},
+		{"Knight", "NT", "NT"},
+		{"Arnow", "ARN", "ARNF"},
+		{"Caesar", "SSR", "SSR"},
+		{"Czerny", "SRN", "XRN"},
+		{"Filipowicz", "FLPT", "FLPF"},
+		{"Breaux", "PR", "PR"},
+		{"Jose", "HS", "HS"},
+		{"Peña", "PN", "PN"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			primary, alternate := phonetic.DoubleMetaphone(tt.word)
+			assert.Equal(t, tt.primary, primary)
+			assert.Equal(t, tt.alternate, alternate)
+		})
+	}
+}
+
+func TestKeys_SoundAlike(t *testing.T) {
+	pairs := [][2]string{
+		{"Catherine", "Kathryn"},
+		{"Smith", "Schmidt"},
+		{"Schneider", "Snider"},
+		{"Philip", "Filip"},
+		{"Jackson", "Jaxon"},
+		{"Wasserman", "Vasserman"},
+		{"José Álvarez", "Jose Alvarez"},
+	}
+
+	for _, pair := range pairs {
+		t.Run(pair[0]+"/"+pair[1], func(t *testing.T) {
+			assert.Subset(t, phonetic.Keys(pair[0]), phonetic.Keys(pair[1])[:1])
+		})
+	}
+}
+
+func TestWords(t *testing.T) {
+	assert.Equal(t, []string{"JOSE", "ALVAREZ", "OBRIEN"}, phonetic.Words("José Álvarez-O'Brien"))
+	assert.Equal(t, []string{"FRANÇOISE", "PEÑA"}, phonetic.Words("Françoise  Peña"))
+	assert.Empty(t, phonetic.Words(" - "))
+}
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, "K0RN KTRN PRS", phonetic.Encode("Catherine Price"))
+	assert.Equal(t, "", phonetic.Encode(""))
+}
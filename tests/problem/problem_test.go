@@ -0,0 +1,156 @@
+package problem_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/problem"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serve runs handler for a single request and decodes the problem response
+func serve(t *testing.T, body string, handler gin.HandlerFunc) (*httptest.ResponseRecorder, problem.Problem) {
+	router := gin.New()
+	router.POST("/api/patients", handler)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/patients", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, request)
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &p))
+	return recorder, p
+}
+
+func TestWrite_MapsKindsToStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{apperror.Validation(apperror.CodeInvalidID, "invalid patient ID"), http.StatusBadRequest, apperror.CodeInvalidID},
+		{apperror.NotFound(apperror.CodePatientNotFound, "patient not found"), http.StatusNotFound, apperror.CodePatientNotFound},
+		{apperror.Conflict(apperror.CodePatientDuplicate, "duplicate"), http.StatusConflict, apperror.CodePatientDuplicate},
+		{apperror.Forbidden(apperror.CodeForbidden, "forbidden"), http.StatusForbidden, apperror.CodeForbidden},
+		{apperror.Unauthorized(apperror.CodeInvalidToken, "invalid token"), http.StatusUnauthorized, apperror.CodeInvalidToken},
+		{apperror.New(apperror.KindUnavailable, apperror.CodeNotReady, "not ready"), http.StatusServiceUnavailable, apperror.CodeNotReady},
+		{apperror.New(apperror.KindTooManyRequests, apperror.CodeRateLimited, "slow down"), http.StatusTooManyRequests, apperror.CodeRateLimited},
+	}
+
+	for _, tt := range tests {
+		recorder, p := serve(t, "", func(ctx *gin.Context) { problem.Write(ctx, tt.err) })
+
+		assert.Equal(t, tt.status, recorder.Code)
+		assert.Equal(t, problem.ContentType, recorder.Header().Get("Content-Type"))
+		assert.Equal(t, tt.status, p.Status)
+		assert.Equal(t, tt.code, p.Code)
+		assert.Equal(t, tt.err.Error(), p.Detail)
+		assert.Equal(t, "/api/patients", p.Instance)
+	}
+}
+
+func TestWrite_WrappedError(t *testing.T) {
+	err := apperror.NotFound(apperror.CodePatientNotFound, "patient not found")
+	recorder, p := serve(t, "", func(ctx *gin.Context) {
+		problem.Write(ctx, errors.Join(errors.New("lookup failed"), err))
+	})
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, apperror.CodePatientNotFound, p.Code)
+}
+
+func TestWrite_HidesInternalErrors(t *testing.T) {
+	recorder, p := serve(t, "", func(ctx *gin.Context) {
+		problem.Write(ctx, errors.New("pq: connection refused"))
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, apperror.CodeInternal, p.Code)
+	assert.NotContains(t, recorder.Body.String(), "connection refused")
+}
+
+func TestWrite_Timeouts(t *testing.T) {
+	tests := []error{
+		fmt.Errorf("find patient: %w", context.DeadlineExceeded),
+		&pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"},
+	}
+
+	for _, err := range tests {
+		recorder, p := serve(t, "", func(ctx *gin.Context) { problem.Write(ctx, err) })
+
+		assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+		assert.Equal(t, apperror.CodeRequestTimeout, p.Code)
+	}
+}
+
+func TestWrite_ClientDisconnected(t *testing.T) {
+	recorder, p := serve(t, "", func(ctx *gin.Context) {
+		problem.Write(ctx, fmt.Errorf("list patients: %w", context.Canceled))
+	})
+
+	assert.Equal(t, problem.StatusClientClosedRequest, recorder.Code)
+	assert.Equal(t, apperror.CodeClientClosed, p.Code)
+}
+
+func TestBinding_FieldErrors(t *testing.T) {
+	recorder, p := serve(t, `{"name":"","age":-1,"gender":"male"}`, func(ctx *gin.Context) {
+		var request models.CreatePatientRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problem.Write(ctx, problem.Binding(err))
+		}
+	})
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, apperror.CodeValidationFailed, p.Code)
+
+	fields := make(map[string]string)
+	for _, fieldErr := range p.Errors {
+		fields[fieldErr.Field] = fieldErr.Code
+	}
+	assert.Equal(t, "required", fields["name"])
+	assert.Equal(t, "min", fields["age"])
+	assert.Equal(t, "required", fields["contact_info"])
+	assert.NotContains(t, fields, "gender")
+}
+
+func TestBinding_MalformedBody(t *testing.T) {
+	recorder, p := serve(t, `{"name":`, func(ctx *gin.Context) {
+		var request models.CreatePatientRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problem.Write(ctx, problem.Binding(err))
+		}
+	})
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, apperror.CodeMalformedBody, p.Code)
+}
+
+func TestBinding_WrongType(t *testing.T) {
+	_, p := serve(t, `{"name":"John","age":"old"}`, func(ctx *gin.Context) {
+		var request models.CreatePatientRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			problem.Write(ctx, problem.Binding(err))
+		}
+	})
+
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "age", p.Errors[0].Field)
+	assert.Equal(t, "type", p.Errors[0].Code)
+}
@@ -0,0 +1,83 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/ratelimit"
+)
+
+func TestParse(t *testing.T) {
+	limit, err := ratelimit.Parse("120/1m")
+	require.NoError(t, err)
+	assert.Equal(t, 120, limit.Burst)
+	assert.InDelta(t, 2.0, limit.Rate, 1e-9)
+	assert.Equal(t, time.Minute, limit.Window())
+
+	for _, invalid := range []string{"", "120", "0/1m", "x/1m", "10/soon", "10/-1s"} {
+		_, err := ratelimit.Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBucket_Take(t *testing.T) {
+	limit := ratelimit.PerPeriod(3, 3*time.Second)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var bucket ratelimit.Bucket
+
+	// A new bucket allows a burst
+	for want := 2; want >= 0; want-- {
+		result := bucket.Take(limit, now)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, want, result.Remaining)
+	}
+
+	denied := bucket.Take(limit, now)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, 0, denied.Remaining)
+	assert.Equal(t, time.Second, denied.RetryAfter)
+	assert.Equal(t, 3*time.Second, denied.Reset)
+
+	// One token is back after a second
+	result := bucket.Take(limit, now.Add(time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// The bucket never holds more than the burst
+	result = bucket.Take(limit, now.Add(time.Hour))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestBucket_Take_ClockBehind(t *testing.T) {
+	limit := ratelimit.PerPeriod(1, time.Minute)
+	now := time.Now()
+	var bucket ratelimit.Bucket
+
+	assert.True(t, bucket.Take(limit, now).Allowed)
+	assert.False(t, bucket.Take(limit, now.Add(-time.Second)).Allowed)
+	assert.Equal(t, now, bucket.UpdatedAt)
+}
+
+func TestMemoryStore_SeparatesKeys(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.PerPeriod(1, time.Hour)
+	ctx := context.Background()
+
+	first, err := store.Take(ctx, "user:1", limit)
+	require.NoError(t, err)
+	assert.True(t, first.Allowed)
+
+	second, err := store.Take(ctx, "user:1", limit)
+	require.NoError(t, err)
+	assert.False(t, second.Allowed)
+
+	other, err := store.Take(ctx, "user:2", limit)
+	require.NoError(t, err)
+	assert.True(t, other.Allowed)
+}
@@ -0,0 +1,114 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/repositories"
+)
+
+func TestDuplicateRepository_QueueAndReview(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	patientRepo := repositories.NewPatientRepository(db)
+	repo := repositories.NewDuplicateRepository(db)
+
+	var patients []*models.Patient
+	for _, name := range []string{"John Doe", "Jon Doe", "Johnny Doe"} {
+		patient := &models.Patient{Name: name, Age: 30, Gender: models.GenderMale, ContactInfo: "555-0100", CreatedBy: 1}
+		require.NoError(t, patientRepo.Create(context.Background(), patient))
+		patients = append(patients, patient)
+	}
+
+	err := repo.Queue(context.Background(), []models.PatientDuplicate{
+		{PatientID: patients[1].ID, CandidateID: patients[0].ID, Score: 0.95, Features: map[string]float64{"name": 0.95}, Status: models.DuplicateStatusPending},
+		{PatientID: patients[2].ID, CandidateID: patients[0].ID, Score: 0.75, Features: map[string]float64{"name": 0.6}, Status: models.DuplicateStatusPending},
+	})
+	require.NoError(t, err)
+
+	// The most probable duplicates are listed first, with their features
+	firstPage := pagination.Request{Number: 1, Limit: 10}
+	page, err := repo.List(context.Background(), models.DuplicateStatusPending, firstPage)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, patients[1].ID, page.Items[0].PatientID)
+	assert.Equal(t, 0.95, page.Items[0].Features["name"])
+	assert.Equal(t, patients[2].ID, page.Items[1].PatientID)
+
+	// Dismissing a pair takes it out of the pending queue
+	dismissed, err := repo.Review(context.Background(), page.Items[0].ID, models.DuplicateStatusDismissed, 1, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.DuplicateStatusDismissed, dismissed.Status)
+	require.NotNil(t, dismissed.ReviewedBy)
+	assert.Equal(t, uint(1), *dismissed.ReviewedBy)
+
+	page, err = repo.List(context.Background(), models.DuplicateStatusPending, firstPage)
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	// A pair is reviewed once
+	_, err = repo.Review(context.Background(), dismissed.ID, models.DuplicateStatusConfirmed, 1, time.Now())
+	assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+	_, err = repo.Review(context.Background(), 999, models.DuplicateStatusConfirmed, 1, time.Now())
+	assert.True(t, apperror.IsNotFound(err))
+
+	// Queueing a dismissed pair again keeps the decision
+	err = repo.Queue(context.Background(), []models.PatientDuplicate{
+		{PatientID: patients[1].ID, CandidateID: patients[0].ID, Score: 0.95, Status: models.DuplicateStatusPending},
+	})
+	require.NoError(t, err)
+	stored, err := repo.FindByID(context.Background(), dismissed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DuplicateStatusDismissed, stored.Status)
+}
+
+func TestDuplicateRepository_CloseMergedAndReopen(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	patientRepo := repositories.NewPatientRepository(db)
+	repo := repositories.NewDuplicateRepository(db)
+
+	var patients []*models.Patient
+	for _, name := range []string{"John Doe", "Jon Doe", "Johnny Doe", "Joan Doe"} {
+		patient := &models.Patient{Name: name, Age: 30, Gender: models.GenderMale, ContactInfo: "555-0100", CreatedBy: 1}
+		require.NoError(t, patientRepo.Create(ctx, patient))
+		patients = append(patients, patient)
+	}
+	require.NoError(t, repo.Queue(ctx, []models.PatientDuplicate{
+		{PatientID: patients[1].ID, CandidateID: patients[0].ID, Score: 0.95, Status: models.DuplicateStatusPending},
+		{PatientID: patients[2].ID, CandidateID: patients[1].ID, Score: 0.8, Status: models.DuplicateStatusPending},
+		{PatientID: patients[3].ID, CandidateID: patients[0].ID, Score: 0.7, Status: models.DuplicateStatusPending},
+	}))
+
+	// Merging Jon into John confirms their pair and closes Jon's others
+	confirmed, err := repo.ConfirmPair(ctx, patients[0].ID, patients[1].ID, 1, time.Now())
+	require.NoError(t, err)
+	require.Len(t, confirmed, 1)
+	closed, err := repo.CloseMerged(ctx, patients[1].ID, 1, time.Now())
+	require.NoError(t, err)
+	require.Len(t, closed, 1)
+
+	page, err := repo.List(ctx, models.DuplicateStatusPending, pagination.Request{Number: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, patients[3].ID, page.Items[0].PatientID)
+
+	// Undoing the merge puts both back in the queue
+	require.NoError(t, repo.Reopen(ctx, append(confirmed, closed...)))
+	for _, id := range append(confirmed, closed...) {
+		stored, err := repo.FindByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, models.DuplicateStatusPending, stored.Status)
+		assert.Nil(t, stored.ReviewedBy)
+	}
+}
@@ -0,0 +1,79 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+func TestMergeRepository_RelinkAndRestore(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	patientRepo := repositories.NewPatientRepository(db)
+	identifierRepo := repositories.NewPatientIdentifierRepository(db)
+	repo := repositories.NewMergeRepository(db)
+
+	survivor := &models.Patient{Name: "John Doe", Age: 30, Gender: models.GenderMale, CreatedBy: 1}
+	merged := &models.Patient{Name: "Jon Doe", Age: 30, Gender: models.GenderMale, CreatedBy: 1}
+	require.NoError(t, patientRepo.Create(ctx, survivor))
+	require.NoError(t, patientRepo.Create(ctx, merged))
+
+	identifier := &models.PatientIdentifier{System: "HOSP", Value: "67890", PatientID: merged.ID}
+	require.NoError(t, identifierRepo.Create(ctx, identifier))
+
+	// The merged patient's records move to the survivor
+	relinked, err := repo.Relink(ctx, merged.ID, survivor.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]uint{"patient_identifiers": {identifier.ID}}, relinked)
+
+	moved, err := identifierRepo.FindByValue(ctx, "HOSP", "67890")
+	require.NoError(t, err)
+	assert.Equal(t, survivor.ID, moved.PatientID)
+
+	require.NoError(t, patientRepo.Delete(ctx, merged.ID))
+	merge := &models.PatientMerge{
+		SurvivorID:      survivor.ID,
+		MergedID:        merged.ID,
+		MergedBy:        1,
+		SurvivorBefore:  survivor.ToDocument(),
+		SurvivorVersion: survivor.Version,
+		Relinked:        relinked,
+		UnmergeBefore:   time.Now().Add(time.Hour),
+	}
+	require.NoError(t, repo.Create(ctx, merge))
+
+	// The tombstone resolves the merged patient
+	active, err := repo.FindActiveByMergedID(ctx, merged.ID)
+	require.NoError(t, err)
+	assert.Equal(t, merge.ID, active.ID)
+	assert.Equal(t, "John Doe", active.SurvivorBefore.Name)
+	assert.Equal(t, relinked, active.Relinked)
+
+	// Undoing the merge brings the patient and its records back
+	require.NoError(t, patientRepo.Restore(ctx, merged.ID))
+	require.NoError(t, repo.Restore(ctx, merge.Relinked, survivor.ID, merged.ID))
+
+	restored, err := identifierRepo.FindByValue(ctx, "HOSP", "67890")
+	require.NoError(t, err)
+	assert.Equal(t, merged.ID, restored.PatientID)
+	_, err = patientRepo.FindByID(ctx, merged.ID)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	merge.UnmergedAt = &now
+	require.NoError(t, repo.Update(ctx, merge))
+	_, err = repo.FindActiveByMergedID(ctx, merged.ID)
+	assert.True(t, apperror.IsNotFound(err))
+
+	// Only a deleted patient can be restored
+	assert.True(t, apperror.IsNotFound(patientRepo.Restore(ctx, merged.ID)))
+}
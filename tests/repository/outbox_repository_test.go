@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+func TestOutboxRepository_ListPending(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repositories.NewOutboxRepository(db)
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	appendEvent := func(patientID uint, nextAttemptAt *time.Time) *models.OutboxEvent {
+		event := &models.OutboxEvent{EventType: "patient.updated", AggregateType: "patient", AggregateID: patientID, Payload: "{}", OccurredAt: now, NextAttemptAt: nextAttemptAt}
+		require.NoError(t, repo.Append(ctx, event))
+		return event
+	}
+	waiting := appendEvent(1, &later)
+	appendEvent(1, nil)
+	first := appendEvent(2, nil)
+	appendEvent(2, nil)
+	other := appendEvent(3, nil)
+
+	// Only the earliest event of each patient is listed, and none of a
+	// patient whose earliest event waits to be retried
+	pending, err := repo.ListPending(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, first.ID, pending[0].ID)
+	assert.Equal(t, other.ID, pending[1].ID)
+
+	// Events locked by another dispatcher are skipped
+	err = db.Transaction(func(tx *gorm.DB) error {
+		locked, err := repositories.NewOutboxRepository(tx).ListPending(ctx, now, 1)
+		require.NoError(t, err)
+		require.Len(t, locked, 1)
+
+		pending, err := repo.ListPending(ctx, now, 10)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, other.ID, pending[0].ID)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Published events are purged once past the retention period
+	published := now.Add(-2 * time.Hour)
+	waiting.PublishedAt = &published
+	require.NoError(t, repo.Update(ctx, waiting))
+	purged, err := repo.PurgePublished(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var remaining int64
+	require.NoError(t, db.Unscoped().Model(&models.OutboxEvent{}).Count(&remaining).Error)
+	assert.Equal(t, int64(4), remaining)
+}
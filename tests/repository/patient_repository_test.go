@@ -2,6 +2,7 @@ package repository_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -12,11 +13,14 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
 	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
 	"hospital-project/internal/repositories"
 )
 
-func setupPatientTestDB(t *testing.T) (*gorm.DB, func()) {
+func setupPatientTestDB(t testing.TB) (*gorm.DB, func()) {
 	ctx := context.Background()
 
 	// Create PostgreSQL container
@@ -51,8 +55,8 @@ func setupPatientTestDB(t *testing.T) (*gorm.DB, func()) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
 
-	// Migrate schema
-	err = db.AutoMigrate(&models.Patient{})
+	// Migrate schema, including the search indexes AutoMigrate cannot create
+	err = config.MigrateDB(db)
 	require.NoError(t, err)
 
 	// Return cleanup function
@@ -81,12 +85,12 @@ func TestPatientRepository_CRUD(t *testing.T) {
 		CreatedBy:   1,
 	}
 
-	err := repo.Create(patient)
+	err := repo.Create(context.Background(), patient)
 	assert.NoError(t, err)
 	assert.NotZero(t, patient.ID)
 
 	// Test FindByID
-	found, err := repo.FindByID(patient.ID)
+	found, err := repo.FindByID(context.Background(), patient.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, patient.Name, found.Name)
 	assert.Equal(t, patient.Age, found.Age)
@@ -95,43 +99,46 @@ func TestPatientRepository_CRUD(t *testing.T) {
 
 	// Test Update
 	patient.Name = "Jane Doe"
-	err = repo.Update(patient)
+	err = repo.Update(context.Background(), patient)
 	assert.NoError(t, err)
 
-	updated, err := repo.FindByID(patient.ID)
+	updated, err := repo.FindByID(context.Background(), patient.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, "Jane Doe", updated.Name)
+	assert.Equal(t, uint(2), updated.Version)
+
+	// Test Update with a stale version
+	stale := *updated
+	stale.Version = 1
+	err = repo.Update(context.Background(), &stale)
+	assert.Equal(t, apperror.KindPreconditionFailed, apperror.KindOf(err))
 
 	// Test UpdateMedicalNotes
-	err = repo.UpdateMedicalNotes(patient.ID, "Updated notes")
+	err = repo.UpdateMedicalNotes(context.Background(), patient.ID, "Updated notes", updated.Version)
 	assert.NoError(t, err)
-	updated, err = repo.FindByID(patient.ID)
+	updated, err = repo.FindByID(context.Background(), patient.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, "Updated notes", updated.MedicalNotes)
-
-	// Test ExistsByNameOrContact
-	exists, err := repo.ExistsByNameOrContact("Jane Doe", "1234567890")
-	assert.NoError(t, err)
-	assert.True(t, exists)
+	assert.Equal(t, uint(3), updated.Version)
 
 	// Test List
-	patients, total, err := repo.List(1, 10)
+	page, err := repo.List(context.Background(), pagination.Request{Number: 1, Limit: 10})
 	assert.NoError(t, err)
-	assert.Equal(t, int64(1), total)
-	assert.Len(t, patients, 1)
+	assert.Equal(t, int64(1), *page.Total)
+	assert.Len(t, page.Items, 1)
 
 	// Test Search
 	searchParams := models.PatientSearchRequest{Name: "Jane"}
-	results, err := repo.Search(searchParams)
+	results, err := repo.Search(context.Background(), searchParams, nil, pagination.Request{Number: 1, Limit: 10})
 	assert.NoError(t, err)
-	assert.Len(t, results, 1)
-	assert.Equal(t, "Jane Doe", results[0].Name)
+	assert.Len(t, results.Items, 1)
+	assert.Equal(t, "Jane Doe", results.Items[0].Name)
 
 	// Test Delete
-	err = repo.Delete(patient.ID)
+	err = repo.Delete(context.Background(), patient.ID)
 	assert.NoError(t, err)
 
-	_, err = repo.FindByID(patient.ID)
+	_, err = repo.FindByID(context.Background(), patient.ID)
 	assert.Error(t, err)
 }
 
@@ -156,7 +163,7 @@ func TestPatientRepository_Concurrent(t *testing.T) {
 	// Create patients concurrently
 	for _, patient := range patients {
 		go func(p *models.Patient) {
-			err := repo.Create(p)
+			err := repo.Create(context.Background(), p)
 			assert.NoError(t, err)
 		}(patient)
 	}
@@ -166,7 +173,7 @@ func TestPatientRepository_Concurrent(t *testing.T) {
 
 	// Verify all patients were created
 	for _, patient := range patients {
-		found, err := repo.FindByID(patient.ID)
+		found, err := repo.FindByID(context.Background(), patient.ID)
 		assert.NoError(t, err)
 		assert.NotNil(t, found)
 	}
@@ -187,21 +194,71 @@ func TestPatientRepository_ListPagination(t *testing.T) {
 			ContactInfo: "123456789" + string(rune('0'+i)),
 			CreatedBy:   1,
 		}
-		err := repo.Create(patient)
+		err := repo.Create(context.Background(), patient)
 		assert.NoError(t, err)
 	}
 
 	// Test first page
-	patients, total, err := repo.List(1, 10)
+	page, err := repo.List(context.Background(), pagination.Request{Number: 1, Limit: 10})
 	assert.NoError(t, err)
-	assert.Equal(t, int64(15), total)
-	assert.Len(t, patients, 10)
+	assert.Equal(t, int64(15), *page.Total)
+	assert.Len(t, page.Items, 10)
+	assert.NotNil(t, page.Next)
+	assert.Nil(t, page.Prev)
 
 	// Test second page
-	patients, total, err = repo.List(2, 10)
+	page, err = repo.List(context.Background(), pagination.Request{Number: 2, Limit: 10})
 	assert.NoError(t, err)
-	assert.Equal(t, int64(15), total)
-	assert.Len(t, patients, 5)
+	assert.Equal(t, int64(15), *page.Total)
+	assert.Len(t, page.Items, 5)
+	assert.Nil(t, page.Next)
+	assert.NotNil(t, page.Prev)
+}
+
+func TestPatientRepository_ListCursor(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	repo := repositories.NewPatientRepository(db)
+	create := func(name string) {
+		require.NoError(t, repo.Create(context.Background(), &models.Patient{Name: name, Age: 30, Gender: models.GenderMale, ContactInfo: name, CreatedBy: 1}))
+	}
+	for i := 0; i < 5; i++ {
+		create(fmt.Sprintf("Patient %d", i))
+	}
+
+	first, err := repo.List(context.Background(), pagination.Request{Number: 1, Limit: 2})
+	require.NoError(t, err)
+	require.NotNil(t, first.Next)
+
+	// A patient registered while paging neither shifts nor repeats rows
+	create("Patient 5")
+
+	var names []string
+	for _, patient := range first.Items {
+		names = append(names, patient.Name)
+	}
+	cursor := first.Next
+	for cursor != nil {
+		page, err := repo.List(context.Background(), pagination.Request{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+		assert.Nil(t, page.Total)
+		for _, patient := range page.Items {
+			names = append(names, patient.Name)
+		}
+		cursor = page.Next
+	}
+	assert.Equal(t, []string{"Patient 0", "Patient 1", "Patient 2", "Patient 3", "Patient 4", "Patient 5"}, names)
+
+	// Paging back from the second page returns the first
+	second, err := repo.List(context.Background(), pagination.Request{Limit: 2, Cursor: first.Next, IncludeTotal: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), *second.Total)
+	previous, err := repo.List(context.Background(), pagination.Request{Limit: 2, Cursor: second.Prev})
+	require.NoError(t, err)
+	assert.Equal(t, first.Items[0].ID, previous.Items[0].ID)
+	assert.Equal(t, first.Items[1].ID, previous.Items[1].ID)
+	assert.Nil(t, previous.Prev)
 }
 
 func TestPatientRepository_Search(t *testing.T) {
@@ -236,24 +293,327 @@ func TestPatientRepository_Search(t *testing.T) {
 	}
 
 	for _, patient := range patients {
-		err := repo.Create(patient)
+		err := repo.Create(context.Background(), patient)
 		assert.NoError(t, err)
 	}
 
+	firstPage := pagination.Request{Number: 1, Limit: 10}
+
 	// Test search by name
-	results, err := repo.Search(models.PatientSearchRequest{Name: "Smith"})
+	results, err := repo.Search(context.Background(), models.PatientSearchRequest{Name: "Smith"}, nil, firstPage)
 	assert.NoError(t, err)
-	assert.Len(t, results, 2)
+	assert.Len(t, results.Items, 2)
+	assert.Equal(t, int64(2), *results.Total)
 
 	// Test search by gender
-	results, err = repo.Search(models.PatientSearchRequest{Gender: models.GenderFemale})
+	results, err = repo.Search(context.Background(), models.PatientSearchRequest{Gender: models.GenderFemale}, nil, firstPage)
 	assert.NoError(t, err)
-	assert.Len(t, results, 1)
-	assert.Equal(t, "Jane Smith", results[0].Name)
+	assert.Len(t, results.Items, 1)
+	assert.Equal(t, "Jane Smith", results.Items[0].Name)
 
 	// Test search by age range
-	results, err = repo.Search(models.PatientSearchRequest{AgeMin: 35})
+	results, err = repo.Search(context.Background(), models.PatientSearchRequest{AgeMin: 35}, nil, firstPage)
 	assert.NoError(t, err)
-	assert.Len(t, results, 1)
-	assert.Equal(t, "Bob Johnson", results[0].Name)
+	assert.Len(t, results.Items, 1)
+	assert.Equal(t, "Bob Johnson", results.Items[0].Name)
+
+	// Test search by filter expression
+	results, err = repo.Search(context.Background(), models.PatientSearchRequest{Filter: "gender = male and age < 35 or name ~ jane"}, nil, firstPage)
+	assert.NoError(t, err)
+	assert.Len(t, results.Items, 2)
+	assert.Equal(t, int64(2), *results.Total)
+
+	// Test search by a filter that does not parse
+	_, err = repo.Search(context.Background(), models.PatientSearchRequest{Filter: "weight > 80"}, nil, firstPage)
+	assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+}
+
+func TestPatientRepository_SearchSorted(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	repo := repositories.NewPatientRepository(db)
+	for _, patient := range []*models.Patient{
+		{Name: "Carol", Age: 30, Gender: models.GenderFemale, ContactInfo: "1", CreatedBy: 1},
+		{Name: "Alice", Age: 30, Gender: models.GenderFemale, ContactInfo: "2", CreatedBy: 1},
+		{Name: "Bob", Age: 40, Gender: models.GenderMale, ContactInfo: "3", CreatedBy: 1},
+		{Name: "Dave", Age: 20, Gender: models.GenderMale, ContactInfo: "4", CreatedBy: 1},
+	} {
+		require.NoError(t, repo.Create(context.Background(), patient))
+	}
+
+	// Oldest first, then by name, paged by cursor
+	sort := []pagination.SortKey{{Field: "age", Descending: true}, {Field: "name"}}
+	var names []string
+	request := pagination.Request{Number: 1, Limit: 3}
+	for {
+		page, err := repo.Search(context.Background(), models.PatientSearchRequest{}, sort, request)
+		require.NoError(t, err)
+		for _, patient := range page.Items {
+			names = append(names, patient.Name)
+		}
+		if page.Next == nil {
+			break
+		}
+		request = pagination.Request{Limit: 3, Cursor: page.Next}
+	}
+	assert.Equal(t, []string{"Bob", "Alice", "Carol", "Dave"}, names)
+
+	// A cursor is tied to the sort it was made with
+	first, err := repo.Search(context.Background(), models.PatientSearchRequest{}, sort, pagination.Request{Number: 1, Limit: 1})
+	require.NoError(t, err)
+	_, err = repo.Search(context.Background(), models.PatientSearchRequest{}, nil, pagination.Request{Limit: 1, Cursor: first.Next})
+	assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+}
+
+func TestPatientRepository_FuzzySearch(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	repo := repositories.NewPatientRepository(db)
+	for _, patient := range []*models.Patient{
+		{Name: "Joseph Alvarado", Age: 50, Gender: models.GenderMale, ContactInfo: "555-0199", CreatedBy: 1},
+		{Name: "José Álvarez", Age: 40, Gender: models.GenderMale, ContactInfo: "555-0100", CreatedBy: 1},
+		{Name: "Maria Garcia", Age: 30, Gender: models.GenderFemale, ContactInfo: "555-0142", CreatedBy: 1},
+	} {
+		require.NoError(t, repo.Create(context.Background(), patient))
+	}
+	firstPage := pagination.Request{Number: 1, Limit: 10}
+	names := func(page *pagination.Page[models.PatientMatch]) []string {
+		var names []string
+		for _, match := range page.Items {
+			names = append(names, match.Name)
+		}
+		return names
+	}
+
+	// Accents and typos are tolerated, and the most similar come first
+	page, err := repo.Search(context.Background(), models.PatientSearchRequest{Name: "Jose Alvares"}, nil, firstPage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"José Álvarez", "Joseph Alvarado"}, names(page))
+	assert.Greater(t, page.Items[0].Rank, page.Items[1].Rank)
+
+	// A stricter threshold drops the looser match
+	page, err = repo.Search(context.Background(), models.PatientSearchRequest{Name: "Jose Alvares", Threshold: 0.8}, nil, firstPage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"José Álvarez"}, names(page))
+
+	// Substrings still match, whatever their similarity
+	page, err = repo.Search(context.Background(), models.PatientSearchRequest{Name: "Garc"}, nil, firstPage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Maria Garcia"}, names(page))
+
+	// An explicit sort takes precedence over similarity
+	page, err = repo.Search(context.Background(), models.PatientSearchRequest{Name: "Jose Alvares"}, []pagination.SortKey{{Field: "age", Descending: true}}, firstPage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Joseph Alvarado", "José Álvarez"}, names(page))
+}
+
+func TestPatientRepository_PhoneticSearch(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	repo := repositories.NewPatientRepository(db)
+	catherine := &models.Patient{Name: "Catherine Price", Age: 40, Gender: models.GenderFemale, ContactInfo: "555-0100", CreatedBy: 1}
+	for _, patient := range []*models.Patient{
+		catherine,
+		{Name: "Kathryn Smith", Age: 35, Gender: models.GenderFemale, ContactInfo: "555-0101", CreatedBy: 1},
+		{Name: "Karen Pierce", Age: 30, Gender: models.GenderFemale, ContactInfo: "555-0102", CreatedBy: 1},
+	} {
+		require.NoError(t, repo.Create(context.Background(), patient))
+	}
+	assert.Equal(t, "K0RN KTRN PRS", catherine.NamePhonetic)
+	firstPage := pagination.Request{Number: 1, Limit: 10}
+	names := func(page *pagination.Page[models.PatientMatch]) []string {
+		var names []string
+		for _, match := range page.Items {
+			names = append(names, match.Name)
+		}
+		return names
+	}
+
+	// Spelt as heard, the name is too far from Catherine for trigrams
+	page, err := repo.Search(context.Background(), models.PatientSearchRequest{Name: "Kathryn"}, nil, firstPage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kathryn Smith"}, names(page))
+
+	// but sounds the same, and the closer spelling ranks first
+	page, err = repo.Search(context.Background(), models.PatientSearchRequest{Name: "Kathryn", Match: models.MatchPhonetic}, nil, firstPage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kathryn Smith", "Catherine Price"}, names(page))
+	assert.Greater(t, page.Items[0].Rank, page.Items[1].Rank)
+
+	// Keys follow the name when it changes
+	catherine.Name = "Catherine Schmidt"
+	require.NoError(t, repo.Update(context.Background(), catherine))
+	page, err = repo.Search(context.Background(), models.PatientSearchRequest{Name: "Smyth", Match: models.MatchPhonetic}, nil, firstPage)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Kathryn Smith", "Catherine Schmidt"}, names(page))
+
+	// Patients saved before names had keys get them on the next migration
+	require.NoError(t, db.Model(&models.Patient{}).Where("id = ?", catherine.ID).UpdateColumn("name_phonetic", "").Error)
+	require.NoError(t, config.MigrateDB(db))
+	stored, err := repo.FindByID(context.Background(), catherine.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "K0RN KTRN XMT SMT", stored.NamePhonetic)
+}
+
+func TestPatientRepository_FindMatchCandidates(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	repo := repositories.NewPatientRepository(db)
+	john := &models.Patient{Name: "John Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "(555) 010-0100", CreatedBy: 1}
+	sibling := &models.Patient{Name: "Mary Roe", Age: 28, Gender: models.GenderFemale, ContactInfo: "Mother: 555.010.0100", CreatedBy: 1}
+	stranger := &models.Patient{Name: "Ann Smith", Age: 50, Gender: models.GenderFemale, ContactInfo: "ann@example.com", CreatedBy: 1}
+	for _, patient := range []*models.Patient{john, sibling, stranger} {
+		require.NoError(t, repo.Create(context.Background(), patient))
+	}
+	assert.Equal(t, "5550100100", john.ContactKeys)
+
+	ids := func(patients []models.Patient) []uint {
+		var ids []uint
+		for _, patient := range patients {
+			ids = append(ids, patient.ID)
+		}
+		return ids
+	}
+
+	// A name that sounds alike or a shared phone number makes a candidate
+	candidates, err := repo.FindMatchCandidates(context.Background(), &models.Patient{Name: "Jon Dough", ContactInfo: "+1 555 010 0100"}, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{john.ID, sibling.ID}, ids(candidates))
+
+	// A patient is not a candidate for itself
+	candidates, err = repo.FindMatchCandidates(context.Background(), john, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{sibling.ID}, ids(candidates))
+
+	// Email addresses match regardless of case
+	candidates, err = repo.FindMatchCandidates(context.Background(), &models.Patient{Name: "Zed", ContactInfo: "ANN@example.com"}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{stranger.ID}, ids(candidates))
+
+	// Patients saved before contact info had keys get them on the next migration
+	require.NoError(t, db.Model(&models.Patient{}).Where("id = ?", john.ID).UpdateColumn("contact_keys", "").Error)
+	require.NoError(t, config.MigrateDB(db))
+	stored, err := repo.FindByID(context.Background(), john.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "5550100100", stored.ContactKeys)
+}
+
+func TestPatientRepository_SearchNotes(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	repo := repositories.NewPatientRepository(db)
+	for _, patient := range []*models.Patient{
+		{Name: "John Smith", Age: 70, Gender: models.GenderMale, MedicalNotes: "Atrial fibrillation. Started warfarin, warfarin dose reviewed weekly.", CreatedBy: 1},
+		{Name: "Jane Smith", Age: 65, Gender: models.GenderFemale, MedicalNotes: "Stopped Warfarin before surgery.", CreatedBy: 1},
+		{Name: "Bob Johnson", Age: 40, Gender: models.GenderMale, MedicalNotes: "Seasonal asthma.", CreatedBy: 1},
+	} {
+		require.NoError(t, repo.Create(context.Background(), patient))
+	}
+
+	// The trigger indexes notes as they are written
+	page, err := repo.SearchNotes(context.Background(), "warfarin", models.PatientSearchRequest{}, pagination.Request{Number: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, "John Smith", page.Items[0].Name)
+	assert.GreaterOrEqual(t, page.Items[0].Rank, page.Items[1].Rank)
+	assert.Contains(t, page.Items[0].ToResult().Snippet, "<mark>warfarin</mark>")
+
+	// Demographic filters narrow the matches
+	page, err = repo.SearchNotes(context.Background(), "warfarin", models.PatientSearchRequest{Gender: models.GenderFemale}, pagination.Request{Number: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "Jane Smith", page.Items[0].Name)
+
+	// Updated notes are indexed again
+	jane := page.Items[0].Patient
+	jane.MedicalNotes = "Recovered well from surgery."
+	require.NoError(t, repo.Update(context.Background(), &jane))
+	page, err = repo.SearchNotes(context.Background(), "warfarin", models.PatientSearchRequest{}, pagination.Request{Number: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "John Smith", page.Items[0].Name)
+
+	// Migrating again leaves the index alone, and notes written before the
+	// search existed are indexed when it is added
+	require.NoError(t, config.MigrateDB(db))
+	require.NoError(t, db.Exec("ALTER TABLE patients DROP COLUMN notes_tsv CASCADE").Error)
+	require.NoError(t, config.MigrateDB(db))
+	page, err = repo.SearchNotes(context.Background(), "asthma", models.PatientSearchRequest{}, pagination.Request{Number: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "Bob Johnson", page.Items[0].Name)
+}
+
+func TestPatientRepository_Export(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	repo := repositories.NewPatientRepository(db)
+
+	// Create test patients
+	for i := 0; i < 5; i++ {
+		gender := models.GenderMale
+		if i%2 == 1 {
+			gender = models.GenderFemale
+		}
+		patient := &models.Patient{
+			Name:        "Patient " + string(rune('0'+i)),
+			Age:         30 + i,
+			Gender:      gender,
+			ContactInfo: "123456789" + string(rune('0'+i)),
+			CreatedBy:   1,
+		}
+		err := repo.Create(context.Background(), patient)
+		assert.NoError(t, err)
+	}
+
+	// Export applies the same filters as Search and streams rows in ID order
+	var names []string
+	err := repo.Export(context.Background(), models.PatientSearchRequest{Gender: models.GenderMale}, func(p *models.Patient) error {
+		names = append(names, p.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Patient 0", "Patient 2", "Patient 4"}, names)
+}
+
+func TestPatientRepository_Facets(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repositories.NewPatientRepository(db)
+
+	patients := []*models.Patient{
+		{Name: "John Smith", Age: 12, Gender: models.GenderMale, MedicalNotes: "Asthma", CreatedBy: 1},
+		{Name: "Jane Smith", Age: 70, Gender: models.GenderFemale, MedicalNotes: "Takes warfarin", CreatedBy: 1},
+		{Name: "Bob Johnson", Age: 85, Gender: models.GenderMale, MedicalNotes: "Takes warfarin", CreatedBy: 1},
+	}
+	for _, patient := range patients {
+		require.NoError(t, repo.Create(ctx, patient))
+	}
+	month := patients[0].CreatedAt.UTC().Format("2006-01")
+
+	// Without filters every patient is counted
+	counts, err := repo.Facets(ctx, "", models.PatientSearchRequest{}, models.PatientFacets)
+	require.NoError(t, err)
+	assert.Equal(t, []models.FacetCount{{Value: "female", Count: 1}, {Value: "male", Count: 2}}, counts[models.FacetGender])
+	assert.Equal(t, []models.FacetCount{{Value: "0-17", Count: 1}, {Value: "65-79", Count: 1}, {Value: "80+", Count: 1}}, counts[models.FacetAgeBand])
+	assert.Equal(t, []models.FacetCount{{Value: month, Count: 3}}, counts[models.FacetCreatedMonth])
+
+	// The search filters and the notes query apply to the counts
+	counts, err = repo.Facets(ctx, "warfarin", models.PatientSearchRequest{Filter: "age >= 65"}, []string{models.FacetGender})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]models.FacetCount{
+		models.FacetGender: {{Value: "female", Count: 1}, {Value: "male", Count: 1}},
+	}, counts)
+
+	counts, err = repo.Facets(ctx, "", models.PatientSearchRequest{Name: "Smith", Gender: models.GenderMale}, []string{models.FacetAgeBand})
+	require.NoError(t, err)
+	assert.Equal(t, []models.FacetCount{{Value: "0-17", Count: 1}}, counts[models.FacetAgeBand])
 }
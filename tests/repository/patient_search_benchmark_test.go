@@ -0,0 +1,109 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/repositories"
+)
+
+// seededPatients is the size of the table fuzzy searches are benchmarked on
+const seededPatients = 100000
+
+// BenchmarkPatientRepository_FuzzySearch searches a large table by a
+// misspelt, unaccented name, after checking that the planner answers the
+// search from the trigram index rather than by scanning the table
+func BenchmarkPatientRepository_FuzzySearch(b *testing.B) {
+	db, cleanup := setupPatientTestDB(b)
+	defer cleanup()
+	seedPatients(b, db, seededPatients)
+
+	repo := repositories.NewPatientRepository(db)
+	require.NoError(b, repo.Create(context.Background(), &models.Patient{Name: "José Álvarez", Age: 40, Gender: models.GenderMale, ContactInfo: "555-0100", CreatedBy: 1}))
+	require.NoError(b, db.Exec("ANALYZE patients").Error)
+
+	params := models.PatientSearchRequest{Name: "Jose Alvares"}
+	request := pagination.Request{Number: 1, Limit: 10}
+
+	// Record the queries of one search to explain them
+	var queries []string
+	require.NoError(b, db.Callback().Query().After("gorm:query").Register("benchmark:capture", func(tx *gorm.DB) {
+		queries = append(queries, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	}))
+	page, err := repo.Search(context.Background(), params, nil, request)
+	require.NoError(b, db.Callback().Query().Remove("benchmark:capture"))
+	require.NoError(b, err)
+	require.NotEmpty(b, page.Items)
+	assert.Equal(b, "José Álvarez", page.Items[0].Name)
+
+	require.NotEmpty(b, queries)
+	for _, query := range queries {
+		assert.Contains(b, explain(b, db, params, query), "idx_patients_name_trgm", query)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Search(context.Background(), params, nil, request); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// seedPatients inserts count patients with made-up names
+func seedPatients(tb testing.TB, db *gorm.DB, count int) {
+	syllables := []string{"an", "bel", "cor", "da", "el", "fin", "gar", "hal", "is", "jor", "ka", "lin", "mar", "no", "or", "pe", "ri", "sol", "ta", "ur", "vin", "wen", "xa", "yo", "zel"}
+	random := rand.New(rand.NewSource(1))
+	word := func() string {
+		var w string
+		for n := 2 + random.Intn(2); n > 0; n-- {
+			w += syllables[random.Intn(len(syllables))]
+		}
+		return w
+	}
+	genders := []models.Gender{models.GenderMale, models.GenderFemale, models.GenderOther}
+
+	patients := make([]models.Patient, count)
+	for i := range patients {
+		patients[i] = models.Patient{
+			Name:        word() + " " + word(),
+			Age:         random.Intn(100),
+			Gender:      genders[random.Intn(len(genders))],
+			ContactInfo: fmt.Sprintf("555-%07d", i),
+			CreatedBy:   1,
+			Version:     1,
+		}
+	}
+	require.NoError(tb, db.CreateInBatches(patients, 1000).Error)
+}
+
+// explain returns the plan of a search query, run with the search's
+// similarity threshold as the repository runs it
+func explain(tb testing.TB, db *gorm.DB, params models.PatientSearchRequest, query string) string {
+	var plan string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		threshold := strconv.FormatFloat(params.SimilarityThreshold(), 'f', -1, 64)
+		if err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)", threshold).Error; err != nil {
+			return err
+		}
+
+		var lines []string
+		if err := tx.Raw("EXPLAIN " + query).Scan(&lines).Error; err != nil {
+			return err
+		}
+		for _, line := range lines {
+			plan += line + "\n"
+		}
+		return nil
+	})
+	require.NoError(tb, err)
+	return plan
+}
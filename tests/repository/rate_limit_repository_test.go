@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/ratelimit"
+	"hospital-project/internal/repositories"
+)
+
+func TestRateLimitRepository_Take(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+	require.NoError(t, db.AutoMigrate(&models.RateLimitBucket{}))
+
+	repo := repositories.NewRateLimitRepository(db)
+	limit := ratelimit.PerPeriod(10, time.Hour)
+
+	// Concurrent requests never spend more tokens than the bucket holds
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := repo.Take(context.Background(), "default:user:1", limit)
+			assert.NoError(t, err)
+			if result.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, allowed)
+
+	result, err := repo.Take(context.Background(), "default:user:2", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 9, result.Remaining)
+
+	// Idle buckets are swept
+	deleted, err := repo.DeleteIdle(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
@@ -0,0 +1,81 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/repositories"
+)
+
+func TestSavedSearchRepository_ListVisible(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := repositories.NewSavedSearchRepository(db)
+
+	wardRound := &models.SavedSearch{
+		OwnerID:    1,
+		Name:       "Ward round",
+		Search:     models.PatientSearchRequest{Filter: "age >= 65"},
+		SharedWith: models.RoleDoctor,
+	}
+	mine := &models.SavedSearch{OwnerID: 2, Name: "Mine"}
+	private := &models.SavedSearch{OwnerID: 3, Name: "Private"}
+	for _, search := range []*models.SavedSearch{wardRound, mine, private} {
+		require.NoError(t, repo.Create(ctx, search))
+	}
+
+	// Pins belong to each user; pinning twice changes nothing
+	require.NoError(t, repo.SetPinned(ctx, wardRound.ID, 2, true))
+	require.NoError(t, repo.SetPinned(ctx, wardRound.ID, 2, true))
+	require.NoError(t, repo.SetPinned(ctx, private.ID, 3, true))
+	require.NoError(t, repo.SetPinned(ctx, mine.ID, 2, true))
+	require.NoError(t, repo.SetPinned(ctx, mine.ID, 2, false))
+
+	// Owners cannot reuse a name, but other users can
+	err := repo.Create(ctx, &models.SavedSearch{OwnerID: 1, Name: "Ward round"})
+	assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+	require.NoError(t, repo.Create(ctx, &models.SavedSearch{OwnerID: 3, Name: "Ward round"}))
+
+	firstPage := pagination.Request{Number: 1, Limit: 10}
+
+	// Users see their own searches and the ones shared with their role, by name
+	page, err := repo.ListVisible(ctx, 2, models.RoleDoctor, false, firstPage)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, "Mine", page.Items[0].Name)
+	assert.Equal(t, "Ward round", page.Items[1].Name)
+	assert.Equal(t, "age >= 65", page.Items[1].Search.Filter)
+	assert.False(t, page.Items[0].Pinned)
+	assert.True(t, page.Items[1].Pinned)
+
+	page, err = repo.ListVisible(ctx, 2, models.RoleDoctor, true, firstPage)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, wardRound.ID, page.Items[0].ID)
+
+	// The owner has not pinned the search another doctor pinned
+	found, err := repo.FindByID(ctx, wardRound.ID, 1)
+	require.NoError(t, err)
+	assert.False(t, found.Pinned)
+	page, err = repo.ListVisible(ctx, 1, models.RoleDoctor, true, firstPage)
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	page, err = repo.ListVisible(ctx, 4, models.RoleReceptionist, false, firstPage)
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	// A deleted search frees its name
+	require.NoError(t, repo.Delete(ctx, wardRound.ID))
+	_, err = repo.FindByID(ctx, wardRound.ID, 1)
+	assert.True(t, apperror.IsNotFound(err))
+	require.NoError(t, repo.Create(ctx, &models.SavedSearch{OwnerID: 1, Name: "Ward round"}))
+}
@@ -0,0 +1,114 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+)
+
+func TestTransactionManager_RollsBackEveryRepository(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+	require.NoError(t, db.AutoMigrate(&models.OutboxEvent{}))
+
+	transactions := repositories.NewTransactionManager(db)
+	patientRepo := repositories.NewPatientRepository(db)
+
+	failure := errors.New("fail after writing")
+	err := transactions.WithinTransaction(context.Background(), func(ctx context.Context, repos *repositories.Repositories) error {
+		patient := &models.Patient{Name: "Bound", Age: 30, Gender: models.GenderMale, ContactInfo: "1", CreatedBy: 1}
+		require.NoError(t, repos.Patients.Create(ctx, patient))
+		require.NoError(t, repos.Outbox.Append(ctx, &models.OutboxEvent{EventType: "patient.registered", AggregateType: "patient", AggregateID: patient.ID, Payload: "{}", OccurredAt: time.Now()}))
+
+		// A repository created outside the transaction joins it through ctx
+		joined := &models.Patient{Name: "Joined", Age: 40, Gender: models.GenderFemale, ContactInfo: "2", CreatedBy: 1}
+		require.NoError(t, patientRepo.Create(ctx, joined))
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	var patients, outbox int64
+	require.NoError(t, db.Model(&models.Patient{}).Count(&patients).Error)
+	require.NoError(t, db.Model(&models.OutboxEvent{}).Count(&outbox).Error)
+	assert.Zero(t, patients)
+	assert.Zero(t, outbox)
+}
+
+func TestTransactionManager_NestedUnitOfWork(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	transactions := repositories.NewTransactionManager(db)
+
+	err := transactions.WithinTransaction(context.Background(), func(ctx context.Context, repos *repositories.Repositories) error {
+		require.NoError(t, repos.Patients.Create(ctx, &models.Patient{Name: "Outer", Age: 30, Gender: models.GenderMale, ContactInfo: "1", CreatedBy: 1}))
+
+		// A failing inner unit of work only rolls back to its savepoint
+		inner := transactions.WithinTransaction(ctx, func(ctx context.Context, repos *repositories.Repositories) error {
+			require.NoError(t, repos.Patients.Create(ctx, &models.Patient{Name: "Inner", Age: 40, Gender: models.GenderFemale, ContactInfo: "2", CreatedBy: 1}))
+			return errors.New("inner failure")
+		})
+		assert.Error(t, inner)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var names []string
+	require.NoError(t, db.Model(&models.Patient{}).Pluck("name", &names).Error)
+	assert.Equal(t, []string{"Outer"}, names)
+}
+
+func TestPatientRepository_FindByIDForUpdate(t *testing.T) {
+	db, cleanup := setupPatientTestDB(t)
+	defer cleanup()
+
+	transactions := repositories.NewTransactionManager(db)
+	repo := repositories.NewPatientRepository(db)
+
+	patient := &models.Patient{Name: "John Doe", Age: 30, Gender: models.GenderMale, ContactInfo: "1", CreatedBy: 1}
+	require.NoError(t, repo.Create(context.Background(), patient))
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- transactions.WithinTransaction(context.Background(), func(ctx context.Context, repos *repositories.Repositories) error {
+			if _, err := repos.Patients.FindByIDForUpdate(ctx, patient.ID); err != nil {
+				return err
+			}
+			close(locked)
+			<-release
+			return repos.Patients.UpdateMedicalNotes(ctx, patient.ID, "first", 1)
+		})
+	}()
+	<-locked
+
+	// The second writer waits for the lock and then sees the new version
+	second := make(chan error, 1)
+	go func() {
+		second <- transactions.WithinTransaction(context.Background(), func(ctx context.Context, repos *repositories.Repositories) error {
+			current, err := repos.Patients.FindByIDForUpdate(ctx, patient.ID)
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, "first", current.MedicalNotes)
+			return repos.Patients.UpdateMedicalNotes(ctx, patient.ID, "second", current.Version)
+		})
+	}()
+
+	close(release)
+	require.NoError(t, <-done)
+	require.NoError(t, <-second)
+
+	found, err := repo.FindByID(context.Background(), patient.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "second", found.MedicalNotes)
+	assert.Equal(t, uint(3), found.Version)
+}
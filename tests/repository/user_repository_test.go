@@ -12,6 +12,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"hospital-project/internal/apperror"
 	"hospital-project/internal/models"
 	"hospital-project/internal/repositories"
 )
@@ -79,41 +80,41 @@ func TestUserRepository_CRUD(t *testing.T) {
 		Role:         models.RoleReceptionist,
 	}
 
-	err := repo.Create(user)
+	err := repo.Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotZero(t, user.ID)
 
 	// Test FindByID
-	found, err := repo.FindByID(user.ID)
+	found, err := repo.FindByID(context.Background(), user.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, user.Username, found.Username)
 	assert.Equal(t, user.PasswordHash, found.PasswordHash)
 	assert.Equal(t, user.Role, found.Role)
 
 	// Test FindByUsername
-	foundByUsername, err := repo.FindByUsername("testuser")
+	foundByUsername, err := repo.FindByUsername(context.Background(), "testuser")
 	assert.NoError(t, err)
 	assert.Equal(t, user.ID, foundByUsername.ID)
 
 	// Test Update
 	user.Username = "updateduser"
-	err = repo.Update(user)
+	err = repo.Update(context.Background(), user)
 	assert.NoError(t, err)
 
-	updated, err := repo.FindByID(user.ID)
+	updated, err := repo.FindByID(context.Background(), user.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, "updateduser", updated.Username)
 
 	// Test List
-	users, err := repo.List()
+	users, err := repo.List(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, users, 1)
 
 	// Test Delete
-	err = repo.Delete(user.ID)
+	err = repo.Delete(context.Background(), user.ID)
 	assert.NoError(t, err)
 
-	_, err = repo.FindByID(user.ID)
+	_, err = repo.FindByID(context.Background(), user.ID)
 	assert.Error(t, err)
 }
 
@@ -136,7 +137,7 @@ func TestUserRepository_Concurrent(t *testing.T) {
 	// Create users concurrently
 	for _, user := range users {
 		go func(u *models.User) {
-			err := repo.Create(u)
+			err := repo.Create(context.Background(), u)
 			assert.NoError(t, err)
 		}(user)
 	}
@@ -146,12 +147,27 @@ func TestUserRepository_Concurrent(t *testing.T) {
 
 	// Verify all users were created
 	for _, user := range users {
-		found, err := repo.FindByUsername(user.Username)
+		found, err := repo.FindByUsername(context.Background(), user.Username)
 		assert.NoError(t, err)
 		assert.NotNil(t, found)
 	}
 }
 
+func TestUserRepository_Create_DuplicateUsername(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := repositories.NewUserRepository(db)
+
+	first := &models.User{Username: "testuser", PasswordHash: "hashedpassword", Role: models.RoleReceptionist}
+	require.NoError(t, repo.Create(context.Background(), first))
+
+	// The unique index catches a username that slipped past the service check
+	second := &models.User{Username: "testuser", PasswordHash: "hashedpassword", Role: models.RoleDoctor}
+	err := repo.Create(context.Background(), second)
+	assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+}
+
 func TestUserRepository_List(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -165,12 +181,12 @@ func TestUserRepository_List(t *testing.T) {
 			PasswordHash: "hashedpassword",
 			Role:         models.RoleReceptionist,
 		}
-		err := repo.Create(user)
+		err := repo.Create(context.Background(), user)
 		assert.NoError(t, err)
 	}
 
 	// Test List
-	users, err := repo.List()
+	users, err := repo.List(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, users, 15)
 }
@@ -0,0 +1,67 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hospital-project/internal/models"
+	"hospital-project/internal/services"
+)
+
+// MockAuditRepository is a mock implementation of the AuditRepository interface
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) ListByResource(ctx context.Context, resource string, resourceID uint) ([]models.AuditLog, error) {
+	args := m.Called(resource, resourceID)
+	return args.Get(0).([]models.AuditLog), args.Error(1)
+}
+
+func TestAuditService_Record_Success(t *testing.T) {
+	// Create mock repository
+	mockRepo := new(MockAuditRepository)
+
+	// Set up expectations
+	mockRepo.On("Create", mock.MatchedBy(func(entry *models.AuditLog) bool {
+		return entry.UserID == 1 &&
+			entry.Action == models.AuditActionPatientExport &&
+			entry.Resource == "patients" &&
+			entry.Details == "format=csv"
+	})).Return(nil)
+
+	// Create audit service with mock repository
+	auditService := services.NewAuditService(mockRepo)
+
+	// Call the method being tested
+	err := auditService.Record(context.Background(), 1, models.AuditActionPatientExport, "patients", 0, "format=csv")
+
+	// Assert expectations
+	assert.NoError(t, err)
+
+	// Verify that the mock was called as expected
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuditService_Record_MissingUser(t *testing.T) {
+	// Create mock repository
+	mockRepo := new(MockAuditRepository)
+
+	// Create audit service with mock repository
+	auditService := services.NewAuditService(mockRepo)
+
+	// Call the method being tested
+	err := auditService.Record(context.Background(), 0, models.AuditActionPatientExport, "patients", 0, "")
+
+	// Assert expectations
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
@@ -1,12 +1,14 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"hospital-project/internal/apperror"
 	"hospital-project/internal/models"
 	"hospital-project/internal/services"
 )
@@ -16,12 +18,12 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) Create(user *models.User) error {
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) FindByID(id uint) (*models.User, error) {
+func (m *MockUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -29,7 +31,7 @@ func (m *MockUserRepository) FindByID(id uint) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByUsername(username string) (*models.User, error) {
+func (m *MockUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	args := m.Called(username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -37,17 +39,17 @@ func (m *MockUserRepository) FindByUsername(username string) (*models.User, erro
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Update(user *models.User) error {
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id uint) error {
+func (m *MockUserRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) List() ([]models.User, error) {
+func (m *MockUserRepository) List(ctx context.Context) ([]models.User, error) {
 	args := m.Called()
 	return args.Get(0).([]models.User), args.Error(1)
 }
@@ -80,7 +82,7 @@ func TestAuthService_Login_Success(t *testing.T) {
 	}
 
 	// Call the method being tested
-	response, err := authService.Login(loginRequest)
+	response, err := authService.Login(context.Background(), loginRequest)
 
 	// Assert expectations
 	assert.NoError(t, err)
@@ -121,7 +123,7 @@ func TestAuthService_Login_InvalidCredentials(t *testing.T) {
 	}
 
 	// Call the method being tested
-	response, err := authService.Login(loginRequest)
+	response, err := authService.Login(context.Background(), loginRequest)
 
 	// Assert expectations
 	assert.Error(t, err)
@@ -137,7 +139,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 
 	// Set up expectations
-	mockRepo.On("FindByUsername", "nonexistentuser").Return(nil, errors.New("user not found"))
+	mockRepo.On("FindByUsername", "nonexistentuser").Return(nil, apperror.NotFound(apperror.CodeUserNotFound, "user not found"))
 
 	// Create auth service with mock repository
 	authService := services.NewAuthService(mockRepo)
@@ -149,7 +151,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 	}
 
 	// Call the method being tested
-	response, err := authService.Login(loginRequest)
+	response, err := authService.Login(context.Background(), loginRequest)
 
 	// Assert expectations
 	assert.Error(t, err)
@@ -182,3 +184,22 @@ func TestAuthService_HashPassword(t *testing.T) {
 	err = authService.VerifyPassword(hashedPassword, "wrongpassword")
 	assert.Error(t, err)
 }
+
+func TestAuthService_Login_RepositoryError(t *testing.T) {
+	// Create mock repository
+	mockRepo := new(MockUserRepository)
+
+	// Set up expectations
+	mockRepo.On("FindByUsername", "testuser").Return(nil, errors.New("connection refused"))
+
+	// Create auth service with mock repository
+	authService := services.NewAuthService(mockRepo)
+
+	// Call the method being tested
+	response, err := authService.Login(context.Background(), models.LoginRequest{Username: "testuser", Password: "password123"})
+
+	// A database failure is not reported as bad credentials
+	assert.Error(t, err)
+	assert.Nil(t, response)
+	assert.Equal(t, apperror.KindInternal, apperror.KindOf(err))
+}
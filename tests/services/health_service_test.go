@@ -0,0 +1,90 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/services"
+)
+
+// MockHealthRepository is a mock implementation of the HealthRepository interface
+type MockHealthRepository struct {
+	mock.Mock
+}
+
+func (m *MockHealthRepository) Ping(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockHealthRepository) MissingTables(ctx context.Context, models []interface{}) ([]string, error) {
+	args := m.Called(models)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+var healthModels = []interface{}{&models.User{}, &models.Patient{}}
+
+func TestHealthService_Ready(t *testing.T) {
+	mockRepo := new(MockHealthRepository)
+	mockRepo.On("Ping").Return(nil)
+	mockRepo.On("MissingTables", healthModels).Return([]string{}, nil)
+
+	healthService := services.NewHealthService(mockRepo, healthModels)
+
+	assert.NoError(t, healthService.Ready(context.Background()))
+	mockRepo.AssertExpectations(t)
+
+	// Tables are only looked up until they have all been found
+	assert.NoError(t, healthService.Ready(context.Background()))
+	mockRepo.AssertNumberOfCalls(t, "Ping", 2)
+	mockRepo.AssertNumberOfCalls(t, "MissingTables", 1)
+}
+
+func TestHealthService_NotReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		ping    error
+		missing []string
+		message string
+	}{
+		{"database unreachable", errors.New("connection refused"), nil, "database is unreachable"},
+		{"migrations missing", nil, []string{"patients", "audit_logs"}, "database migrations have not been applied: missing patients, audit_logs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockHealthRepository)
+			mockRepo.On("Ping").Return(tt.ping)
+			mockRepo.On("MissingTables", healthModels).Return(tt.missing, nil)
+
+			err := services.NewHealthService(mockRepo, healthModels).Ready(context.Background())
+
+			appErr, ok := apperror.As(err)
+			assert.True(t, ok)
+			assert.Equal(t, apperror.KindUnavailable, appErr.Kind)
+			assert.Equal(t, apperror.CodeNotReady, appErr.Code)
+			assert.Equal(t, tt.message, appErr.Message)
+		})
+	}
+}
+
+func TestHealthService_Drain(t *testing.T) {
+	mockRepo := new(MockHealthRepository)
+	healthService := services.NewHealthService(mockRepo, healthModels)
+
+	// Once draining, the server reports not ready without touching the database
+	healthService.Drain()
+	err := healthService.Ready(context.Background())
+
+	assert.Equal(t, apperror.KindUnavailable, apperror.KindOf(err))
+	mockRepo.AssertNotCalled(t, "Ping")
+}
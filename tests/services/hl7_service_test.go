@@ -0,0 +1,450 @@
+package services_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/hl7"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/services"
+)
+
+// MockPatientService is a mock implementation of the PatientService interface
+type MockPatientService struct {
+	mock.Mock
+}
+
+func (m *MockPatientService) Create(ctx context.Context, patient *models.Patient, overrideDuplicates bool) ([]models.DuplicateMatch, error) {
+	args := m.Called(patient, overrideDuplicates)
+	matches, _ := args.Get(0).([]models.DuplicateMatch)
+	return matches, args.Error(1)
+}
+
+func (m *MockPatientService) GetByID(ctx context.Context, id uint) (*models.Patient, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Patient), args.Error(1)
+}
+
+func (m *MockPatientService) Update(ctx context.Context, patient *models.Patient) error {
+	args := m.Called(patient)
+	return args.Error(0)
+}
+
+func (m *MockPatientService) UpdateMedicalNotes(ctx context.Context, id uint, medicalNotes string, version uint) error {
+	args := m.Called(id, medicalNotes, version)
+	return args.Error(0)
+}
+
+func (m *MockPatientService) Delete(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockPatientService) List(ctx context.Context, request pagination.Request) (*pagination.Page[models.Patient], error) {
+	args := m.Called(request)
+	return args.Get(0).(*pagination.Page[models.Patient]), args.Error(1)
+}
+
+func (m *MockPatientService) Search(ctx context.Context, params models.PatientSearchRequest, sort []pagination.SortKey, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	args := m.Called(params, sort, request)
+	return args.Get(0).(*pagination.Page[models.PatientMatch]), args.Error(1)
+}
+
+func (m *MockPatientService) SearchNotes(ctx context.Context, query string, params models.PatientSearchRequest, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	args := m.Called(query, params, request)
+	return args.Get(0).(*pagination.Page[models.PatientMatch]), args.Error(1)
+}
+
+func (m *MockPatientService) Export(ctx context.Context, params models.PatientSearchRequest, fn func(*models.Patient) error) error {
+	args := m.Called(params, fn)
+	return args.Error(0)
+}
+
+func (m *MockPatientService) Facets(ctx context.Context, query string, params models.PatientSearchRequest, facets []string) (map[string][]models.FacetCount, error) {
+	args := m.Called(query, params, facets)
+	counts, _ := args.Get(0).(map[string][]models.FacetCount)
+	return counts, args.Error(1)
+}
+
+// MockHL7MessageRepository is a mock implementation of the HL7MessageRepository interface
+type MockHL7MessageRepository struct {
+	mock.Mock
+}
+
+func (m *MockHL7MessageRepository) Create(ctx context.Context, message *models.HL7Message) error {
+	args := m.Called(message)
+	message.ID = 1
+	return args.Error(0)
+}
+
+func (m *MockHL7MessageRepository) FindByID(ctx context.Context, id uint) (*models.HL7Message, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HL7Message), args.Error(1)
+}
+
+func (m *MockHL7MessageRepository) Update(ctx context.Context, message *models.HL7Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+func (m *MockHL7MessageRepository) List(ctx context.Context, page, limit int) ([]models.HL7Message, int64, error) {
+	args := m.Called(page, limit)
+	return args.Get(0).([]models.HL7Message), args.Get(1).(int64), args.Error(2)
+}
+
+// MockPatientIdentifierRepository is a mock implementation of the PatientIdentifierRepository interface
+type MockPatientIdentifierRepository struct {
+	mock.Mock
+}
+
+func (m *MockPatientIdentifierRepository) Create(ctx context.Context, identifier *models.PatientIdentifier) error {
+	args := m.Called(identifier)
+	return args.Error(0)
+}
+
+func (m *MockPatientIdentifierRepository) FindByValue(ctx context.Context, system, value string) (*models.PatientIdentifier, error) {
+	args := m.Called(system, value)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientIdentifier), args.Error(1)
+}
+
+func (m *MockPatientIdentifierRepository) Relink(ctx context.Context, id, patientID uint) error {
+	args := m.Called(id, patientID)
+	return args.Error(0)
+}
+
+// errIdentifierNotFound is returned by FindByValue for unknown identifiers
+var errIdentifierNotFound = apperror.NotFound(apperror.CodePatientIdentifierNotFound, "patient identifier not found")
+
+// MockMergeService is a mock implementation of the MergeService interface
+type MockMergeService struct {
+	mock.Mock
+}
+
+func (m *MockMergeService) Merge(ctx context.Context, survivorID, mergedID, userID uint) (*models.PatientMerge, error) {
+	args := m.Called(survivorID, mergedID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientMerge), args.Error(1)
+}
+
+func (m *MockMergeService) Unmerge(ctx context.Context, id, userID uint) (*models.PatientMerge, error) {
+	args := m.Called(id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientMerge), args.Error(1)
+}
+
+func (m *MockMergeService) GetByID(ctx context.Context, id uint) (*models.PatientMerge, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientMerge), args.Error(1)
+}
+
+func (m *MockMergeService) Survivor(ctx context.Context, patientID uint) (uint, error) {
+	args := m.Called(patientID)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+const hl7Header = "MSH|^~\\&|REG|HOSP|EHR|HOSP|20261018120000||"
+
+func parseACK(t *testing.T, ack []byte) (string, string) {
+	msg, err := hl7.Parse(string(ack))
+	require.NoError(t, err)
+	msa, ok := msg.Segment("MSA")
+	require.True(t, ok)
+	return msa.Field(1), msa.Component(3, 1)
+}
+
+func TestHL7Service_A04_RegistersPatient(t *testing.T) {
+	// Create mocks
+	mockPatientService := new(MockPatientService)
+	mockMessageRepo := new(MockHL7MessageRepository)
+	mockIdentifierRepo := new(MockPatientIdentifierRepository)
+
+	raw := hl7Header + "ADT^A04^ADT_A01|MSG1|P|2.5.1\r" +
+		"PID|1||12345^^^HOSP^MR||Doe^John||19800315|M|||||555-0100\r" +
+		"PV1|1|O|CLINIC\r"
+
+	// Set up expectations
+	mockMessageRepo.On("Create", mock.AnythingOfType("*models.HL7Message")).Return(nil)
+	mockIdentifierRepo.On("FindByValue", "HOSP", "12345").Return(nil, errIdentifierNotFound)
+	mockPatientService.On("Create", mock.MatchedBy(func(p *models.Patient) bool {
+		return p.Name == "John Doe" && p.Gender == models.GenderMale && p.ContactInfo == "555-0100" && p.CreatedBy == 9 &&
+			models.FormatDate(p.BirthDate) == "1980-03-15"
+	}), true).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Patient).ID = 42
+	}).Return(nil, nil)
+	mockIdentifierRepo.On("Create", &models.PatientIdentifier{System: "HOSP", Value: "12345", PatientID: 42}).Return(nil)
+	mockMessageRepo.On("Update", mock.MatchedBy(func(m *models.HL7Message) bool {
+		return m.Status == models.HL7MessageProcessed && m.PatientID == 42 && m.PatientClass == "O" && m.AckCode == hl7.AckAccept
+	})).Return(nil)
+
+	// Create HL7 service with mocks
+	hl7Service := services.NewHL7Service(mockPatientService, new(MockMergeService), mockMessageRepo, mockIdentifierRepo, newTransactions(nil, nil), 9)
+
+	// Call the method being tested
+	code, _ := parseACK(t, hl7Service.HandleMessage(context.Background(), []byte(raw)))
+
+	// Assert expectations
+	assert.Equal(t, hl7.AckAccept, code)
+
+	// Verify that the mocks were called as expected
+	mockPatientService.AssertExpectations(t)
+	mockMessageRepo.AssertExpectations(t)
+	mockIdentifierRepo.AssertExpectations(t)
+}
+
+func TestHL7Service_A08_RegistersDeletedPatientAgain(t *testing.T) {
+	// Create mocks
+	mockPatientService := new(MockPatientService)
+	mockMessageRepo := new(MockHL7MessageRepository)
+	mockIdentifierRepo := new(MockPatientIdentifierRepository)
+
+	raw := hl7Header + "ADT^A08^ADT_A01|MSG7|P|2.5.1\r" +
+		"PID|1||12345^^^HOSP^MR||Doe^John||19800315|M|||||555-0100\r"
+
+	// Set up expectations: the identifier still names a patient deleted through the API
+	mockMessageRepo.On("Create", mock.Anything).Return(nil)
+	mockIdentifierRepo.On("FindByValue", "HOSP", "12345").Return(&models.PatientIdentifier{Model: gorm.Model{ID: 5}, PatientID: 42}, nil)
+	mockPatientService.On("GetByID", uint(42)).Return(nil, apperror.NotFound(apperror.CodePatientNotFound, "patient not found"))
+	mockPatientService.On("Create", mock.AnythingOfType("*models.Patient"), true).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Patient).ID = 43
+	}).Return(nil, nil)
+	mockIdentifierRepo.On("Relink", uint(5), uint(43)).Return(nil)
+	mockMessageRepo.On("Update", mock.MatchedBy(func(m *models.HL7Message) bool {
+		return m.Status == models.HL7MessageProcessed && m.PatientID == 43
+	})).Return(nil)
+
+	// Create HL7 service with mocks
+	hl7Service := services.NewHL7Service(mockPatientService, new(MockMergeService), mockMessageRepo, mockIdentifierRepo, newTransactions(nil, nil), 9)
+
+	// Call the method being tested
+	code, _ := parseACK(t, hl7Service.HandleMessage(context.Background(), []byte(raw)))
+
+	// Assert expectations
+	assert.Equal(t, hl7.AckAccept, code)
+	mockIdentifierRepo.AssertExpectations(t)
+	mockIdentifierRepo.AssertNotCalled(t, "Create", mock.Anything)
+	mockMessageRepo.AssertExpectations(t)
+}
+
+func TestHL7Service_A08_UpdatesKnownPatient(t *testing.T) {
+	// Create mocks
+	mockPatientService := new(MockPatientService)
+	mockMessageRepo := new(MockHL7MessageRepository)
+	mockIdentifierRepo := new(MockPatientIdentifierRepository)
+
+	raw := hl7Header + "ADT^A08^ADT_A01|MSG2|P|2.5.1\r" +
+		"PID|1||12345^^^HOSP^MR||Doe^Jonathan||||||||555-0199\r"
+
+	existing := &models.Patient{Model: gorm.Model{ID: 42}, Name: "John Doe", Age: 46, Gender: models.GenderMale, ContactInfo: "555-0100"}
+
+	// Set up expectations
+	mockMessageRepo.On("Create", mock.Anything).Return(nil)
+	mockIdentifierRepo.On("FindByValue", "HOSP", "12345").Return(&models.PatientIdentifier{PatientID: 42}, nil)
+	mockPatientService.On("GetByID", uint(42)).Return(existing, nil)
+	mockPatientService.On("Update", mock.MatchedBy(func(p *models.Patient) bool {
+		// Fields absent from the message are left alone
+		return p.Name == "Jonathan Doe" && p.ContactInfo == "555-0199" && p.Age == 46 && p.Gender == models.GenderMale
+	})).Return(nil)
+	mockMessageRepo.On("Update", mock.Anything).Return(nil)
+
+	// Create HL7 service with mocks
+	hl7Service := services.NewHL7Service(mockPatientService, new(MockMergeService), mockMessageRepo, mockIdentifierRepo, newTransactions(nil, nil), 9)
+
+	// Call the method being tested
+	code, _ := parseACK(t, hl7Service.HandleMessage(context.Background(), []byte(raw)))
+
+	// Assert expectations
+	assert.Equal(t, hl7.AckAccept, code)
+	mockPatientService.AssertExpectations(t)
+}
+
+func TestHL7Service_A40_MergesPatients(t *testing.T) {
+	// Create mocks
+	mockPatientService := new(MockPatientService)
+	mockMergeService := new(MockMergeService)
+	mockMessageRepo := new(MockHL7MessageRepository)
+	mockIdentifierRepo := new(MockPatientIdentifierRepository)
+
+	raw := hl7Header + "ADT^A40^ADT_A39|MSG3|P|2.5.1\r" +
+		"PID|1||12345^^^HOSP^MR||Doe^John\r" +
+		"MRG|67890^^^HOSP^MR\r"
+
+	survivor := &models.Patient{Model: gorm.Model{ID: 42}, Name: "John Doe"}
+	prior := &models.Patient{Model: gorm.Model{ID: 43}, Name: "Jon Doe"}
+
+	// Set up expectations
+	mockMessageRepo.On("Create", mock.Anything).Return(nil)
+	mockIdentifierRepo.On("FindByValue", "HOSP", "67890").Return(&models.PatientIdentifier{PatientID: 43}, nil)
+	mockIdentifierRepo.On("FindByValue", "HOSP", "12345").Return(&models.PatientIdentifier{PatientID: 42}, nil)
+	mockPatientService.On("GetByID", uint(43)).Return(prior, nil)
+	mockPatientService.On("GetByID", uint(42)).Return(survivor, nil)
+	mockMergeService.On("Merge", uint(42), uint(43), uint(9)).Return(&models.PatientMerge{SurvivorID: 42, MergedID: 43}, nil)
+	mockMessageRepo.On("Update", mock.Anything).Return(nil)
+
+	// Create HL7 service with mocks
+	hl7Service := services.NewHL7Service(mockPatientService, mockMergeService, mockMessageRepo, mockIdentifierRepo, newTransactions(nil, nil), 9)
+
+	// Call the method being tested
+	code, _ := parseACK(t, hl7Service.HandleMessage(context.Background(), []byte(raw)))
+
+	// Assert expectations
+	assert.Equal(t, hl7.AckAccept, code)
+	mockPatientService.AssertExpectations(t)
+	mockMergeService.AssertExpectations(t)
+	mockIdentifierRepo.AssertExpectations(t)
+}
+
+func TestHL7Service_UnsupportedEvent_Rejected(t *testing.T) {
+	// Create mocks
+	mockPatientService := new(MockPatientService)
+	mockMessageRepo := new(MockHL7MessageRepository)
+	mockIdentifierRepo := new(MockPatientIdentifierRepository)
+
+	raw := hl7Header + "ADT^A03^ADT_A03|MSG4|P|2.5.1\rPID|1||12345\r"
+
+	// Set up expectations
+	mockMessageRepo.On("Create", mock.Anything).Return(nil)
+	mockMessageRepo.On("Update", mock.MatchedBy(func(m *models.HL7Message) bool {
+		return m.Status == models.HL7MessageFailed && m.AckCode == hl7.AckReject
+	})).Return(nil)
+
+	// Create HL7 service with mocks
+	hl7Service := services.NewHL7Service(mockPatientService, new(MockMergeService), mockMessageRepo, mockIdentifierRepo, newTransactions(nil, nil), 9)
+
+	// Call the method being tested
+	code, text := parseACK(t, hl7Service.HandleMessage(context.Background(), []byte(raw)))
+
+	// Assert expectations
+	assert.Equal(t, hl7.AckReject, code)
+	assert.Contains(t, text, "unsupported")
+	mockMessageRepo.AssertExpectations(t)
+}
+
+func TestHL7Service_InvalidMessages_Rejected(t *testing.T) {
+	tests := map[string]string{
+		"bad birth date":     "PID|1||12345^^^HOSP^MR||Doe^John||1980-03-15|M|||||555-0100\r",
+		"missing identifier": "PID|1||||Doe^John||19800315|M|||||555-0100\r",
+		"missing birth date": "PID|1||12345^^^HOSP^MR||Doe^John|||M|||||555-0100\r",
+		"missing sex":        "PID|1||12345^^^HOSP^MR||Doe^John||19800315||||||555-0100\r",
+		"missing phone":      "PID|1||12345^^^HOSP^MR||Doe^John||19800315|M\r",
+	}
+
+	for name, pid := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Create mocks
+			mockMessageRepo := new(MockHL7MessageRepository)
+			mockIdentifierRepo := new(MockPatientIdentifierRepository)
+
+			raw := hl7Header + "ADT^A04^ADT_A01|MSG5|P|2.5.1\r" + pid
+
+			// Set up expectations
+			mockMessageRepo.On("Create", mock.Anything).Return(nil)
+			mockIdentifierRepo.On("FindByValue", "HOSP", "12345").Return(nil, errIdentifierNotFound).Maybe()
+			mockMessageRepo.On("Update", mock.MatchedBy(func(m *models.HL7Message) bool {
+				return m.Status == models.HL7MessageFailed && m.AckCode == hl7.AckReject
+			})).Return(nil)
+
+			// Create HL7 service with mocks
+			hl7Service := services.NewHL7Service(new(MockPatientService), new(MockMergeService), mockMessageRepo, mockIdentifierRepo, newTransactions(nil, nil), 9)
+
+			// Resending a message that can never be applied does not help
+			code, _ := parseACK(t, hl7Service.HandleMessage(context.Background(), []byte(raw)))
+			assert.Equal(t, hl7.AckReject, code)
+			mockMessageRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHL7Service_Replay(t *testing.T) {
+	// Create mocks
+	mockPatientService := new(MockPatientService)
+	mockMessageRepo := new(MockHL7MessageRepository)
+	mockIdentifierRepo := new(MockPatientIdentifierRepository)
+
+	stored := &models.HL7Message{
+		Model:  gorm.Model{ID: 5},
+		Raw:    hl7Header + "ADT^A08^ADT_A01|MSG5|P|2.5.1\rPID|1||12345^^^HOSP^MR||Doe^John\r",
+		Status: models.HL7MessageFailed,
+		Error:  "database unavailable",
+	}
+
+	// Set up expectations
+	mockMessageRepo.On("FindByID", uint(5)).Return(stored, nil)
+	mockIdentifierRepo.On("FindByValue", "HOSP", "12345").Return(&models.PatientIdentifier{PatientID: 42}, nil)
+	mockPatientService.On("GetByID", uint(42)).Return(&models.Patient{Model: gorm.Model{ID: 42}}, nil)
+	mockPatientService.On("Update", mock.Anything).Return(nil)
+	mockMessageRepo.On("Update", stored).Return(nil)
+
+	// Create HL7 service with mocks
+	hl7Service := services.NewHL7Service(mockPatientService, new(MockMergeService), mockMessageRepo, mockIdentifierRepo, newTransactions(nil, nil), 9)
+
+	// Call the method being tested
+	result, err := hl7Service.Replay(context.Background(), 5)
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, models.HL7MessageProcessed, result.Status)
+	assert.Empty(t, result.Error)
+	mockMessageRepo.AssertExpectations(t)
+}
+
+func TestHL7Service_OverMLLP(t *testing.T) {
+	// Create mocks
+	mockPatientService := new(MockPatientService)
+	mockMessageRepo := new(MockHL7MessageRepository)
+	mockIdentifierRepo := new(MockPatientIdentifierRepository)
+
+	// Registration fails because the patient is missing a phone number
+	raw := hl7Header + "ADT^A04^ADT_A01|MSG6|P|2.5.1\rPID|1||555^^^HOSP^MR||Doe^Jane||19900101|F\r"
+
+	// Set up expectations
+	mockMessageRepo.On("Create", mock.Anything).Return(nil)
+	mockIdentifierRepo.On("FindByValue", "HOSP", "555").Return(nil, errIdentifierNotFound)
+	mockMessageRepo.On("Update", mock.Anything).Return(nil)
+
+	// Serve the HL7 service on a local port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &hl7.Server{Handler: services.NewHL7Service(mockPatientService, new(MockMergeService), mockMessageRepo, mockIdentifierRepo, newTransactions(nil, nil), 9)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// Send the message with a local MLLP client
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, hl7.WriteFrame(conn, []byte(raw)))
+	ack, err := hl7.ReadFrame(bufio.NewReader(conn))
+	require.NoError(t, err)
+
+	// Assert expectations
+	code, text := parseACK(t, ack)
+	assert.Equal(t, hl7.AckReject, code)
+	assert.Contains(t, text, "PID-13")
+	mockPatientService.AssertNotCalled(t, "Create", mock.Anything)
+}
@@ -0,0 +1,173 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
+	"hospital-project/internal/models"
+	"hospital-project/internal/services"
+)
+
+// MockIdempotencyRepository is a mock implementation of the IdempotencyRepository interface
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyKey) (bool, error) {
+	args := m.Called(record)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) FindByKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error) {
+	args := m.Called(userID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IdempotencyKey), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyKey) error {
+	args := m.Called(record)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func testIdempotencyConfig() *config.Idempotency {
+	return &config.Idempotency{TTL: time.Hour, CleanupInterval: time.Minute}
+}
+
+// storedKey returns the record a first request with the given body leaves behind
+func storedKey(t *testing.T, body string) *models.IdempotencyKey {
+	mockRepo := new(MockIdempotencyRepository)
+	mockRepo.On("Reserve", mock.Anything).Return(true, nil)
+
+	record, err := services.NewIdempotencyService(mockRepo, testIdempotencyConfig()).
+		Begin(context.Background(), 1, "key-1", http.MethodPost, "/api/patients", []byte(body))
+	require.NoError(t, err)
+	record.ID = 7
+	return record
+}
+
+func TestIdempotencyService_Begin_NewKey(t *testing.T) {
+	mockRepo := new(MockIdempotencyRepository)
+	mockRepo.On("Reserve", mock.Anything).Return(true, nil)
+
+	idempotencyService := services.NewIdempotencyService(mockRepo, testIdempotencyConfig())
+	record, err := idempotencyService.Begin(context.Background(), 1, "key-1", http.MethodPost, "/api/patients", []byte(`{"name":"John"}`))
+
+	require.NoError(t, err)
+	assert.False(t, record.Completed())
+	assert.Equal(t, "key-1", record.Key)
+	assert.NotEmpty(t, record.Fingerprint)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), record.ExpiresAt, time.Minute)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestIdempotencyService_Begin_Replay(t *testing.T) {
+	existing := storedKey(t, `{"name":"John"}`)
+	completedAt := time.Now()
+	existing.StatusCode = http.StatusCreated
+	existing.CompletedAt = &completedAt
+
+	mockRepo := new(MockIdempotencyRepository)
+	mockRepo.On("Reserve", mock.Anything).Return(false, nil)
+	mockRepo.On("FindByKey", uint(1), "key-1").Return(existing, nil)
+
+	idempotencyService := services.NewIdempotencyService(mockRepo, testIdempotencyConfig())
+	record, err := idempotencyService.Begin(context.Background(), 1, "key-1", http.MethodPost, "/api/patients", []byte(`{"name":"John"}`))
+
+	require.NoError(t, err)
+	assert.Same(t, existing, record)
+	assert.True(t, record.Completed())
+}
+
+func TestIdempotencyService_Begin_DifferentBody(t *testing.T) {
+	mockRepo := new(MockIdempotencyRepository)
+	mockRepo.On("Reserve", mock.Anything).Return(false, nil)
+	mockRepo.On("FindByKey", uint(1), "key-1").Return(storedKey(t, `{"name":"John"}`), nil)
+
+	idempotencyService := services.NewIdempotencyService(mockRepo, testIdempotencyConfig())
+	_, err := idempotencyService.Begin(context.Background(), 1, "key-1", http.MethodPost, "/api/patients", []byte(`{"name":"Jane"}`))
+
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	assert.Equal(t, apperror.KindUnprocessable, appErr.Kind)
+	assert.Equal(t, apperror.CodeIdempotencyKeyReused, appErr.Code)
+}
+
+func TestIdempotencyService_Begin_InProgress(t *testing.T) {
+	mockRepo := new(MockIdempotencyRepository)
+	mockRepo.On("Reserve", mock.Anything).Return(false, nil)
+	mockRepo.On("FindByKey", uint(1), "key-1").Return(storedKey(t, `{"name":"John"}`), nil)
+
+	idempotencyService := services.NewIdempotencyService(mockRepo, testIdempotencyConfig())
+	_, err := idempotencyService.Begin(context.Background(), 1, "key-1", http.MethodPost, "/api/patients", []byte(`{"name":"John"}`))
+
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	assert.Equal(t, apperror.CodeIdempotencyKeyInProgress, appErr.Code)
+}
+
+func TestIdempotencyService_Begin_ExpiredKeyIsReused(t *testing.T) {
+	expired := storedKey(t, `{"name":"John"}`)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+	mockRepo := new(MockIdempotencyRepository)
+	mockRepo.On("Reserve", mock.Anything).Return(false, nil).Once()
+	mockRepo.On("FindByKey", uint(1), "key-1").Return(expired, nil)
+	mockRepo.On("Delete", uint(7)).Return(nil)
+	mockRepo.On("Reserve", mock.Anything).Return(true, nil).Once()
+
+	idempotencyService := services.NewIdempotencyService(mockRepo, testIdempotencyConfig())
+	record, err := idempotencyService.Begin(context.Background(), 1, "key-1", http.MethodPost, "/api/patients", []byte(`{"name":"Jane"}`))
+
+	require.NoError(t, err)
+	assert.False(t, record.Completed())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestIdempotencyService_Begin_InvalidKey(t *testing.T) {
+	idempotencyService := services.NewIdempotencyService(new(MockIdempotencyRepository), testIdempotencyConfig())
+
+	_, err := idempotencyService.Begin(context.Background(), 1, string(make([]byte, 256)), http.MethodPost, "/api/patients", nil)
+
+	assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+}
+
+func TestIdempotencyService_Complete(t *testing.T) {
+	record := storedKey(t, `{"name":"John"}`)
+
+	mockRepo := new(MockIdempotencyRepository)
+	mockRepo.On("Complete", record).Return(nil)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Request-Id", "abc")
+
+	idempotencyService := services.NewIdempotencyService(mockRepo, testIdempotencyConfig())
+	err := idempotencyService.Complete(context.Background(), record, http.StatusCreated, header, []byte(`{"id":1}`))
+
+	require.NoError(t, err)
+	assert.True(t, record.Completed())
+	assert.Equal(t, http.StatusCreated, record.StatusCode)
+	assert.JSONEq(t, `{"Content-Type":"application/json"}`, record.ResponseHeaders)
+	assert.Equal(t, `{"id":1}`, string(record.ResponseBody))
+	mockRepo.AssertExpectations(t)
+}
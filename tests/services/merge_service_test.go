@@ -0,0 +1,327 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
+	"hospital-project/internal/events"
+	"hospital-project/internal/models"
+	"hospital-project/internal/repositories"
+	"hospital-project/internal/services"
+)
+
+// fakeMergeRepository keeps merges in memory and records the rows moved back
+type fakeMergeRepository struct {
+	merges   []*models.PatientMerge
+	relinked map[string][]uint
+	restored map[string][]uint
+}
+
+func (f *fakeMergeRepository) Create(ctx context.Context, merge *models.PatientMerge) error {
+	merge.ID = uint(len(f.merges) + 1)
+	f.merges = append(f.merges, merge)
+	return nil
+}
+
+func (f *fakeMergeRepository) FindByID(ctx context.Context, id uint) (*models.PatientMerge, error) {
+	for _, merge := range f.merges {
+		if merge.ID == id {
+			return merge, nil
+		}
+	}
+	return nil, apperror.NotFound(apperror.CodeMergeNotFound, "patient merge not found")
+}
+
+func (f *fakeMergeRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.PatientMerge, error) {
+	return f.FindByID(ctx, id)
+}
+
+func (f *fakeMergeRepository) FindActiveByMergedID(ctx context.Context, mergedID uint) (*models.PatientMerge, error) {
+	for _, merge := range f.merges {
+		if merge.MergedID == mergedID && merge.UnmergedAt == nil {
+			return merge, nil
+		}
+	}
+	return nil, apperror.NotFound(apperror.CodeMergeNotFound, "patient merge not found")
+}
+
+func (f *fakeMergeRepository) Update(ctx context.Context, merge *models.PatientMerge) error {
+	return nil
+}
+
+func (f *fakeMergeRepository) Relink(ctx context.Context, fromPatientID, toPatientID uint) (map[string][]uint, error) {
+	return f.relinked, nil
+}
+
+func (f *fakeMergeRepository) Restore(ctx context.Context, relinked map[string][]uint, fromPatientID, toPatientID uint) error {
+	f.restored = relinked
+	return nil
+}
+
+// mergeConfig is the merge configuration of the tests
+var mergeConfig = &config.Merge{UnmergeWindow: time.Hour}
+
+// newMergeTransactions returns a transaction manager over the mock patient
+// repository and in-memory merges, duplicates, audit trail and outbox
+func newMergeTransactions(patientRepo repositories.PatientRepository, merges *fakeMergeRepository, duplicates *fakeDuplicateRepository, audit *MockAuditRepository, outbox *fakeOutboxRepository) repositories.TransactionManager {
+	return &fakeTransactionManager{repos: &repositories.Repositories{
+		Patients:   patientRepo,
+		Outbox:     outbox,
+		Audit:      audit,
+		Duplicates: duplicates,
+		Merges:     merges,
+	}}
+}
+
+// bumpVersion makes the mocked Update increment the version like the repository does
+func bumpVersion(args mock.Arguments) {
+	args.Get(0).(*models.Patient).Version++
+}
+
+func TestMergeService_Merge_FoldsDuplicate(t *testing.T) {
+	mockRepo := new(MockPatientRepository)
+	mockAudit := new(MockAuditRepository)
+	merges := &fakeMergeRepository{relinked: map[string][]uint{"patient_identifiers": {7, 8}}}
+	duplicates := &fakeDuplicateRepository{}
+	outbox := &fakeOutboxRepository{}
+
+	birthDate, _ := models.ParseDate("1980-03-15")
+	survivor := &models.Patient{Model: gorm.Model{ID: 1}, Name: "John Doe", MedicalNotes: "Asthma", Version: 3}
+	merged := &models.Patient{Model: gorm.Model{ID: 2}, Name: "Jon Doe", MedicalNotes: "Penicillin allergy", BirthDate: birthDate, Address: "12 High Street", Version: 1}
+
+	mockRepo.On("FindByIDForUpdate", uint(1)).Return(survivor, nil)
+	mockRepo.On("FindByIDForUpdate", uint(2)).Return(merged, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(p *models.Patient) bool {
+		return p.ID == 1 && p.MedicalNotes == "Asthma\n\n[Merged from patient 2]\nPenicillin allergy" &&
+			p.BirthDate == birthDate && p.Address == "12 High Street"
+	})).Run(bumpVersion).Return(nil)
+	mockRepo.On("Delete", uint(2)).Return(nil)
+	mockAudit.On("Create", mock.MatchedBy(func(entry *models.AuditLog) bool {
+		return entry.Action == models.AuditActionPatientMerge && entry.UserID == 5
+	})).Return(nil).Twice()
+
+	mergeService := services.NewMergeService(merges, newMergeTransactions(mockRepo, merges, duplicates, mockAudit, outbox), mergeConfig)
+
+	merge, err := mergeService.Merge(context.Background(), 1, 2, 5)
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), merge.SurvivorID)
+	assert.Equal(t, uint(2), merge.MergedID)
+	assert.Equal(t, "Asthma", merge.SurvivorBefore.MedicalNotes)
+	assert.Empty(t, merge.SurvivorBefore.BirthDate)
+	assert.Equal(t, uint(4), merge.SurvivorVersion)
+	assert.Equal(t, []uint{7, 8}, merge.Relinked["patient_identifiers"])
+	assert.WithinDuration(t, time.Now().Add(time.Hour), merge.UnmergeBefore, time.Minute)
+	assert.Equal(t, [][2]uint{{1, 2}}, duplicates.confirmed)
+
+	require.Len(t, outbox.events, 2)
+	assert.Equal(t, events.PatientUpdated, outbox.events[0].EventType)
+	assert.Equal(t, uint(1), outbox.events[0].AggregateID)
+	assert.Equal(t, events.PatientDeleted, outbox.events[1].EventType)
+	assert.Equal(t, uint(2), outbox.events[1].AggregateID)
+	mockRepo.AssertExpectations(t)
+	mockAudit.AssertExpectations(t)
+}
+
+// queuedDuplicates returns suspected duplicates of patient 2, who is merged
+// into patient 1, and of other patients
+func queuedDuplicates() map[uint]*models.PatientDuplicate {
+	return map[uint]*models.PatientDuplicate{
+		10: {PatientID: 2, CandidateID: 1, Status: models.DuplicateStatusPending},
+		11: {PatientID: 2, CandidateID: 3, Status: models.DuplicateStatusPending},
+		12: {PatientID: 4, CandidateID: 2, Status: models.DuplicateStatusPending},
+		13: {PatientID: 4, CandidateID: 3, Status: models.DuplicateStatusPending},
+		14: {PatientID: 2, CandidateID: 5, Status: models.DuplicateStatusDismissed},
+	}
+}
+
+// statuses returns the status of each suspected duplicate by ID
+func statuses(rows map[uint]*models.PatientDuplicate) map[uint]models.DuplicateStatus {
+	statuses := make(map[uint]models.DuplicateStatus, len(rows))
+	for id, row := range rows {
+		statuses[id] = row.Status
+	}
+	return statuses
+}
+
+// expectMerge sets up the patient repository for merging patient 2 into 1
+func expectMerge(mockRepo *MockPatientRepository, mockAudit *MockAuditRepository) {
+	mockRepo.On("FindByIDForUpdate", uint(1)).Return(&models.Patient{Model: gorm.Model{ID: 1}, Name: "John Doe", Version: 3}, nil)
+	mockRepo.On("FindByIDForUpdate", uint(2)).Return(&models.Patient{Model: gorm.Model{ID: 2}, Name: "Jon Doe", Version: 1}, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*models.Patient")).Run(bumpVersion).Return(nil)
+	mockRepo.On("Delete", uint(2)).Return(nil)
+	mockAudit.On("Create", mock.AnythingOfType("*models.AuditLog")).Return(nil)
+}
+
+func TestMergeService_Merge_ClosesPendingDuplicates(t *testing.T) {
+	mockRepo := new(MockPatientRepository)
+	mockAudit := new(MockAuditRepository)
+	merges := &fakeMergeRepository{}
+	duplicates := &fakeDuplicateRepository{rows: queuedDuplicates()}
+	expectMerge(mockRepo, mockAudit)
+
+	mergeService := services.NewMergeService(merges, newMergeTransactions(mockRepo, merges, duplicates, mockAudit, &fakeOutboxRepository{}), mergeConfig)
+
+	merge, err := mergeService.Merge(context.Background(), 1, 2, 5)
+
+	// The merged pair is confirmed and the merged patient's other pending
+	// pairs leave the queue; reviewed pairs and other patients' stay as they are
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]models.DuplicateStatus{
+		10: models.DuplicateStatusConfirmed,
+		11: models.DuplicateStatusMerged,
+		12: models.DuplicateStatusMerged,
+		13: models.DuplicateStatusPending,
+		14: models.DuplicateStatusDismissed,
+	}, statuses(duplicates.rows))
+	assert.ElementsMatch(t, []uint{10, 11, 12}, merge.ClosedDuplicates)
+}
+
+func TestMergeService_Unmerge_ReopensDuplicates(t *testing.T) {
+	mockRepo := new(MockPatientRepository)
+	mockAudit := new(MockAuditRepository)
+	merges := &fakeMergeRepository{}
+	duplicates := &fakeDuplicateRepository{rows: queuedDuplicates()}
+	expectMerge(mockRepo, mockAudit)
+	mockRepo.On("Restore", uint(2)).Return(nil)
+	mockRepo.On("FindByID", uint(2)).Return(&models.Patient{Model: gorm.Model{ID: 2}, Name: "Jon Doe"}, nil)
+
+	mergeService := services.NewMergeService(merges, newMergeTransactions(mockRepo, merges, duplicates, mockAudit, &fakeOutboxRepository{}), mergeConfig)
+
+	merge, err := mergeService.Merge(context.Background(), 1, 2, 5)
+	require.NoError(t, err)
+	_, err = mergeService.Unmerge(context.Background(), merge.ID, 5)
+	require.NoError(t, err)
+
+	// Every pair is back in the status it had before the merge
+	assert.Equal(t, statuses(queuedDuplicates()), statuses(duplicates.rows))
+}
+
+func TestMergeService_Merge_SamePatient(t *testing.T) {
+	mockRepo := new(MockPatientRepository)
+	merges := &fakeMergeRepository{}
+	mergeService := services.NewMergeService(merges, newMergeTransactions(mockRepo, merges, &fakeDuplicateRepository{}, new(MockAuditRepository), &fakeOutboxRepository{}), mergeConfig)
+
+	_, err := mergeService.Merge(context.Background(), 1, 1, 5)
+
+	assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+	mockRepo.AssertNotCalled(t, "FindByIDForUpdate", mock.Anything)
+}
+
+func TestMergeService_Unmerge_RestoresPatients(t *testing.T) {
+	mockRepo := new(MockPatientRepository)
+	mockAudit := new(MockAuditRepository)
+	outbox := &fakeOutboxRepository{}
+
+	survivor := &models.Patient{Model: gorm.Model{ID: 1}, Name: "John Doe", MedicalNotes: "Asthma\n\n[Merged from patient 2]\nPenicillin allergy", Version: 4}
+	before := models.Patient{Name: "John Doe", MedicalNotes: "Asthma"}
+	relinked := map[string][]uint{"patient_identifiers": {7, 8}}
+	merges := &fakeMergeRepository{merges: []*models.PatientMerge{{
+		Model:           gorm.Model{ID: 1},
+		SurvivorID:      1,
+		MergedID:        2,
+		SurvivorBefore:  before.ToDocument(),
+		SurvivorVersion: 4,
+		Relinked:        relinked,
+		UnmergeBefore:   time.Now().Add(time.Hour),
+	}}}
+
+	mockRepo.On("FindByIDForUpdate", uint(1)).Return(survivor, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(p *models.Patient) bool {
+		return p.ID == 1 && p.MedicalNotes == "Asthma"
+	})).Run(bumpVersion).Return(nil)
+	mockRepo.On("Restore", uint(2)).Return(nil)
+	mockRepo.On("FindByID", uint(2)).Return(&models.Patient{Model: gorm.Model{ID: 2}, Name: "Jon Doe"}, nil)
+	mockAudit.On("Create", mock.MatchedBy(func(entry *models.AuditLog) bool {
+		return entry.Action == models.AuditActionPatientUnmerge && entry.UserID == 5
+	})).Return(nil).Twice()
+
+	mergeService := services.NewMergeService(merges, newMergeTransactions(mockRepo, merges, &fakeDuplicateRepository{}, mockAudit, outbox), mergeConfig)
+
+	merge, err := mergeService.Unmerge(context.Background(), 1, 5)
+
+	require.NoError(t, err)
+	require.NotNil(t, merge.UnmergedAt)
+	assert.Equal(t, uint(5), *merge.UnmergedBy)
+	assert.Equal(t, relinked, merges.restored)
+
+	// The merged patient no longer redirects
+	_, err = mergeService.Survivor(context.Background(), 2)
+	assert.True(t, apperror.IsNotFound(err))
+
+	require.Len(t, outbox.events, 2)
+	assert.Equal(t, events.PatientUpdated, outbox.events[0].EventType)
+	assert.Equal(t, events.PatientRegistered, outbox.events[1].EventType)
+	assert.Equal(t, uint(2), outbox.events[1].AggregateID)
+	mockRepo.AssertExpectations(t)
+	mockAudit.AssertExpectations(t)
+}
+
+func TestMergeService_Unmerge_Refused(t *testing.T) {
+	undone := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		merge models.PatientMerge
+		code  string
+	}{
+		{
+			name:  "already undone",
+			merge: models.PatientMerge{SurvivorVersion: 4, UnmergeBefore: time.Now().Add(time.Hour), UnmergedAt: &undone},
+			code:  apperror.CodeMergeUndone,
+		},
+		{
+			name:  "window has passed",
+			merge: models.PatientMerge{SurvivorVersion: 4, UnmergeBefore: time.Now().Add(-time.Hour)},
+			code:  apperror.CodeMergeExpired,
+		},
+		{
+			name:  "survivor changed since",
+			merge: models.PatientMerge{SurvivorVersion: 3, UnmergeBefore: time.Now().Add(time.Hour)},
+			code:  apperror.CodeMergeSurvivorChanged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockPatientRepository)
+			mockRepo.On("FindByIDForUpdate", uint(1)).Return(&models.Patient{Model: gorm.Model{ID: 1}, Version: 4}, nil)
+
+			merge := tt.merge
+			merge.ID, merge.SurvivorID, merge.MergedID = 1, 1, 2
+			merges := &fakeMergeRepository{merges: []*models.PatientMerge{&merge}}
+			mergeService := services.NewMergeService(merges, newMergeTransactions(mockRepo, merges, &fakeDuplicateRepository{}, new(MockAuditRepository), &fakeOutboxRepository{}), mergeConfig)
+
+			_, err := mergeService.Unmerge(context.Background(), 1, 5)
+
+			appErr, ok := apperror.As(err)
+			require.True(t, ok)
+			assert.Equal(t, apperror.KindConflict, appErr.Kind)
+			assert.Equal(t, tt.code, appErr.Code)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+		})
+	}
+}
+
+func TestMergeService_Survivor_FollowsMerges(t *testing.T) {
+	merges := &fakeMergeRepository{merges: []*models.PatientMerge{
+		{Model: gorm.Model{ID: 1}, SurvivorID: 3, MergedID: 2},
+		{Model: gorm.Model{ID: 2}, SurvivorID: 5, MergedID: 3},
+	}}
+	mergeService := services.NewMergeService(merges, newMergeTransactions(nil, merges, nil, nil, nil), mergeConfig)
+
+	survivorID, err := mergeService.Survivor(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint(5), survivorID)
+
+	_, err = mergeService.Survivor(context.Background(), 9)
+	assert.True(t, apperror.IsNotFound(err))
+}
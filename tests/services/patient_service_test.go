@@ -1,13 +1,22 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
+	"hospital-project/internal/events"
 	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/repositories"
 	"hospital-project/internal/services"
 )
 
@@ -16,12 +25,12 @@ type MockPatientRepository struct {
 	mock.Mock
 }
 
-func (m *MockPatientRepository) Create(patient *models.Patient) error {
+func (m *MockPatientRepository) Create(ctx context.Context, patient *models.Patient) error {
 	args := m.Called(patient)
 	return args.Error(0)
 }
 
-func (m *MockPatientRepository) FindByID(id uint) (*models.Patient, error) {
+func (m *MockPatientRepository) FindByID(ctx context.Context, id uint) (*models.Patient, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -29,36 +38,181 @@ func (m *MockPatientRepository) FindByID(id uint) (*models.Patient, error) {
 	return args.Get(0).(*models.Patient), args.Error(1)
 }
 
-func (m *MockPatientRepository) Update(patient *models.Patient) error {
+func (m *MockPatientRepository) FindByIDForUpdate(ctx context.Context, id uint) (*models.Patient, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Patient), args.Error(1)
+}
+
+func (m *MockPatientRepository) Update(ctx context.Context, patient *models.Patient) error {
 	args := m.Called(patient)
 	return args.Error(0)
 }
 
-func (m *MockPatientRepository) UpdateMedicalNotes(id uint, medicalNotes string) error {
-	args := m.Called(id, medicalNotes)
+func (m *MockPatientRepository) UpdateMedicalNotes(ctx context.Context, id uint, medicalNotes string, version uint) error {
+	args := m.Called(id, medicalNotes, version)
 	return args.Error(0)
 }
 
-func (m *MockPatientRepository) Delete(id uint) error {
+func (m *MockPatientRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockPatientRepository) List(page, limit int) ([]models.Patient, int64, error) {
-	args := m.Called(page, limit)
-	return args.Get(0).([]models.Patient), args.Get(1).(int64), args.Error(2)
+func (m *MockPatientRepository) Restore(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockPatientRepository) List(ctx context.Context, request pagination.Request) (*pagination.Page[models.Patient], error) {
+	args := m.Called(request)
+	return args.Get(0).(*pagination.Page[models.Patient]), args.Error(1)
+}
+
+func (m *MockPatientRepository) Search(ctx context.Context, params models.PatientSearchRequest, sort []pagination.SortKey, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	args := m.Called(params, sort, request)
+	return args.Get(0).(*pagination.Page[models.PatientMatch]), args.Error(1)
+}
+
+func (m *MockPatientRepository) SearchNotes(ctx context.Context, query string, params models.PatientSearchRequest, request pagination.Request) (*pagination.Page[models.PatientMatch], error) {
+	args := m.Called(query, params, request)
+	return args.Get(0).(*pagination.Page[models.PatientMatch]), args.Error(1)
+}
+
+func (m *MockPatientRepository) Export(ctx context.Context, params models.PatientSearchRequest, fn func(*models.Patient) error) error {
+	args := m.Called(params, fn)
+	if patients, ok := args.Get(0).([]models.Patient); ok {
+		for i := range patients {
+			if err := fn(&patients[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockPatientRepository) Facets(ctx context.Context, query string, params models.PatientSearchRequest, facets []string) (map[string][]models.FacetCount, error) {
+	args := m.Called(query, params, facets)
+	counts, _ := args.Get(0).(map[string][]models.FacetCount)
+	return counts, args.Error(1)
+}
+
+func (m *MockPatientRepository) FindMatchCandidates(ctx context.Context, patient *models.Patient, limit int) ([]models.Patient, error) {
+	args := m.Called(patient, limit)
+	candidates, _ := args.Get(0).([]models.Patient)
+	return candidates, args.Error(1)
+}
+
+func (m *MockPatientRepository) CountCreatedSince(ctx context.Context, since time.Time) (int64, error) {
+	args := m.Called(since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// fakeOutboxRepository records appended events in memory
+type fakeOutboxRepository struct {
+	events []*models.OutboxEvent
+	err    error
+}
+
+func (f *fakeOutboxRepository) Append(ctx context.Context, event *models.OutboxEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakeOutboxRepository) ListPending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	return nil, nil
+}
+
+func (f *fakeOutboxRepository) Update(ctx context.Context, event *models.OutboxEvent) error {
+	return nil
+}
+
+func (f *fakeOutboxRepository) PurgePublished(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeDuplicateRepository records queued duplicates and confirmed pairs in
+// memory, and closes and reopens the queued rows it is given by ID
+type fakeDuplicateRepository struct {
+	queued    []models.PatientDuplicate
+	confirmed [][2]uint
+	rows      map[uint]*models.PatientDuplicate
+}
+
+func (f *fakeDuplicateRepository) Queue(ctx context.Context, duplicates []models.PatientDuplicate) error {
+	f.queued = append(f.queued, duplicates...)
+	return nil
+}
+
+func (f *fakeDuplicateRepository) FindByID(ctx context.Context, id uint) (*models.PatientDuplicate, error) {
+	return nil, nil
+}
+
+func (f *fakeDuplicateRepository) List(ctx context.Context, status models.DuplicateStatus, request pagination.Request) (*pagination.Page[models.PatientDuplicate], error) {
+	return nil, nil
+}
+
+func (f *fakeDuplicateRepository) Review(ctx context.Context, id uint, status models.DuplicateStatus, reviewerID uint, at time.Time) (*models.PatientDuplicate, error) {
+	return nil, nil
+}
+
+func (f *fakeDuplicateRepository) ConfirmPair(ctx context.Context, patientID, otherID, reviewerID uint, at time.Time) ([]uint, error) {
+	f.confirmed = append(f.confirmed, [2]uint{patientID, otherID})
+	return f.close(models.DuplicateStatusConfirmed, func(d *models.PatientDuplicate) bool {
+		return (d.PatientID == patientID && d.CandidateID == otherID) || (d.PatientID == otherID && d.CandidateID == patientID)
+	}), nil
+}
+
+func (f *fakeDuplicateRepository) CloseMerged(ctx context.Context, patientID, reviewerID uint, at time.Time) ([]uint, error) {
+	return f.close(models.DuplicateStatusMerged, func(d *models.PatientDuplicate) bool {
+		return d.PatientID == patientID || d.CandidateID == patientID
+	}), nil
+}
+
+func (f *fakeDuplicateRepository) close(status models.DuplicateStatus, match func(*models.PatientDuplicate) bool) []uint {
+	var ids []uint
+	for id, duplicate := range f.rows {
+		if duplicate.Status == models.DuplicateStatusPending && match(duplicate) {
+			duplicate.Status = status
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
 
-func (m *MockPatientRepository) Search(params models.PatientSearchRequest) ([]models.Patient, error) {
-	args := m.Called(params)
-	return args.Get(0).([]models.Patient), args.Error(1)
+func (f *fakeDuplicateRepository) Reopen(ctx context.Context, ids []uint) error {
+	for _, id := range ids {
+		f.rows[id].Status = models.DuplicateStatusPending
+	}
+	return nil
 }
 
-func (m *MockPatientRepository) ExistsByNameOrContact(name, contactInfo string) (bool, error) {
-	args := m.Called(name, contactInfo)
-	return args.Bool(0), args.Error(1)
+// fakeTransactionManager runs the callback against the mocks without a database
+type fakeTransactionManager struct {
+	repos *repositories.Repositories
 }
 
+func (f *fakeTransactionManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos *repositories.Repositories) error) error {
+	return fn(ctx, f.repos)
+}
+
+// newTransactions returns a transaction manager over the mock repository,
+// an in-memory outbox and an in-memory duplicate queue
+func newTransactions(patientRepo repositories.PatientRepository, outbox repositories.OutboxRepository) repositories.TransactionManager {
+	if outbox == nil {
+		outbox = &fakeOutboxRepository{}
+	}
+	return &fakeTransactionManager{repos: &repositories.Repositories{Patients: patientRepo, Outbox: outbox, Duplicates: &fakeDuplicateRepository{}}}
+}
+
+// duplicatesConfig is the duplicate detection configuration of the tests
+var duplicatesConfig = &config.Duplicates{BlockThreshold: 0.9, ReviewThreshold: 0.7, Candidates: 50}
+
 func TestPatientService_Create_Success(t *testing.T) {
 	// Create mock repository
 	mockRepo := new(MockPatientRepository)
@@ -73,49 +227,127 @@ func TestPatientService_Create_Success(t *testing.T) {
 	}
 
 	// Set up expectations
-	mockRepo.On("ExistsByNameOrContact", patient.Name, patient.ContactInfo).Return(false, nil)
+	mockRepo.On("FindMatchCandidates", patient, 50).Return(nil, nil)
 	mockRepo.On("Create", patient).Return(nil)
 
 	// Create patient service with mock repository
-	patientService := services.NewPatientService(mockRepo)
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
 
 	// Call the method being tested
-	err := patientService.Create(patient)
+	matches, err := patientService.Create(context.Background(), patient, false)
 
 	// Assert expectations
 	assert.NoError(t, err)
+	assert.Empty(t, matches)
 
 	// Verify that the mock was called as expected
 	mockRepo.AssertExpectations(t)
 }
 
 func TestPatientService_Create_DuplicatePatient(t *testing.T) {
-	// Create mock repository
+	// Create mock repository and duplicate queue
 	mockRepo := new(MockPatientRepository)
+	queue := &fakeDuplicateRepository{}
+	transactions := &fakeTransactionManager{repos: &repositories.Repositories{Patients: mockRepo, Outbox: &fakeOutboxRepository{}, Duplicates: queue}}
 
-	// Create test patient
+	// A misspelt registration of an existing patient
 	patient := &models.Patient{
-		Name:         "John Doe",
-		ContactInfo:  "1234567890",
+		Name:         "Jon Doe",
+		ContactInfo:  "+1 (123) 456-7890",
 		Age:          30,
 		Gender:       models.GenderMale,
 		CreatedBy:    1,
 	}
+	existing := models.Patient{Model: gorm.Model{ID: 7}, Name: "John Doe", ContactInfo: "123-456-7890", Age: 30, Gender: models.GenderMale}
 
 	// Set up expectations
-	mockRepo.On("ExistsByNameOrContact", patient.Name, patient.ContactInfo).Return(true, nil)
+	mockRepo.On("FindMatchCandidates", patient, 50).Return([]models.Patient{existing}, nil)
 
 	// Create patient service with mock repository
-	patientService := services.NewPatientService(mockRepo)
+	patientService := services.NewPatientService(mockRepo, transactions, duplicatesConfig)
 
 	// Call the method being tested
-	err := patientService.Create(patient)
+	matches, err := patientService.Create(context.Background(), patient, false)
+
+	// The patient is refused and the match returned, but nothing is queued
+	assert.Equal(t, apperror.KindConflict, apperror.KindOf(err))
+	require.Len(t, matches, 1)
+	assert.Equal(t, uint(7), matches[0].Patient.ID)
+	assert.GreaterOrEqual(t, matches[0].Score, 0.9)
+	assert.Equal(t, 1.0, matches[0].Features["contact"])
+	assert.Empty(t, queue.queued)
+	mockRepo.AssertNotCalled(t, "Create", patient)
+}
 
-	// Assert expectations
-	assert.Error(t, err)
-	assert.Equal(t, "patient with this name or contact info already exists", err.Error())
+func TestPatientService_Create_OverrideDuplicates(t *testing.T) {
+	// Create mock repository and duplicate queue
+	mockRepo := new(MockPatientRepository)
+	queue := &fakeDuplicateRepository{}
+	transactions := &fakeTransactionManager{repos: &repositories.Repositories{Patients: mockRepo, Outbox: &fakeOutboxRepository{}, Duplicates: queue}}
 
-	// Verify that the mock was called as expected
+	// A misspelt registration of an existing patient
+	patient := &models.Patient{
+		Name:         "Jon Doe",
+		ContactInfo:  "+1 (123) 456-7890",
+		Age:          30,
+		Gender:       models.GenderMale,
+		CreatedBy:    1,
+	}
+	existing := models.Patient{Model: gorm.Model{ID: 7}, Name: "John Doe", ContactInfo: "123-456-7890", Age: 30, Gender: models.GenderMale}
+
+	// Set up expectations
+	mockRepo.On("FindMatchCandidates", patient, 50).Return([]models.Patient{existing}, nil)
+	mockRepo.On("Create", patient).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Patient).ID = 8
+	}).Return(nil)
+
+	// Create patient service with mock repository
+	patientService := services.NewPatientService(mockRepo, transactions, duplicatesConfig)
+
+	// Call the method being tested
+	matches, err := patientService.Create(context.Background(), patient, true)
+
+	// The patient is registered and the pair queued for review
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	require.Len(t, queue.queued, 1)
+	assert.Equal(t, uint(8), queue.queued[0].PatientID)
+	assert.Equal(t, uint(7), queue.queued[0].CandidateID)
+	assert.Equal(t, models.DuplicateStatusPending, queue.queued[0].Status)
+	assert.Equal(t, matches[0].Score, queue.queued[0].Score)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatientService_Create_Namesake(t *testing.T) {
+	// Create mock repository and duplicate queue
+	mockRepo := new(MockPatientRepository)
+	queue := &fakeDuplicateRepository{}
+	transactions := &fakeTransactionManager{repos: &repositories.Repositories{Patients: mockRepo, Outbox: &fakeOutboxRepository{}, Duplicates: queue}}
+
+	// A different person with the same name as an existing patient
+	patient := &models.Patient{
+		Name:         "John Doe",
+		ContactInfo:  "john.doe@example.com",
+		Age:          62,
+		Gender:       models.GenderMale,
+		CreatedBy:    1,
+	}
+	existing := models.Patient{Model: gorm.Model{ID: 7}, Name: "John Doe", ContactInfo: "1234567890", Age: 30, Gender: models.GenderMale}
+
+	// Set up expectations
+	mockRepo.On("FindMatchCandidates", patient, 50).Return([]models.Patient{existing}, nil)
+	mockRepo.On("Create", patient).Return(nil)
+
+	// Create patient service with mock repository
+	patientService := services.NewPatientService(mockRepo, transactions, duplicatesConfig)
+
+	// Call the method being tested
+	matches, err := patientService.Create(context.Background(), patient, false)
+
+	// The namesake is registered without being queued
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.Empty(t, queue.queued)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -136,10 +368,10 @@ func TestPatientService_GetByID_Success(t *testing.T) {
 	mockRepo.On("FindByID", uint(1)).Return(patient, nil)
 
 	// Create patient service with mock repository
-	patientService := services.NewPatientService(mockRepo)
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
 
 	// Call the method being tested
-	result, err := patientService.GetByID(1)
+	result, err := patientService.GetByID(context.Background(), 1)
 
 	// Assert expectations
 	assert.NoError(t, err)
@@ -161,10 +393,10 @@ func TestPatientService_GetByID_NotFound(t *testing.T) {
 	mockRepo.On("FindByID", uint(1)).Return(nil, errors.New("not found"))
 
 	// Create patient service with mock repository
-	patientService := services.NewPatientService(mockRepo)
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
 
 	// Call the method being tested
-	result, err := patientService.GetByID(1)
+	result, err := patientService.GetByID(context.Background(), 1)
 
 	// Assert expectations
 	assert.Error(t, err)
@@ -187,14 +419,14 @@ func TestPatientService_UpdateMedicalNotes_Success(t *testing.T) {
 	}
 
 	// Set up expectations
-	mockRepo.On("FindByID", uint(1)).Return(patient, nil)
-	mockRepo.On("UpdateMedicalNotes", uint(1), "Updated notes").Return(nil)
+	mockRepo.On("FindByIDForUpdate", uint(1)).Return(patient, nil)
+	mockRepo.On("UpdateMedicalNotes", uint(1), "Updated notes", uint(1)).Return(nil)
 
 	// Create patient service with mock repository
-	patientService := services.NewPatientService(mockRepo)
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
 
 	// Call the method being tested
-	err := patientService.UpdateMedicalNotes(1, "Updated notes")
+	err := patientService.UpdateMedicalNotes(context.Background(), 1, "Updated notes", 1)
 
 	// Assert expectations
 	assert.NoError(t, err)
@@ -226,21 +458,23 @@ func TestPatientService_List_Success(t *testing.T) {
 	}
 
 	// Set up expectations
-	mockRepo.On("List", 1, 10).Return(patients, int64(2), nil)
+	total := int64(2)
+	request := pagination.Request{Number: 1, Limit: 10}
+	mockRepo.On("List", request).Return(&pagination.Page[models.Patient]{Items: patients, Number: 1, Limit: 10, Total: &total}, nil)
 
 	// Create patient service with mock repository
-	patientService := services.NewPatientService(mockRepo)
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
 
 	// Call the method being tested
-	result, total, err := patientService.List(1, 10)
+	result, err := patientService.List(context.Background(), request)
 
 	// Assert expectations
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
-	assert.Equal(t, int64(2), total)
-	assert.Len(t, result, 2)
-	assert.Equal(t, patients[0].Name, result[0].Name)
-	assert.Equal(t, patients[1].Name, result[1].Name)
+	assert.Equal(t, int64(2), *result.Total)
+	assert.Len(t, result.Items, 2)
+	assert.Equal(t, patients[0].Name, result.Items[0].Name)
+	assert.Equal(t, patients[1].Name, result.Items[1].Name)
 
 	// Verify that the mock was called as expected
 	mockRepo.AssertExpectations(t)
@@ -251,13 +485,16 @@ func TestPatientService_Search_Success(t *testing.T) {
 	mockRepo := new(MockPatientRepository)
 
 	// Create test patients
-	patients := []models.Patient{
+	patients := []models.PatientMatch{
 		{
-			Name:         "John Doe",
-			ContactInfo:  "1234567890",
-			Age:          30,
-			Gender:       models.GenderMale,
-			CreatedBy:    1,
+			Patient: models.Patient{
+				Name:        "John Doe",
+				ContactInfo: "1234567890",
+				Age:         30,
+				Gender:      models.GenderMale,
+				CreatedBy:   1,
+			},
+			Rank: 0.5,
 		},
 	}
 
@@ -266,21 +503,228 @@ func TestPatientService_Search_Success(t *testing.T) {
 		Name: "John",
 	}
 
+	sort := []pagination.SortKey{{Field: "name"}}
+	request := pagination.Request{Number: 1, Limit: 10}
+
 	// Set up expectations
-	mockRepo.On("Search", params).Return(patients, nil)
+	mockRepo.On("Search", params, sort, request).Return(&pagination.Page[models.PatientMatch]{Items: patients, Number: 1, Limit: 10}, nil)
 
 	// Create patient service with mock repository
-	patientService := services.NewPatientService(mockRepo)
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
 
 	// Call the method being tested
-	result, err := patientService.Search(params)
+	result, err := patientService.Search(context.Background(), params, sort, request)
 
 	// Assert expectations
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
-	assert.Len(t, result, 1)
-	assert.Equal(t, patients[0].Name, result[0].Name)
+	assert.Len(t, result.Items, 1)
+	assert.Equal(t, patients[0].Name, result.Items[0].Name)
+
+	// Verify that the mock was called as expected
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatientService_SearchNotes_Success(t *testing.T) {
+	mockRepo := new(MockPatientRepository)
+	request := pagination.Request{Number: 1, Limit: 10}
+	page := &pagination.Page[models.PatientMatch]{Items: []models.PatientMatch{{Rank: 0.1}}}
+	mockRepo.On("SearchNotes", "warfarin", models.PatientSearchRequest{}, request).Return(page, nil)
+
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
+	result, err := patientService.SearchNotes(context.Background(), " warfarin ", models.PatientSearchRequest{}, request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, page, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatientService_Facets(t *testing.T) {
+	mockRepo := new(MockPatientRepository)
+	params := models.PatientSearchRequest{Filter: "age >= 65"}
+	facets := []string{models.FacetGender, models.FacetAgeBand}
+	counts := map[string][]models.FacetCount{
+		models.FacetGender:  {{Value: "female", Count: 3}, {Value: "male", Count: 2}},
+		models.FacetAgeBand: {{Value: "65-79", Count: 4}, {Value: "80+", Count: 1}},
+	}
+	mockRepo.On("Facets", "warfarin", params, facets).Return(counts, nil)
+
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
+	result, err := patientService.Facets(context.Background(), " warfarin ", params, facets)
+
+	assert.NoError(t, err)
+	assert.Equal(t, counts, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatientService_SearchNotes_EmptyQuery(t *testing.T) {
+	mockRepo := new(MockPatientRepository)
+
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
+	_, err := patientService.SearchNotes(context.Background(), "  ", models.PatientSearchRequest{}, pagination.Request{Number: 1, Limit: 10})
+
+	assert.Equal(t, apperror.KindValidation, apperror.KindOf(err))
+	mockRepo.AssertNotCalled(t, "SearchNotes", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPatientService_Export_Success(t *testing.T) {
+	// Create mock repository
+	mockRepo := new(MockPatientRepository)
+
+	// Create test patients
+	patients := []models.Patient{
+		{Name: "John Doe", Age: 30, Gender: models.GenderMale, CreatedBy: 1},
+		{Name: "Jane Doe", Age: 25, Gender: models.GenderFemale, CreatedBy: 1},
+	}
+
+	// Create search parameters
+	params := models.PatientSearchRequest{Name: "Doe"}
+
+	// Set up expectations
+	mockRepo.On("Export", params, mock.Anything).Return(patients, nil)
+
+	// Create patient service with mock repository
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
+
+	// Call the method being tested
+	var exported []string
+	err := patientService.Export(context.Background(), params, func(patient *models.Patient) error {
+		exported = append(exported, patient.Name)
+		return nil
+	})
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"John Doe", "Jane Doe"}, exported)
 
 	// Verify that the mock was called as expected
 	mockRepo.AssertExpectations(t)
 }
+
+func TestPatientService_Export_HandlerError(t *testing.T) {
+	// Create mock repository
+	mockRepo := new(MockPatientRepository)
+
+	// Create test patients
+	patients := []models.Patient{
+		{Name: "John Doe"},
+		{Name: "Jane Doe"},
+	}
+
+	// Set up expectations
+	mockRepo.On("Export", models.PatientSearchRequest{}, mock.Anything).Return(patients, nil)
+
+	// Create patient service with mock repository
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, nil), duplicatesConfig)
+
+	// Call the method being tested, failing on the first row
+	calls := 0
+	err := patientService.Export(context.Background(), models.PatientSearchRequest{}, func(patient *models.Patient) error {
+		calls++
+		return errors.New("client disconnected")
+	})
+
+	// Assert expectations
+	assert.Error(t, err)
+	assert.Equal(t, "client disconnected", err.Error())
+	assert.Equal(t, 1, calls)
+}
+
+func TestPatientService_RecordsEvents(t *testing.T) {
+	// Create mock repository and outbox
+	mockRepo := new(MockPatientRepository)
+	outbox := &fakeOutboxRepository{}
+
+	// Create test patient
+	patient := &models.Patient{Name: "John Doe", ContactInfo: "1234567890"}
+	patient.ID = 1
+
+	// Set up expectations
+	mockRepo.On("FindMatchCandidates", patient, 50).Return(nil, nil)
+	mockRepo.On("Create", patient).Return(nil)
+	mockRepo.On("FindByIDForUpdate", uint(1)).Return(patient, nil)
+	mockRepo.On("UpdateMedicalNotes", uint(1), "notes", uint(1)).Return(nil)
+	mockRepo.On("Delete", uint(1)).Return(nil)
+
+	// Create patient service with mock repository and outbox
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, outbox), duplicatesConfig)
+
+	// Call the methods being tested
+	_, err := patientService.Create(context.Background(), patient, false)
+	assert.NoError(t, err)
+	assert.NoError(t, patientService.UpdateMedicalNotes(context.Background(), 1, "notes", 1))
+	assert.NoError(t, patientService.Delete(context.Background(), 1))
+
+	// Verify that every change was recorded in order
+	require.Len(t, outbox.events, 3)
+	assert.Equal(t, events.PatientRegistered, outbox.events[0].EventType)
+	assert.Equal(t, events.MedicalNotesUpdated, outbox.events[1].EventType)
+	assert.Equal(t, events.PatientDeleted, outbox.events[2].EventType)
+	for _, event := range outbox.events {
+		assert.Equal(t, events.AggregatePatient, event.AggregateType)
+		assert.Equal(t, uint(1), event.AggregateID)
+	}
+	assert.Contains(t, outbox.events[1].Payload, `"medical_notes":"notes"`)
+}
+
+func TestPatientService_OutboxFailureFailsChange(t *testing.T) {
+	// Create mock repository and a failing outbox
+	mockRepo := new(MockPatientRepository)
+	outbox := &fakeOutboxRepository{err: errors.New("database error")}
+
+	// Set up expectations
+	mockRepo.On("Delete", uint(1)).Return(nil)
+
+	// Create patient service with mock repository and outbox
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, outbox), duplicatesConfig)
+
+	// The change must not be reported as saved without its event
+	assert.Error(t, patientService.Delete(context.Background(), 1))
+}
+
+func TestPatientService_UpdateMedicalNotes_VersionMismatch(t *testing.T) {
+	// Create mock repository and outbox
+	mockRepo := new(MockPatientRepository)
+	outbox := &fakeOutboxRepository{}
+
+	// The patient was changed by someone else since version 1 was read
+	mismatch := apperror.New(apperror.KindPreconditionFailed, apperror.CodeVersionMismatch, "patient has been modified")
+	mockRepo.On("FindByIDForUpdate", uint(1)).Return(&models.Patient{Name: "John Doe", Version: 2}, nil)
+	mockRepo.On("UpdateMedicalNotes", uint(1), "notes", uint(1)).Return(mismatch)
+
+	// Create patient service with mock repository and outbox
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, outbox), duplicatesConfig)
+
+	// The conflict reaches the caller and no event is recorded
+	err := patientService.UpdateMedicalNotes(context.Background(), 1, "notes", 1)
+	assert.Equal(t, apperror.KindPreconditionFailed, apperror.KindOf(err))
+	assert.Empty(t, outbox.events)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatientService_Update_RecomputesAge(t *testing.T) {
+	// Create mock repository and outbox
+	mockRepo := new(MockPatientRepository)
+	outbox := &fakeOutboxRepository{}
+
+	// The stored patient was registered with another birth date
+	oldBirthDate := time.Date(1950, time.January, 1, 0, 0, 0, 0, time.UTC)
+	existing := &models.Patient{Name: "John Doe", BirthDate: &oldBirthDate, Age: 76}
+	existing.ID = 1
+
+	birthDate := time.Now().AddDate(-40, 0, -1)
+	patient := &models.Patient{Name: "John Doe", BirthDate: &birthDate, Age: 76}
+	patient.ID = 1
+
+	// Set up expectations
+	mockRepo.On("FindByIDForUpdate", uint(1)).Return(existing, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(updated *models.Patient) bool { return updated.Age == 40 })).Return(nil)
+
+	// Create patient service with mock repository and outbox
+	patientService := services.NewPatientService(mockRepo, newTransactions(mockRepo, outbox), duplicatesConfig)
+
+	// The age follows the new birth date
+	require.NoError(t, patientService.Update(context.Background(), patient))
+	assert.Equal(t, 40, patient.Age)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,224 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/models"
+	"hospital-project/internal/pagination"
+	"hospital-project/internal/services"
+)
+
+// fakeSavedSearchRepository keeps saved searches and the pins of each user in
+// memory
+type fakeSavedSearchRepository struct {
+	searches map[uint]*models.SavedSearch
+	pins     map[[2]uint]bool
+}
+
+func newFakeSavedSearchRepository(searches ...*models.SavedSearch) *fakeSavedSearchRepository {
+	f := &fakeSavedSearchRepository{searches: map[uint]*models.SavedSearch{}, pins: map[[2]uint]bool{}}
+	for _, search := range searches {
+		f.searches[search.ID] = search
+	}
+	return f
+}
+
+func (f *fakeSavedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	search.ID = uint(len(f.searches) + 1)
+	f.searches[search.ID] = search
+	return nil
+}
+
+func (f *fakeSavedSearchRepository) FindByID(ctx context.Context, id, userID uint) (*models.SavedSearch, error) {
+	search, ok := f.searches[id]
+	if !ok {
+		return nil, apperror.NotFound(apperror.CodeSavedSearchNotFound, "saved search not found")
+	}
+	found := *search
+	found.Pinned = f.pins[[2]uint{userID, id}]
+	return &found, nil
+}
+
+func (f *fakeSavedSearchRepository) Update(ctx context.Context, search *models.SavedSearch) error {
+	f.searches[search.ID] = search
+	return nil
+}
+
+func (f *fakeSavedSearchRepository) Delete(ctx context.Context, id uint) error {
+	delete(f.searches, id)
+	return nil
+}
+
+func (f *fakeSavedSearchRepository) ListVisible(ctx context.Context, userID uint, role models.Role, pinnedOnly bool, request pagination.Request) (*pagination.Page[models.SavedSearch], error) {
+	page := &pagination.Page[models.SavedSearch]{Number: request.Number, Limit: request.Limit}
+	for _, search := range f.searches {
+		pinned := f.pins[[2]uint{userID, search.ID}]
+		if search.VisibleTo(userID, role) && (pinned || !pinnedOnly) {
+			found := *search
+			found.Pinned = pinned
+			page.Items = append(page.Items, found)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeSavedSearchRepository) SetPinned(ctx context.Context, id, userID uint, pinned bool) error {
+	if pinned {
+		f.pins[[2]uint{userID, id}] = true
+	} else {
+		delete(f.pins, [2]uint{userID, id})
+	}
+	return nil
+}
+
+// wardRound is a worklist owned by user 1 and shared with doctors
+func wardRound() *models.SavedSearch {
+	return &models.SavedSearch{
+		Model:      gorm.Model{ID: 1},
+		OwnerID:    1,
+		Name:       "Ward round",
+		Search:     models.PatientSearchRequest{Filter: "age >= 65"},
+		Sort:       "-age",
+		SharedWith: models.RoleDoctor,
+	}
+}
+
+func TestSavedSearchService_Create(t *testing.T) {
+	repo := newFakeSavedSearchRepository()
+	savedSearchService := services.NewSavedSearchService(repo, new(MockPatientService))
+
+	search, err := savedSearchService.Create(context.Background(), models.SavedSearchRequest{
+		Name:   "  Elderly  ",
+		Search: models.PatientSearchRequest{Gender: models.GenderFemale, Filter: "age >= 65"},
+		Sort:   "name",
+	}, 4)
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(4), search.OwnerID)
+	assert.Equal(t, "Elderly", search.Name)
+	assert.Equal(t, "age >= 65", search.Search.Filter)
+	assert.False(t, search.Pinned)
+	assert.Empty(t, search.SharedWith)
+}
+
+func TestSavedSearchService_Create_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		request models.SavedSearchRequest
+		code    string
+	}{
+		{"blank name", models.SavedSearchRequest{Name: "  "}, apperror.CodeValidationFailed},
+		{"invalid filter", models.SavedSearchRequest{Name: "Heavy", Search: models.PatientSearchRequest{Filter: "weight > 80"}}, apperror.CodeInvalidFilter},
+		{"invalid sort", models.SavedSearchRequest{Name: "By notes", Sort: "medical_notes"}, apperror.CodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeSavedSearchRepository()
+			savedSearchService := services.NewSavedSearchService(repo, new(MockPatientService))
+
+			_, err := savedSearchService.Create(context.Background(), tt.request, 4)
+
+			appErr, ok := apperror.As(err)
+			require.True(t, ok)
+			assert.Equal(t, apperror.KindValidation, appErr.Kind)
+			assert.Equal(t, tt.code, appErr.Code)
+			assert.Empty(t, repo.searches)
+		})
+	}
+}
+
+func TestSavedSearchService_Run(t *testing.T) {
+	mockPatients := new(MockPatientService)
+	search := wardRound()
+	savedSearchService := services.NewSavedSearchService(newFakeSavedSearchRepository(search), mockPatients)
+
+	total := int64(1)
+	results := &pagination.Page[models.PatientMatch]{Items: []models.PatientMatch{{Patient: models.Patient{Name: "John Doe"}}}, Total: &total}
+	request, _ := pagination.NewRequest(1, 10, "", false)
+	counted := request
+	counted.IncludeTotal = true
+	mockPatients.On("Search", search.Search, []pagination.SortKey{{Field: "age", Descending: true}}, counted).Return(results, nil)
+
+	// Another doctor runs the worklist shared with doctors
+	page, err := savedSearchService.Run(context.Background(), 1, 2, models.RoleDoctor, request)
+
+	require.NoError(t, err)
+	assert.Equal(t, results, page)
+	mockPatients.AssertExpectations(t)
+}
+
+func TestSavedSearchService_Run_NotShared(t *testing.T) {
+	mockPatients := new(MockPatientService)
+	savedSearchService := services.NewSavedSearchService(newFakeSavedSearchRepository(wardRound()), mockPatients)
+
+	_, err := savedSearchService.Run(context.Background(), 1, 3, models.RoleReceptionist, pagination.Request{Number: 1, Limit: 10})
+
+	assert.True(t, apperror.IsNotFound(err))
+	mockPatients.AssertNotCalled(t, "Search")
+}
+
+func TestSavedSearchService_Update_OnlyOwner(t *testing.T) {
+	repo := newFakeSavedSearchRepository(wardRound())
+	savedSearchService := services.NewSavedSearchService(repo, new(MockPatientService))
+	request := models.SavedSearchRequest{Name: "Ward round", Search: models.PatientSearchRequest{Filter: "age >= 70"}}
+
+	// Users the search is shared with may run it but not change it
+	_, err := savedSearchService.Update(context.Background(), 1, request, 2, models.RoleDoctor)
+	assert.Equal(t, apperror.KindForbidden, apperror.KindOf(err))
+	assert.True(t, apperror.IsNotFound(savedSearchService.Delete(context.Background(), 1, 3, models.RoleReceptionist)))
+	assert.Equal(t, "age >= 65", repo.searches[1].Search.Filter)
+
+	search, err := savedSearchService.Update(context.Background(), 1, request, 1, models.RoleDoctor)
+	require.NoError(t, err)
+	assert.Equal(t, "age >= 70", search.Search.Filter)
+	assert.Empty(t, search.SharedWith)
+
+	require.NoError(t, savedSearchService.Delete(context.Background(), 1, 1, models.RoleDoctor))
+	assert.Empty(t, repo.searches)
+}
+
+func TestSavedSearchService_List(t *testing.T) {
+	own := &models.SavedSearch{Model: gorm.Model{ID: 2}, OwnerID: 2, Name: "Mine"}
+	other := &models.SavedSearch{Model: gorm.Model{ID: 3}, OwnerID: 3, Name: "Private"}
+	repo := newFakeSavedSearchRepository(wardRound(), own, other)
+	repo.pins[[2]uint{2, 1}] = true
+	repo.pins[[2]uint{3, 3}] = true
+	savedSearchService := services.NewSavedSearchService(repo, new(MockPatientService))
+
+	page, err := savedSearchService.List(context.Background(), 2, models.RoleDoctor, true, pagination.Request{Number: 1, Limit: 10})
+
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "Ward round", page.Items[0].Name)
+}
+
+func TestSavedSearchService_SetPinned(t *testing.T) {
+	repo := newFakeSavedSearchRepository(wardRound())
+	savedSearchService := services.NewSavedSearchService(repo, new(MockPatientService))
+
+	// A doctor the search is shared with pins it for themselves only
+	search, err := savedSearchService.SetPinned(context.Background(), 1, 2, models.RoleDoctor, true)
+	require.NoError(t, err)
+	assert.True(t, search.Pinned)
+
+	owned, err := savedSearchService.GetByID(context.Background(), 1, 1, models.RoleDoctor)
+	require.NoError(t, err)
+	assert.False(t, owned.Pinned)
+
+	search, err = savedSearchService.SetPinned(context.Background(), 1, 2, models.RoleDoctor, false)
+	require.NoError(t, err)
+	assert.False(t, search.Pinned)
+	assert.Empty(t, repo.pins)
+
+	// Users who cannot see the search cannot pin it
+	_, err = savedSearchService.SetPinned(context.Background(), 1, 3, models.RoleReceptionist, true)
+	assert.True(t, apperror.IsNotFound(err))
+	assert.Empty(t, repo.pins)
+}
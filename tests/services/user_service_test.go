@@ -1,6 +1,7 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -17,7 +18,7 @@ type MockAuthService struct {
 	mock.Mock
 }
 
-func (m *MockAuthService) Login(request models.LoginRequest) (*models.LoginResponse, error) {
+func (m *MockAuthService) Login(ctx context.Context, request models.LoginRequest) (*models.LoginResponse, error) {
 	args := m.Called(request)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -48,7 +49,7 @@ func (m *MockAuthService) ValidateToken(tokenString string) (*jwt.Token, error)
 	return args.Get(0).(*jwt.Token), args.Error(1)
 }
 
-func (m *MockAuthService) GetUserFromToken(token *jwt.Token) (*models.User, error) {
+func (m *MockAuthService) GetUserFromToken(ctx context.Context, token *jwt.Token) (*models.User, error) {
 	args := m.Called(token)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -70,7 +71,7 @@ func TestUserService_Create_Success(t *testing.T) {
 	userService := services.NewUserService(mockUserRepo, mockAuthService)
 
 	// Call the method being tested
-	user, err := userService.Create("testuser", "password123", models.RoleReceptionist)
+	user, err := userService.Create(context.Background(), "testuser", "password123", models.RoleReceptionist)
 
 	// Assert expectations
 	assert.NoError(t, err)
@@ -102,7 +103,7 @@ func TestUserService_Create_UsernameExists(t *testing.T) {
 	userService := services.NewUserService(mockUserRepo, mockAuthService)
 
 	// Call the method being tested
-	user, err := userService.Create("testuser", "password123", models.RoleReceptionist)
+	user, err := userService.Create(context.Background(), "testuser", "password123", models.RoleReceptionist)
 
 	// Assert expectations
 	assert.Error(t, err)
@@ -132,7 +133,7 @@ func TestUserService_GetByID_Success(t *testing.T) {
 	userService := services.NewUserService(mockUserRepo, mockAuthService)
 
 	// Call the method being tested
-	result, err := userService.GetByID(1)
+	result, err := userService.GetByID(context.Background(), 1)
 
 	// Assert expectations
 	assert.NoError(t, err)
@@ -156,7 +157,7 @@ func TestUserService_GetByID_NotFound(t *testing.T) {
 	userService := services.NewUserService(mockUserRepo, mockAuthService)
 
 	// Call the method being tested
-	result, err := userService.GetByID(1)
+	result, err := userService.GetByID(context.Background(), 1)
 
 	// Assert expectations
 	assert.Error(t, err)
@@ -191,7 +192,7 @@ func TestUserService_List_Success(t *testing.T) {
 	userService := services.NewUserService(mockUserRepo, mockAuthService)
 
 	// Call the method being tested
-	result, err := userService.List()
+	result, err := userService.List(context.Background())
 
 	// Assert expectations
 	assert.NoError(t, err)
@@ -0,0 +1,449 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hospital-project/internal/apperror"
+	"hospital-project/internal/config"
+	"hospital-project/internal/events"
+	"hospital-project/internal/models"
+	"hospital-project/internal/services"
+)
+
+// MockWebhookRepository is a mock implementation of the WebhookRepository interface
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	args := m.Called(subscription)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) FindSubscriptionByID(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	args := m.Called(subscription)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) DeleteSubscription(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	args := m.Called()
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	args := m.Called()
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) DeliveryExists(ctx context.Context, subscriptionID uint, eventID string) (bool, error) {
+	args := m.Called(subscriptionID, eventID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) FindDeliveryByID(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uint, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	args := m.Called(subscriptionID, page, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockWebhookRepository) ClaimDueDeliveries(ctx context.Context, now, claimedUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(now, claimedUntil, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func testWebhookConfig() *config.Webhook {
+	return &config.Webhook{
+		PollInterval: time.Second,
+		Timeout:      time.Second,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Minute,
+		MaxBackoff:   time.Hour,
+		DisableAfter: 2,
+		// Test endpoints are not resolved, and test servers listen on loopback
+		AllowedHosts: []string{"example.com", "127.0.0.1"},
+	}
+}
+
+func TestWebhookService_CreateSubscription_GeneratesSecret(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+
+	mockRepo.On("CreateSubscription", mock.AnythingOfType("*models.WebhookSubscription")).Return(nil)
+
+	subscription, err := webhookService.CreateSubscription(context.Background(), models.CreateWebhookRequest{
+		URL:    "https://example.com/hooks",
+		Events: []models.PatientEvent{models.PatientEventCreated},
+	}, 1)
+
+	require.NoError(t, err)
+	assert.Len(t, subscription.Secret, 64)
+	assert.True(t, subscription.Active)
+	assert.Equal(t, "patient.created", subscription.Events)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWebhookService_CreateSubscription_Invalid(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+
+	_, err := webhookService.CreateSubscription(context.Background(), models.CreateWebhookRequest{
+		URL:    "ftp://example.com/hooks",
+		Events: []models.PatientEvent{models.PatientEventCreated},
+	}, 1)
+	assert.Error(t, err)
+
+	_, err = webhookService.CreateSubscription(context.Background(), models.CreateWebhookRequest{
+		URL:    "https://example.com/hooks",
+		Events: []models.PatientEvent{"patient.exploded"},
+	}, 1)
+	assert.Error(t, err)
+
+	mockRepo.AssertNotCalled(t, "CreateSubscription", mock.Anything)
+}
+
+func TestWebhookService_CreateSubscription_InternalAddress(t *testing.T) {
+	cfg := testWebhookConfig()
+	cfg.AllowedHosts = nil
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, cfg)
+
+	for _, endpoint := range []string{
+		"http://127.0.0.1:8080/hooks",
+		"http://localhost/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hooks",
+		"http://192.168.1.20/hooks",
+		"http://[::1]/hooks",
+		"http://0.0.0.0/hooks",
+	} {
+		_, err := webhookService.CreateSubscription(context.Background(), models.CreateWebhookRequest{
+			URL:    endpoint,
+			Events: []models.PatientEvent{models.PatientEventCreated},
+		}, 1)
+
+		appErr, ok := apperror.As(err)
+		require.True(t, ok, endpoint)
+		assert.Equal(t, apperror.KindValidation, appErr.Kind, endpoint)
+	}
+	mockRepo.AssertNotCalled(t, "CreateSubscription", mock.Anything)
+}
+
+func TestWebhookService_HandleEvent_QueuesRedactedPayload(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+
+	subscribed := models.WebhookSubscription{Events: "patient.created,patient.updated", Active: true}
+	subscribed.ID = 1
+	other := models.WebhookSubscription{Events: "patient.deleted", Active: true}
+	other.ID = 2
+	mockRepo.On("ListActiveSubscriptions").Return([]models.WebhookSubscription{subscribed, other}, nil)
+	mockRepo.On("DeliveryExists", uint(1), "42").Return(false, nil)
+
+	var queued []*models.WebhookDelivery
+	mockRepo.On("CreateDelivery", mock.AnythingOfType("*models.WebhookDelivery")).
+		Run(func(args mock.Arguments) { queued = append(queued, args.Get(0).(*models.WebhookDelivery)) }).
+		Return(nil)
+
+	err := webhookService.HandleEvent(context.Background(), events.Event{
+		ID:        42,
+		Type:      events.PatientRegistered,
+		PatientID: 7,
+		Patient:   models.PatientResponse{ID: 7, Name: "John Doe", MedicalNotes: "confidential"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, queued, 1)
+	assert.Equal(t, uint(1), queued[0].SubscriptionID)
+	assert.Equal(t, "42", queued[0].EventID)
+	assert.Equal(t, models.WebhookDeliveryPending, queued[0].Status)
+	assert.NotContains(t, queued[0].Payload, "confidential")
+
+	var payload models.WebhookPayload
+	require.NoError(t, json.Unmarshal([]byte(queued[0].Payload), &payload))
+	assert.Equal(t, models.PatientEventCreated, payload.Event)
+	assert.Equal(t, "42", payload.ID)
+	assert.Equal(t, uint(7), payload.Data.ID)
+}
+
+func TestWebhookService_HandleEvent_SkipsAlreadyQueued(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+
+	subscription := models.WebhookSubscription{Events: "patient.deleted", Active: true}
+	subscription.ID = 1
+	mockRepo.On("ListActiveSubscriptions").Return([]models.WebhookSubscription{subscription}, nil)
+	mockRepo.On("DeliveryExists", uint(1), "42").Return(true, nil)
+
+	// The outbox redelivers events, which must not be sent twice
+	err := webhookService.HandleEvent(context.Background(), events.Event{ID: 42, Type: events.PatientDeleted, PatientID: 7})
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "CreateDelivery", mock.Anything)
+}
+
+func TestWebhookService_ProcessDue_SignsAndDelivers(t *testing.T) {
+	secret := "0123456789abcdef"
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: secret, Active: true, ConsecutiveFailures: 1}
+	subscription.ID = 1
+	delivery := models.WebhookDelivery{
+		SubscriptionID: 1,
+		EventID:        "abc",
+		Event:          models.PatientEventUpdated,
+		Payload:        `{"id":"abc"}`,
+		Status:         models.WebhookDeliveryPending,
+	}
+
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 50).Return([]models.WebhookDelivery{delivery}, nil)
+	mockRepo.On("FindSubscriptionByID", uint(1)).Return(subscription, nil)
+	mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Status == models.WebhookDeliverySucceeded && d.Attempts == 1 && d.ResponseStatus == http.StatusNoContent
+	})).Return(nil)
+	mockRepo.On("UpdateSubscription", mock.MatchedBy(func(s *models.WebhookSubscription) bool {
+		return s.ConsecutiveFailures == 0
+	})).Return(nil)
+
+	require.NoError(t, webhookService.ProcessDue(context.Background()))
+
+	require.NotNil(t, received)
+	assert.Equal(t, `{"id":"abc"}`, string(body))
+	assert.Equal(t, "abc", received.Header.Get("X-Webhook-ID"))
+	assert.Equal(t, "patient.updated", received.Header.Get("X-Webhook-Event"))
+
+	// Verify the signature the way a receiver would
+	parts := strings.Split(received.Header.Get("X-Webhook-Signature"), ",")
+	require.Len(t, parts, 2)
+	timestamp := strings.TrimPrefix(parts[0], "t=")
+	assert.Equal(t, "v1="+services.Sign(secret, timestamp, body), parts[1])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWebhookService_ProcessDue_RetriesWithBackoffAndDisables(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: "secret", Active: true, ConsecutiveFailures: 1}
+	subscription.ID = 1
+	delivery := models.WebhookDelivery{SubscriptionID: 1, Payload: "{}", Status: models.WebhookDeliveryPending, Attempts: 1}
+
+	var saved *models.WebhookDelivery
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 50).Return([]models.WebhookDelivery{delivery}, nil)
+	mockRepo.On("FindSubscriptionByID", uint(1)).Return(subscription, nil)
+	mockRepo.On("UpdateDelivery", mock.AnythingOfType("*models.WebhookDelivery")).
+		Run(func(args mock.Arguments) { saved = args.Get(0).(*models.WebhookDelivery) }).
+		Return(nil)
+	mockRepo.On("UpdateSubscription", subscription).Return(nil)
+
+	before := time.Now()
+	require.NoError(t, webhookService.ProcessDue(context.Background()))
+
+	// Second attempt failed: still pending, retried after twice the base backoff
+	require.NotNil(t, saved)
+	assert.Equal(t, models.WebhookDeliveryPending, saved.Status)
+	assert.Equal(t, 2, saved.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, saved.ResponseStatus)
+	assert.WithinDuration(t, before.Add(2*time.Minute), saved.NextAttemptAt, 5*time.Second)
+
+	// Second consecutive failure reaches the disable threshold
+	assert.False(t, subscription.Active)
+	assert.NotNil(t, subscription.DisabledAt)
+	assert.Equal(t, 2, subscription.ConsecutiveFailures)
+}
+
+func TestWebhookService_ProcessDue_GivesUpAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockWebhookRepository)
+	cfg := testWebhookConfig()
+	cfg.DisableAfter = 100
+	webhookService := services.NewWebhookService(mockRepo, cfg)
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: "secret", Active: true}
+	subscription.ID = 1
+	delivery := models.WebhookDelivery{SubscriptionID: 1, Payload: "{}", Status: models.WebhookDeliveryPending, Attempts: 2}
+
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 50).Return([]models.WebhookDelivery{delivery}, nil)
+	mockRepo.On("FindSubscriptionByID", uint(1)).Return(subscription, nil)
+	mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Status == models.WebhookDeliveryFailed && d.Attempts == 3
+	})).Return(nil)
+	mockRepo.On("UpdateSubscription", subscription).Return(nil)
+
+	require.NoError(t, webhookService.ProcessDue(context.Background()))
+	assert.True(t, subscription.Active)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWebhookService_ProcessDue_RefusesInternalAddress(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// The endpoint was registered at a public address and now resolves to
+	// loopback, which is only checked when connecting
+	cfg := testWebhookConfig()
+	cfg.AllowedHosts = nil
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, cfg)
+
+	subscription := &models.WebhookSubscription{URL: server.URL, Secret: "secret", Active: true}
+	subscription.ID = 1
+	delivery := models.WebhookDelivery{SubscriptionID: 1, Payload: "{}", Status: models.WebhookDeliveryPending}
+
+	var saved *models.WebhookDelivery
+	mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 50).Return([]models.WebhookDelivery{delivery}, nil)
+	mockRepo.On("FindSubscriptionByID", uint(1)).Return(subscription, nil)
+	mockRepo.On("UpdateDelivery", mock.AnythingOfType("*models.WebhookDelivery")).
+		Run(func(args mock.Arguments) { saved = args.Get(0).(*models.WebhookDelivery) }).
+		Return(nil)
+	mockRepo.On("UpdateSubscription", subscription).Return(nil)
+
+	require.NoError(t, webhookService.ProcessDue(context.Background()))
+
+	assert.False(t, called)
+	require.NotNil(t, saved)
+	assert.Equal(t, models.WebhookDeliveryPending, saved.Status)
+	assert.Contains(t, saved.LastError, "loopback")
+}
+
+func TestWebhookService_ProcessDue_SubscriptionLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status models.WebhookDeliveryStatus
+	}{
+		// A deleted subscription will never take the delivery
+		{"deleted", apperror.NotFound(apperror.CodeWebhookNotFound, "webhook not found"), models.WebhookDeliveryFailed},
+		// but a database outage is no reason to give up
+		{"unavailable", errors.New("connection refused"), models.WebhookDeliveryPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockWebhookRepository)
+			webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+			delivery := models.WebhookDelivery{SubscriptionID: 1, Payload: "{}", Status: models.WebhookDeliveryPending, Attempts: 1}
+
+			var saved *models.WebhookDelivery
+			mockRepo.On("ClaimDueDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 50).Return([]models.WebhookDelivery{delivery}, nil)
+			mockRepo.On("FindSubscriptionByID", uint(1)).Return(nil, tt.err)
+			mockRepo.On("UpdateDelivery", mock.AnythingOfType("*models.WebhookDelivery")).
+				Run(func(args mock.Arguments) { saved = args.Get(0).(*models.WebhookDelivery) }).
+				Return(nil)
+
+			before := time.Now()
+			require.NoError(t, webhookService.ProcessDue(context.Background()))
+
+			require.NotNil(t, saved)
+			assert.Equal(t, tt.status, saved.Status)
+			assert.Equal(t, 1, saved.Attempts)
+			if tt.status == models.WebhookDeliveryPending {
+				assert.WithinDuration(t, before, saved.NextAttemptAt, 5*time.Second)
+			}
+		})
+	}
+}
+
+func TestWebhookService_ReplayDelivery(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+
+	delivery := &models.WebhookDelivery{SubscriptionID: 1, Status: models.WebhookDeliveryFailed, Attempts: 3, LastError: "boom"}
+	delivery.ID = 9
+	mockRepo.On("FindDeliveryByID", uint(9)).Return(delivery, nil)
+	mockRepo.On("UpdateDelivery", delivery).Return(nil)
+
+	replayed, err := webhookService.ReplayDelivery(context.Background(), 1, 9)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookDeliveryPending, replayed.Status)
+	assert.Equal(t, 0, replayed.Attempts)
+	assert.Empty(t, replayed.LastError)
+
+	// A delivery can only be replayed through its own subscription
+	_, err = webhookService.ReplayDelivery(context.Background(), 2, 9)
+	assert.Error(t, err)
+}
+
+func TestWebhookService_UpdateSubscription_ReEnable(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	webhookService := services.NewWebhookService(mockRepo, testWebhookConfig())
+
+	disabledAt := time.Now()
+	subscription := &models.WebhookSubscription{
+		URL:                 "https://example.com/hooks",
+		Events:              "patient.created",
+		ConsecutiveFailures: 20,
+		DisabledAt:          &disabledAt,
+	}
+	mockRepo.On("FindSubscriptionByID", uint(1)).Return(subscription, nil)
+	mockRepo.On("UpdateSubscription", subscription).Return(nil)
+
+	active := true
+	updated, err := webhookService.UpdateSubscription(context.Background(), 1, models.UpdateWebhookRequest{Active: &active})
+	require.NoError(t, err)
+	assert.True(t, updated.Active)
+	assert.Zero(t, updated.ConsecutiveFailures)
+	assert.Nil(t, updated.DisabledAt)
+}
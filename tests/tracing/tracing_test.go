@@ -0,0 +1,118 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"hospital-project/internal/config"
+	"hospital-project/internal/models"
+	"hospital-project/internal/tracing"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// record installs a tracer provider that keeps finished spans in memory
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+// dryRunDB returns a GORM connection that builds statements without running them
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=unused"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(tracing.GORMPlugin{}))
+	return db
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	values := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestGORMPlugin_RecordsStatementWithoutValues(t *testing.T) {
+	recorder := record(t)
+	db := dryRunDB(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	var patients []models.Patient
+	db.WithContext(ctx).Where("name = ?", "John Doe").Find(&patients)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	query := spans[0]
+	assert.Equal(t, "gorm.query", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+
+	attrs := attributes(query)
+	assert.Equal(t, "patients", attrs["db.sql.table"].AsString())
+	assert.Contains(t, attrs["db.statement"].AsString(), "name = $1")
+	assert.NotContains(t, attrs["db.statement"].AsString(), "John Doe")
+}
+
+func TestGORMPlugin_SkipsUntracedQueries(t *testing.T) {
+	recorder := record(t)
+	db := dryRunDB(t)
+
+	var patients []models.Patient
+	db.WithContext(context.Background()).Find(&patients)
+
+	assert.Empty(t, recorder.Ended())
+}
+
+func TestMiddleware_ContinuesInboundTraceWithRole(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), &config.Tracing{Exporter: config.TracesExporterNone})
+	require.NoError(t, err)
+	recorder := record(t)
+
+	router := gin.New()
+	router.Use(otelgin.Middleware("hospital-project"))
+	router.GET("/api/patients/:id", func(c *gin.Context) {
+		tracing.SetRole(c.Request.Context(), string(models.RoleDoctor))
+		c.Status(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/api/patients/42", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+
+	attrs := attributes(span)
+	assert.Equal(t, "doctor", attrs[tracing.RoleKey].AsString())
+	assert.Equal(t, "/api/patients/:id", attrs["http.route"].AsString())
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), &config.Tracing{Exporter: "zipkin"})
+	assert.Error(t, err)
+}